@@ -0,0 +1,110 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+	"github.com/tidwall/pair-rtree/rtreetest"
+)
+
+func TestAnnotate(t *testing.T) {
+	// count the items under every node
+	opts := *DefaultOptions
+	opts.Annotate = func(items []pair.Pair, children []interface{}) interface{} {
+		n := len(items)
+		for _, child := range children {
+			n += child.(int)
+		}
+		return n
+	}
+	tr := New(&opts)
+	var items []pair.Pair
+	for i := 0; i < 2000; i++ {
+		item := makeRandom("point")
+		tr.Insert(item)
+		items = append(items, item)
+	}
+	for _, item := range items[:500] {
+		tr.Remove(item)
+	}
+	var root int
+	tr.TraverseAnnotations(func(min, max [2]float64, level int, annotation interface{}) bool {
+		root = annotation.(int)
+		return false
+	})
+	assert.Equal(t, 1500, root)
+
+	box := makeBoundsPair2("", -100, -50, 100, 50)
+	var count, calls int
+	tr.Aggregate(box, func(annotation interface{}, item pair.Pair) bool {
+		if item.Zero() {
+			count += annotation.(int)
+		} else {
+			count++
+		}
+		calls++
+		return true
+	})
+	var expect int
+	tr.Search(box, func(item pair.Pair) bool {
+		expect++
+		return true
+	})
+	assert.Equal(t, expect, count)
+	assert.True(t, calls < expect)
+}
+
+func TestScanAnnotated(t *testing.T) {
+	// each node is annotated with the largest x of its items
+	var tr *RTree
+	tr = New(&Options{
+		MaxEntries: 9,
+		Annotate: func(items []pair.Pair, children []interface{}) interface{} {
+			x := mathInfNeg
+			for _, item := range items {
+				min, _ := tr.rect(item.Value())
+				x = mathMax(x, min[0])
+			}
+			for _, c := range children {
+				x = mathMax(x, c.(float64))
+			}
+			return x
+		},
+	})
+	var items, expect []pair.Pair
+	for i := 0; i < 5000; i++ {
+		items = append(items, makeRandom("point"))
+		tr.Insert(items[i])
+		if min, _ := tr.rect(items[i].Value()); min[0] >= 150 {
+			expect = append(expect, items[i])
+		}
+	}
+	var got []pair.Pair
+	var read int
+	tr.ScanAnnotated(func(annotation interface{}) bool {
+		return annotation.(float64) >= 150
+	}, func(item pair.Pair) bool {
+		read++
+		if min, _ := tr.rect(item.Value()); min[0] >= 150 {
+			got = append(got, item)
+		}
+		return true
+	})
+	assert.True(t, rtreetest.SameItems(expect, got))
+	assert.True(t, read < len(items)/2)
+
+	// nothing is skipped without annotations
+	tr = New(nil)
+	for _, item := range items {
+		tr.Insert(item)
+	}
+	read = 0
+	tr.ScanAnnotated(func(annotation interface{}) bool {
+		return false
+	}, func(item pair.Pair) bool {
+		read++
+		return true
+	})
+	assert.Equal(t, len(items), read)
+}
@@ -0,0 +1,49 @@
+package rtree
+
+import "math/bits"
+
+// b2u converts a bool to a 0 or 1 without a branch. On amd64 the compiler
+// turns this pattern into a SETcc instruction.
+func b2u(b bool) uint64 {
+	var u uint64
+	if b {
+		u = 1
+	}
+	return u
+}
+
+// intersectsMask tests up to 64 child nodes against the bbox at once, and
+// returns a mask with a bit set for each child that intersects. There are
+// no branches on the comparisons, so the loads of the children can all be in
+// flight together rather than waiting on each mispredicted test. This is
+// scalar Go, there is no assembly.
+func (tr *RTree) intersectsMask(bbox *treeNode, children []int32) uint64 {
+	var mask uint64
+	for i, index := range children {
+		child := tr.node(index)
+		hit := b2u(child.minX <= bbox.maxX) & b2u(child.minY <= bbox.maxY) &
+			b2u(child.maxX >= bbox.minX) & b2u(child.maxY >= bbox.minY)
+		mask |= hit << uint(i)
+	}
+	return mask
+}
+
+// searchChildren calls fn for each child node that intersects the bbox, in
+// order, using intersectsMask on batches of 64 children.
+func (tr *RTree) searchChildren(node, bbox *treeNode, fn func(child *treeNode) bool) bool {
+	for start := 0; start < len(node.children); start += 64 {
+		end := start + 64
+		if end > len(node.children) {
+			end = len(node.children)
+		}
+		mask := tr.intersectsMask(bbox, node.children[start:end])
+		for mask != 0 {
+			i := bits.TrailingZeros64(mask)
+			mask &= mask - 1
+			if !fn(tr.node(node.children[start+i])) {
+				return false
+			}
+		}
+	}
+	return true
+}
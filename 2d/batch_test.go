@@ -0,0 +1,55 @@
+package rtree
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+	"github.com/tidwall/pair-rtree/rtreetest"
+)
+
+func TestIntersectsMask(t *testing.T) {
+	tr := New(nil)
+	var children []int32
+	for i := 0; i < 64; i++ {
+		child := tr.newNode()
+		fillBBox(makeRandom("rect"), child, tr.rect)
+		children = append(children, child.index)
+	}
+	for i := 0; i < 100; i++ {
+		var bbox treeNode
+		fillBBox(makeRandom("rect"), &bbox, tr.rect)
+		bbox.maxX += rand.Float64() * 100
+		mask := tr.intersectsMask(&bbox, children)
+		for j, index := range children {
+			assert.Equal(t, bbox.intersects(tr.node(index)), mask&(1<<uint(j)) != 0)
+		}
+	}
+}
+
+func TestSearchWideNodes(t *testing.T) {
+	// more than 64 children per node
+	opts := *DefaultOptions
+	opts.MaxEntries = 200
+	tr := New(&opts)
+	var objs []pair.Pair
+	for i := 0; i < 20000; i++ {
+		objs = append(objs, makeRandom("rect"))
+		tr.Insert(objs[i])
+	}
+	assert.True(t, len(tr.data.children) > 64)
+	min, max := [2]float64{-90, -45}, [2]float64{90, 45}
+	var expect, got []pair.Pair
+	for _, obj := range objs {
+		omin, omax := tr.rect(obj.Value())
+		if omin[0] <= max[0] && omin[1] <= max[1] && omax[0] >= min[0] && omax[1] >= min[1] {
+			expect = append(expect, obj)
+		}
+	}
+	tr.SearchRect(min, max, func(item pair.Pair) bool {
+		got = append(got, item)
+		return true
+	})
+	assert.True(t, rtreetest.SameItems(expect, got))
+}
@@ -0,0 +1,40 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+	"github.com/tidwall/pair-rtree/rtreetest"
+)
+
+func TestClone(t *testing.T) {
+	tr := New(nil)
+	var items []pair.Pair
+	for i := 0; i < 1000; i++ {
+		item := makeRandom("rect")
+		items = append(items, item)
+		tr.Insert(item)
+	}
+	clone := tr.Clone()
+	for _, item := range items[:500] {
+		tr.Remove(item)
+	}
+	for i := 0; i < 500; i++ {
+		tr.Insert(makeRandom("rect"))
+	}
+	var got []pair.Pair
+	clone.Scan(func(item pair.Pair) bool {
+		got = append(got, item)
+		return true
+	})
+	assert.True(t, rtreetest.SameItems(items, got))
+	assert.Equal(t, 1000, tr.Count())
+
+	// the clone can be changed on its own
+	for _, item := range items[500:] {
+		clone.Remove(item)
+	}
+	assert.Equal(t, 500, clone.Count())
+	assert.Equal(t, 1000, tr.Count())
+}
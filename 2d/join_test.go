@@ -0,0 +1,53 @@
+package rtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+	"github.com/tidwall/pair-rtree/rtreetest"
+)
+
+func TestJoinWithin(t *testing.T) {
+	tr1, tr2 := New(nil), New(nil)
+	var items1, items2 []pair.Pair
+	for i := 0; i < 500; i++ {
+		items1 = append(items1, makeRandom("point"))
+		items2 = append(items2, makeRandom("rect"))
+		tr1.Insert(items1[i])
+		tr2.Insert(items2[i])
+	}
+	const maxDist = 5.0
+	expect := make(map[[2]string]float64)
+	for _, a := range items1 {
+		for _, b := range items2 {
+			min, _ := tr1.rect(a.Value())
+			dist := math.Sqrt(rtreetest.BoxDist(b, min[0], min[1], 0))
+			if dist <= maxDist {
+				expect[[2]string{string(a.Value()), string(b.Value())}] = dist
+			}
+		}
+	}
+	got := make(map[[2]string]float64)
+	assert.True(t, tr1.JoinWithin(tr2, maxDist, func(a, b pair.Pair, dist float64) bool {
+		got[[2]string{string(a.Value()), string(b.Value())}] = dist
+		return true
+	}))
+	assert.True(t, len(expect) > 0)
+	assert.Equal(t, len(expect), len(got))
+	for k, dist := range expect {
+		assert.InDelta(t, dist, got[k], 1e-9)
+	}
+
+	var n int
+	assert.False(t, tr1.JoinWithin(tr2, maxDist, func(a, b pair.Pair, dist float64) bool {
+		n++
+		return false
+	}))
+	assert.Equal(t, 1, n)
+	assert.True(t, New(nil).JoinWithin(tr2, maxDist, func(a, b pair.Pair, dist float64) bool {
+		t.Fatal("empty tree")
+		return true
+	}))
+}
@@ -1,55 +1,92 @@
 package rtree
 
 import (
+	"sync/atomic"
 	"unsafe"
 
-	"github.com/tidwall/geobin"
 	"github.com/tidwall/pair"
-	"github.com/tidwall/tinyqueue"
 )
 
-type queueItem struct {
-	node   unsafe.Pointer
-	isItem bool
-	dist   float64
+func (tr *RTree) KNN(x, y float64, iter func(item pair.Pair, dist float64) bool) bool {
+	return tr.knn(x, y, iter, nil, nil, nil)
 }
 
-func (item *queueItem) Less(b tinyqueue.Item) bool {
-	return item.dist < b.(*queueItem).dist
+// KNNAxes is like KNN, but the iterator also receives the x and y distances
+// from the point to the item.
+func (tr *RTree) KNNAxes(x, y float64,
+	iter func(item pair.Pair, dist float64, axes [2]float64) bool) bool {
+	return tr.KNN(x, y, func(item pair.Pair, dist float64) bool {
+		min, max := tr.rect(item.Value())
+		return iter(item, dist, [2]float64{
+			tr.xDist(x, min[0], max[0]),
+			axisDist(y, min[1], max[1]),
+		})
+	})
 }
 
-func (tr *RTree) KNN(x, y float64, iter func(item pair.Pair, dist float64) bool) bool {
+// KNNDebug is like KNN, but also calls visit for each node as it's popped
+// from the queue, starting with the root, which shows the regions that the
+// search expands into and why. The level of a node counts from one at the
+// leaves, as in Traverse, and its distance is a lower bound on the distances
+// of its items. Each node is visited before the items that it leads to.
+func (tr *RTree) KNNDebug(x, y float64, iter func(item pair.Pair, dist float64) bool,
+	visit func(level int, min, max [2]float64, dist float64)) bool {
+	return tr.knn(x, y, iter, func(node *treeNode, dist float64) {
+		visit(int(node.height), [2]float64{node.minX, node.minY}, [2]float64{node.maxX, node.maxY}, dist)
+	}, nil, nil)
+}
+
+// knn performs the KNN, calling visit for each node as it is popped from the
+// queue, starting with the root. Nodes and items that fail the filter, if
+// any, are skipped. The distances are measured with the metric, or are
+// squared when it's nil.
+func (tr *RTree) knn(x, y float64, iter func(item pair.Pair, dist float64) bool,
+	visit func(node *treeNode, dist float64),
+	filter func(min, max [2]float64, isItem bool) bool, metric Metric) bool {
+	atomic.AddUint64(&tr.stats.knns, 1)
 	node := tr.data
-	queue := tinyqueue.New(nil)
+	queue := getQueue()
+	defer putQueue(queue)
+	if visit != nil {
+		visit(node, tr.dist(metric, x, y, [2]float64{node.minX, node.minY}, [2]float64{node.maxX, node.maxY}))
+	}
 	for node != nil {
-		for _, child := range node.children {
+		for i := 0; i < node.len(); i++ {
+			var child unsafe.Pointer
 			var min, max [2]float64
 			if node.leaf {
-				item := pair.FromPointer(child)
-				omin, omax := geobin.WrapBinary(item.Value()).Rect(tr.t)
+				item := node.items[i]
+				omin, omax := tr.rect(item.Value())
 				min[0], min[1] = omin[0], omin[1]
 				max[0], max[1] = omax[0], omax[1]
+				child = item.Pointer()
 			} else {
-				node := (*treeNode)(child)
+				node := tr.node(node.children[i])
 				min[0], min[1] = node.minX, node.minY
 				max[0], max[1] = node.maxX, node.maxY
+				child = unsafe.Pointer(node)
 			}
-			queue.Push(&queueItem{
+			if filter != nil && !filter(min, max, node.leaf) {
+				continue
+			}
+			queue.push(queueItem{
 				node:   child,
 				isItem: node.leaf,
-				dist:   boxDist(x, y, min, max),
+				dist:   tr.dist(metric, x, y, min, max),
 			})
 		}
-		for queue.Len() > 0 && queue.Peek().(*queueItem).isItem {
-			item := queue.Pop().(*queueItem)
-			candidate := item.node
-			if !iter(pair.FromPointer(candidate), item.dist) {
+		for queue.len() > 0 && queue.peek().isItem {
+			item, _ := queue.pop()
+			if !iter(pair.FromPointer(item.node), item.dist) {
 				return false
 			}
 		}
-		last := queue.Pop()
-		if last != nil {
-			node = (*treeNode)(last.(*queueItem).node)
+		last, ok := queue.pop()
+		if ok {
+			node = (*treeNode)(last.node)
+			if visit != nil {
+				visit(node, last.dist)
+			}
 		} else {
 			node = nil
 		}
@@ -62,12 +99,9 @@ func boxDist(x, y float64, min, max [2]float64) float64 {
 	dy := axisDist(y, min[1], max[1])
 	return dx*dx + dy*dy
 }
+
+// axisDist returns the distance from k to the range. At most one of the
+// differences is positive, so the largest of them, or zero, is the distance.
 func axisDist(k, min, max float64) float64 {
-	if k < min {
-		return min - k
-	}
-	if k <= max {
-		return 0
-	}
-	return k - max
+	return mathMax(mathMax(min-k, k-max), 0)
 }
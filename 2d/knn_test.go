@@ -0,0 +1,44 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestKNNDebug(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 5000; i++ {
+		tr.Insert(makeRandom("rect"))
+	}
+	var expect []float64
+	tr.KNN(10, 20, func(item pair.Pair, dist float64) bool {
+		expect = append(expect, dist)
+		return len(expect) < 100
+	})
+	var got []float64
+	var levels []int
+	last := -1.0
+	tr.KNNDebug(10, 20, func(item pair.Pair, dist float64) bool {
+		assert.True(t, dist >= last)
+		got = append(got, dist)
+		return len(got) < 100
+	}, func(level int, min, max [2]float64, dist float64) {
+		assert.True(t, dist >= last)
+		assert.True(t, min[0] <= max[0] && min[1] <= max[1])
+		last = dist
+		levels = append(levels, level)
+	})
+	assert.Equal(t, expect, got)
+	assert.Equal(t, int(tr.data.height), levels[0])
+	// the search stops after 100 items, which may be after it pops a
+	// branch, but it has passed through leaves
+	var leaves int
+	for _, level := range levels {
+		if level == 1 {
+			leaves++
+		}
+	}
+	assert.True(t, leaves > 0)
+}
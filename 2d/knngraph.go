@@ -0,0 +1,113 @@
+package rtree
+
+import (
+	"unsafe"
+
+	"github.com/tidwall/pair"
+)
+
+// KNNGraph calls iter for every item with its k nearest items, nearest
+// first, which are the edges of the k-nearest-neighbor graph. The item
+// itself is not a neighbor. The items in each leaf share a single best-first
+// traversal of the tree, which stops once the rest of the tree is farther
+// than the kth neighbor of every item in the leaf. The neighbors slice is
+// only valid until iter returns.
+func (tr *RTree) KNNGraph(k int, iter func(item pair.Pair, neighbors []pair.Pair) bool) bool {
+	if k < 0 {
+		k = 0
+	}
+	return tr.knnGraph(tr.data, k, iter)
+}
+
+func (tr *RTree) knnGraph(node *treeNode, k int,
+	iter func(item pair.Pair, neighbors []pair.Pair) bool) bool {
+	if node.leaf {
+		return tr.leafNeighbors(node, k, iter)
+	}
+	for _, index := range node.children {
+		if !tr.knnGraph(tr.node(index), k, iter) {
+			return false
+		}
+	}
+	return true
+}
+
+// neighborList is a list of up to k items, nearest first.
+type neighborList struct {
+	items []pair.Pair
+	dists []float64
+}
+
+func (nl *neighborList) add(item pair.Pair, dist float64, k int) {
+	if len(nl.items) == k {
+		if k == 0 || dist >= nl.dists[k-1] {
+			return
+		}
+		nl.items, nl.dists = nl.items[:k-1], nl.dists[:k-1]
+	}
+	i := len(nl.dists)
+	for i > 0 && nl.dists[i-1] > dist {
+		i--
+	}
+	nl.items = append(nl.items, pair.Pair{})
+	nl.dists = append(nl.dists, 0)
+	copy(nl.items[i+1:], nl.items[i:])
+	copy(nl.dists[i+1:], nl.dists[i:])
+	nl.items[i], nl.dists[i] = item, dist
+}
+
+// leafNeighbors finds the neighbors of every item in the leaf.
+func (tr *RTree) leafNeighbors(leaf *treeNode, k int,
+	iter func(item pair.Pair, neighbors []pair.Pair) bool) bool {
+	boxes := make([]treeNode, len(leaf.items))
+	lists := make([]neighborList, len(leaf.items))
+	for i, item := range leaf.items {
+		fillBBox(item, &boxes[i], tr.rect)
+	}
+	// full returns true when every item has k neighbors that are no
+	// farther than dist.
+	full := func(dist float64) bool {
+		for i := range lists {
+			if len(lists[i].items) < k || lists[i].dists[k-1] > dist {
+				return false
+			}
+		}
+		return true
+	}
+	queue := getQueue()
+	defer putQueue(queue)
+	queue.push(queueItem{node: unsafe.Pointer(tr.data), dist: rectDist(leaf, tr.data)})
+	for queue.len() > 0 {
+		qi, _ := queue.pop()
+		if full(qi.dist) {
+			break
+		}
+		if qi.isItem {
+			item := pair.FromPointer(qi.node)
+			var bbox treeNode
+			fillBBox(item, &bbox, tr.rect)
+			for i, other := range leaf.items {
+				if other.Pointer() != qi.node {
+					lists[i].add(item, rectDist(&boxes[i], &bbox), k)
+				}
+			}
+			continue
+		}
+		node := (*treeNode)(qi.node)
+		for _, item := range node.items {
+			var bbox treeNode
+			fillBBox(item, &bbox, tr.rect)
+			queue.push(queueItem{node: item.Pointer(), isItem: true, dist: rectDist(leaf, &bbox)})
+		}
+		for _, index := range node.children {
+			child := tr.node(index)
+			queue.push(queueItem{node: unsafe.Pointer(child), dist: rectDist(leaf, child)})
+		}
+	}
+	for i, item := range leaf.items {
+		if !iter(item, lists[i].items) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package rtree
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/tidwall/pair"
+)
+
+// KNNK is like KNN for when the number of items, k, is known upfront. Once k
+// items are in the queue, the nodes and items that are farther than the k-th
+// nearest of them are not pushed, and the search stops after k items.
+func (tr *RTree) KNNK(x, y float64, k int, iter func(item pair.Pair, dist float64) bool) bool {
+	if k <= 0 {
+		return true
+	}
+	queue := getQueue()
+	defer putQueue(queue)
+	return tr.knnk(queue, make([]float64, 0, k), x, y, k, iter)
+}
+
+// knnk performs the KNNK with an empty queue, and with best for holding the
+// distances of the k nearest items that are pushed, in order, so the bound is
+// the last of them when it's full.
+func (tr *RTree) knnk(queue *knnQueue, best []float64, x, y float64, k int,
+	iter func(item pair.Pair, dist float64) bool) bool {
+	atomic.AddUint64(&tr.stats.knns, 1)
+	var n int
+	node := tr.data
+	for node != nil {
+		for i := 0; i < node.len(); i++ {
+			var child unsafe.Pointer
+			var min, max [2]float64
+			if node.leaf {
+				item := node.items[i]
+				omin, omax := tr.rect(item.Value())
+				min[0], min[1] = omin[0], omin[1]
+				max[0], max[1] = omax[0], omax[1]
+				child = item.Pointer()
+			} else {
+				node := tr.node(node.children[i])
+				min[0], min[1] = node.minX, node.minY
+				max[0], max[1] = node.maxX, node.maxY
+				child = unsafe.Pointer(node)
+			}
+			dist := tr.dist(nil, x, y, min, max)
+			if len(best) == k && dist > best[k-1] {
+				continue
+			}
+			if node.leaf {
+				best = insertBest(best, dist, k)
+			}
+			queue.push(queueItem{node: child, isItem: node.leaf, dist: dist})
+		}
+		for queue.len() > 0 && queue.peek().isItem {
+			item, _ := queue.pop()
+			if !iter(pair.FromPointer(item.node), item.dist) {
+				return false
+			}
+			n++
+			if n == k {
+				return true
+			}
+		}
+		node = nil
+		if last, ok := queue.pop(); ok && (len(best) < k || last.dist <= best[k-1]) {
+			node = (*treeNode)(last.node)
+		}
+	}
+	return true
+}
+
+// insertBest inserts the dist into the sorted distances, dropping the
+// farthest when there are k of them.
+func insertBest(best []float64, dist float64, k int) []float64 {
+	if len(best) < k {
+		best = append(best, 0)
+	}
+	i := len(best) - 1
+	for ; i > 0 && best[i-1] > dist; i-- {
+		best[i] = best[i-1]
+	}
+	best[i] = dist
+	return best
+}
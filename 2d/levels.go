@@ -0,0 +1,64 @@
+package rtree
+
+// BoundsAtLevel returns the bboxes of the nodes at a level, where leaves are
+// level one, which is the same level that's passed to Traverse. Each bbox is
+// the min followed by the max. Returns nil for a level that's not in the
+// tree.
+func (tr *RTree) BoundsAtLevel(level int) [][2][2]float64 {
+	var bounds [][2][2]float64
+	tr.SearchLevel(level, [2]float64{mathInfNeg, mathInfNeg},
+		[2]float64{mathInfPos, mathInfPos}, func(min, max [2]float64) bool {
+			bounds = append(bounds, [2][2]float64{min, max})
+			return true
+		})
+	return bounds
+}
+
+// SearchLevel iterates over the bboxes of the nodes at a level that
+// intersect the rect. Only the nodes above the level that intersect the
+// rect are visited.
+func (tr *RTree) SearchLevel(level int, min, max [2]float64,
+	iter func(min, max [2]float64) bool) bool {
+	if tr.data.len() == 0 || level < 1 || level > int(tr.data.height) {
+		return true
+	}
+	var bbox treeNode
+	bbox.minX, bbox.minY = min[0], min[1]
+	bbox.maxX, bbox.maxY = max[0], max[1]
+	if !tr.data.intersects(&bbox) {
+		return true
+	}
+	return tr.searchLevel(tr.data, &bbox, int8(level), iter)
+}
+
+func (tr *RTree) searchLevel(node, bbox *treeNode, level int8,
+	iter func(min, max [2]float64) bool) bool {
+	if node.height == level {
+		return iter([2]float64{node.minX, node.minY}, [2]float64{node.maxX, node.maxY})
+	}
+	return tr.searchChildren(node, bbox, func(child *treeNode) bool {
+		return tr.searchLevel(child, bbox, level, iter)
+	})
+}
+
+// Leaves iterates over the leaf nodes, passing the bbox and the number of
+// items of each, which summarizes the items without reading them.
+func (tr *RTree) Leaves(iter func(min, max [2]float64, count int) bool) bool {
+	if tr.data.len() == 0 {
+		return true
+	}
+	return tr.leaves(tr.data, iter)
+}
+
+func (tr *RTree) leaves(node *treeNode, iter func(min, max [2]float64, count int) bool) bool {
+	if node.leaf {
+		return iter([2]float64{node.minX, node.minY}, [2]float64{node.maxX, node.maxY},
+			len(node.items))
+	}
+	for _, index := range node.children {
+		if !tr.leaves(tr.node(index), iter) {
+			return false
+		}
+	}
+	return true
+}
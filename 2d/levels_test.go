@@ -0,0 +1,54 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestBoundsAtLevel(t *testing.T) {
+	tr := New(nil)
+	assert.Nil(t, tr.BoundsAtLevel(1))
+	for i := 0; i < 5000; i++ {
+		tr.Insert(makeRandom("rect"))
+	}
+	for level := 1; level <= int(tr.data.height); level++ {
+		var expect [][2][2]float64
+		tr.Traverse(func(min, max [2]float64, l int, item pair.Pair) bool {
+			if l == level {
+				expect = append(expect, [2][2]float64{min, max})
+			}
+			return true
+		})
+		assert.Equal(t, expect, tr.BoundsAtLevel(level))
+	}
+	assert.Nil(t, tr.BoundsAtLevel(int(tr.data.height)+1))
+	assert.Nil(t, tr.BoundsAtLevel(0))
+
+	// only the leaves that intersect
+	min, max := [2]float64{0, 0}, [2]float64{10, 10}
+	var n int
+	tr.SearchLevel(1, min, max, func(nmin, nmax [2]float64) bool {
+		assert.True(t, nmin[0] <= max[0] && nmin[1] <= max[1] &&
+			nmax[0] >= min[0] && nmax[1] >= min[1])
+		n++
+		return true
+	})
+	assert.True(t, n > 0 && n < len(tr.BoundsAtLevel(1)))
+}
+
+func TestLeaves(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 1000; i++ {
+		tr.Insert(makeRandom("point"))
+	}
+	var n, count int
+	tr.Leaves(func(min, max [2]float64, c int) bool {
+		n++
+		count += c
+		return true
+	})
+	assert.Equal(t, len(tr.BoundsAtLevel(1)), n)
+	assert.Equal(t, 1000, count)
+}
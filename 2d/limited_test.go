@@ -0,0 +1,69 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+	"github.com/tidwall/pair-rtree/rtreetest"
+)
+
+func TestSearchLimited(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 5000; i++ {
+		tr.Insert(makeRandom("rect"))
+	}
+	bbox := makeRandom("rect")
+	var expect []pair.Pair
+	tr.Search(bbox, func(item pair.Pair) bool {
+		expect = append(expect, item)
+		return true
+	})
+	var got []pair.Pair
+	complete := tr.SearchLimited(bbox, 1<<30, func(item pair.Pair) bool {
+		got = append(got, item)
+		return true
+	})
+	assert.True(t, complete)
+	assert.True(t, rtreetest.SameItems(expect, got))
+
+	var n int
+	complete = tr.SearchLimited(makeBoundsPair2("", -180, -90, 180, 90), 10,
+		func(item pair.Pair) bool {
+			n++
+			return true
+		})
+	assert.False(t, complete)
+	assert.True(t, n < tr.Count())
+
+	// a shared budget
+	budget := 3
+	min, max := [2]float64{-180, -90}, [2]float64{180, 90}
+	tr.SearchRectLimited(min, max, &budget, func(item pair.Pair) bool { return true })
+	assert.Equal(t, 0, budget)
+
+	// stopping early is not cut short
+	assert.True(t, tr.SearchLimited(bbox, 1<<30, func(item pair.Pair) bool { return false }))
+}
+
+func TestSearchLimit(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 1000; i++ {
+		tr.Insert(makeRandom("point"))
+	}
+	bbox := makeRandom("rect")
+	var all []pair.Pair
+	tr.Search(bbox, func(item pair.Pair) bool {
+		all = append(all, item)
+		return true
+	})
+	var pages []pair.Pair
+	for offset := 0; offset < len(all); offset += 3 {
+		page := tr.SearchLimit(bbox, offset, 3)
+		assert.True(t, len(page) > 0 && len(page) <= 3)
+		pages = append(pages, page...)
+	}
+	assert.Equal(t, all, pages)
+	assert.Nil(t, tr.SearchLimit(bbox, len(all), 3))
+	assert.Nil(t, tr.SearchLimit(bbox, 0, 0))
+}
@@ -0,0 +1,129 @@
+package rtree
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+	"github.com/tidwall/pair-rtree/rtreetest"
+)
+
+func TestLoad(t *testing.T) {
+	for _, n := range []int{0, 3, 9, 10, 100, 10000} {
+		var items []pair.Pair
+		for i := 0; i < n; i++ {
+			items = append(items, makeRandom("rect"))
+		}
+		tr := New(nil)
+		tr.Load(items)
+		assert.NoError(t, tr.Check())
+		assert.Equal(t, n, tr.Count())
+		var got []pair.Pair
+		tr.Scan(func(item pair.Pair) bool {
+			got = append(got, item)
+			return true
+		})
+		assert.True(t, rtreetest.SameItems(items, got))
+
+		// the search results are the same as a scan
+		for i := 0; i < 20; i++ {
+			min, max := tr.rect(makeRandom("rect").Value())
+			var expect []pair.Pair
+			for _, item := range items {
+				imin, imax := tr.rect(item.Value())
+				if imin[0] <= max[0] && imin[1] <= max[1] &&
+					imax[0] >= min[0] && imax[1] >= min[1] {
+					expect = append(expect, item)
+				}
+			}
+			got = got[:0]
+			tr.SearchRect([2]float64{min[0], min[1]}, [2]float64{max[0], max[1]}, func(item pair.Pair) bool {
+				got = append(got, item)
+				return true
+			})
+			assert.True(t, rtreetest.SameItems(expect, got))
+		}
+	}
+}
+
+func TestLoadFill(t *testing.T) {
+	var items []pair.Pair
+	for i := 0; i < 10000; i++ {
+		items = append(items, makeRandom("point"))
+	}
+	tr := New(nil)
+	tr.Load(items)
+	var leaves int
+	tr.Leaves(func(min, max [2]float64, count int) bool {
+		leaves++
+		return true
+	})
+	// every leaf is full but for the last one in each parent
+	assert.True(t, leaves <= 10000/tr.maxEntries+10000/tr.maxEntries/tr.maxEntries+1)
+}
+
+func TestLoadIntoTree(t *testing.T) {
+	// loads that are shorter, taller, and as tall as the tree
+	for _, sizes := range [][2]int{{10000, 100}, {100, 10000}, {1000, 1000}} {
+		tr := New(nil)
+		var items []pair.Pair
+		for i := 0; i < sizes[0]; i++ {
+			items = append(items, makeRandom("rect"))
+			tr.Insert(items[i])
+		}
+		var loaded []pair.Pair
+		for i := 0; i < sizes[1]; i++ {
+			loaded = append(loaded, makeRandom("rect"))
+		}
+		tr.Load(loaded)
+		items = append(items, loaded...)
+		assert.NoError(t, tr.Check())
+		var got []pair.Pair
+		tr.Scan(func(item pair.Pair) bool {
+			got = append(got, item)
+			return true
+		})
+		assert.True(t, rtreetest.SameItems(items, got))
+		for _, item := range items[:len(items)/2] {
+			assert.True(t, tr.RemoveOK(item))
+		}
+		assert.NoError(t, tr.Check())
+		assert.Equal(t, len(items)-len(items)/2, tr.Count())
+	}
+}
+
+func TestLoadAnnotate(t *testing.T) {
+	tr := New(&Options{
+		MaxEntries: 9,
+		Annotate: func(items []pair.Pair, children []interface{}) interface{} {
+			n := len(items)
+			for _, c := range children {
+				n += c.(int)
+			}
+			return n
+		},
+	})
+	var items []pair.Pair
+	for i := 0; i < 5000; i++ {
+		items = append(items, makeRandom("point"))
+	}
+	tr.Load(items[:4000])
+	tr.Load(items[4000:])
+	assert.Equal(t, 5000, tr.data.annotation)
+	assert.Equal(t, uint64(5000), tr.Stats().Inserts)
+}
+
+func BenchmarkLoad(b *testing.B) {
+	rand.Seed(0)
+	var points []pair.Pair
+	for i := 0; i < b.N; i++ {
+		x := rand.Float64()*360 - 180
+		y := rand.Float64()*180 - 90
+		points = append(points, makePointPair2("", x, y))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	tr := New(nil)
+	tr.Load(points)
+}
@@ -0,0 +1,88 @@
+package rtree
+
+import (
+	"math"
+
+	"github.com/tidwall/pair"
+)
+
+// Metric measures a distance from the distances along each axis between a
+// point and a rect. It must never decrease when an axis distance increases,
+// which is what makes the distance to a node a lower bound for the distance
+// to every item under it, so any such metric is safe for pruning.
+type Metric func(axes [2]float64) float64
+
+var (
+	// SquaredEuclidean is the metric of KNN.
+	SquaredEuclidean Metric = func(axes [2]float64) float64 {
+		return axes[0]*axes[0] + axes[1]*axes[1]
+	}
+	// Euclidean is the straight line distance.
+	Euclidean Metric = func(axes [2]float64) float64 {
+		return math.Sqrt(axes[0]*axes[0] + axes[1]*axes[1])
+	}
+	// Manhattan is the sum of the axis distances, or the L1 distance.
+	Manhattan Metric = func(axes [2]float64) float64 {
+		return axes[0] + axes[1]
+	}
+	// Chebyshev is the largest of the axis distances, or the L∞ distance.
+	Chebyshev Metric = func(axes [2]float64) float64 {
+		return mathMax(axes[0], axes[1])
+	}
+)
+
+// WeightedEuclidean returns a straight line metric that scales each axis
+// distance by a weight. The weights must not be negative.
+func WeightedEuclidean(weights [2]float64) Metric {
+	return func(axes [2]float64) float64 {
+		dx, dy := axes[0]*weights[0], axes[1]*weights[1]
+		return math.Sqrt(dx*dx + dy*dy)
+	}
+}
+
+// dist returns the distance from the point to the rect. A nil metric is
+// squared euclidean.
+func (m Metric) dist(x, y float64, min, max [2]float64) float64 {
+	if m == nil {
+		return boxDist(x, y, min, max)
+	}
+	return m([2]float64{axisDist(x, min[0], max[0]), axisDist(y, min[1], max[1])})
+}
+
+// KNNMetric is like KNN, but measures the distances with the metric.
+func (tr *RTree) KNNMetric(x, y float64, metric Metric,
+	iter func(item pair.Pair, dist float64) bool) bool {
+	return tr.knn(x, y, iter, nil, nil, metric)
+}
+
+// SearchRadius iterates over the items that are within the radius of the
+// point, as measured by the metric, in no particular order. Nodes that are
+// farther away than the radius are not visited.
+func (tr *RTree) SearchRadius(x, y, radius float64, metric Metric,
+	iter func(item pair.Pair, dist float64) bool) bool {
+	if tr.data.len() == 0 {
+		return true
+	}
+	return tr.searchRadius(tr.data, x, y, radius, metric, iter)
+}
+
+func (tr *RTree) searchRadius(node *treeNode, x, y, radius float64, metric Metric,
+	iter func(item pair.Pair, dist float64) bool) bool {
+	for _, item := range node.items {
+		min, max := tr.rect(item.Value())
+		dist := tr.dist(metric, x, y, [2]float64{min[0], min[1]}, [2]float64{max[0], max[1]})
+		if dist <= radius && !iter(item, dist) {
+			return false
+		}
+	}
+	for _, index := range node.children {
+		child := tr.node(index)
+		if tr.dist(metric, x, y, [2]float64{child.minX, child.minY},
+			[2]float64{child.maxX, child.maxY}) <= radius {
+			if !tr.searchRadius(child, x, y, radius, metric, iter) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package rtree
+
+import (
+	"sort"
+
+	"github.com/tidwall/pair"
+)
+
+// Update replaces an item with a new item for the same object.
+type Update struct {
+	Item, NewItem pair.Pair
+}
+
+// Nudge replaces an item with a new item for the same object, such as one
+// that has moved a little. When the leaf that holds the item still covers
+// the rect of the new item, the new item takes its place in the leaf, which
+// is much faster than a Remove and Insert. Otherwise the item is removed
+// and the new item is inserted. Returns false, and changes nothing, if the
+// item isn't in the tree.
+func (tr *RTree) Nudge(item, newItem pair.Pair) bool {
+	return len(tr.UpdateAll([]Update{{item, newItem}})) == 0
+}
+
+// UpdateAll is like Nudge for many items, such as the objects that moved
+// in one tick of a simulation. The new items that are still covered by
+// their leaves are put in place first, and the bboxes and annotations of the
+// changed nodes are computed once, then the rest of the items are removed
+// and the new items inserted. Returns the updates with items that aren't in the
+// tree, which are skipped.
+func (tr *RTree) UpdateAll(updates []Update) (missing []Update) {
+	tr.checkFrozen()
+	var moved []Update
+	var dirty []*treeNode
+	seen := make(map[*treeNode]bool)
+	for _, u := range updates {
+		var bbox, nbbox treeNode
+		fillBBox(u.Item, &bbox, tr.rect)
+		path, index := tr.findPath(tr.data, &bbox, u.Item, tr.reusePath[:0])
+		if path == nil {
+			missing = append(missing, u)
+			continue
+		}
+		tr.reusePath = path
+		fillBBox(u.NewItem, &nbbox, tr.rect)
+		leaf := path[len(path)-1]
+		if !leaf.contains(&nbbox) {
+			moved = append(moved, u)
+			continue
+		}
+		leaf.items[index] = u.NewItem
+		for _, node := range path {
+			if !seen[node] {
+				seen[node] = true
+				dirty = append(dirty, node)
+			}
+		}
+	}
+	// the bboxes of the changed nodes may have shrunk, so they're computed
+	// again along with the annotations, for the children before their
+	// parents
+	sort.Slice(dirty, func(i, j int) bool {
+		return dirty[i].height < dirty[j].height
+	})
+	for _, node := range dirty {
+		tr.calcBBox(node)
+		tr.annotateNode(node)
+	}
+	for _, u := range moved {
+		tr.Remove(u.Item)
+	}
+	for _, u := range moved {
+		tr.Insert(u.NewItem)
+	}
+	return missing
+}
+
+// findPath returns the path of nodes from node to the leaf that holds the
+// item, and the index of the item in the leaf. Only the nodes that contain
+// the bbox of the item are visited. Returns nil if the item isn't found.
+func (tr *RTree) findPath(node, bbox *treeNode, item pair.Pair, path []*treeNode) ([]*treeNode, int) {
+	path = append(path, node)
+	if node.leaf {
+		if index := findItem(item, node); index != -1 {
+			return path, index
+		}
+		return nil, -1
+	}
+	for _, index := range node.children {
+		child := tr.node(index)
+		if child.contains(bbox) {
+			if found, index := tr.findPath(child, bbox, item, path); found != nil {
+				return found, index
+			}
+		}
+	}
+	return nil, -1
+}
@@ -0,0 +1,83 @@
+package rtree
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+	"github.com/tidwall/pair-rtree/rtreetest"
+)
+
+func TestNudge(t *testing.T) {
+	tr := New(nil)
+	var items []pair.Pair
+	for i := 0; i < 1000; i++ {
+		x, y := rand.Float64()*360-180, rand.Float64()*180-90
+		items = append(items, makePointPair2("", x, y))
+		tr.Insert(items[i])
+	}
+	for i, item := range items {
+		min, _ := tr.rect(item.Value())
+		x, y := min[0]+rand.Float64()*0.01, min[1]+rand.Float64()*0.01
+		if i%10 == 0 {
+			// far away
+			x, y = -x, -y
+		}
+		items[i] = makePointPair2("", x, y)
+		assert.True(t, tr.Nudge(item, items[i]))
+		assert.False(t, tr.Nudge(item, items[i]))
+	}
+	var got []pair.Pair
+	tr.Search(makeBoundsPair2("", -200, -100, 200, 100), func(item pair.Pair) bool {
+		got = append(got, item)
+		return true
+	})
+	assert.True(t, rtreetest.SameItems(items, got))
+	// the bboxes are still tight
+	assert.NoError(t, tr.Check())
+}
+
+func TestUpdateAll(t *testing.T) {
+	// each node is annotated with the sum of the x of its items
+	var tr *RTree
+	tr = New(&Options{
+		MaxEntries: 9,
+		Annotate: func(items []pair.Pair, children []interface{}) interface{} {
+			var sum float64
+			for _, item := range items {
+				min, _ := tr.rect(item.Value())
+				sum += min[0]
+			}
+			for _, c := range children {
+				sum += c.(float64)
+			}
+			return sum
+		},
+	})
+	var items []pair.Pair
+	for i := 0; i < 1000; i++ {
+		items = append(items, makeRandom("point"))
+		tr.Insert(items[i])
+	}
+	var updates []Update
+	for i, item := range items {
+		min, _ := tr.rect(item.Value())
+		items[i] = makePointPair2("", min[0]+rand.Float64()*0.01, min[1])
+		updates = append(updates, Update{item, items[i]})
+	}
+	missing := Update{makeRandom("point"), makeRandom("point")}
+	updates = append(updates, missing)
+	assert.Equal(t, []Update{missing}, tr.UpdateAll(updates))
+	var got []pair.Pair
+	var sum float64
+	tr.Scan(func(item pair.Pair) bool {
+		got = append(got, item)
+		min, _ := tr.rect(item.Value())
+		sum += min[0]
+		return true
+	})
+	assert.True(t, rtreetest.SameItems(items, got))
+	assert.InDelta(t, sum, tr.data.annotation.(float64), 1e-6)
+	assert.NoError(t, tr.Check())
+}
@@ -0,0 +1,200 @@
+package rtree
+
+import (
+	"errors"
+
+	"github.com/tidwall/pair"
+	"github.com/tidwall/tinyqueue"
+)
+
+// packedNodeSize is the number of entries in each node of a packed tree.
+const packedNodeSize = 16
+
+var (
+	ErrPackedFull     = errors.New("packed tree is full")
+	ErrPackedFinished = errors.New("packed tree is finished")
+	ErrPackedShort    = errors.New("packed tree is missing items")
+)
+
+// Packed is an immutable tree that's packed into flat arrays, in the style of
+// flatbush. It's built by adding a known number of items, in order, and then
+// calling Finish. The items are not sorted, so they should be added in a
+// spatial order, such as the HilbertOrder of ScanSorted, for the nodes to be
+// tight.
+//
+// The rects are used as is. There's no transformer, and no RectFunc.
+type Packed struct {
+	numItems    int
+	pos         int
+	boxes       [][4]float64 // minX, minY, maxX, maxY of each item and node
+	index       []int        // the first child of each node
+	items       []pair.Pair
+	levelBounds []int // the end of each level in boxes, starting with items
+	finished    bool
+}
+
+// NewPacked returns a packed tree for n items.
+func NewPacked(n int) *Packed {
+	if n < 0 {
+		n = 0
+	}
+	levelBounds := []int{n}
+	numNodes := n
+	for count := n; count > 0; {
+		count = (count + packedNodeSize - 1) / packedNodeSize
+		numNodes += count
+		levelBounds = append(levelBounds, numNodes)
+		if count == 1 {
+			break
+		}
+	}
+	return &Packed{
+		numItems:    n,
+		boxes:       make([][4]float64, numNodes),
+		index:       make([]int, numNodes),
+		items:       make([]pair.Pair, n),
+		levelBounds: levelBounds,
+	}
+}
+
+// Add adds the next item.
+func (p *Packed) Add(min, max [2]float64, item pair.Pair) error {
+	if p.finished {
+		return ErrPackedFinished
+	}
+	if p.pos == p.numItems {
+		return ErrPackedFull
+	}
+	p.boxes[p.pos] = [4]float64{min[0], min[1], max[0], max[1]}
+	p.index[p.pos] = p.pos
+	p.items[p.pos] = item
+	p.pos++
+	return nil
+}
+
+// Finish builds the nodes once every item has been added. The tree can't be
+// searched until it's finished.
+func (p *Packed) Finish() error {
+	if p.finished {
+		return ErrPackedFinished
+	}
+	if p.pos != p.numItems {
+		return ErrPackedShort
+	}
+	var start int
+	for _, end := range p.levelBounds[:len(p.levelBounds)-1] {
+		for i := start; i < end; {
+			node := p.boxes[i]
+			first := i
+			for j := 0; j < packedNodeSize && i < end; j++ {
+				box := p.boxes[i]
+				node[0], node[1] = mathMin(node[0], box[0]), mathMin(node[1], box[1])
+				node[2], node[3] = mathMax(node[2], box[2]), mathMax(node[3], box[3])
+				i++
+			}
+			p.boxes[p.pos] = node
+			p.index[p.pos] = first
+			p.pos++
+		}
+		start = end
+	}
+	p.finished = true
+	return nil
+}
+
+// Count returns the number of items.
+func (p *Packed) Count() int {
+	return p.numItems
+}
+
+// Bounds returns the bounds of all items.
+func (p *Packed) Bounds() (min, max [2]float64) {
+	if !p.finished || p.numItems == 0 {
+		return
+	}
+	root := p.boxes[len(p.boxes)-1]
+	return [2]float64{root[0], root[1]}, [2]float64{root[2], root[3]}
+}
+
+// children returns the range of the children of a node at a level, where
+// the items are level zero.
+func (p *Packed) children(node, level int) (start, end int) {
+	start = p.index[node]
+	end = start + packedNodeSize
+	if end > p.levelBounds[level-1] {
+		end = p.levelBounds[level-1]
+	}
+	return start, end
+}
+
+// Search iterates over the items that intersect the rect.
+func (p *Packed) Search(min, max [2]float64, iter func(item pair.Pair) bool) bool {
+	if !p.finished || p.numItems == 0 {
+		return true
+	}
+	type entry struct{ node, level int }
+	stack := []entry{{len(p.boxes) - 1, len(p.levelBounds) - 1}}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		start, end := p.children(e.node, e.level)
+		for i := start; i < end; i++ {
+			box := &p.boxes[i]
+			if max[0] < box[0] || max[1] < box[1] ||
+				min[0] > box[2] || min[1] > box[3] {
+				continue
+			}
+			if e.level == 1 {
+				if !iter(p.items[i]) {
+					return false
+				}
+			} else {
+				stack = append(stack, entry{i, e.level - 1})
+			}
+		}
+	}
+	return true
+}
+
+type packedQueueItem struct {
+	index int
+	level int // zero for items
+	dist  float64
+}
+
+func (item *packedQueueItem) Less(b tinyqueue.Item) bool {
+	return item.dist < b.(*packedQueueItem).dist
+}
+
+// KNN iterates over the items nearest to the point, nearest first. The
+// distance is squared, like KNN on the tree.
+func (p *Packed) KNN(x, y float64, iter func(item pair.Pair, dist float64) bool) bool {
+	if !p.finished || p.numItems == 0 {
+		return true
+	}
+	queue := tinyqueue.New(nil)
+	node, level := len(p.boxes)-1, len(p.levelBounds)-1
+	for {
+		start, end := p.children(node, level)
+		for i := start; i < end; i++ {
+			box := &p.boxes[i]
+			queue.Push(&packedQueueItem{
+				index: i,
+				level: level - 1,
+				dist: boxDist(x, y, [2]float64{box[0], box[1]},
+					[2]float64{box[2], box[3]}),
+			})
+		}
+		for queue.Len() > 0 && queue.Peek().(*packedQueueItem).level == 0 {
+			item := queue.Pop().(*packedQueueItem)
+			if !iter(p.items[item.index], item.dist) {
+				return false
+			}
+		}
+		if queue.Len() == 0 {
+			return true
+		}
+		next := queue.Pop().(*packedQueueItem)
+		node, level = next.index, next.level
+	}
+}
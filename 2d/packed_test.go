@@ -0,0 +1,59 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+	"github.com/tidwall/pair-rtree/rtreetest"
+)
+
+func TestPacked(t *testing.T) {
+	for _, n := range []int{0, 1, 16, 17, 1000} {
+		tr := New(nil)
+		var objs []pair.Pair
+		for i := 0; i < n; i++ {
+			objs = append(objs, makeRandom("rect"))
+			tr.Insert(objs[i])
+		}
+		p := NewPacked(n)
+		tr.ScanSorted(HilbertOrder, func(item pair.Pair) bool {
+			min, max := tr.rect(item.Value())
+			assert.NoError(t, p.Add([2]float64{min[0], min[1]}, [2]float64{max[0], max[1]}, item))
+			return true
+		})
+		assert.Equal(t, ErrPackedFull, p.Add([2]float64{}, [2]float64{}, pair.Pair{}))
+		assert.NoError(t, p.Finish())
+		assert.Equal(t, ErrPackedFinished, p.Finish())
+		assert.Equal(t, n, p.Count())
+		tmin, tmax := tr.Bounds()
+		pmin, pmax := p.Bounds()
+		assert.Equal(t, tmin, pmin)
+		assert.Equal(t, tmax, pmax)
+
+		min, max := [2]float64{-50, -30}, [2]float64{60, 40}
+		var expect, got []pair.Pair
+		tr.SearchRect(min, max, func(item pair.Pair) bool {
+			expect = append(expect, item)
+			return true
+		})
+		p.Search(min, max, func(item pair.Pair) bool {
+			got = append(got, item)
+			return true
+		})
+		assert.True(t, rtreetest.SameItems(expect, got))
+
+		var dists []float64
+		p.KNN(10, 20, func(item pair.Pair, dist float64) bool {
+			dists = append(dists, dist)
+			return true
+		})
+		assert.Equal(t, n, len(dists))
+		for i := 1; i < len(dists); i++ {
+			assert.True(t, dists[i-1] <= dists[i])
+		}
+	}
+	p := NewPacked(2)
+	assert.NoError(t, p.Add([2]float64{}, [2]float64{}, pair.Pair{}))
+	assert.Equal(t, ErrPackedShort, p.Finish())
+}
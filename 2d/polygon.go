@@ -0,0 +1,244 @@
+package rtree
+
+import (
+	"math"
+
+	"github.com/tidwall/pair"
+)
+
+// KNNInPolygon is like KNN, but only returns items with bboxes that are
+// fully inside of the polygon. The polygon is a ring of vertices that may be
+// concave, and the closing vertex is optional. Nodes that are entirely
+// outside of the polygon are not visited.
+func (tr *RTree) KNNInPolygon(x, y float64, polygon [][2]float64,
+	iter func(item pair.Pair, dist float64) bool) bool {
+	if len(polygon) < 3 {
+		return true
+	}
+	pmin, pmax := polygonBounds(polygon)
+	return tr.knn(x, y, iter, nil, func(min, max [2]float64, isItem bool) bool {
+		if min[0] > pmax[0] || min[1] > pmax[1] ||
+			max[0] < pmin[0] || max[1] < pmin[1] {
+			return false
+		}
+		if isItem {
+			return rectInPolygon(min, max, polygon)
+		}
+		return rectIntersectsPolygon(min, max, polygon)
+	}, nil)
+}
+
+func polygonBounds(polygon [][2]float64) (min, max [2]float64) {
+	min, max = polygon[0], polygon[0]
+	for _, p := range polygon[1:] {
+		min[0], min[1] = mathMin(min[0], p[0]), mathMin(min[1], p[1])
+		max[0], max[1] = mathMax(max[0], p[0]), mathMax(max[1], p[1])
+	}
+	return min, max
+}
+
+// pointInPolygon uses the even-odd rule. Points on the edges are inside.
+func pointInPolygon(p [2]float64, polygon [][2]float64) bool {
+	var in bool
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[j], polygon[i]
+		if onSegment(p, a, b) {
+			return true
+		}
+		if (a[1] > p[1]) != (b[1] > p[1]) &&
+			p[0] < (b[0]-a[0])*(p[1]-a[1])/(b[1]-a[1])+a[0] {
+			in = !in
+		}
+	}
+	return in
+}
+
+func cross(a, b, c [2]float64) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+func onSegment(p, a, b [2]float64) bool {
+	return cross(a, b, p) == 0 &&
+		p[0] >= mathMin(a[0], b[0]) && p[0] <= mathMax(a[0], b[0]) &&
+		p[1] >= mathMin(a[1], b[1]) && p[1] <= mathMax(a[1], b[1])
+}
+
+// segmentsCross returns true if the segments properly cross each other,
+// which excludes touching at an endpoint or along an edge.
+func segmentsCross(a, b, c, d [2]float64) bool {
+	d1, d2 := cross(c, d, a), cross(c, d, b)
+	d3, d4 := cross(a, b, c), cross(a, b, d)
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) &&
+		((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+func rectCorners(min, max [2]float64) [4][2]float64 {
+	return [4][2]float64{
+		{min[0], min[1]}, {max[0], min[1]}, {max[0], max[1]}, {min[0], max[1]},
+	}
+}
+
+// rectInPolygon returns true if the rect is fully inside of the polygon,
+// which is when all of its corners are inside and none of the polygon edges
+// pass through it.
+func rectInPolygon(min, max [2]float64, polygon [][2]float64) bool {
+	corners := rectCorners(min, max)
+	for _, c := range corners {
+		if !pointInPolygon(c, polygon) {
+			return false
+		}
+	}
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[j], polygon[i]
+		if a[0] > min[0] && a[0] < max[0] && a[1] > min[1] && a[1] < max[1] {
+			return false
+		}
+		for k := 0; k < 4; k++ {
+			if segmentsCross(a, b, corners[k], corners[(k+1)%4]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// rectIntersectsPolygon returns true if the rect and polygon overlap.
+func rectIntersectsPolygon(min, max [2]float64, polygon [][2]float64) bool {
+	corners := rectCorners(min, max)
+	for _, c := range corners {
+		if pointInPolygon(c, polygon) {
+			return true
+		}
+	}
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[j], polygon[i]
+		if a[0] >= min[0] && a[0] <= max[0] && a[1] >= min[1] && a[1] <= max[1] {
+			return true
+		}
+		for k := 0; k < 4; k++ {
+			if segmentsCross(a, b, corners[k], corners[(k+1)%4]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SearchPolygon iterates over the items with bboxes that intersect the
+// convex polygon. The vertices may be in either winding order, and the
+// closing vertex is optional. Nodes and items are tested with separating
+// axes, so nothing outside of the polygon is returned.
+func (tr *RTree) SearchPolygon(vertices [][2]float64, iter func(item pair.Pair) bool) bool {
+	if len(vertices) == 0 || tr.data.len() == 0 {
+		return true
+	}
+	c := newConvex(vertices)
+	if !c.intersects([2]float64{tr.data.minX, tr.data.minY},
+		[2]float64{tr.data.maxX, tr.data.maxY}) {
+		return true
+	}
+	return tr.searchConvex(tr.data, c, iter)
+}
+
+func (tr *RTree) searchConvex(node *treeNode, c *convex, iter func(item pair.Pair) bool) bool {
+	if node.leaf {
+		for _, item := range node.items {
+			var child treeNode
+			fillBBox(item, &child, tr.rect)
+			if c.intersects([2]float64{child.minX, child.minY},
+				[2]float64{child.maxX, child.maxY}) {
+				if !iter(item) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	for _, index := range node.children {
+		child := tr.node(index)
+		min := [2]float64{child.minX, child.minY}
+		max := [2]float64{child.maxX, child.maxY}
+		if c.contains(min, max) {
+			if !tr.scan(child, iter) {
+				return false
+			}
+		} else if c.intersects(min, max) {
+			if !tr.searchConvex(child, c, iter) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// convex is a convex polygon that is prepared for separating axis tests.
+type convex struct {
+	axes   [][2]float64
+	ranges [][2]float64 // the polygon projected onto each axis
+}
+
+func newConvex(vertices [][2]float64) *convex {
+	c := &convex{axes: [][2]float64{{1, 0}, {0, 1}}}
+	for i, j := 0, len(vertices)-1; i < len(vertices); j, i = i, i+1 {
+		a, b := vertices[j], vertices[i]
+		if a != b {
+			c.axes = append(c.axes, [2]float64{a[1] - b[1], b[0] - a[0]})
+		}
+	}
+	for _, axis := range c.axes {
+		lo := axis[0]*vertices[0][0] + axis[1]*vertices[0][1]
+		hi := lo
+		for _, v := range vertices[1:] {
+			d := axis[0]*v[0] + axis[1]*v[1]
+			lo, hi = mathMin(lo, d), mathMax(hi, d)
+		}
+		c.ranges = append(c.ranges, [2]float64{lo, hi})
+	}
+	return c
+}
+
+// project returns the range of the rect projected onto the axis.
+func project(min, max [2]float64, axis [2]float64) (lo, hi float64) {
+	lo = mathMin(axis[0]*min[0], axis[0]*max[0]) + mathMin(axis[1]*min[1], axis[1]*max[1])
+	hi = mathMax(axis[0]*min[0], axis[0]*max[0]) + mathMax(axis[1]*min[1], axis[1]*max[1])
+	return lo, hi
+}
+
+// intersects returns true if there's no separating axis between the polygon
+// and the rect.
+func (c *convex) intersects(min, max [2]float64) bool {
+	for i, axis := range c.axes {
+		lo, hi := project(min, max, axis)
+		if hi < c.ranges[i][0] || lo > c.ranges[i][1] {
+			return false
+		}
+	}
+	return true
+}
+
+// contains returns true if the rect is fully inside of the polygon.
+func (c *convex) contains(min, max [2]float64) bool {
+	for i, axis := range c.axes {
+		lo, hi := project(min, max, axis)
+		if lo < c.ranges[i][0] || hi > c.ranges[i][1] {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchOBB iterates over the items with bboxes that intersect the oriented
+// box. The box is rotated counter-clockwise by angle, in radians, around its
+// center.
+func (tr *RTree) SearchOBB(center, halfExtents [2]float64, angle float64,
+	iter func(item pair.Pair) bool) bool {
+	sin, cos := math.Sincos(angle)
+	ux := [2]float64{cos * halfExtents[0], sin * halfExtents[0]}
+	uy := [2]float64{-sin * halfExtents[1], cos * halfExtents[1]}
+	return tr.SearchPolygon([][2]float64{
+		{center[0] - ux[0] - uy[0], center[1] - ux[1] - uy[1]},
+		{center[0] + ux[0] - uy[0], center[1] + ux[1] - uy[1]},
+		{center[0] + ux[0] + uy[0], center[1] + ux[1] + uy[1]},
+		{center[0] - ux[0] + uy[0], center[1] - ux[1] + uy[1]},
+	}, iter)
+}
@@ -0,0 +1,109 @@
+package rtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+func TestKNNInPolygon(t *testing.T) {
+	// a concave "C" shape that opens to the east
+	polygon := [][2]float64{
+		{-100, -50}, {100, -50}, {100, -25}, {-50, -25},
+		{-50, 25}, {100, 25}, {100, 50}, {-100, 50},
+	}
+	tr := New(nil)
+	var objs []pair.Pair
+	for i := 0; i < 5000; i++ {
+		obj := makeRandom("rect")
+		tr.Insert(obj)
+		objs = append(objs, obj)
+	}
+	var expect int
+	for _, obj := range objs {
+		min, max := geobin.WrapBinary(obj.Value()).Rect(nil)
+		if rectInPolygon([2]float64{min[0], min[1]}, [2]float64{max[0], max[1]}, polygon) {
+			expect++
+		}
+	}
+	var n int
+	pdist := -1.0
+	tr.KNNInPolygon(0, 0, polygon, func(item pair.Pair, dist float64) bool {
+		assert.True(t, dist >= pdist)
+		pdist = dist
+		n++
+		return true
+	})
+	assert.Equal(t, expect, n)
+
+	assert.True(t, rectInPolygon([2]float64{-90, -40}, [2]float64{-60, 40}, polygon))
+	assert.False(t, rectInPolygon([2]float64{-90, -40}, [2]float64{0, 40}, polygon))
+	assert.False(t, rectInPolygon([2]float64{0, -10}, [2]float64{10, 10}, polygon))
+	assert.False(t, rectIntersectsPolygon([2]float64{-10, -10}, [2]float64{10, 10}, polygon))
+	assert.True(t, rectIntersectsPolygon([2]float64{-60, -10}, [2]float64{10, 10}, polygon))
+}
+
+func TestSearchPolygon(t *testing.T) {
+	// a diamond, in clockwise order
+	vertices := [][2]float64{{0, 60}, {100, 0}, {0, -60}, {-100, 0}}
+	tr := New(nil)
+	var objs []pair.Pair
+	for i := 0; i < 5000; i++ {
+		obj := makeRandom("rect")
+		tr.Insert(obj)
+		objs = append(objs, obj)
+	}
+	var expect int
+	for _, obj := range objs {
+		min, max := geobin.WrapBinary(obj.Value()).Rect(nil)
+		// brute force test with the polygon edges and rect corners
+		rmin, rmax := [2]float64{min[0], min[1]}, [2]float64{max[0], max[1]}
+		if rectIntersectsPolygon(rmin, rmax, vertices) {
+			expect++
+		}
+	}
+	var n int
+	tr.SearchPolygon(vertices, func(item pair.Pair) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, expect, n)
+
+	c := newConvex(vertices)
+	assert.True(t, c.contains([2]float64{-10, -10}, [2]float64{10, 10}))
+	assert.False(t, c.contains([2]float64{-10, -10}, [2]float64{90, 10}))
+	assert.True(t, c.intersects([2]float64{-10, -10}, [2]float64{90, 10}))
+	assert.False(t, c.intersects([2]float64{60, 40}, [2]float64{90, 50}))
+}
+
+func TestSearchOBB(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 5000; i++ {
+		tr.Insert(makeRandom("point"))
+	}
+	var n1, n2 int
+	// a quarter turn swaps the extents
+	tr.SearchOBB([2]float64{10, 20}, [2]float64{30, 60}, math.Pi/2, func(item pair.Pair) bool {
+		n1++
+		return true
+	})
+	tr.Search(makeBoundsPair2("", -50, -10, 70, 50), func(item pair.Pair) bool {
+		n2++
+		return true
+	})
+	assert.Equal(t, n2, n1)
+	var n3 int
+	tr.SearchOBB([2]float64{0, 0}, [2]float64{50, 10}, math.Pi/4, func(item pair.Pair) bool {
+		min, _ := geobin.WrapBinary(item.Value()).Rect(nil)
+		// rotate back into the box space
+		x := min[0]*math.Cos(-math.Pi/4) - min[1]*math.Sin(-math.Pi/4)
+		y := min[0]*math.Sin(-math.Pi/4) + min[1]*math.Cos(-math.Pi/4)
+		assert.True(t, math.Abs(x) <= 50+1e-9 && math.Abs(y) <= 10+1e-9)
+		n3++
+		return true
+	})
+	assert.True(t, n3 > 0)
+}
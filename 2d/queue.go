@@ -0,0 +1,90 @@
+package rtree
+
+import (
+	"sync"
+	"unsafe"
+)
+
+type queueItem struct {
+	node   unsafe.Pointer
+	isItem bool
+	dist   float64
+}
+
+// knnQueue is a binary min-heap of nodes and items, ordered by distance. The
+// entries are stored by value, and the queues are reused through a pool, so
+// a search doesn't allocate once the queues have grown to its size.
+type knnQueue struct {
+	items []queueItem
+}
+
+var queuePool = sync.Pool{New: func() interface{} { return new(knnQueue) }}
+
+// getQueue returns an empty queue from the pool.
+func getQueue() *knnQueue {
+	return queuePool.Get().(*knnQueue)
+}
+
+// putQueue empties the queue and returns it to the pool. The entries are
+// zeroed first, so the pool doesn't keep the nodes and items alive.
+func putQueue(q *knnQueue) {
+	for i := range q.items {
+		q.items[i] = queueItem{}
+	}
+	q.items = q.items[:0]
+	queuePool.Put(q)
+}
+
+func (q *knnQueue) len() int {
+	return len(q.items)
+}
+
+// peek returns the nearest entry, which must exist.
+func (q *knnQueue) peek() queueItem {
+	return q.items[0]
+}
+
+func (q *knnQueue) push(item queueItem) {
+	q.items = append(q.items, item)
+	i := len(q.items) - 1
+	for i > 0 {
+		parent := (i - 1) / 2
+		if q.items[parent].dist <= item.dist {
+			break
+		}
+		q.items[i] = q.items[parent]
+		i = parent
+	}
+	q.items[i] = item
+}
+
+// pop removes and returns the nearest entry. The ok is false when the queue
+// is empty.
+func (q *knnQueue) pop() (item queueItem, ok bool) {
+	n := len(q.items) - 1
+	if n < 0 {
+		return item, false
+	}
+	item = q.items[0]
+	last := q.items[n]
+	q.items = q.items[:n]
+	if n > 0 {
+		i := 0
+		for {
+			child := 2*i + 1
+			if child >= n {
+				break
+			}
+			if child+1 < n && q.items[child+1].dist < q.items[child].dist {
+				child++
+			}
+			if last.dist <= q.items[child].dist {
+				break
+			}
+			q.items[i] = q.items[child]
+			i = child
+		}
+		q.items[i] = last
+	}
+	return item, true
+}
@@ -0,0 +1,50 @@
+package rtree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestQueue(t *testing.T) {
+	q := getQueue()
+	defer putQueue(q)
+	var dists []float64
+	for i := 0; i < 1000; i++ {
+		dist := rand.Float64()
+		dists = append(dists, dist)
+		q.push(queueItem{dist: dist})
+	}
+	sort.Float64s(dists)
+	for _, dist := range dists {
+		assert.Equal(t, dist, q.peek().dist)
+		item, ok := q.pop()
+		assert.True(t, ok)
+		assert.Equal(t, dist, item.dist)
+	}
+	_, ok := q.pop()
+	assert.False(t, ok)
+}
+
+func TestKNNAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	tr := New(nil)
+	for i := 0; i < 5000; i++ {
+		tr.Insert(makeRandom("rect"))
+	}
+	var n int
+	iter := func(item pair.Pair, dist float64) bool {
+		n++
+		return n < 100
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		n = 0
+		tr.KNN(10, 20, iter)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
@@ -0,0 +1,8 @@
+//go:build race
+// +build race
+
+package rtree
+
+// raceEnabled is set when the race detector is on, which allocates on its
+// own and breaks the allocation counts of tests.
+const raceEnabled = true
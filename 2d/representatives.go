@@ -0,0 +1,52 @@
+package rtree
+
+import "github.com/tidwall/pair"
+
+// Representatives iterates over up to perNode items from each leaf that
+// intersect the bbox, which thins out dense areas without reading or
+// sorting every result. The items are in tree order.
+func (tr *RTree) Representatives(bbox pair.Pair, perNode int,
+	iter func(item pair.Pair) bool) bool {
+	min, max := tr.rect(bbox.Value())
+	return tr.RepresentativesRect([2]float64{min[0], min[1]},
+		[2]float64{max[0], max[1]}, perNode, iter)
+}
+
+// RepresentativesRect is like Representatives, but takes the rect directly
+// rather than reading it from an item value. The rect is not transformed.
+func (tr *RTree) RepresentativesRect(min, max [2]float64, perNode int,
+	iter func(item pair.Pair) bool) bool {
+	if perNode < 1 || tr.data.len() == 0 {
+		return true
+	}
+	var bbox treeNode
+	bbox.minX, bbox.minY = min[0], min[1]
+	bbox.maxX, bbox.maxY = max[0], max[1]
+	tr.exclude(&bbox)
+	if !tr.data.intersects(&bbox) {
+		return true
+	}
+	return tr.representatives(tr.data, &bbox, perNode, iter)
+}
+
+func (tr *RTree) representatives(node, bbox *treeNode, perNode int,
+	iter func(item pair.Pair) bool) bool {
+	if node.leaf {
+		var n int
+		for i := 0; i < len(node.items) && n < perNode; i++ {
+			item := node.items[i]
+			var child treeNode
+			fillBBox(item, &child, tr.rect)
+			if bbox.intersects(&child) {
+				if !iter(item) {
+					return false
+				}
+				n++
+			}
+		}
+		return true
+	}
+	return tr.searchChildren(node, bbox, func(child *treeNode) bool {
+		return tr.representatives(child, bbox, perNode, iter)
+	})
+}
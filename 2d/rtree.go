@@ -1,26 +1,59 @@
 package rtree
 
 import (
-	"fmt"
-	"image"
-	"image/color"
-	"image/color/palette"
-	"image/draw"
-	"image/gif"
-	"io"
 	"math"
-	"os"
+	"math/bits"
 	"sort"
-	"strings"
-	"unsafe"
+	"sync/atomic"
 
 	"github.com/tidwall/geobin"
 	"github.com/tidwall/pair"
-	"github.com/tidwall/pinhole"
 )
 
 type transformer func(minIn, maxIn [3]float64) (minOut, maxOut [3]float64)
 
+// rectFunc returns the transformed rect of an item value.
+type rectFunc func(value []byte) (min, max [3]float64)
+
+// newRectFunc returns a rectFunc that reads values with fn, or as geobin
+// objects when fn is nil, then applies the transformer, and then snaps to
+// the grid when it's greater than zero.
+func newRectFunc(fn func(value []byte) (min, max [3]float64, dims int),
+	t transformer, grid float64) rectFunc {
+	var rect rectFunc
+	if fn == nil {
+		rect = func(value []byte) (min, max [3]float64) {
+			return geobin.WrapBinary(value).Rect(t)
+		}
+	} else {
+		rect = func(value []byte) (min, max [3]float64) {
+			min, max, _ = fn(value)
+			if t != nil {
+				min, max = t(min, max)
+			}
+			return min, max
+		}
+	}
+	if grid <= 0 {
+		return rect
+	}
+	return func(value []byte) (min, max [3]float64) {
+		min, max = rect(value)
+		return snap(min, max, grid)
+	}
+}
+
+// snap rounds the rect to the nearest multiples of the grid resolution.
+// Rounding keeps the order of coordinates, so rects that intersect before
+// snapping still intersect after.
+func snap(min, max [3]float64, grid float64) (minOut, maxOut [3]float64) {
+	for i := 0; i < 3; i++ {
+		min[i] = math.Round(min[i]/grid) * grid
+		max[i] = math.Round(max[i]/grid) * grid
+	}
+	return min, max
+}
+
 var mathInfNeg = math.Inf(-1)
 var mathInfPos = math.Inf(+1)
 
@@ -41,9 +74,20 @@ func mathMax(a, b float64) float64 {
 type treeNode struct {
 	minX, minY float64
 	maxX, maxY float64
-	children   []unsafe.Pointer
+	children   []int32     // the indexes of the child nodes of a branch
+	items      []pair.Pair // the items of a leaf
+	index      int32       // the index of the node in the slabs
 	leaf       bool
 	height     int8
+	annotation interface{}
+}
+
+// len returns the number of items or child nodes.
+func (a *treeNode) len() int {
+	if a.leaf {
+		return len(a.items)
+	}
+	return len(a.children)
 }
 
 func (a *treeNode) extend(b *treeNode) {
@@ -79,22 +123,81 @@ func (a *treeNode) margin() float64 {
 	return (a.maxX - a.minX) + (a.maxY - a.minY)
 }
 
+// exclude shrinks a search bbox by the smallest amount on every side when
+// touching items are excluded, so the inclusive tests of intersects and
+// contains become the exclusive tests against the original bbox. A float
+// is less than another exactly when it's not more than the next float below.
+func (tr *RTree) exclude(bbox *treeNode) {
+	if tr.excludeTouching {
+		bbox.minX = math.Nextafter(bbox.minX, mathInfPos)
+		bbox.minY = math.Nextafter(bbox.minY, mathInfPos)
+		bbox.maxX = math.Nextafter(bbox.maxX, mathInfNeg)
+		bbox.maxY = math.Nextafter(bbox.maxY, mathInfNeg)
+	}
+}
+
 type RTree struct {
-	maxEntries int
-	minEntries int
-	t          transformer
-	data       *treeNode
-	reusePath  []*treeNode
+	maxEntries      int
+	minEntries      int
+	rect            rectFunc
+	annotate        func(items []pair.Pair, children []interface{}) interface{}
+	data            *treeNode
+	reusePath       []*treeNode
+	slabs           [][]treeNode
+	slabShift       uint
+	numNodes        int32
+	free            []int32
+	presize         bool
+	frozen          bool
+	reinsertOrphans bool
+	wrapX           float64
+	excludeTouching bool
+	merged          int
+	reinserted      int
+	stats           *stats
 }
 
 type Options struct {
 	MaxEntries  int
 	Transformer func(minIn, maxIn [3]float64) (minOut, maxOut [3]float64)
+	// RectFunc returns the rect and dimensions of an item value. The default
+	// reads the value as a geobin object.
+	RectFunc func(value []byte) (min, max [3]float64, dims int)
+	// Grid snaps the coordinates of items and queries to multiples of this
+	// resolution, after the transformer. Zero disables snapping.
+	Grid float64
+	// Annotate, when set, is called to compute the annotation for a node
+	// whenever its children change. For a leaf it's called with the items,
+	// otherwise with the annotations of the child nodes. The annotations are
+	// available from TraverseAnnotations and Aggregate.
+	Annotate func(items []pair.Pair, children []interface{}) interface{}
+	// ReinsertOrphans removes nodes that are left with fewer than the
+	// minimum number of entries by a Remove, and reinserts their children
+	// at their original levels. This costs more per Remove, but keeps the
+	// tree from degrading under deletion-heavy workloads.
+	ReinsertOrphans bool
+	// ExpectedItems is a hint for the number of items that the tree will
+	// hold. When set, the children of each node have room for a split, so
+	// inserts don't grow them, and the nodes are allocated in larger slabs.
+	ExpectedItems int
+	// WrapX is the period at which the x axis wraps around, such as 360 for
+	// longitudes, in the coordinates of the tree. Search, SearchRect, KNN,
+	// KNNMetric, and SearchRadius find the items on the other side of the
+	// edge. The items themselves are not wrapped, so they should be inside of
+	// a single period. Zero disables wrapping.
+	WrapX float64
+	// ExcludeTouching has searches skip the items that only touch the
+	// search rect, sharing an edge or a corner with it but none of its
+	// inside, as with the interior intersection of some GIS systems. A point
+	// is then found only when it's inside of the rect, not on its edge. By
+	// default, touching items are found.
+	ExcludeTouching bool
 }
 
 var DefaultOptions = &Options{
 	MaxEntries:  9,
 	Transformer: nil,
+	Grid:        0,
 }
 
 func New(opts *Options) *RTree {
@@ -102,46 +205,71 @@ func New(opts *Options) *RTree {
 	if opts == nil {
 		opts = DefaultOptions
 	}
-	tr.t = opts.Transformer
+	tr.rect = newRectFunc(opts.RectFunc, opts.Transformer, opts.Grid)
+	tr.annotate = opts.Annotate
+	tr.reinsertOrphans = opts.ReinsertOrphans
+	tr.wrapX = opts.WrapX
+	tr.excludeTouching = opts.ExcludeTouching
+	tr.stats = &stats{}
 	tr.maxEntries = int(mathMax(4, float64(opts.MaxEntries)))
 	tr.minEntries = int(mathMax(2, math.Ceil(float64(tr.maxEntries)*0.4)))
-	tr.data = createNode(nil)
+	tr.presize = opts.ExpectedItems > 0
+	tr.slabShift = uint(bits.TrailingZeros(uint(slabSizeFor(opts.ExpectedItems, tr.maxEntries))))
+	tr.data = tr.newNode()
+	tr.data.items = tr.makeItems(0)
 	return tr
 }
 
-func createNode(children []unsafe.Pointer) *treeNode {
+func createNode() *treeNode {
 	return &treeNode{
-		children: children,
-		height:   1,
-		leaf:     true,
-		minX:     mathInfPos,
-		minY:     mathInfPos,
-		maxX:     mathInfNeg,
-		maxY:     mathInfNeg,
+		height: 1,
+		leaf:   true,
+		minX:   mathInfPos,
+		minY:   mathInfPos,
+		maxX:   mathInfNeg,
+		maxY:   mathInfNeg,
 	}
 }
-func fillBBox(item pair.Pair, bbox *treeNode, t transformer) {
-	min, max := geobin.WrapBinary(item.Value()).Rect(t)
+func fillBBox(item pair.Pair, bbox *treeNode, rect rectFunc) {
+	min, max := rect(item.Value())
 	bbox.minX, bbox.minY, bbox.maxX, bbox.maxY = min[0], min[1], max[0], max[1]
 }
 func (tr *RTree) Insert(item pair.Pair) {
-	min, max := geobin.WrapBinary(item.Value()).Rect(tr.t)
+	tr.checkFrozen()
+	min, max := tr.rect(item.Value())
+	tr.insertBBox(item, min[0], min[1], max[0], max[1])
+}
+
+// InsertRect inserts an item with a rect that was already computed, which
+// must be the same as the rect that the tree computes for the item value,
+// including any transform and snapping. It allows for the rects of many
+// items to be computed in parallel before they are inserted.
+func (tr *RTree) InsertRect(item pair.Pair, min, max [2]float64) {
+	tr.checkFrozen()
 	tr.insertBBox(item, min[0], min[1], max[0], max[1])
 }
+
 func (tr *RTree) insertBBox(item pair.Pair, minX, minY, maxX, maxY float64) {
+	atomic.AddUint64(&tr.stats.inserts, 1)
 	var bbox treeNode
 	bbox.minX, bbox.minY = minX, minY
 	bbox.maxX, bbox.maxY = maxX, maxY
 	tr.insert(&bbox, item, tr.data.height-1, false)
 }
 
+// insert inserts the item at the level, or when isNode is set, inserts the
+// bbox itself, which is then a node.
 func (tr *RTree) insert(bbox *treeNode, item pair.Pair, level int8, isNode bool) {
 	tr.reusePath = tr.reusePath[:0]
 	node, insertPath := tr.chooseSubtree(bbox, tr.data, level, tr.reusePath)
-	node.children = append(node.children, item.Pointer())
+	if isNode {
+		node.children = append(node.children, bbox.index)
+	} else {
+		node.items = append(node.items, item)
+	}
 	node.extend(bbox)
 	for level >= 0 {
-		if len(insertPath[level].children) > tr.maxEntries {
+		if insertPath[level].len() > tr.maxEntries {
 			insertPath = tr.split(insertPath, level)
 			level--
 		} else {
@@ -149,6 +277,14 @@ func (tr *RTree) insert(bbox *treeNode, item pair.Pair, level int8, isNode bool)
 		}
 	}
 	tr.adjustParentBBoxes(bbox, insertPath, level)
+	if tr.annotate != nil {
+		for i := len(insertPath) - 1; i >= 0; i-- {
+			tr.annotateNode(insertPath[i])
+		}
+		if tr.data != insertPath[0] {
+			tr.annotateNode(tr.data)
+		}
+	}
 	tr.reusePath = insertPath
 }
 
@@ -160,38 +296,47 @@ func (tr *RTree) adjustParentBBoxes(bbox *treeNode, path []*treeNode, level int8
 }
 func (tr *RTree) split(insertPath []*treeNode, level int8) []*treeNode {
 	var node = insertPath[level]
-	var M = len(node.children)
+	var M = node.len()
 	var m = tr.minEntries
 
+	atomic.AddUint64(&tr.stats.splits, 1)
 	tr.chooseSplitAxis(node, m, M)
 	splitIndex := tr.chooseSplitIndex(node, m, M)
 
-	spliced := make([]unsafe.Pointer, len(node.children)-splitIndex)
-	copy(spliced, node.children[splitIndex:])
-
-	newChildren := make([]unsafe.Pointer, splitIndex)
-	copy(newChildren, node.children[:splitIndex])
-	node.children = newChildren
-
-	newNode := createNode(spliced)
+	newNode := tr.newNode()
 	newNode.height = node.height
 	newNode.leaf = node.leaf
+	if node.leaf {
+		newNode.items = tr.makeItems(len(node.items) - splitIndex)
+		copy(newNode.items, node.items[splitIndex:])
+		node.items = node.items[:splitIndex]
+	} else {
+		newNode.children = tr.makeChildren(len(node.children) - splitIndex)
+		copy(newNode.children, node.children[splitIndex:])
+		node.children = node.children[:splitIndex]
+	}
 
-	calcBBox(node, tr.t)
-	calcBBox(newNode, tr.t)
+	tr.calcBBox(node)
+	tr.calcBBox(newNode)
+	tr.annotateNode(node)
+	tr.annotateNode(newNode)
 
 	if level != 0 {
-		insertPath[level-1].children = append(insertPath[level-1].children, unsafe.Pointer(newNode))
+		insertPath[level-1].children = append(insertPath[level-1].children, newNode.index)
 	} else {
 		tr.splitRoot(node, newNode)
 	}
 	return insertPath
 }
 func (tr *RTree) splitRoot(node, newNode *treeNode) {
-	tr.data = createNode([]unsafe.Pointer{unsafe.Pointer(node), unsafe.Pointer(newNode)})
-	tr.data.height = node.height + 1
-	tr.data.leaf = false
-	calcBBox(tr.data, tr.t)
+	root := tr.newNode()
+	root.children = tr.makeChildren(2)
+	root.children[0], root.children[1] = node.index, newNode.index
+	root.height = node.height + 1
+	root.leaf = false
+	tr.data = root
+	tr.calcBBox(root)
+	tr.annotateNode(root)
 }
 func (tr *RTree) chooseSplitIndex(node *treeNode, m, M int) int {
 	var i int
@@ -203,8 +348,8 @@ func (tr *RTree) chooseSplitIndex(node *treeNode, m, M int) int {
 	minOverlap = minArea
 
 	for i = m; i <= M-m; i++ {
-		bbox1 = distBBox(node, 0, i, nil, tr.t)
-		bbox2 = distBBox(node, i, M, nil, tr.t)
+		bbox1 = tr.distBBox(node, 0, i, nil)
+		bbox2 = tr.distBBox(node, i, M, nil)
 
 		overlap = bbox1.intersectionArea(bbox2)
 		area = bbox1.area() + bbox2.area()
@@ -232,39 +377,40 @@ func (tr *RTree) chooseSplitAxis(node *treeNode, m, M int) {
 	var xMargin = tr.allDistMargin(node, m, M, 1)
 	var yMargin = tr.allDistMargin(node, m, M, 2)
 	if xMargin < yMargin { // xy
-		sortNodes(node, 1, tr.t)
+		tr.sortNodes(node, 1)
 	}
 }
 
 type leafByDim struct {
 	node *treeNode
 	dim  int
-	t    transformer
+	rect rectFunc
 }
 
-func (arr *leafByDim) Len() int { return len(arr.node.children) }
+func (arr *leafByDim) Len() int { return len(arr.node.items) }
 func (arr *leafByDim) Less(i, j int) bool {
 	var a, b treeNode
-	fillBBox(pair.FromPointer(arr.node.children[i]), &a, arr.t)
-	fillBBox(pair.FromPointer(arr.node.children[j]), &b, arr.t)
+	fillBBox(arr.node.items[i], &a, arr.rect)
+	fillBBox(arr.node.items[j], &b, arr.rect)
 	if arr.dim == 1 {
 		return a.minX < b.minX
 	}
 	return a.minY < b.minY
 }
 func (arr *leafByDim) Swap(i, j int) {
-	arr.node.children[i], arr.node.children[j] = arr.node.children[j], arr.node.children[i]
+	arr.node.items[i], arr.node.items[j] = arr.node.items[j], arr.node.items[i]
 }
 
 type nodeByDim struct {
+	tr   *RTree
 	node *treeNode
 	dim  int
 }
 
 func (arr *nodeByDim) Len() int { return len(arr.node.children) }
 func (arr *nodeByDim) Less(i, j int) bool {
-	a := (*treeNode)(arr.node.children[i])
-	b := (*treeNode)(arr.node.children[j])
+	a := arr.tr.node(arr.node.children[i])
+	b := arr.tr.node(arr.node.children[j])
 	if arr.dim == 1 {
 		return a.minX < b.minX
 	}
@@ -273,18 +419,18 @@ func (arr *nodeByDim) Less(i, j int) bool {
 func (arr *nodeByDim) Swap(i, j int) {
 	arr.node.children[i], arr.node.children[j] = arr.node.children[j], arr.node.children[i]
 }
-func sortNodes(node *treeNode, dim int, t transformer) {
+func (tr *RTree) sortNodes(node *treeNode, dim int) {
 	if node.leaf {
-		sort.Sort(&leafByDim{node: node, dim: dim, t: t})
+		sort.Sort(&leafByDim{node: node, dim: dim, rect: tr.rect})
 	} else {
-		sort.Sort(&nodeByDim{node: node, dim: dim})
+		sort.Sort(&nodeByDim{tr: tr, node: node, dim: dim})
 	}
 }
 
 func (tr *RTree) allDistMargin(node *treeNode, m, M int, dim int) float64 {
-	sortNodes(node, dim, tr.t)
-	var leftBBox = distBBox(node, 0, m, nil, tr.t)
-	var rightBBox = distBBox(node, M-m, M, nil, tr.t)
+	tr.sortNodes(node, dim)
+	var leftBBox = tr.distBBox(node, 0, m, nil)
+	var rightBBox = tr.distBBox(node, M-m, M, nil)
 	var margin = leftBBox.margin() + rightBBox.margin()
 
 	var i int
@@ -292,23 +438,23 @@ func (tr *RTree) allDistMargin(node *treeNode, m, M int, dim int) float64 {
 	if node.leaf {
 		var child treeNode
 		for i = m; i < M-m; i++ {
-			fillBBox(pair.FromPointer(node.children[i]), &child, tr.t)
+			fillBBox(node.items[i], &child, tr.rect)
 			leftBBox.extend(&child)
 			margin += leftBBox.margin()
 		}
 		for i = M - m - 1; i >= m; i-- {
-			fillBBox(pair.FromPointer(node.children[i]), &child, tr.t)
+			fillBBox(node.items[i], &child, tr.rect)
 			leftBBox.extend(&child)
 			margin += rightBBox.margin()
 		}
 	} else {
 		for i = m; i < M-m; i++ {
-			child := (*treeNode)(node.children[i])
+			child := tr.node(node.children[i])
 			leftBBox.extend(child)
 			margin += leftBBox.margin()
 		}
 		for i = M - m - 1; i >= m; i-- {
-			child := (*treeNode)(node.children[i])
+			child := tr.node(node.children[i])
 			leftBBox.extend(child)
 			margin += rightBBox.margin()
 		}
@@ -325,8 +471,8 @@ func (tr *RTree) chooseSubtree(bbox, node *treeNode, level int8, path []*treeNod
 		}
 		minEnlargement = mathInfPos
 		minArea = minEnlargement
-		for _, ptr := range node.children {
-			child := (*treeNode)(ptr)
+		for _, index := range node.children {
+			child := tr.node(index)
 			area = child.area()
 			enlargement = bbox.enlargedArea(child) - area
 			if enlargement < minEnlargement {
@@ -345,7 +491,7 @@ func (tr *RTree) chooseSubtree(bbox, node *treeNode, level int8, path []*treeNod
 		if targetNode != nil {
 			node = targetNode
 		} else if len(node.children) > 0 {
-			node = (*treeNode)(node.children[0])
+			node = tr.node(node.children[0])
 		} else {
 			node = nil
 		}
@@ -353,12 +499,12 @@ func (tr *RTree) chooseSubtree(bbox, node *treeNode, level int8, path []*treeNod
 	return node, path
 }
 
-func calcBBox(node *treeNode, t transformer) {
-	distBBox(node, 0, len(node.children), node, t)
+func (tr *RTree) calcBBox(node *treeNode) {
+	tr.distBBox(node, 0, node.len(), node)
 }
-func distBBox(node *treeNode, k, p int, destNode *treeNode, t transformer) *treeNode {
+func (tr *RTree) distBBox(node *treeNode, k, p int, destNode *treeNode) *treeNode {
 	if destNode == nil {
-		destNode = createNode(nil)
+		destNode = createNode()
 	} else {
 		destNode.minX = mathInfPos
 		destNode.minY = mathInfPos
@@ -367,41 +513,64 @@ func distBBox(node *treeNode, k, p int, destNode *treeNode, t transformer) *tree
 	}
 
 	for i := k; i < p; i++ {
-		ptr := node.children[i]
 		if node.leaf {
 			var child treeNode
-			fillBBox(pair.FromPointer(ptr), &child, t)
+			fillBBox(node.items[i], &child, tr.rect)
 			destNode.extend(&child)
 		} else {
-			child := (*treeNode)(ptr)
-			destNode.extend(child)
+			destNode.extend(tr.node(node.children[i]))
 		}
 	}
 	return destNode
 }
 
 func (tr *RTree) Search(bbox pair.Pair, iter func(item pair.Pair) bool) bool {
-	min, max := geobin.WrapBinary(bbox.Value()).Rect(tr.t)
-	return tr.searchBBox(min[0], min[1], max[0], max[1], iter)
+	min, max := tr.rect(bbox.Value())
+	return tr.searchBBox(min[0], min[1], max[0], max[1], false, iter)
+}
+
+// SearchRect is like Search, but takes the rect directly rather than reading
+// it from an item value. The rect is not transformed.
+func (tr *RTree) SearchRect(min, max [2]float64, iter func(item pair.Pair) bool) bool {
+	return tr.searchBBox(min[0], min[1], max[0], max[1], false, iter)
+}
+
+// SearchRectTouching is like SearchRect, but finds the items that only
+// touch the rect even with the ExcludeTouching option, so an item can be
+// found by its own rect, which has no inside when it's a point or a line.
+func (tr *RTree) SearchRectTouching(min, max [2]float64, iter func(item pair.Pair) bool) bool {
+	return tr.searchBBox(min[0], min[1], max[0], max[1], true, iter)
 }
 
-func (tr *RTree) searchBBox(minX, minY, maxX, maxY float64,
+func (tr *RTree) searchBBox(minX, minY, maxX, maxY float64, touching bool,
 	iter func(item pair.Pair) bool) bool {
+	atomic.AddUint64(&tr.stats.searches, 1)
 	var bboxn treeNode
 	bboxn.minX, bboxn.minY = minX, minY
 	bboxn.maxX, bboxn.maxY = maxX, maxY
+	if !touching {
+		tr.exclude(&bboxn)
+	}
+	if tr.wrapX > 0 {
+		return tr.searchWrapped(bboxn, iter, tr.search)
+	}
 	if !tr.data.intersects(&bboxn) {
 		return true
 	}
-	return search(tr.data, &bboxn, iter, tr.t)
+	return tr.search(&bboxn, iter)
+}
+
+// search searches from the root, which must intersect the bbox.
+func (tr *RTree) search(bbox *treeNode, iter func(item pair.Pair) bool) bool {
+	return tr.searchNode(tr.data, bbox, iter)
 }
 
-func search(node, bbox *treeNode, iter func(item pair.Pair) bool, t transformer) bool {
+func (tr *RTree) searchNode(node, bbox *treeNode, iter func(item pair.Pair) bool) bool {
 	if node.leaf {
-		for i := 0; i < len(node.children); i++ {
-			item := pair.FromPointer(node.children[i])
+		for i := 0; i < len(node.items); i++ {
+			item := node.items[i]
 			var child treeNode
-			fillBBox(item, &child, t)
+			fillBBox(item, &child, tr.rect)
 			if bbox.intersects(&child) {
 				if !iter(item) {
 					return false
@@ -409,24 +578,26 @@ func search(node, bbox *treeNode, iter func(item pair.Pair) bool, t transformer)
 			}
 		}
 	} else {
-		for i := 0; i < len(node.children); i++ {
-			child := (*treeNode)(node.children[i])
-			if bbox.intersects(child) {
-				if !search(child, bbox, iter, t) {
-					return false
-				}
-			}
-		}
+		return tr.searchChildren(node, bbox, func(child *treeNode) bool {
+			return tr.searchNode(child, bbox, iter)
+		})
 	}
 	return true
 }
 
 func (tr *RTree) Remove(item pair.Pair) {
-	min, max := geobin.WrapBinary(item.Value()).Rect(tr.t)
-	tr.removeBBox(item, min[0], min[1], max[0], max[1])
+	tr.RemoveOK(item)
+}
+
+// RemoveOK is like Remove, but returns false if the item wasn't in the
+// tree.
+func (tr *RTree) RemoveOK(item pair.Pair) bool {
+	tr.checkFrozen()
+	min, max := tr.rect(item.Value())
+	return tr.removeBBox(item, min[0], min[1], max[0], max[1])
 }
 
-func (tr *RTree) removeBBox(item pair.Pair, minX, minY, maxX, maxY float64) {
+func (tr *RTree) removeBBox(item pair.Pair, minX, minY, maxX, maxY float64) bool {
 	var bbox treeNode
 	bbox.minX, bbox.minY = minX, minY
 	bbox.maxX, bbox.maxY = maxX, maxY
@@ -458,12 +629,14 @@ func (tr *RTree) removeBBox(item pair.Pair, minX, minY, maxX, maxY float64) {
 			index = findItem(item, node)
 			if index != -1 {
 				// item found, remove the item and condense tree upwards
-				copy(node.children[index:], node.children[index+1:])
-				node.children[len(node.children)-1] = nil
-				node.children = node.children[:len(node.children)-1]
+				copy(node.items[index:], node.items[index+1:])
+				node.items[len(node.items)-1] = pair.Pair{}
+				node.items = node.items[:len(node.items)-1]
 				path = append(path, node)
 				tr.condense(path)
-				goto done
+				tr.reusePath = path
+				atomic.AddUint64(&tr.stats.removes, 1)
+				return true
 			}
 		}
 		if !goingUp && !node.leaf && node.contains(&bbox) { // go down
@@ -471,77 +644,167 @@ func (tr *RTree) removeBBox(item pair.Pair, minX, minY, maxX, maxY float64) {
 			indexes = append(indexes, i)
 			i = 0
 			parent = node
-			node = (*treeNode)(node.children[0])
+			node = tr.node(node.children[0])
 		} else if parent != nil { // go right
 			i++
 			if i == len(parent.children) {
 				node = nil
 			} else {
-				node = (*treeNode)(parent.children[i])
+				node = tr.node(parent.children[i])
 			}
 			goingUp = false
 		} else {
 			node = nil
 		}
 	}
-done:
 	tr.reusePath = path
-	return
+	return false
 }
+
+// orphan is a child of an under-filled node that was removed by condense,
+// which is either an item or a node.
+type orphan struct {
+	item pair.Pair
+	node *treeNode // nil for items
+}
+
 func (tr *RTree) condense(path []*treeNode) {
-	// go through the path, removing empty nodes and updating bboxes
-	var siblings []unsafe.Pointer
+	// go through the path, removing empty nodes and updating bboxes. With
+	// the ReinsertOrphans option, under-filled nodes are removed too. Their
+	// children are merged into a sibling that has room for them, otherwise
+	// they are reinserted afterwards.
+	var siblings []int32
+	var orphans []orphan
 	for i := len(path) - 1; i >= 0; i-- {
-		if len(path[i].children) == 0 {
+		underfilled := tr.reinsertOrphans && i > 0 &&
+			path[i].len() > 0 && path[i].len() < tr.minEntries
+		if underfilled {
+			if sibling := tr.mergeSibling(path[i-1], path[i]); sibling != nil {
+				sibling.items = append(sibling.items, path[i].items...)
+				sibling.children = append(sibling.children, path[i].children...)
+				tr.calcBBox(sibling)
+				tr.annotateNode(sibling)
+				tr.merged++
+			} else {
+				for _, item := range path[i].items {
+					orphans = append(orphans, orphan{item: item})
+				}
+				for _, index := range path[i].children {
+					orphans = append(orphans, orphan{node: tr.node(index)})
+				}
+				tr.reinserted++
+			}
+		}
+		if path[i].len() == 0 || underfilled {
 			if i > 0 {
+				atomic.AddUint64(&tr.stats.condenses, 1)
 				siblings = path[i-1].children
 				index := -1
 				for j := 0; j < len(siblings); j++ {
-					if siblings[j] == unsafe.Pointer(path[i]) {
+					if siblings[j] == path[i].index {
 						index = j
 						break
 					}
 				}
 				copy(siblings[index:], siblings[index+1:])
-				siblings[len(siblings)-1] = nil
 				siblings = siblings[:len(siblings)-1]
 				path[i-1].children = siblings
+				tr.freeNode(path[i])
 			} else {
-				tr.data = createNode(nil) // clear tree
+				// clear tree
+				tr.freeNode(tr.data)
+				tr.data = tr.newNode()
+				tr.data.items = tr.makeItems(0)
 			}
 		} else {
-			calcBBox(path[i], tr.t)
+			tr.calcBBox(path[i])
+			tr.annotateNode(path[i])
+		}
+	}
+	if len(orphans) > 0 {
+		tr.reinsert(orphans)
+	}
+}
+
+// mergeSibling returns the sibling of the node that has room for all of the
+// node's children with the least enlargement, or nil if none have room.
+func (tr *RTree) mergeSibling(parent, node *treeNode) *treeNode {
+	var best *treeNode
+	var bestEnlargement float64
+	for _, index := range parent.children {
+		sibling := tr.node(index)
+		if sibling == node || sibling.len()+node.len() > tr.maxEntries {
+			continue
+		}
+		enlargement := node.enlargedArea(sibling) - sibling.area()
+		if best == nil || enlargement < bestEnlargement {
+			best, bestEnlargement = sibling, enlargement
+		}
+	}
+	return best
+}
+
+// Underflows returns the number of nodes that were left under-filled by a
+// Remove and then merged into a sibling or reinserted. Both are zero unless
+// the ReinsertOrphans option is set.
+func (tr *RTree) Underflows() (merged, reinserted int) {
+	return tr.merged, tr.reinserted
+}
+
+// reinsert shortens the tree while the root has a single child node, and
+// then inserts the orphans back at their original levels.
+func (tr *RTree) reinsert(orphans []orphan) {
+	for !tr.data.leaf && len(tr.data.children) == 1 {
+		root := tr.data
+		tr.data = tr.node(root.children[0])
+		tr.freeNode(root)
+	}
+	for _, o := range orphans {
+		var bbox treeNode
+		if o.node == nil {
+			fillBBox(o.item, &bbox, tr.rect)
+			tr.insert(&bbox, o.item, tr.data.height-1, false)
+		} else if o.node.height < tr.data.height {
+			tr.insert(o.node, pair.Pair{}, tr.data.height-o.node.height-1, true)
+		} else {
+			// the tree is now too short for the node, so reinsert its items
+			tr.scan(o.node, func(item pair.Pair) bool {
+				fillBBox(item, &bbox, tr.rect)
+				tr.insert(&bbox, item, tr.data.height-1, false)
+				return true
+			})
+			tr.freeTree(o.node)
 		}
 	}
 }
 func findItem(item pair.Pair, node *treeNode) int {
 	ptr := item.Pointer()
-	for i := 0; i < len(node.children); i++ {
-		if node.children[i] == ptr {
+	for i := 0; i < len(node.items); i++ {
+		if node.items[i].Pointer() == ptr {
 			return i
 		}
 	}
 	return -1
 }
 func (tr *RTree) Count() int {
-	return count(tr.data)
+	return tr.count(tr.data)
 }
-func count(node *treeNode) int {
+func (tr *RTree) count(node *treeNode) int {
 	if node.leaf {
-		return len(node.children)
+		return len(node.items)
 	}
 	var n int
-	for _, ptr := range node.children {
-		n += count((*treeNode)(ptr))
+	for _, index := range node.children {
+		n += tr.count(tr.node(index))
 	}
 	return n
 }
 
 func (tr *RTree) Traverse(iter func(min, max [2]float64, level int, item pair.Pair) bool) {
-	traverse(tr.data, iter, tr.t)
+	tr.traverse(tr.data, iter)
 }
 
-func traverse(node *treeNode, iter func(min, max [2]float64, level int, item pair.Pair) bool, t transformer) bool {
+func (tr *RTree) traverse(node *treeNode, iter func(min, max [2]float64, level int, item pair.Pair) bool) bool {
 	if !iter(
 		[2]float64{node.minX, node.minY},
 		[2]float64{node.maxX, node.maxY},
@@ -550,10 +813,9 @@ func traverse(node *treeNode, iter func(min, max [2]float64, level int, item pai
 		return false
 	}
 	if node.leaf {
-		for _, ptr := range node.children {
-			item := pair.FromPointer(ptr)
+		for _, item := range node.items {
 			var bbox treeNode
-			fillBBox(item, &bbox, t)
+			fillBBox(item, &bbox, tr.rect)
 			if !iter(
 				[2]float64{bbox.minX, bbox.minY},
 				[2]float64{bbox.maxX, bbox.maxY},
@@ -563,8 +825,8 @@ func traverse(node *treeNode, iter func(min, max [2]float64, level int, item pai
 			}
 		}
 	} else {
-		for _, ptr := range node.children {
-			if !traverse((*treeNode)(ptr), iter, t) {
+		for _, index := range node.children {
+			if !tr.traverse(tr.node(index), iter) {
 				return false
 			}
 		}
@@ -573,19 +835,19 @@ func traverse(node *treeNode, iter func(min, max [2]float64, level int, item pai
 }
 
 func (tr *RTree) Scan(iter func(item pair.Pair) bool) bool {
-	return scan(tr.data, iter)
+	return tr.scan(tr.data, iter)
 }
 
-func scan(node *treeNode, iter func(item pair.Pair) bool) bool {
+func (tr *RTree) scan(node *treeNode, iter func(item pair.Pair) bool) bool {
 	if node.leaf {
-		for _, ptr := range node.children {
-			if !iter(pair.FromPointer(ptr)) {
+		for _, item := range node.items {
+			if !iter(item) {
 				return false
 			}
 		}
 	} else {
-		for _, ptr := range node.children {
-			if !scan((*treeNode)(ptr), iter) {
+		for _, index := range node.children {
+			if !tr.scan(tr.node(index), iter) {
 				return false
 			}
 		}
@@ -594,85 +856,9 @@ func scan(node *treeNode, iter func(item pair.Pair) bool) bool {
 }
 
 func (tr *RTree) Bounds() (min, max [2]float64) {
-	if len(tr.data.children) == 0 {
+	if tr.data.len() == 0 {
 		return [2]float64{0, 0}, [2]float64{0, 0}
 	}
 	return [2]float64{tr.data.minX, tr.data.minY},
 		[2]float64{tr.data.maxX, tr.data.maxY}
 }
-
-// Load bulk loads items. For now it only loads each item one at a time.
-// In the future it should use the OMT algorithm.
-func (tr *RTree) Load(items []pair.Pair) {
-	for _, item := range items {
-		tr.Insert(item)
-	}
-}
-
-func (tr *RTree) SavePNG(path string, width, height int, scale float64, showNodes bool, withGIF bool, printer io.Writer) error {
-	p := pinhole.New()
-	tr.Traverse(func(min, max [2]float64, level int, item pair.Pair) bool {
-		p.Begin()
-		if level > 0 && showNodes {
-			p.DrawCube(min[0], min[1], 0, max[0], max[1], 0)
-			switch level {
-			default:
-				p.Colorize(color.RGBA{64, 64, 64, 128})
-			case 1:
-				p.Colorize(color.RGBA{32, 64, 32, 64})
-			case 2:
-				p.Colorize(color.RGBA{48, 48, 96, 96})
-			case 3:
-				p.Colorize(color.RGBA{96, 128, 128, 128})
-			case 4:
-				p.Colorize(color.RGBA{128, 128, 196, 196})
-			}
-		} else {
-			p.DrawDot(min[0], min[1], 0, 0.05)
-			p.Colorize(color.White)
-		}
-		p.End()
-		return true
-	})
-	p.Scale(scale, scale, scale)
-	// render the paths in an image
-	opts := *pinhole.DefaultImageOptions
-	opts.LineWidth = 0.025
-	opts.BGColor = color.Black
-	if err := p.SavePNG(path, width, height, &opts); err != nil {
-		return err
-	}
-	if printer != nil {
-		fmt.Fprintf(printer, "wrote %s\n", path)
-	}
-	if withGIF {
-		var palette = palette.WebSafe
-		outGif := &gif.GIF{}
-		for i := 0; i < 60; i++ {
-			p.Rotate(0, math.Pi*2/60.0, 0)
-			inPng := p.Image(width, height, &opts)
-			inGif := image.NewPaletted(inPng.Bounds(), palette)
-			draw.Draw(inGif, inPng.Bounds(), inPng, image.Point{}, draw.Src)
-			outGif.Image = append(outGif.Image, inGif)
-			outGif.Delay = append(outGif.Delay, 0)
-			if printer != nil {
-				fmt.Fprintf(printer, "wrote gif frame %d/%d\n", i, 60)
-			}
-		}
-		if strings.HasSuffix(path, ".png") {
-			path = path[:len(path)-4] + ".gif"
-		}
-		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0600)
-		if err != nil {
-			return err
-		}
-		defer f.Close()
-		if err := gif.EncodeAll(f, outGif); err != nil {
-			return err
-		}
-		if printer != nil {
-			fmt.Fprintf(printer, "wrote %s\n", path)
-		}
-	}
-	return nil
-}
@@ -6,14 +6,16 @@ import (
 	"math/rand"
 	"os"
 	"runtime"
-	"sort"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/tidwall/geobin"
 	"github.com/tidwall/pair"
 	"github.com/tidwall/pair-rtree/cities"
+	"github.com/tidwall/pair-rtree/rtreetest"
+	"github.com/tidwall/pair-rtree/viz"
 )
 
 func makePointPair2(key string, x, y float64) pair.Pair {
@@ -158,7 +160,7 @@ func testRandom(t *testing.T, which string, n int) {
 		arr = append(arr, item)
 		return true
 	})
-	assert.True(t, testHasSameItems(objs, arr))
+	assert.True(t, rtreetest.SameItems(objs, arr))
 
 	// search
 	testSearch(t, tr, objs, 0.10, true)
@@ -212,41 +214,13 @@ func testKNN(t *testing.T, tr *RTree, objs []pair.Pair, n int, check bool) {
 	assert.True(t, n > len(objs) || n == len(arr1))
 
 	// get the KNN for the original array
-	nobjs := make([]pair.Pair, len(objs))
-	copy(nobjs, objs)
-	sort.Slice(nobjs, func(i, j int) bool {
-		imin, imax := geobin.WrapBinary(nobjs[i].Value()).Rect(nil)
-		jmin, jmax := geobin.WrapBinary(nobjs[j].Value()).Rect(nil)
-		idist := testBoxDist(x, y, [2]float64{imin[0], imin[1]}, [2]float64{imax[0], imax[1]})
-		jdist := testBoxDist(x, y, [2]float64{jmin[0], jmin[1]}, [2]float64{jmax[0], jmax[1]})
-		return idist < jdist
-	})
-	arr2 := nobjs[:len(arr1)]
-	var dists2 []float64
-	for i := 0; i < len(arr2); i++ {
-		min, max := geobin.WrapBinary(arr2[i].Value()).Rect(nil)
-		dist := testBoxDist(x, y, [2]float64{min[0], min[1]}, [2]float64{max[0], max[1]})
-		dists2 = append(dists2, dist)
-	}
+	_, dists2 := rtreetest.KNN(objs, x, y, 0)
+	dists2 = dists2[:len(arr1)]
 	// only compare the distances, not the objects because rectangles with
 	// a dist of zero will not be ordered.
 	assert.Equal(t, dists1, dists2)
 
 }
-func testBoxDist(x, y float64, min, max [2]float64) float64 {
-	dx := testAxisDist(x, min[0], max[0])
-	dy := testAxisDist(y, min[1], max[1])
-	return dx*dx + dy*dy
-}
-func testAxisDist(k, min, max float64) float64 {
-	if k < min {
-		return min - k
-	}
-	if k <= max {
-		return 0
-	}
-	return k - max
-}
 func testSearch(t *testing.T, tr *RTree, objs []pair.Pair, percent float64, check bool) {
 	min, max := tr.Bounds()
 	minx := ((max[0]+min[0])/2 - ((max[0]-min[0])*percent)/2)
@@ -264,51 +238,9 @@ func testSearch(t *testing.T, tr *RTree, objs []pair.Pair, percent float64, chec
 	if !check {
 		return
 	}
-	var arr2 []pair.Pair
-	for _, obj := range objs {
-		if testIntersects(obj, box) {
-			arr2 = append(arr2, obj)
-		}
-	}
+	arr2 := rtreetest.Search(objs, box)
 	assert.Equal(t, len(arr1), len(arr2))
-	for _, o1 := range arr1 {
-		var found bool
-		for _, o2 := range arr2 {
-			if o2 == o1 {
-				found = true
-				break
-			}
-		}
-		if !found {
-			t.Fatalf("not found")
-		}
-	}
-}
-
-func testIntersects(obj, box pair.Pair) bool {
-	amin, amax := geobin.WrapBinary(obj.Value()).Rect(nil)
-	bmin, bmax := geobin.WrapBinary(box.Value()).Rect(nil)
-	return bmin[0] <= amax[0] && bmin[1] <= amax[1] &&
-		bmax[0] >= amin[0] && bmax[1] >= amin[1]
-}
-
-func testHasSameItems(a1, a2 []pair.Pair) bool {
-	if len(a1) != len(a2) {
-		return false
-	}
-	for _, p1 := range a1 {
-		var found bool
-		for _, p2 := range a2 {
-			if p1 == p2 {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
-		}
-	}
-	return true
+	assert.True(t, rtreetest.SameItems(arr1, arr2))
 }
 
 func TestOutputPNG(t *testing.T) {
@@ -329,7 +261,7 @@ func TestOutputPNG(t *testing.T) {
 		}
 	}
 	withGIF := os.Getenv("GIFOUTPUT") != ""
-	if err := tr.SavePNG("out.png", 1000, 1000, 2/360.0, true, withGIF, os.Stdout); err != nil {
+	if err := savePNG(tr, "out.png", 2/360.0, withGIF); err != nil {
 		t.Fatal(err)
 	}
 	if !withGIF {
@@ -337,6 +269,46 @@ func TestOutputPNG(t *testing.T) {
 	}
 }
 
+func savePNG(tr *RTree, path string, scale float64, withGIF bool) error {
+	opts := *viz.Default2DOptions
+	opts.Scale = scale
+	opts.GIF = withGIF
+	return viz.SavePNG(viz.From2D(tr), path, 1000, 1000, &opts, os.Stdout)
+}
+
+func TestOutputQueryPNG(t *testing.T) {
+	tr := New(nil)
+	for _, city := range cities.Cities {
+		tr.Insert(makePointPair2("", city.Longitude, city.Latitude))
+	}
+	opts := *viz.Default2DOptions
+	opts.Scale = 2 / 360.0
+	opts.Queries = []pair.Pair{
+		makeBoundsPair2("", -125, 30, -110, 45),
+		makeBoundsPair2("", 0, 40, 20, 55),
+	}
+	if err := viz.SavePNG(viz.From2D(tr), "query.png", 1000, 1000, &opts, os.Stdout); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOutputKNNGIF(t *testing.T) {
+	if os.Getenv("GIFOUTPUT") == "" {
+		fmt.Println("use GIFOUTPUT=1 for knn animated gif")
+		return
+	}
+	tr := New(nil)
+	for _, city := range cities.Cities {
+		tr.Insert(makePointPair2("", city.Longitude, city.Latitude))
+	}
+	opts := *viz.Default2DOptions
+	opts.Scale = 2 / 360.0
+	opts.Delay = 10
+	if err := viz.SaveKNNGIF(viz.From2D(tr), "knn.gif", 1000, 1000, -112, 33, 0, 10, &opts, os.Stdout); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func BenchmarkInsert(b *testing.B) {
 	rand.Seed(time.Now().UnixNano())
 	var points []pair.Pair
@@ -353,3 +325,85 @@ func BenchmarkInsert(b *testing.B) {
 	}
 
 }
+
+func TestReinsertOrphans(t *testing.T) {
+	opts := *DefaultOptions
+	opts.ReinsertOrphans = true
+	tr := New(&opts)
+	var items []pair.Pair
+	for i := 0; i < 5000; i++ {
+		item := makeRandom("rect")
+		tr.Insert(item)
+		items = append(items, item)
+	}
+	perm := rand.Perm(len(items))
+	for _, i := range perm[:4000] {
+		tr.Remove(items[i])
+	}
+	var remain []pair.Pair
+	for _, i := range perm[4000:] {
+		remain = append(remain, items[i])
+	}
+	assert.Equal(t, len(remain), tr.Count())
+	merged, reinserted := tr.Underflows()
+	assert.True(t, merged > 0 && reinserted > 0)
+
+	// every node, other than the root, must have the minimum entries and
+	// the leaves must all be at the same depth.
+	var check func(node *treeNode, depth int)
+	leafDepth := -1
+	check = func(node *treeNode, depth int) {
+		if node != tr.data {
+			assert.True(t, node.len() >= tr.minEntries)
+		}
+		if node.leaf {
+			if leafDepth == -1 {
+				leafDepth = depth
+			}
+			assert.Equal(t, leafDepth, depth)
+			return
+		}
+		for _, index := range node.children {
+			child := tr.node(index)
+			assert.Equal(t, node.height-1, child.height)
+			assert.True(t, node.contains(child))
+			check(child, depth+1)
+		}
+	}
+	check(tr.data, 0)
+
+	var found []pair.Pair
+	tr.Search(makeBoundsPair2("", -180, -90, 180, 90), func(item pair.Pair) bool {
+		found = append(found, item)
+		return true
+	})
+	assert.True(t, rtreetest.SameItems(remain, found))
+	for _, item := range remain {
+		tr.Remove(item)
+	}
+	assert.Equal(t, 0, tr.Count())
+}
+
+func TestNodeSlab(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < tr.maxEntries+1; i++ {
+		tr.Insert(makeRandom("point"))
+	}
+	// the split created a new node and a new root from the same slab
+	assert.Equal(t, 2, len(tr.data.children))
+	a := uintptr(unsafe.Pointer(tr.node(tr.data.children[1])))
+	b := uintptr(unsafe.Pointer(tr.data))
+	assert.Equal(t, unsafe.Sizeof(treeNode{}), b-a)
+	assert.Equal(t, int32(3), tr.numNodes)
+	assert.Equal(t, 1, len(tr.slabs))
+}
+
+func TestRemoveOK(t *testing.T) {
+	tr := New(nil)
+	item := makeRandom("point")
+	tr.Insert(item)
+	assert.False(t, tr.RemoveOK(makeRandom("point")))
+	assert.True(t, tr.RemoveOK(item))
+	assert.False(t, tr.RemoveOK(item))
+	assert.Equal(t, 0, tr.Count())
+}
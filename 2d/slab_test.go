@@ -0,0 +1,62 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+	"github.com/tidwall/pair-rtree/rtreetest"
+)
+
+func TestExpectedItems(t *testing.T) {
+	opts := *DefaultOptions
+	opts.ExpectedItems = 100000
+	tr := New(&opts)
+	assert.Equal(t, 1024, 1<<tr.slabShift)
+	var items []pair.Pair
+	for i := 0; i < 5000; i++ {
+		items = append(items, makeRandom("rect"))
+		tr.Insert(items[i])
+	}
+	var walk func(node *treeNode)
+	walk = func(node *treeNode) {
+		if node.leaf {
+			assert.Equal(t, tr.maxEntries+1, cap(node.items))
+		} else {
+			assert.Equal(t, tr.maxEntries+1, cap(node.children))
+			for _, index := range node.children {
+				walk(tr.node(index))
+			}
+		}
+	}
+	walk(tr.data)
+	var got []pair.Pair
+	tr.Scan(func(item pair.Pair) bool {
+		got = append(got, item)
+		return true
+	})
+	assert.True(t, rtreetest.SameItems(items, got))
+
+	assert.Equal(t, nodeSlabSize, slabSizeFor(0, 9))
+	assert.Equal(t, maxSlabSize, slabSizeFor(1<<30, 9))
+}
+
+func TestFreeNodes(t *testing.T) {
+	tr := New(nil)
+	var items []pair.Pair
+	for i := 0; i < 5000; i++ {
+		items = append(items, makeRandom("rect"))
+		tr.Insert(items[i])
+	}
+	numNodes := tr.numNodes
+	for _, item := range items {
+		tr.Remove(item)
+	}
+	// the nodes that were dropped by the removes are used again
+	for _, item := range items {
+		tr.Insert(item)
+	}
+	assert.NoError(t, tr.Check())
+	assert.Equal(t, numNodes, tr.numNodes)
+	assert.Equal(t, 5000, tr.Count())
+}
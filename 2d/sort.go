@@ -0,0 +1,111 @@
+package rtree
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/tidwall/pair"
+)
+
+type SortOrder int
+
+const (
+	KeyOrder     SortOrder = iota // by key, then by value
+	HilbertOrder                  // by the hilbert value of the item center
+)
+
+// ScanSorted iterates over all items in a stable order that does not
+// depend on the shape of the tree. All items are gathered and sorted before
+// the first call to iter.
+func (tr *RTree) ScanSorted(by SortOrder, iter func(item pair.Pair) bool) bool {
+	var items []pair.Pair
+	tr.Scan(func(item pair.Pair) bool {
+		items = append(items, item)
+		return true
+	})
+	if by == HilbertOrder {
+		min, max := tr.Bounds()
+		hvals := make(map[pair.Pair]uint64, len(items))
+		for _, item := range items {
+			imin, imax := tr.rect(item.Value())
+			var coords [2]uint32
+			for i := 0; i < 2; i++ {
+				coords[i] = hilbertCoord((imin[i]+imax[i])/2, min[i], max[i])
+			}
+			hvals[item] = hilbert(coords[:], 32)
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			hi, hj := hvals[items[i]], hvals[items[j]]
+			if hi != hj {
+				return hi < hj
+			}
+			return keyLess(items[i], items[j])
+		})
+	} else {
+		sort.SliceStable(items, func(i, j int) bool {
+			return keyLess(items[i], items[j])
+		})
+	}
+	for _, item := range items {
+		if !iter(item) {
+			return false
+		}
+	}
+	return true
+}
+
+func keyLess(a, b pair.Pair) bool {
+	if c := bytes.Compare(a.Key(), b.Key()); c != 0 {
+		return c < 0
+	}
+	return bytes.Compare(a.Value(), b.Value()) < 0
+}
+
+// hilbertCoord scales a value within min/max to the full uint32 range.
+func hilbertCoord(v, min, max float64) uint32 {
+	if max <= min || v <= min {
+		return 0
+	}
+	if v >= max {
+		return 1<<32 - 1
+	}
+	return uint32((v - min) / (max - min) * (1<<32 - 1))
+}
+
+// hilbert returns the distance along an n-dimensional hilbert curve using
+// Skilling's transpose method. The coords are modified.
+func hilbert(x []uint32, bits uint) uint64 {
+	n := len(x)
+	m := uint32(1) << (bits - 1)
+	for q := m; q > 1; q >>= 1 {
+		p := q - 1
+		for i := 0; i < n; i++ {
+			if x[i]&q != 0 {
+				x[0] ^= p
+			} else {
+				t := (x[0] ^ x[i]) & p
+				x[0] ^= t
+				x[i] ^= t
+			}
+		}
+	}
+	for i := 1; i < n; i++ {
+		x[i] ^= x[i-1]
+	}
+	var t uint32
+	for q := m; q > 1; q >>= 1 {
+		if x[n-1]&q != 0 {
+			t ^= q - 1
+		}
+	}
+	for i := 0; i < n; i++ {
+		x[i] ^= t
+	}
+	var d uint64
+	for b := int(bits) - 1; b >= 0; b-- {
+		for i := 0; i < n; i++ {
+			d = d<<1 | uint64(x[i]>>uint(b)&1)
+		}
+	}
+	return d
+}
@@ -0,0 +1,50 @@
+package rtree
+
+import "sync/atomic"
+
+// Stats are the numbers of operations on a tree since it was created, or
+// since ResetStats.
+type Stats struct {
+	Inserts   uint64
+	Removes   uint64 // only the removes of items that were in the tree
+	Searches  uint64 // searches with a bbox
+	KNNs      uint64
+	Splits    uint64 // nodes that were split by inserts
+	Condenses uint64 // nodes that were emptied or under-filled by removes
+}
+
+// stats are the counters behind Stats. They're updated atomically, so that
+// the searches on a tree may be run from many goroutines.
+type stats struct {
+	inserts, removes, searches, knns, splits, condenses uint64
+}
+
+// Stats returns the numbers of operations on the tree.
+func (tr *RTree) Stats() Stats {
+	return tr.stats.load()
+}
+
+func (s *stats) load() Stats {
+	return Stats{
+		Inserts:   atomic.LoadUint64(&s.inserts),
+		Removes:   atomic.LoadUint64(&s.removes),
+		Searches:  atomic.LoadUint64(&s.searches),
+		KNNs:      atomic.LoadUint64(&s.knns),
+		Splits:    atomic.LoadUint64(&s.splits),
+		Condenses: atomic.LoadUint64(&s.condenses),
+	}
+}
+
+func (s *stats) copy() *stats {
+	st := s.load()
+	return &stats{st.Inserts, st.Removes, st.Searches, st.KNNs, st.Splits, st.Condenses}
+}
+
+// ResetStats sets the numbers of operations to zero.
+func (tr *RTree) ResetStats() {
+	s := tr.stats
+	for _, n := range []*uint64{&s.inserts, &s.removes, &s.searches, &s.knns,
+		&s.splits, &s.condenses} {
+		atomic.StoreUint64(n, 0)
+	}
+}
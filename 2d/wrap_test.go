@@ -0,0 +1,52 @@
+package rtree
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestWrapDist(t *testing.T) {
+	assert.Equal(t, 0.0, wrapDist(5, 0, 10, 360))
+	assert.Equal(t, 10.0, wrapDist(-180, 160, 170, 360))
+	assert.Equal(t, 20.0, wrapDist(-170, 160, 170, 360))
+	assert.Equal(t, 5.0, wrapDist(15, 0, 10, 360))
+	assert.Equal(t, 0.0, wrapDist(5, 100, 500, 360))
+}
+
+func TestWrapX(t *testing.T) {
+	opts := *DefaultOptions
+	opts.WrapX = 360
+	tr := New(&opts)
+	tr.Insert(makePointPair2("east", 179, 0))
+	tr.Insert(makePointPair2("west", -179, 0))
+	tr.Insert(makePointPair2("wide", 0, 0))
+	tr.Insert(makeBoundsPair2("band", -180, 10, 180, 11))
+	for i := 0; i < 100; i++ {
+		tr.Insert(makePointPair2("mid", float64(i%50)-25, 50))
+	}
+
+	var keys []string
+	tr.SearchRect([2]float64{170, -1}, [2]float64{190, 20}, func(item pair.Pair) bool {
+		keys = append(keys, string(item.Key()))
+		return true
+	})
+	sort.Strings(keys)
+	assert.Equal(t, []string{"band", "east", "west"}, keys)
+
+	keys = nil
+	tr.KNN(-178, 0, func(item pair.Pair, dist float64) bool {
+		keys = append(keys, string(item.Key()))
+		return len(keys) < 2
+	})
+	assert.Equal(t, []string{"west", "east"}, keys)
+
+	var n int
+	tr.SearchRect([2]float64{-400, 49}, [2]float64{400, 51}, func(item pair.Pair) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, 100, n)
+}
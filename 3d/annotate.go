@@ -0,0 +1,118 @@
+package rtree
+
+import "github.com/tidwall/pair"
+
+// annotateNode recomputes the annotation for the node from its children.
+func (tr *RTree) annotateNode(node *treeNode) {
+	if tr.annotate == nil {
+		return
+	}
+	if node.leaf {
+		items := make([]pair.Pair, len(node.items))
+		copy(items, node.items)
+		node.annotation = tr.annotate(items, nil)
+	} else {
+		children := make([]interface{}, len(node.children))
+		for i, index := range node.children {
+			children[i] = tr.node(index).annotation
+		}
+		node.annotation = tr.annotate(nil, children)
+	}
+}
+
+// TraverseAnnotations iterates over every node with its annotation, parents
+// before children. Return false from iter to skip the children of a node.
+// The annotations are nil unless the Annotate option is set.
+func (tr *RTree) TraverseAnnotations(iter func(min, max [3]float64, level int, annotation interface{}) bool) {
+	if tr.data.len() > 0 {
+		tr.traverseAnnotations(tr.data, iter)
+	}
+}
+
+func (tr *RTree) traverseAnnotations(node *treeNode, iter func(min, max [3]float64, level int, annotation interface{}) bool) {
+	if !iter([3]float64{node.minX, node.minY, node.minZ}, [3]float64{node.maxX, node.maxY, node.maxZ},
+		int(node.height), node.annotation) {
+		return
+	}
+	if !node.leaf {
+		for _, index := range node.children {
+			tr.traverseAnnotations(tr.node(index), iter)
+		}
+	}
+}
+
+// Aggregate visits everything in the bbox using as few calls to iter as
+// possible. Nodes that are fully inside of the bbox and have an annotation
+// are passed as their annotation, and the items in the other nodes are
+// passed one at a time when they intersect the bbox. Exactly one of
+// annotation and item is set for each call.
+func (tr *RTree) Aggregate(bbox pair.Pair, iter func(annotation interface{}, item pair.Pair) bool) bool {
+	min, max := tr.rect(bbox.Value())
+	var bboxn treeNode
+	bboxn.minX, bboxn.minY, bboxn.minZ = min[0], min[1], min[2]
+	bboxn.maxX, bboxn.maxY, bboxn.maxZ = max[0], max[1], max[2]
+	tr.exclude(&bboxn)
+	if !tr.data.intersects(&bboxn) {
+		return true
+	}
+	return tr.aggregate(tr.data, &bboxn, iter)
+}
+
+func (tr *RTree) aggregate(node, bbox *treeNode,
+	iter func(annotation interface{}, item pair.Pair) bool) bool {
+	if node.annotation != nil && bbox.contains(node) {
+		return iter(node.annotation, pair.Pair{})
+	}
+	if node.leaf {
+		for _, item := range node.items {
+			var child treeNode
+			fillBBox(item, &child, tr.rect)
+			if bbox.intersects(&child) {
+				if !iter(nil, item) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	for _, index := range node.children {
+		child := tr.node(index)
+		if bbox.intersects(child) {
+			if !tr.aggregate(child, bbox, iter) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ScanAnnotated iterates over the items of the tree, skipping the nodes
+// whose annotations don't pass keep, along with everything under them. A
+// node with a nil annotation is never skipped. With annotations that
+// summarize what's under each node, such as a filter of the keys, this
+// finds the items that match without reading every item.
+func (tr *RTree) ScanAnnotated(keep func(annotation interface{}) bool,
+	iter func(item pair.Pair) bool) bool {
+	return tr.scanAnnotated(tr.data, keep, iter)
+}
+
+func (tr *RTree) scanAnnotated(node *treeNode, keep func(annotation interface{}) bool,
+	iter func(item pair.Pair) bool) bool {
+	if node.annotation != nil && !keep(node.annotation) {
+		return true
+	}
+	if node.leaf {
+		for _, item := range node.items {
+			if !iter(item) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, index := range node.children {
+		if !tr.scanAnnotated(tr.node(index), keep, iter) {
+			return false
+		}
+	}
+	return true
+}
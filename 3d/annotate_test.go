@@ -0,0 +1,63 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+	"github.com/tidwall/pair-rtree/rtreetest"
+)
+
+func TestScanAnnotated(t *testing.T) {
+	// each node is annotated with the largest x of its items
+	var tr *RTree
+	tr = New(&Options{
+		MaxEntries: 9,
+		Annotate: func(items []pair.Pair, children []interface{}) interface{} {
+			x := mathInfNeg
+			for _, item := range items {
+				min, _ := tr.rect(item.Value())
+				x = mathMax(x, min[0])
+			}
+			for _, c := range children {
+				x = mathMax(x, c.(float64))
+			}
+			return x
+		},
+	})
+	var items, expect []pair.Pair
+	for i := 0; i < 5000; i++ {
+		items = append(items, makeRandom("point"))
+		tr.Insert(items[i])
+		if min, _ := tr.rect(items[i].Value()); min[0] >= 150 {
+			expect = append(expect, items[i])
+		}
+	}
+	var got []pair.Pair
+	var read int
+	tr.ScanAnnotated(func(annotation interface{}) bool {
+		return annotation.(float64) >= 150
+	}, func(item pair.Pair) bool {
+		read++
+		if min, _ := tr.rect(item.Value()); min[0] >= 150 {
+			got = append(got, item)
+		}
+		return true
+	})
+	assert.True(t, rtreetest.SameItems(expect, got))
+	assert.True(t, read < len(items)/2)
+
+	// nothing is skipped without annotations
+	tr = New(nil)
+	for _, item := range items {
+		tr.Insert(item)
+	}
+	read = 0
+	tr.ScanAnnotated(func(annotation interface{}) bool {
+		return false
+	}, func(item pair.Pair) bool {
+		read++
+		return true
+	})
+	assert.Equal(t, len(items), read)
+}
@@ -0,0 +1,46 @@
+package rtree
+
+import "errors"
+
+// ErrCorrupt is returned by Check when the nodes of the tree don't agree
+// with each other or with the items.
+var ErrCorrupt = errors.New("tree is corrupt")
+
+// Check walks the tree and returns ErrCorrupt when a node has no children,
+// has a child at the wrong height, or has a bbox that isn't the exact bbox
+// of its children. Bboxes go stale when the value of an item is changed
+// after it was inserted, which Repair fixes.
+func (tr *RTree) Check() error {
+	if tr.data.len() == 0 {
+		if !tr.data.leaf || tr.data.height != 1 {
+			return ErrCorrupt
+		}
+		return nil
+	}
+	if !tr.check(tr.data) {
+		return ErrCorrupt
+	}
+	return nil
+}
+
+func (tr *RTree) check(node *treeNode) bool {
+	if node.len() == 0 || node.leaf != (node.height == 1) {
+		return false
+	}
+	if !node.leaf {
+		for _, index := range node.children {
+			child := tr.node(index)
+			if child.height != node.height-1 || !tr.check(child) {
+				return false
+			}
+		}
+	}
+	var bbox treeNode
+	bbox.leaf = node.leaf
+	bbox.children = node.children
+	bbox.items = node.items
+	tr.calcBBox(&bbox)
+	return bbox.minX == node.minX && bbox.minY == node.minY &&
+		bbox.minZ == node.minZ && bbox.maxX == node.maxX &&
+		bbox.maxY == node.maxY && bbox.maxZ == node.maxZ
+}
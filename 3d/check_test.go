@@ -0,0 +1,49 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestCheck(t *testing.T) {
+	tr := New(nil)
+	assert.NoError(t, tr.Check())
+	var items []pair.Pair
+	for i := 0; i < 2000; i++ {
+		items = append(items, makeRandom("rect"))
+		tr.Insert(items[i])
+	}
+	assert.NoError(t, tr.Check())
+	for _, item := range items[:1500] {
+		tr.Remove(item)
+	}
+	assert.NoError(t, tr.Check())
+
+	// a stale bbox
+	tr.data.maxX++
+	assert.Equal(t, ErrCorrupt, tr.Check())
+	_, err := tr.Repair()
+	assert.NoError(t, err)
+	assert.NoError(t, tr.Check())
+
+	// a child at the wrong height
+	child := tr.node(tr.data.children[0])
+	child.height++
+	assert.Equal(t, ErrCorrupt, tr.Check())
+}
+
+func TestCheckNudge(t *testing.T) {
+	// an item on the edge of its leaf is nudged inside, which shrinks the
+	// leaf, so its bbox must be computed again
+	tr := New(nil)
+	item := makePointPair3("", 1, 1, 1)
+	tr.Insert(item)
+	tr.Insert(makePointPair3("", 2, 2, 2))
+	assert.True(t, tr.Nudge(item, makePointPair3("", 1.5, 1.5, 1.5)))
+	assert.NoError(t, tr.Check())
+	fixed, err := tr.Repair()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fixed)
+}
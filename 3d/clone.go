@@ -0,0 +1,30 @@
+package rtree
+
+import "github.com/tidwall/pair"
+
+// Clone returns a copy of the tree with its own nodes, which shares the
+// items. Changes to either tree are not seen by the other. The copy starts
+// with the Stats of the tree. Nodes refer to each other by their index in
+// the slabs, so the slabs are copied as they are, and only the children and
+// items of each node are copied on their own.
+func (tr *RTree) Clone() *RTree {
+	clone := *tr
+	clone.reusePath = nil
+	clone.stats = tr.stats.copy()
+	clone.free = append([]int32(nil), tr.free...)
+	clone.slabs = make([][]treeNode, len(tr.slabs))
+	for i, slab := range tr.slabs {
+		clone.slabs[i] = append([]treeNode(nil), slab...)
+		for j := range clone.slabs[i] {
+			node := &clone.slabs[i][j]
+			if node.children != nil {
+				node.children = append(make([]int32, 0, cap(node.children)), node.children...)
+			}
+			if node.items != nil {
+				node.items = append(make([]pair.Pair, 0, cap(node.items)), node.items...)
+			}
+		}
+	}
+	clone.data = clone.node(tr.data.index)
+	return &clone
+}
@@ -0,0 +1,51 @@
+package rtree
+
+import (
+	"math"
+	"unsafe"
+)
+
+// Distance returns the distance from the rect to the nearest item, or +Inf
+// when the tree is empty. The search is best-first and stops at the first
+// item that it reaches, which is much cheaper than a KNN when only the
+// distance is needed. The rect is not transformed.
+func (tr *RTree) Distance(min, max [3]float64) float64 {
+	var bbox treeNode
+	bbox.minX, bbox.minY, bbox.minZ = min[0], min[1], min[2]
+	bbox.maxX, bbox.maxY, bbox.maxZ = max[0], max[1], max[2]
+	queue := getQueue()
+	defer putQueue(queue)
+	node := tr.data
+	for {
+		for _, item := range node.items {
+			var cbox treeNode
+			fillBBox(item, &cbox, tr.rect)
+			queue.push(queueItem{node: item.Pointer(), isItem: true, dist: rectDist(&bbox, &cbox)})
+		}
+		for _, index := range node.children {
+			child := tr.node(index)
+			queue.push(queueItem{node: unsafe.Pointer(child), dist: rectDist(&bbox, child)})
+		}
+		last, ok := queue.pop()
+		if !ok {
+			return math.Inf(+1)
+		}
+		if last.isItem {
+			return math.Sqrt(last.dist)
+		}
+		node = (*treeNode)(last.node)
+	}
+}
+
+// DistanceToPoint returns the distance from the point to the nearest item.
+func (tr *RTree) DistanceToPoint(x, y, z float64) float64 {
+	return tr.Distance([3]float64{x, y, z}, [3]float64{x, y, z})
+}
+
+// rectDist returns the squared distance between two rects.
+func rectDist(a, b *treeNode) float64 {
+	dx := mathMax(0, mathMax(a.minX-b.maxX, b.minX-a.maxX))
+	dy := mathMax(0, mathMax(a.minY-b.maxY, b.minY-a.maxY))
+	dz := mathMax(0, mathMax(a.minZ-b.maxZ, b.minZ-a.maxZ))
+	return dx*dx + dy*dy + dz*dz
+}
@@ -0,0 +1,28 @@
+package rtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestDistance(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 1000; i++ {
+		tr.Insert(makeRandom("point"))
+	}
+	for i := 0; i < 10; i++ {
+		x, y, z := float64(i*50-250), float64(i*20-100), float64(i*10-50)
+		var nearest float64
+		tr.KNN(x, y, z, func(_ pair.Pair, dist float64) bool {
+			nearest = math.Sqrt(dist)
+			return false
+		})
+		assert.Equal(t, nearest, tr.DistanceToPoint(x, y, z))
+	}
+	tr.Insert(makeBoundsPair3("", 0, 0, 0, 10, 10, 10))
+	assert.Equal(t, 0.0, tr.Distance([3]float64{5, 5, 5}, [3]float64{20, 20, 20}))
+	assert.True(t, math.IsInf(New(nil).DistanceToPoint(0, 0, 0), +1))
+}
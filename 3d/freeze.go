@@ -0,0 +1,26 @@
+package rtree
+
+import "errors"
+
+// ErrFrozen is the panic value for an Insert or Remove on a frozen tree.
+var ErrFrozen = errors.New("tree is frozen")
+
+// Freeze makes the tree read-only. Any Insert or Remove that follows panics
+// with ErrFrozen, which catches accidental writes to a tree that is shared
+// by goroutines. The scratch space used by writes is released. A frozen
+// tree cannot be unfrozen.
+func (tr *RTree) Freeze() {
+	tr.frozen = true
+	tr.reusePath = nil
+}
+
+// Frozen returns true if the tree has been frozen.
+func (tr *RTree) Frozen() bool {
+	return tr.frozen
+}
+
+func (tr *RTree) checkFrozen() {
+	if tr.frozen {
+		panic(ErrFrozen)
+	}
+}
@@ -0,0 +1,65 @@
+package rtree
+
+import "github.com/tidwall/pair"
+
+// SearchHalfSpace iterates over the items that are at least partially in
+// the half-space where the dot product of the normal and a point is greater
+// than or equal to the offset. For example, a normal of {0, 0, 1} and an
+// offset of 100 returns the items that reach a z of 100 or higher. The
+// normal does not need to be a unit vector.
+func (tr *RTree) SearchHalfSpace(normal [3]float64, offset float64,
+	iter func(item pair.Pair) bool) bool {
+	if tr.data.len() == 0 {
+		return true
+	}
+	if extent(tr.data, normal, false) < offset {
+		return true
+	}
+	return tr.searchHalfSpace(tr.data, normal, offset, iter)
+}
+
+// extent returns the smallest, or largest, dot product of the normal and a
+// corner of the box.
+func extent(bbox *treeNode, normal [3]float64, smallest bool) float64 {
+	min := [3]float64{bbox.minX, bbox.minY, bbox.minZ}
+	max := [3]float64{bbox.maxX, bbox.maxY, bbox.maxZ}
+	var d float64
+	for i := 0; i < 3; i++ {
+		if (normal[i] >= 0) != smallest {
+			d += normal[i] * max[i]
+		} else {
+			d += normal[i] * min[i]
+		}
+	}
+	return d
+}
+
+func (tr *RTree) searchHalfSpace(node *treeNode, normal [3]float64, offset float64,
+	iter func(item pair.Pair) bool) bool {
+	if node.leaf {
+		for _, item := range node.items {
+			var child treeNode
+			fillBBox(item, &child, tr.rect)
+			if extent(&child, normal, false) >= offset {
+				if !iter(item) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	for _, index := range node.children {
+		child := tr.node(index)
+		if extent(child, normal, true) >= offset {
+			// entirely inside the half-space
+			if !tr.scan(child, iter) {
+				return false
+			}
+		} else if extent(child, normal, false) >= offset {
+			if !tr.searchHalfSpace(child, normal, offset, iter) {
+				return false
+			}
+		}
+	}
+	return true
+}
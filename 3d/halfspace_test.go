@@ -0,0 +1,47 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+func TestSearchHalfSpace(t *testing.T) {
+	tr := New(nil)
+	var objs []pair.Pair
+	for i := 0; i < 5000; i++ {
+		obj := makeRandom("rect")
+		tr.Insert(obj)
+		objs = append(objs, obj)
+	}
+	for _, plane := range []struct {
+		normal [3]float64
+		offset float64
+	}{
+		{[3]float64{0, 0, 1}, 0},
+		{[3]float64{0, 0, -1}, 10},
+		{[3]float64{1, 1, 1}, 50},
+		{[3]float64{-0.5, 2, 0}, -30},
+		{[3]float64{1, 0, 0}, 1000},
+	} {
+		var expect int
+		for _, obj := range objs {
+			min, max := geobin.WrapBinary(obj.Value()).Rect(nil)
+			var d float64
+			for i := 0; i < 3; i++ {
+				d += mathMax(plane.normal[i]*min[i], plane.normal[i]*max[i])
+			}
+			if d >= plane.offset {
+				expect++
+			}
+		}
+		var n int
+		tr.SearchHalfSpace(plane.normal, plane.offset, func(item pair.Pair) bool {
+			n++
+			return true
+		})
+		assert.Equal(t, expect, n)
+	}
+}
@@ -0,0 +1,73 @@
+package rtree
+
+import (
+	"math"
+	"sort"
+)
+
+// HausdorffApprox returns the Hausdorff distance between the items in two
+// trees, which is the farthest that an item in either tree is from the
+// nearest item in the other tree. The result is never more than eps below
+// the exact distance, and larger values of eps allow for more of the trees
+// to be skipped. Returns +Inf when only one of the trees is empty.
+func (tr *RTree) HausdorffApprox(other *RTree, eps float64) float64 {
+	empty1, empty2 := tr.data.len() == 0, other.data.len() == 0
+	if empty1 || empty2 {
+		if empty1 && empty2 {
+			return 0
+		}
+		return math.Inf(+1)
+	}
+	h := tr.directedHausdorff(other, eps, 0)
+	return other.directedHausdorff(tr, eps, h)
+}
+
+// directedHausdorff returns the farthest that an item in the tree is from
+// the nearest item in the other tree, or h if that is larger. Nodes that
+// cannot raise the distance by more than eps are skipped.
+func (tr *RTree) directedHausdorff(other *RTree, eps, h float64) float64 {
+	type candidate struct {
+		node  *treeNode
+		dist  float64
+		bound float64
+	}
+	var walk func(node *treeNode)
+	walk = func(node *treeNode) {
+		if node.leaf {
+			for _, item := range node.items {
+				var bbox treeNode
+				fillBBox(item, &bbox, tr.rect)
+				h = mathMax(h, other.distanceToBBox(&bbox))
+			}
+			return
+		}
+		// visit the children that are farthest from the other tree first,
+		// which raises h sooner and prunes more of their siblings.
+		cands := make([]candidate, len(node.children))
+		for i, index := range node.children {
+			child := tr.node(index)
+			dist := other.distanceToBBox(child)
+			// every item in the child is within the child's diagonal of the
+			// point that is nearest to the other tree.
+			diag := math.Sqrt((child.maxX-child.minX)*(child.maxX-child.minX) +
+				(child.maxY-child.minY)*(child.maxY-child.minY) +
+				(child.maxZ-child.minZ)*(child.maxZ-child.minZ))
+			cands[i] = candidate{child, dist, dist + diag}
+		}
+		sort.Slice(cands, func(i, j int) bool {
+			return cands[i].dist > cands[j].dist
+		})
+		for _, c := range cands {
+			if c.bound > h+eps {
+				walk(c.node)
+			}
+		}
+	}
+	walk(tr.data)
+	return h
+}
+
+func (tr *RTree) distanceToBBox(bbox *treeNode) float64 {
+	return tr.Distance([3]float64{bbox.minX, bbox.minY, bbox.minZ},
+		[3]float64{bbox.maxX, bbox.maxY, bbox.maxZ})
+}
@@ -0,0 +1,44 @@
+package rtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestHausdorffApprox(t *testing.T) {
+	tr1, tr2 := New(nil), New(nil)
+	assert.Equal(t, 0.0, tr1.HausdorffApprox(tr2, 0))
+	var items1, items2 []pair.Pair
+	for i := 0; i < 500; i++ {
+		item := makeRandom("point")
+		tr1.Insert(item)
+		items1 = append(items1, item)
+		item = makeRandom("rect")
+		tr2.Insert(item)
+		items2 = append(items2, item)
+	}
+	assert.True(t, math.IsInf(tr1.HausdorffApprox(New(nil), 0), +1))
+	directed := func(a, b []pair.Pair) float64 {
+		var h float64
+		for _, ia := range a {
+			var abox treeNode
+			fillBBox(ia, &abox, tr1.rect)
+			nearest := math.Inf(+1)
+			for _, ib := range b {
+				var bbox treeNode
+				fillBBox(ib, &bbox, tr1.rect)
+				nearest = math.Min(nearest, math.Sqrt(rectDist(&abox, &bbox)))
+			}
+			h = math.Max(h, nearest)
+		}
+		return h
+	}
+	expect := math.Max(directed(items1, items2), directed(items2, items1))
+	assert.Equal(t, expect, tr1.HausdorffApprox(tr2, 0))
+	assert.Equal(t, expect, tr2.HausdorffApprox(tr1, 0))
+	approx := tr1.HausdorffApprox(tr2, 5)
+	assert.True(t, approx <= expect && approx >= expect-5)
+}
@@ -0,0 +1,65 @@
+package rtree
+
+import (
+	"math"
+
+	"github.com/tidwall/pair"
+)
+
+// JoinWithin calls iter for every pair of items, one from each tree, with
+// rects that are within maxDist of each other, along with the distance
+// between the rects. The trees are walked together, so the pairs of nodes
+// that are farther apart than maxDist are skipped without reading their
+// items. Returns false if iter returned false.
+func (tr *RTree) JoinWithin(other *RTree, maxDist float64,
+	iter func(a, b pair.Pair, dist float64) bool) bool {
+	if tr.data.len() == 0 || other.data.len() == 0 ||
+		maxDist < 0 || rectDist(tr.data, other.data) > maxDist*maxDist {
+		return true
+	}
+	return tr.joinWithin(other, tr.data, other.data, maxDist*maxDist, iter)
+}
+
+// joinWithin joins the nodes a, from the tree, and b, from the other tree.
+// The nodes are within the squared distance d2 of each other.
+func (tr *RTree) joinWithin(other *RTree, a, b *treeNode, d2 float64,
+	iter func(a, b pair.Pair, dist float64) bool) bool {
+	if a.leaf && b.leaf {
+		bboxes := make([]treeNode, len(b.items))
+		for j, item := range b.items {
+			fillBBox(item, &bboxes[j], other.rect)
+		}
+		for _, aitem := range a.items {
+			var abox treeNode
+			fillBBox(aitem, &abox, tr.rect)
+			if rectDist(&abox, b) > d2 {
+				continue
+			}
+			for j, bitem := range b.items {
+				dist := rectDist(&abox, &bboxes[j])
+				if dist <= d2 && !iter(aitem, bitem, math.Sqrt(dist)) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	// open the higher of the nodes, which keeps the pairs of nodes at about
+	// the same size
+	if b.leaf || (!a.leaf && a.height >= b.height) {
+		for _, index := range a.children {
+			child := tr.node(index)
+			if rectDist(child, b) <= d2 && !tr.joinWithin(other, child, b, d2, iter) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, index := range b.children {
+		child := other.node(index)
+		if rectDist(a, child) <= d2 && !tr.joinWithin(other, a, child, d2, iter) {
+			return false
+		}
+	}
+	return true
+}
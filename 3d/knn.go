@@ -1,56 +1,86 @@
 package rtree
 
 import (
+	"sync/atomic"
 	"unsafe"
 
-	"github.com/tidwall/geobin"
 	"github.com/tidwall/pair"
-	"github.com/tidwall/tinyqueue"
 )
 
-type queueItem struct {
-	node   unsafe.Pointer
-	isItem bool
-	dist   float64
+// KNN returns items nearest to farthest. The dist param is the "box distance".
+func (tr *RTree) KNN(x, y, z float64, iter func(item pair.Pair, dist float64) bool) bool {
+	return tr.knn(x, y, z, iter, nil, nil)
 }
 
-func (item *queueItem) Less(b tinyqueue.Item) bool {
-	return item.dist < b.(*queueItem).dist
+// KNNAxes is like KNN, but also passes the distance along each axis from the
+// point to the item, which allows for filters that treat the axes
+// differently, such as a limit on the difference in elevation.
+func (tr *RTree) KNNAxes(x, y, z float64,
+	iter func(item pair.Pair, dist float64, axes [3]float64) bool) bool {
+	return tr.KNN(x, y, z, func(item pair.Pair, dist float64) bool {
+		min, max := tr.rect(item.Value())
+		return iter(item, dist, [3]float64{
+			tr.xDist(x, min[0], max[0]),
+			axisDist(y, min[1], max[1]),
+			axisDist(z, min[2], max[2]),
+		})
+	})
 }
 
-// KNN returns items nearest to farthest. The dist param is the "box distance".
-func (tr *RTree) KNN(x, y, z float64, iter func(item pair.Pair, dist float64) bool) bool {
+// KNNDebug is like KNN, but also calls visit for each node as it's popped
+// from the queue, starting with the root, which shows the regions that the
+// search expands into and why. The level of a node counts from one at the
+// leaves, as in Traverse, and its distance is a lower bound on the distances
+// of its items. Each node is visited before the items that it leads to.
+func (tr *RTree) KNNDebug(x, y, z float64, iter func(item pair.Pair, dist float64) bool,
+	visit func(level int, min, max [3]float64, dist float64)) bool {
+	return tr.knn(x, y, z, iter, func(node *treeNode, dist float64) {
+		visit(int(node.height), [3]float64{node.minX, node.minY, node.minZ},
+			[3]float64{node.maxX, node.maxY, node.maxZ}, dist)
+	}, nil)
+}
+
+// knn performs the KNN, calling visit for each node as it is popped from the
+// queue, starting with the root. The distances are measured with the
+// metric, or are squared when it's nil.
+func (tr *RTree) knn(x, y, z float64, iter func(item pair.Pair, dist float64) bool,
+	visit func(node *treeNode, dist float64), metric Metric) bool {
+	atomic.AddUint64(&tr.stats.knns, 1)
 	node := tr.data
-	queue := tinyqueue.New(nil)
+	queue := getQueue()
+	defer putQueue(queue)
+	if visit != nil {
+		visit(node, tr.dist(metric, x, y, z, [3]float64{node.minX, node.minY, node.minZ}, [3]float64{node.maxX, node.maxY, node.maxZ}))
+	}
 	for node != nil {
-		for _, child := range node.children {
-			var min, max [3]float64
-			if node.leaf {
-				item := pair.FromPointer(child)
-				omin, omax := geobin.WrapBinary(item.Value()).Rect(tr.t)
-				min[0], min[1], min[2] = omin[0], omin[1], omin[2]
-				max[0], max[1], max[2] = omax[0], omax[1], omax[2]
-			} else {
-				node := (*treeNode)(child)
-				min[0], min[1], min[2] = node.minX, node.minY, node.minZ
-				max[0], max[1], max[2] = node.maxX, node.maxY, node.maxZ
-			}
-			queue.Push(&queueItem{
-				node:   child,
-				isItem: node.leaf,
-				dist:   boxDist(x, y, z, min, max),
+		for _, item := range node.items {
+			min, max := tr.rect(item.Value())
+			queue.push(queueItem{
+				node:   item.Pointer(),
+				isItem: true,
+				dist:   tr.dist(metric, x, y, z, min, max),
+			})
+		}
+		for _, index := range node.children {
+			child := tr.node(index)
+			queue.push(queueItem{
+				node: unsafe.Pointer(child),
+				dist: tr.dist(metric, x, y, z, [3]float64{child.minX, child.minY, child.minZ},
+					[3]float64{child.maxX, child.maxY, child.maxZ}),
 			})
 		}
-		for queue.Len() > 0 && queue.Peek().(*queueItem).isItem {
-			item := queue.Pop().(*queueItem)
-			candidate := item.node
-			if !iter(pair.FromPointer(candidate), item.dist) {
+		for queue.len() > 0 && queue.peek().isItem {
+			item, _ := queue.pop()
+			if !iter(pair.FromPointer(item.node), item.dist) {
 				return false
 			}
 		}
-		last := queue.Pop()
-		if last != nil {
-			node = (*treeNode)(last.(*queueItem).node)
+		last, ok := queue.pop()
+		if ok {
+			node = (*treeNode)(last.node)
+			if visit != nil {
+				visit(node, last.dist)
+			}
 		} else {
 			node = nil
 		}
@@ -64,12 +94,9 @@ func boxDist(x, y, z float64, min, max [3]float64) float64 {
 	dz := axisDist(z, min[2], max[2])
 	return dx*dx + dy*dy + dz*dz
 }
+
+// axisDist returns the distance from k to the range. At most one of the
+// differences is positive, so the largest of them, or zero, is the distance.
 func axisDist(k, min, max float64) float64 {
-	if k < min {
-		return min - k
-	}
-	if k <= max {
-		return 0
-	}
-	return k - max
+	return mathMax(mathMax(min-k, k-max), 0)
 }
@@ -0,0 +1,55 @@
+package rtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+func TestKNNGraph(t *testing.T) {
+	tr := New(nil)
+	var items []pair.Pair
+	for i := 0; i < 1000; i++ {
+		item := makeRandom("point")
+		tr.Insert(item)
+		items = append(items, item)
+	}
+	dist := func(a, b pair.Pair) float64 {
+		pa := geobin.WrapBinary(a.Value()).Position()
+		pb := geobin.WrapBinary(b.Value()).Position()
+		return math.Sqrt((pa.X-pb.X)*(pa.X-pb.X) + (pa.Y-pb.Y)*(pa.Y-pb.Y) + (pa.Z-pb.Z)*(pa.Z-pb.Z))
+	}
+	var n int
+	tr.KNNGraph(5, func(item pair.Pair, neighbors []pair.Pair) bool {
+		n++
+		assert.Equal(t, 5, len(neighbors))
+		// the kth neighbor must be as near as the kth nearest by brute force
+		var dists []float64
+		tr.KNN(geobin.WrapBinary(item.Value()).Position().X,
+			geobin.WrapBinary(item.Value()).Position().Y,
+			geobin.WrapBinary(item.Value()).Position().Z,
+			func(other pair.Pair, _ float64) bool {
+				if other != item {
+					dists = append(dists, dist(item, other))
+				}
+				return len(dists) < 5
+			})
+		for i, neighbor := range neighbors {
+			assert.True(t, neighbor != item)
+			assert.True(t, math.Abs(dist(item, neighbor)-dists[i]) < 1e-9)
+		}
+		return true
+	})
+	assert.Equal(t, len(items), n)
+
+	small := New(nil)
+	small.Insert(items[0])
+	small.Insert(items[1])
+	small.KNNGraph(3, func(item pair.Pair, neighbors []pair.Pair) bool {
+		assert.Equal(t, 1, len(neighbors))
+		return true
+	})
+}
@@ -0,0 +1,79 @@
+package rtree
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/tidwall/pair"
+)
+
+// KNNK is like KNN for when the number of items, k, is known upfront. Once k
+// items are in the queue, the nodes and items that are farther than the k-th
+// nearest of them are not pushed, and the search stops after k items.
+func (tr *RTree) KNNK(x, y, z float64, k int, iter func(item pair.Pair, dist float64) bool) bool {
+	if k <= 0 {
+		return true
+	}
+	queue := getQueue()
+	defer putQueue(queue)
+	return tr.knnk(queue, make([]float64, 0, k), x, y, z, k, iter)
+}
+
+// knnk performs the KNNK with an empty queue, and with best for holding the
+// distances of the k nearest items that are pushed, in order, so the bound is
+// the last of them when it's full.
+func (tr *RTree) knnk(queue *knnQueue, best []float64, x, y, z float64, k int,
+	iter func(item pair.Pair, dist float64) bool) bool {
+	atomic.AddUint64(&tr.stats.knns, 1)
+	var n int
+	node := tr.data
+	for node != nil {
+		for _, item := range node.items {
+			min, max := tr.rect(item.Value())
+			dist := tr.dist(nil, x, y, z, min, max)
+			if len(best) == k && dist > best[k-1] {
+				continue
+			}
+			best = insertBest(best, dist, k)
+			queue.push(queueItem{node: item.Pointer(), isItem: true, dist: dist})
+		}
+		for _, index := range node.children {
+			child := tr.node(index)
+			dist := tr.dist(nil, x, y, z, [3]float64{child.minX, child.minY, child.minZ},
+				[3]float64{child.maxX, child.maxY, child.maxZ})
+			if len(best) == k && dist > best[k-1] {
+				continue
+			}
+			queue.push(queueItem{node: unsafe.Pointer(child), dist: dist})
+		}
+		for queue.len() > 0 && queue.peek().isItem {
+			item, _ := queue.pop()
+			if !iter(pair.FromPointer(item.node), item.dist) {
+				return false
+			}
+			n++
+			if n == k {
+				return true
+			}
+		}
+		node = nil
+		if last, ok := queue.pop(); ok && (len(best) < k || last.dist <= best[k-1]) {
+			node = (*treeNode)(last.node)
+		}
+	}
+	return true
+}
+
+// insertBest inserts the dist into the sorted distances, dropping the
+// farthest when there are k of them.
+func insertBest(best []float64, dist float64, k int) []float64 {
+	if len(best) < k {
+		best = append(best, 0)
+	}
+	i := len(best) - 1
+	for ; i > 0 && best[i-1] > dist; i-- {
+		best[i] = best[i-1]
+	}
+	best[i] = dist
+	return best
+}
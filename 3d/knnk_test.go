@@ -0,0 +1,37 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestKNNK(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 5000; i++ {
+		tr.Insert(makeRandom("rect"))
+	}
+	for _, k := range []int{0, 1, 7, 100, 6000} {
+		var expect []float64
+		tr.KNN(10, 20, 30, func(item pair.Pair, dist float64) bool {
+			if len(expect) == k {
+				return false
+			}
+			expect = append(expect, dist)
+			return true
+		})
+		var got []float64
+		assert.True(t, tr.KNNK(10, 20, 30, k, func(item pair.Pair, dist float64) bool {
+			got = append(got, dist)
+			return true
+		}))
+		assert.Equal(t, expect, got)
+	}
+	var n int
+	assert.False(t, tr.KNNK(10, 20, 30, 10, func(item pair.Pair, dist float64) bool {
+		n++
+		return n < 3
+	}))
+	assert.Equal(t, 3, n)
+}
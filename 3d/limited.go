@@ -0,0 +1,84 @@
+package rtree
+
+import "github.com/tidwall/pair"
+
+// SearchLimited is like Search, but stops after visiting maxNodes nodes,
+// which puts a bound on the work of a query. Returns false if the search
+// was cut short by the limit, in which case some of the items were not
+// returned.
+func (tr *RTree) SearchLimited(bbox pair.Pair, maxNodes int,
+	iter func(item pair.Pair) bool) (complete bool) {
+	min, max := tr.rect(bbox.Value())
+	return tr.SearchRectLimited(min, max, &maxNodes, iter)
+}
+
+// SearchRectLimited is like SearchLimited, but takes the rect directly, and
+// takes the number of nodes that may be visited as a budget that it
+// subtracts from, so the budget can be shared by more than one search.
+func (tr *RTree) SearchRectLimited(min, max [3]float64, budget *int,
+	iter func(item pair.Pair) bool) (complete bool) {
+	var bboxn treeNode
+	bboxn.minX, bboxn.minY, bboxn.minZ = min[0], min[1], min[2]
+	bboxn.maxX, bboxn.maxY, bboxn.maxZ = max[0], max[1], max[2]
+	tr.exclude(&bboxn)
+	search := func(bbox *treeNode, iter func(item pair.Pair) bool) bool {
+		return tr.searchLimited(tr.data, bbox, budget, iter)
+	}
+	if tr.wrapX > 0 {
+		tr.searchWrapped(bboxn, iter, search)
+	} else if tr.data.intersects(&bboxn) {
+		search(&bboxn, iter)
+	}
+	if *budget < 0 {
+		*budget = 0
+		return false
+	}
+	return true
+}
+
+// searchLimited is like search, but each node that's visited takes one from
+// the budget. The budget is set to -1 when it runs out.
+func (tr *RTree) searchLimited(node, bbox *treeNode, budget *int,
+	iter func(item pair.Pair) bool) bool {
+	if *budget <= 0 {
+		*budget = -1
+		return false
+	}
+	*budget--
+	if node.leaf {
+		for i := 0; i < len(node.items); i++ {
+			item := node.items[i]
+			var child treeNode
+			fillBBox(item, &child, tr.rect)
+			if bbox.intersects(&child) {
+				if !iter(item) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return tr.searchChildren(node, bbox, func(child *treeNode) bool {
+		return tr.searchLimited(child, bbox, budget, iter)
+	})
+}
+
+// SearchLimit returns the items that Search would, after skipping the first
+// offset items, and stops once it has limit items. The items are in the
+// order of the tree, so the pages are only consistent while the tree isn't
+// changed.
+func (tr *RTree) SearchLimit(bbox pair.Pair, offset, limit int) []pair.Pair {
+	if limit <= 0 {
+		return nil
+	}
+	var items []pair.Pair
+	tr.Search(bbox, func(item pair.Pair) bool {
+		if offset > 0 {
+			offset--
+			return true
+		}
+		items = append(items, item)
+		return len(items) < limit
+	})
+	return items
+}
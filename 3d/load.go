@@ -0,0 +1,163 @@
+package rtree
+
+import (
+	"math"
+	"sync/atomic"
+
+	"github.com/tidwall/pair"
+)
+
+// loadEntry is an item with its rect, which is read once per Load.
+type loadEntry struct {
+	item     pair.Pair
+	min, max [3]float64
+}
+
+// Load bulk loads items with the OMT (Overlap Minimizing Top-down)
+// algorithm, which packs them into full nodes with little overlap in a
+// single pass, and is several times faster than inserting them one at a
+// time. When the tree already has items, the loaded items are built into a
+// subtree of their own that is then inserted at its level, so the tree is
+// tightest when everything is loaded at once.
+func (tr *RTree) Load(items []pair.Pair) {
+	tr.checkFrozen()
+	if len(items) < tr.minEntries {
+		for _, item := range items {
+			tr.Insert(item)
+		}
+		return
+	}
+	atomic.AddUint64(&tr.stats.inserts, uint64(len(items)))
+	entries := make([]loadEntry, len(items))
+	for i, item := range items {
+		entries[i].item = item
+		entries[i].min, entries[i].max = tr.rect(item.Value())
+	}
+	var height int8 = 1
+	for n := tr.maxEntries; n < len(entries); n *= tr.maxEntries {
+		height++
+	}
+	node := tr.build(entries, height)
+	switch {
+	case tr.data.len() == 0:
+		tr.freeNode(tr.data)
+		tr.data = node
+	case tr.data.height == node.height:
+		tr.splitRoot(tr.data, node)
+	default:
+		if tr.data.height < node.height {
+			tr.data, node = node, tr.data
+		}
+		tr.insert(node, pair.Pair{}, tr.data.height-node.height-1, true)
+	}
+}
+
+// build returns a node of the height for the entries, which must fit in it.
+// The entries are split into as few children as they fit in, by cutting
+// them into slabs on x, then each slab into slabs on y, and then on z.
+func (tr *RTree) build(entries []loadEntry, height int8) *treeNode {
+	if height == 1 {
+		node := tr.newNode()
+		node.items = tr.makeItems(len(entries))
+		for i := range entries {
+			node.items[i] = entries[i].item
+		}
+		for i := range entries {
+			node.minX = mathMin(node.minX, entries[i].min[0])
+			node.minY = mathMin(node.minY, entries[i].min[1])
+			node.minZ = mathMin(node.minZ, entries[i].min[2])
+			node.maxX = mathMax(node.maxX, entries[i].max[0])
+			node.maxY = mathMax(node.maxY, entries[i].max[1])
+			node.maxZ = mathMax(node.maxZ, entries[i].max[2])
+		}
+		tr.annotateNode(node)
+		return node
+	}
+	// the number of items in a full child
+	capacity := 1
+	for i := int8(1); i < height; i++ {
+		capacity *= tr.maxEntries
+	}
+	n := len(entries)
+	k := (n + capacity - 1) / capacity
+	size := (n + k - 1) / k
+	s := int(math.Ceil(math.Cbrt(float64(k))))
+
+	children := tr.makeChildren((n + size - 1) / size)
+	var c int
+	selectEntries(entries, size*s*s, 0)
+	for i := 0; i < n; i += size * s * s {
+		slabX := entries[i:minInt(i+size*s*s, n)]
+		selectEntries(slabX, size*s, 1)
+		for j := 0; j < len(slabX); j += size * s {
+			slabY := slabX[j:minInt(j+size*s, len(slabX))]
+			selectEntries(slabY, size, 2)
+			for l := 0; l < len(slabY); l += size {
+				child := tr.build(slabY[l:minInt(l+size, len(slabY))], height-1)
+				children[c] = child.index
+				c++
+			}
+		}
+	}
+	node := tr.newNode()
+	node.children = children
+	node.height = height
+	node.leaf = false
+	tr.calcBBox(node)
+	tr.annotateNode(node)
+	return node
+}
+
+// selectEntries orders the entries on the min of the axis in runs of n, so
+// each run has the entries that a sort would put there, in any order. This
+// is a quickselect at every run, which is less work than a sort.
+func selectEntries(entries []loadEntry, n, axis int) {
+	if len(entries) <= n {
+		return
+	}
+	mid := len(entries) / n / 2 * n
+	if mid == 0 {
+		mid = n
+	}
+	nthEntry(entries, mid, axis)
+	selectEntries(entries[:mid], n, axis)
+	selectEntries(entries[mid:], n, axis)
+}
+
+// nthEntry moves the entry at k of a sort on the axis to k, with the
+// entries that are not more before it, and those not less after it.
+func nthEntry(entries []loadEntry, k, axis int) {
+	lo, hi := 0, len(entries)-1
+	for lo < hi {
+		p := entries[lo+(hi-lo)/2].min[axis]
+		i, j := lo, hi
+		for i <= j {
+			for entries[i].min[axis] < p {
+				i++
+			}
+			for entries[j].min[axis] > p {
+				j--
+			}
+			if i <= j {
+				entries[i], entries[j] = entries[j], entries[i]
+				i++
+				j--
+			}
+		}
+		switch {
+		case k <= j:
+			hi = j
+		case k >= i:
+			lo = i
+		default:
+			return
+		}
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,45 @@
+package rtree
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestMetrics(t *testing.T) {
+	tr := New(nil)
+	var objs []pair.Pair
+	for i := 0; i < 2000; i++ {
+		objs = append(objs, makeRandom("rect"))
+		tr.Insert(objs[i])
+	}
+	x, y, z := 15.0, -20.0, 5.0
+	metrics := []Metric{SquaredEuclidean, Euclidean, Manhattan, Chebyshev,
+		WeightedEuclidean([3]float64{1, 5, 0.5})}
+	for _, metric := range metrics {
+		var expect []float64
+		for _, obj := range objs {
+			min, max := tr.rect(obj.Value())
+			expect = append(expect, metric.dist(x, y, z, min, max))
+		}
+		sort.Float64s(expect)
+
+		var dists []float64
+		tr.KNNMetric(x, y, z, metric, func(item pair.Pair, dist float64) bool {
+			dists = append(dists, dist)
+			return true
+		})
+		assert.Equal(t, expect, dists)
+
+		radius := expect[100]
+		var n int
+		tr.SearchRadius(x, y, z, radius, metric, func(item pair.Pair, dist float64) bool {
+			assert.True(t, dist <= radius)
+			n++
+			return true
+		})
+		assert.Equal(t, sort.SearchFloat64s(expect, radius+1e-9), n)
+	}
+}
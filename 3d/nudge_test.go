@@ -0,0 +1,83 @@
+package rtree
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+	"github.com/tidwall/pair-rtree/rtreetest"
+)
+
+func TestNudge(t *testing.T) {
+	tr := New(nil)
+	var items []pair.Pair
+	for i := 0; i < 1000; i++ {
+		x, y, z := rand.Float64()*360-180, rand.Float64()*180-90, rand.Float64()*100-50
+		items = append(items, makePointPair3("", x, y, z))
+		tr.Insert(items[i])
+	}
+	for i, item := range items {
+		min, _ := tr.rect(item.Value())
+		x, y, z := min[0]+rand.Float64()*0.01, min[1]+rand.Float64()*0.01, min[2]
+		if i%10 == 0 {
+			// far away
+			x, y, z = -x, -y, -z
+		}
+		items[i] = makePointPair3("", x, y, z)
+		assert.True(t, tr.Nudge(item, items[i]))
+		assert.False(t, tr.Nudge(item, items[i]))
+	}
+	var got []pair.Pair
+	tr.Search(makeBoundsPair3("", -200, -100, -60, 200, 100, 60), func(item pair.Pair) bool {
+		got = append(got, item)
+		return true
+	})
+	assert.True(t, rtreetest.SameItems(items, got))
+	// the bboxes are still tight
+	assert.NoError(t, tr.Check())
+}
+
+func TestUpdateAll(t *testing.T) {
+	// each node is annotated with the sum of the x of its items
+	var tr *RTree
+	tr = New(&Options{
+		MaxEntries: 9,
+		Annotate: func(items []pair.Pair, children []interface{}) interface{} {
+			var sum float64
+			for _, item := range items {
+				min, _ := tr.rect(item.Value())
+				sum += min[0]
+			}
+			for _, c := range children {
+				sum += c.(float64)
+			}
+			return sum
+		},
+	})
+	var items []pair.Pair
+	for i := 0; i < 1000; i++ {
+		items = append(items, makeRandom("point"))
+		tr.Insert(items[i])
+	}
+	var updates []Update
+	for i, item := range items {
+		min, _ := tr.rect(item.Value())
+		items[i] = makePointPair3("", min[0]+rand.Float64()*0.01, min[1], min[2])
+		updates = append(updates, Update{item, items[i]})
+	}
+	missing := Update{makeRandom("point"), makeRandom("point")}
+	updates = append(updates, missing)
+	assert.Equal(t, []Update{missing}, tr.UpdateAll(updates))
+	var got []pair.Pair
+	var sum float64
+	tr.Scan(func(item pair.Pair) bool {
+		got = append(got, item)
+		min, _ := tr.rect(item.Value())
+		sum += min[0]
+		return true
+	})
+	assert.True(t, rtreetest.SameItems(items, got))
+	assert.InDelta(t, sum, tr.data.annotation.(float64), 1e-6)
+	assert.NoError(t, tr.Check())
+}
@@ -0,0 +1,139 @@
+package rtree
+
+import (
+	"math"
+
+	"github.com/tidwall/pair"
+)
+
+// SearchOBB iterates over the items with bboxes that intersect the oriented
+// box. The box is rotated around its center by rotation, in radians, around
+// the x axis, then the y axis, then the z axis. Nodes and items are tested
+// with separating axes, so nothing outside of the box is returned.
+func (tr *RTree) SearchOBB(center, halfExtents, rotation [3]float64,
+	iter func(item pair.Pair) bool) bool {
+	if tr.data.len() == 0 {
+		return true
+	}
+	b := newOBB(center, halfExtents, rotation)
+	if !b.intersects(tr.data) {
+		return true
+	}
+	return tr.searchOBB(tr.data, b, iter)
+}
+
+func (tr *RTree) searchOBB(node *treeNode, b *obb, iter func(item pair.Pair) bool) bool {
+	if node.leaf {
+		for _, item := range node.items {
+			var child treeNode
+			fillBBox(item, &child, tr.rect)
+			if b.intersects(&child) {
+				if !iter(item) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	for _, index := range node.children {
+		child := tr.node(index)
+		if b.contains(child) {
+			if !tr.scan(child, iter) {
+				return false
+			}
+		} else if b.intersects(child) {
+			if !tr.searchOBB(child, b, iter) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// obb is an oriented box that is prepared for separating axis tests.
+type obb struct {
+	center [3]float64
+	extent [3]float64
+	axes   [][3]float64 // the box axes followed by the other test axes
+}
+
+func newOBB(center, halfExtents, rotation [3]float64) *obb {
+	b := &obb{center: center, extent: halfExtents}
+	for i := 0; i < 3; i++ {
+		var v [3]float64
+		v[i] = 1
+		b.axes = append(b.axes, rotate(v, rotation))
+	}
+	for i := 0; i < 3; i++ {
+		var v [3]float64
+		v[i] = 1
+		b.axes = append(b.axes, v)
+		for j := 0; j < 3; j++ {
+			u := b.axes[j]
+			c := [3]float64{
+				v[1]*u[2] - v[2]*u[1],
+				v[2]*u[0] - v[0]*u[2],
+				v[0]*u[1] - v[1]*u[0],
+			}
+			// parallel axes are covered by the others
+			if c[0]*c[0]+c[1]*c[1]+c[2]*c[2] > 1e-12 {
+				b.axes = append(b.axes, c)
+			}
+		}
+	}
+	return b
+}
+
+// rotate rotates v around the x, y, and z axes, in that order.
+func rotate(v, rotation [3]float64) [3]float64 {
+	sin, cos := math.Sincos(rotation[0])
+	v[1], v[2] = v[1]*cos-v[2]*sin, v[1]*sin+v[2]*cos
+	sin, cos = math.Sincos(rotation[1])
+	v[0], v[2] = v[0]*cos+v[2]*sin, -v[0]*sin+v[2]*cos
+	sin, cos = math.Sincos(rotation[2])
+	v[0], v[1] = v[0]*cos-v[1]*sin, v[0]*sin+v[1]*cos
+	return v
+}
+
+// separation returns the distance between the centers of the oriented box
+// and the bbox along the axis, and the radius of each.
+func (b *obb) separation(bbox *treeNode, axis [3]float64) (dist, rbox, robb float64) {
+	center := [3]float64{
+		(bbox.minX + bbox.maxX) / 2, (bbox.minY + bbox.maxY) / 2, (bbox.minZ + bbox.maxZ) / 2,
+	}
+	half := [3]float64{
+		(bbox.maxX - bbox.minX) / 2, (bbox.maxY - bbox.minY) / 2, (bbox.maxZ - bbox.minZ) / 2,
+	}
+	for i := 0; i < 3; i++ {
+		dist += axis[i] * (center[i] - b.center[i])
+		rbox += math.Abs(axis[i]) * half[i]
+	}
+	for j := 0; j < 3; j++ {
+		u := b.axes[j]
+		robb += b.extent[j] * math.Abs(axis[0]*u[0]+axis[1]*u[1]+axis[2]*u[2])
+	}
+	return math.Abs(dist), rbox, robb
+}
+
+// intersects returns true if there's no separating axis between the oriented
+// box and the bbox.
+func (b *obb) intersects(bbox *treeNode) bool {
+	for _, axis := range b.axes {
+		dist, rbox, robb := b.separation(bbox, axis)
+		if dist > rbox+robb {
+			return false
+		}
+	}
+	return true
+}
+
+// contains returns true if the bbox is fully inside of the oriented box.
+func (b *obb) contains(bbox *treeNode) bool {
+	for j := 0; j < 3; j++ {
+		dist, rbox, _ := b.separation(bbox, b.axes[j])
+		if dist+rbox > b.extent[j] {
+			return false
+		}
+	}
+	return true
+}
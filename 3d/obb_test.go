@@ -0,0 +1,56 @@
+package rtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+func TestSearchOBB(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 5000; i++ {
+		tr.Insert(makeRandom("point"))
+	}
+	center := [3]float64{10, 20, 5}
+	extent := [3]float64{60, 20, 15}
+	rotation := [3]float64{0.3, -0.7, math.Pi / 5}
+	b := newOBB(center, extent, rotation)
+	var expect int
+	tr.Scan(func(item pair.Pair) bool {
+		p := geobin.WrapBinary(item.Value()).Position()
+		inside := true
+		for j := 0; j < 3; j++ {
+			u := b.axes[j]
+			d := u[0]*(p.X-center[0]) + u[1]*(p.Y-center[1]) + u[2]*(p.Z-center[2])
+			if math.Abs(d) > extent[j] {
+				inside = false
+			}
+		}
+		if inside {
+			expect++
+		}
+		return true
+	})
+	assert.True(t, expect > 0)
+	var n int
+	tr.SearchOBB(center, extent, rotation, func(item pair.Pair) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, expect, n)
+
+	// an unrotated box is the same as a bbox search
+	var n1, n2 int
+	tr.SearchOBB([3]float64{0, 0, 0}, [3]float64{50, 30, 20}, [3]float64{}, func(item pair.Pair) bool {
+		n1++
+		return true
+	})
+	tr.Search(makeBoundsPair3("", -50, -30, -20, 50, 30, 20), func(item pair.Pair) bool {
+		n2++
+		return true
+	})
+	assert.Equal(t, n2, n1)
+}
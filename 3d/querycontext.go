@@ -0,0 +1,63 @@
+package rtree
+
+import "github.com/tidwall/pair"
+
+// QueryContext runs queries on a tree with buffers that are kept from one
+// query to the next, so that repeated queries don't allocate once the
+// buffers have grown. A context is not safe for concurrent use, so each
+// goroutine should have its own. The slices that a query returns are reused
+// by the next query on the context.
+type QueryContext struct {
+	tr      *RTree
+	queue   knnQueue
+	best    []float64
+	items   []pair.Pair
+	dists   []float64
+	add     func(item pair.Pair) bool
+	addDist func(item pair.Pair, dist float64) bool
+}
+
+// NewQueryContext returns a context for querying the tree.
+func (tr *RTree) NewQueryContext() *QueryContext {
+	ctx := &QueryContext{tr: tr}
+	ctx.add = func(item pair.Pair) bool {
+		ctx.items = append(ctx.items, item)
+		return true
+	}
+	ctx.addDist = func(item pair.Pair, dist float64) bool {
+		ctx.items = append(ctx.items, item)
+		ctx.dists = append(ctx.dists, dist)
+		return true
+	}
+	return ctx
+}
+
+// Search returns the items that intersect the bbox.
+func (ctx *QueryContext) Search(bbox pair.Pair) []pair.Pair {
+	ctx.items = ctx.items[:0]
+	ctx.tr.Search(bbox, ctx.add)
+	return ctx.items
+}
+
+// SearchRect returns the items that intersect the rect, which is in the
+// coordinates of the tree.
+func (ctx *QueryContext) SearchRect(min, max [3]float64) []pair.Pair {
+	ctx.items = ctx.items[:0]
+	ctx.tr.SearchRect(min, max, ctx.add)
+	return ctx.items
+}
+
+// KNN returns the k nearest items to the point, nearest first, with their
+// distances as in KNNK.
+func (ctx *QueryContext) KNN(x, y, z float64, k int) (items []pair.Pair, dists []float64) {
+	ctx.items, ctx.dists = ctx.items[:0], ctx.dists[:0]
+	if k <= 0 {
+		return ctx.items, ctx.dists
+	}
+	if cap(ctx.best) < k {
+		ctx.best = make([]float64, 0, k)
+	}
+	ctx.queue.items = ctx.queue.items[:0]
+	ctx.tr.knnk(&ctx.queue, ctx.best[:0], x, y, z, k, ctx.addDist)
+	return ctx.items, ctx.dists
+}
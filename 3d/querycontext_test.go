@@ -0,0 +1,44 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+	"github.com/tidwall/pair-rtree/rtreetest"
+)
+
+func TestQueryContext(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 5000; i++ {
+		tr.Insert(makeRandom("rect"))
+	}
+	ctx := tr.NewQueryContext()
+	for i := 0; i < 10; i++ {
+		bbox := makeRandom("rect")
+		var expect []pair.Pair
+		tr.Search(bbox, func(item pair.Pair) bool {
+			expect = append(expect, item)
+			return true
+		})
+		assert.True(t, rtreetest.SameItems(expect, ctx.Search(bbox)))
+	}
+
+	var expect []float64
+	tr.KNNK(10, 20, 30, 50, func(item pair.Pair, dist float64) bool {
+		expect = append(expect, dist)
+		return true
+	})
+	items, dists := ctx.KNN(10, 20, 30, 50)
+	assert.Equal(t, 50, len(items))
+	assert.Equal(t, expect, dists)
+	items, dists = ctx.KNN(10, 20, 30, 0)
+	assert.Equal(t, 0, len(items)+len(dists))
+
+	bbox := makeRandom("rect")
+	allocs := testing.AllocsPerRun(100, func() {
+		ctx.Search(bbox)
+		ctx.KNN(10, 20, 30, 50)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
@@ -0,0 +1,50 @@
+package rtree
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrInvalidRect is returned by Repair when an item has a NaN coordinate.
+var ErrInvalidRect = errors.New("item has an invalid rect")
+
+// Repair recomputes the bbox of every node from the bottom up and returns
+// the number of nodes with a bbox that was wrong. Bboxes go stale when the
+// value of an item is changed after it was inserted, which causes searches
+// to miss the item. The shape of the tree is kept, so Repair is much cheaper
+// than a rebuild. Items with NaN coordinates are left in place and cause
+// ErrInvalidRect to be returned after the rest of the tree is repaired.
+func (tr *RTree) Repair() (fixed int, err error) {
+	if tr.frozen {
+		return 0, ErrFrozen
+	}
+	if tr.data.len() == 0 {
+		return 0, nil
+	}
+	tr.repair(tr.data, &fixed, &err)
+	return fixed, err
+}
+
+func (tr *RTree) repair(node *treeNode, fixed *int, err *error) {
+	before := *node
+	if node.leaf {
+		var child treeNode
+		for _, item := range node.items {
+			fillBBox(item, &child, tr.rect)
+			if math.IsNaN(child.minX) || math.IsNaN(child.minY) || math.IsNaN(child.minZ) ||
+				math.IsNaN(child.maxX) || math.IsNaN(child.maxY) || math.IsNaN(child.maxZ) {
+				*err = ErrInvalidRect
+			}
+		}
+	} else {
+		for _, index := range node.children {
+			tr.repair(tr.node(index), fixed, err)
+		}
+	}
+	tr.calcBBox(node)
+	if node.minX != before.minX || node.minY != before.minY || node.minZ != before.minZ ||
+		node.maxX != before.maxX || node.maxY != before.maxY || node.maxZ != before.maxZ {
+		*fixed++
+	}
+	tr.annotateNode(node)
+}
@@ -0,0 +1,52 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestRepresentatives(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 5000; i++ {
+		tr.Insert(makeRandom("point"))
+	}
+	min, max := [3]float64{-90, -45, -45}, [3]float64{90, 45, 45}
+	found := make(map[pair.Pair]bool)
+	tr.SearchRect(min, max, func(item pair.Pair) bool {
+		found[item] = true
+		return true
+	})
+	var leaves int
+	tr.SearchLevel(1, min, max, func(min, max [3]float64) bool {
+		leaves++
+		return true
+	})
+	for _, perNode := range []int{1, 3} {
+		seen := make(map[pair.Pair]bool)
+		tr.RepresentativesRect(min, max, perNode, func(item pair.Pair) bool {
+			assert.True(t, found[item])
+			assert.False(t, seen[item])
+			seen[item] = true
+			return true
+		})
+		assert.True(t, len(seen) > 0 && len(seen) <= leaves*perNode)
+		assert.True(t, len(seen) < len(found))
+	}
+
+	// enough per node returns everything
+	var n int
+	tr.RepresentativesRect(min, max, 1000, func(item pair.Pair) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, len(found), n)
+
+	n = 0
+	tr.RepresentativesRect(min, max, 0, func(item pair.Pair) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, 0, n)
+}
@@ -1,12 +1,12 @@
 package rtree
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"math/rand"
 	"os"
 	"runtime"
-	"sort"
 	"testing"
 	"time"
 
@@ -14,6 +14,8 @@ import (
 	"github.com/tidwall/geobin"
 	"github.com/tidwall/pair"
 	"github.com/tidwall/pair-rtree/cities"
+	"github.com/tidwall/pair-rtree/rtreetest"
+	"github.com/tidwall/pair-rtree/viz"
 )
 
 func makePointPair3(key string, x, y, z float64) pair.Pair {
@@ -160,7 +162,7 @@ func testRandom(t *testing.T, which string, n int) {
 		arr = append(arr, item)
 		return true
 	})
-	assert.True(t, testHasSameItems(objs, arr))
+	assert.True(t, rtreetest.SameItems(objs, arr))
 
 	// search
 	testSearch(t, tr, objs, 0.10, true)
@@ -214,42 +216,13 @@ func testKNN(t *testing.T, tr *RTree, objs []pair.Pair, n int, check bool) {
 	assert.True(t, n > len(objs) || n == len(arr1))
 
 	// get the KNN for the original array
-	nobjs := make([]pair.Pair, len(objs))
-	copy(nobjs, objs)
-	sort.Slice(nobjs, func(i, j int) bool {
-		imin, imax := geobin.WrapBinary(nobjs[i].Value()).Rect(nil)
-		jmin, jmax := geobin.WrapBinary(nobjs[j].Value()).Rect(nil)
-		idist := testBoxDist(x, y, z, imin, imax)
-		jdist := testBoxDist(x, y, z, jmin, jmax)
-		return idist < jdist
-	})
-	arr2 := nobjs[:len(arr1)]
-	var dists2 []float64
-	for i := 0; i < len(arr2); i++ {
-		min, max := geobin.WrapBinary(arr2[i].Value()).Rect(nil)
-		dist := testBoxDist(x, y, z, min, max)
-		dists2 = append(dists2, dist)
-	}
+	_, dists2 := rtreetest.KNN(objs, x, y, z)
+	dists2 = dists2[:len(arr1)]
 	// only compare the distances, not the objects because rectangles with
 	// a dist of zero will not be ordered.
 	assert.Equal(t, dists1, dists2)
 
 }
-func testBoxDist(x, y, z float64, min, max [3]float64) float64 {
-	dx := testAxisDist(x, min[0], max[0])
-	dy := testAxisDist(y, min[1], max[1])
-	dz := testAxisDist(z, min[2], max[2])
-	return dx*dx + dy*dy + dz*dz
-}
-func testAxisDist(k, min, max float64) float64 {
-	if k < min {
-		return min - k
-	}
-	if k <= max {
-		return 0
-	}
-	return k - max
-}
 func testSearch(t *testing.T, tr *RTree, objs []pair.Pair, percent float64, check bool) {
 	min, max := tr.Bounds()
 	minx := ((max[0]+min[0])/2 - ((max[0]-min[0])*percent)/2)
@@ -269,50 +242,9 @@ func testSearch(t *testing.T, tr *RTree, objs []pair.Pair, percent float64, chec
 	if !check {
 		return
 	}
-	var arr2 []pair.Pair
-	for _, obj := range objs {
-		if testIntersects(obj, box) {
-			arr2 = append(arr2, obj)
-		}
-	}
+	arr2 := rtreetest.Search(objs, box)
 	assert.Equal(t, len(arr1), len(arr2))
-	for _, o1 := range arr1 {
-		var found bool
-		for _, o2 := range arr2 {
-			if o2 == o1 {
-				found = true
-				break
-			}
-		}
-		if !found {
-			t.Fatalf("not found")
-		}
-	}
-}
-
-func testIntersects(obj, box pair.Pair) bool {
-	amin, amax := geobin.WrapBinary(obj.Value()).Rect(nil)
-	bmin, bmax := geobin.WrapBinary(box.Value()).Rect(nil)
-	return bmin[0] <= amax[0] && bmin[1] <= amax[1] && bmin[2] <= amax[2] &&
-		bmax[0] >= amin[0] && bmax[1] >= amin[1] && bmax[2] >= amin[2]
-}
-func testHasSameItems(a1, a2 []pair.Pair) bool {
-	if len(a1) != len(a2) {
-		return false
-	}
-	for _, p1 := range a1 {
-		var found bool
-		for _, p2 := range a2 {
-			if p1 == p2 {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
-		}
-	}
-	return true
+	assert.True(t, rtreetest.SameItems(arr1, arr2))
 }
 
 func TestOutputFlatPNG(t *testing.T) {
@@ -332,7 +264,7 @@ func TestOutputFlatPNG(t *testing.T) {
 	dur := time.Since(start)
 	fmt.Printf("wrote %d cities (flat) in %s (%.0f/ops)\n", len(c), dur, float64(len(c))/dur.Seconds())
 	withGIF := os.Getenv("GIFOUTPUT") != ""
-	if err := tr.SavePNG("flat.png", 1000, 1000, 1.25/360.0, true, withGIF, os.Stdout); err != nil {
+	if err := savePNG(tr, "flat.png", 1.25/360.0, withGIF); err != nil {
 		t.Fatal(err)
 	}
 	if !withGIF {
@@ -375,7 +307,7 @@ func TestOutputWGS84PNG(t *testing.T) {
 	dur := time.Since(start)
 	fmt.Printf("wrote %d cities (wgs84) in %s (%.0f/ops)\n", len(c), dur, float64(len(c))/dur.Seconds())
 	withGIF := os.Getenv("GIFOUTPUT") != ""
-	if err := tr.SavePNG("wgs84.png", 1000, 1000, 0.85/(6378137.0*2), true, withGIF, os.Stdout); err != nil {
+	if err := savePNG(tr, "wgs84.png", 0.85/(6378137.0*2), withGIF); err != nil {
 		t.Fatal(err)
 	}
 	if !withGIF {
@@ -395,7 +327,7 @@ func TestOutputSpherePNG(t *testing.T) {
 	dur := time.Since(start)
 	fmt.Printf("wrote %d cities (sphere) in %s (%.0f/ops)\n", len(c), dur, float64(len(c))/dur.Seconds())
 	withGIF := os.Getenv("GIFOUTPUT") != ""
-	if err := tr.SavePNG("sphere.png", 1000, 1000, 0.85/(6378137.0*2), true, withGIF, os.Stdout); err != nil {
+	if err := savePNG(tr, "sphere.png", 0.85/(6378137.0*2), withGIF); err != nil {
 		t.Fatal(err)
 	}
 	if !withGIF {
@@ -403,6 +335,62 @@ func TestOutputSpherePNG(t *testing.T) {
 	}
 }
 
+func savePNG(tr *RTree, path string, scale float64, withGIF bool) error {
+	opts := *viz.DefaultOptions
+	opts.Scale = scale
+	opts.GIF = withGIF
+	return viz.SavePNG(viz.From3D(tr), path, 1000, 1000, &opts, os.Stdout)
+}
+
+func TestOutputSlicePNG(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 5000; i++ {
+		tr.Insert(makeRandom("rect"))
+	}
+	opts := *viz.DefaultOptions
+	opts.Scale = 2 / 360.0
+	if err := viz.SaveSlicePNG(viz.From3D(tr), "slice.png", 1000, 1000, 2, 0, &opts, os.Stdout); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOutputCameraPathGIF(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 1000; i++ {
+		tr.Insert(makeRandom("point"))
+	}
+	opts := *viz.DefaultOptions
+	opts.Scale = 2 / 360.0
+	opts.GIF = true
+	opts.Frames = 8
+	opts.Delay = 5
+	opts.CameraPath = func(frame, frames int) (rx, ry, rz float64) {
+		return 0, math.Pi * 2 * float64(frame) / float64(frames), math.Pi / 8
+	}
+	var frames int
+	opts.Progress = func(percent float64, frame, total int) {
+		assert.Equal(t, opts.Frames, total)
+		frames++
+	}
+	if err := viz.SavePNG(viz.From3D(tr), "camera.png", 200, 200, &opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, opts.Frames, frames)
+
+	// cancel halfway through
+	ctx, cancel := context.WithCancel(context.Background())
+	frames = 0
+	opts.Progress = func(percent float64, frame, total int) {
+		frames++
+		if percent >= 50 {
+			cancel()
+		}
+	}
+	err := viz.SavePNGContext(ctx, viz.From3D(tr), "camera.png", 200, 200, &opts, nil)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, opts.Frames/2, frames)
+}
+
 func BenchmarkInsert(b *testing.B) {
 	rand.Seed(0)
 	var points []pair.Pair
@@ -419,3 +407,28 @@ func BenchmarkInsert(b *testing.B) {
 		tr.Insert(points[i])
 	}
 }
+
+func TestKNNAxes(t *testing.T) {
+	tr := New(nil)
+	tr.Insert(makePointPair3("a", 1, 1, 30))
+	tr.Insert(makePointPair3("b", 4, 4, 1))
+	// only items within 10 of the elevation
+	var keys []string
+	tr.KNNAxes(0, 0, 0, func(item pair.Pair, dist float64, axes [3]float64) bool {
+		if axes[2] <= 10 {
+			keys = append(keys, string(item.Key()))
+		}
+		return true
+	})
+	assert.Equal(t, []string{"b"}, keys)
+}
+
+func TestRemoveOK(t *testing.T) {
+	tr := New(nil)
+	item := makeRandom("point")
+	tr.Insert(item)
+	assert.False(t, tr.RemoveOK(makeRandom("point")))
+	assert.True(t, tr.RemoveOK(item))
+	assert.False(t, tr.RemoveOK(item))
+	assert.Equal(t, 0, tr.Count())
+}
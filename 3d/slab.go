@@ -0,0 +1,92 @@
+package rtree
+
+import (
+	"math/bits"
+
+	"github.com/tidwall/pair"
+)
+
+// nodeSlabSize is the number of nodes that are allocated together, and
+// maxSlabSize is the most that are allocated together for a tree with
+// ExpectedItems. Slab sizes are powers of two.
+const (
+	nodeSlabSize = 32
+	maxSlabSize  = 4096
+)
+
+// node returns the node at the index. Nodes are stored in slabs of
+// contiguous memory that are never moved, and branches refer to their
+// children by index, so a pointer to a node stays valid while it's in the
+// tree, and the slabs of a tree can be copied as they are.
+func (tr *RTree) node(index int32) *treeNode {
+	return &tr.slabs[index>>tr.slabShift][index&(1<<tr.slabShift-1)]
+}
+
+// newNode returns a new empty leaf for the tree. A node that was freed is
+// reused before a new one is carved out of the last slab, so nodes that are
+// created together, such as siblings from a split, are usually near each
+// other.
+func (tr *RTree) newNode() *treeNode {
+	var index int32
+	if n := len(tr.free); n > 0 {
+		index = tr.free[n-1]
+		tr.free = tr.free[:n-1]
+	} else {
+		if int(tr.numNodes>>tr.slabShift) == len(tr.slabs) {
+			tr.slabs = append(tr.slabs, make([]treeNode, 1<<tr.slabShift))
+		}
+		index = tr.numNodes
+		tr.numNodes++
+	}
+	node := tr.node(index)
+	*node = *createNode()
+	node.index = index
+	return node
+}
+
+// freeNode releases a node that's no longer in the tree, so it can be
+// reused.
+func (tr *RTree) freeNode(node *treeNode) {
+	index := node.index
+	*node = treeNode{}
+	tr.free = append(tr.free, index)
+}
+
+// freeTree releases a node and everything under it.
+func (tr *RTree) freeTree(node *treeNode) {
+	for _, index := range node.children {
+		tr.freeTree(tr.node(index))
+	}
+	tr.freeNode(node)
+}
+
+// slabSizeFor returns the slab size for a tree that is expected to hold the
+// number of items, which is about a tenth of the nodes that it will have,
+// rounded down to a power of two.
+func slabSizeFor(expectedItems, maxEntries int) int {
+	n := expectedItems / maxEntries / 10
+	if n < nodeSlabSize {
+		return nodeSlabSize
+	}
+	if n > maxSlabSize {
+		return maxSlabSize
+	}
+	return 1 << (bits.Len(uint(n)) - 1)
+}
+
+// makeChildren returns the children for a branch, with room for the entries
+// of a full node plus the one that splits it when the tree was presized.
+func (tr *RTree) makeChildren(n int) []int32 {
+	if tr.presize {
+		return make([]int32, n, tr.maxEntries+1)
+	}
+	return make([]int32, n)
+}
+
+// makeItems is makeChildren for the items of a leaf.
+func (tr *RTree) makeItems(n int) []pair.Pair {
+	if tr.presize {
+		return make([]pair.Pair, n, tr.maxEntries+1)
+	}
+	return make([]pair.Pair, n)
+}
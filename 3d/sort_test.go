@@ -0,0 +1,71 @@
+package rtree
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestScanSorted(t *testing.T) {
+	var objs []pair.Pair
+	for i := 0; i < 1000; i++ {
+		obj := makeRandom("point")
+		objs = append(objs, pair.New([]byte(fmt.Sprint(i)), obj.Value()))
+	}
+	tr1 := New(nil)
+	tr2 := New(nil)
+	for i := range objs {
+		tr1.Insert(objs[i])
+		tr2.Insert(objs[len(objs)-1-i])
+	}
+	for _, by := range []SortOrder{KeyOrder, HilbertOrder} {
+		var keys1, keys2 []string
+		tr1.ScanSorted(by, func(item pair.Pair) bool {
+			keys1 = append(keys1, string(item.Key()))
+			return true
+		})
+		tr2.ScanSorted(by, func(item pair.Pair) bool {
+			keys2 = append(keys2, string(item.Key()))
+			return true
+		})
+		assert.Equal(t, len(objs), len(keys1))
+		assert.Equal(t, keys1, keys2)
+	}
+	var last pair.Pair
+	tr1.ScanSorted(KeyOrder, func(item pair.Pair) bool {
+		if !last.Zero() {
+			assert.True(t, bytes.Compare(last.Key(), item.Key()) < 0)
+		}
+		last = item
+		return true
+	})
+}
+
+func TestHilbert(t *testing.T) {
+	// neighboring cells on the curve are always adjacent
+	const bits = 4
+	cells := make(map[uint64][3]uint32)
+	for x := uint32(0); x < 1<<bits; x++ {
+		for y := uint32(0); y < 1<<bits; y++ {
+			for z := uint32(0); z < 1<<bits; z++ {
+				cells[hilbert([]uint32{x, y, z}, bits)] = [3]uint32{x, y, z}
+			}
+		}
+	}
+	assert.Equal(t, 1<<(bits*3), len(cells))
+	for d := uint64(1); d < 1<<(bits*3); d++ {
+		a, b := cells[d-1], cells[d]
+		var dist int
+		for i := 0; i < 3; i++ {
+			if a[i] > b[i] {
+				dist += int(a[i] - b[i])
+			} else {
+				dist += int(b[i] - a[i])
+			}
+		}
+		assert.Equal(t, 1, dist)
+	}
+}
@@ -0,0 +1,45 @@
+package rtree
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestStats(t *testing.T) {
+	tr := New(nil)
+	var items []pair.Pair
+	for i := 0; i < 1000; i++ {
+		items = append(items, makeRandom("point"))
+		tr.Insert(items[i])
+	}
+	for _, item := range items[:900] {
+		tr.Remove(item)
+	}
+	tr.Remove(items[0])
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				tr.Search(makeRandom("rect"), func(item pair.Pair) bool { return true })
+				tr.KNN(0, 0, 0, func(item pair.Pair, dist float64) bool { return false })
+			}
+		}()
+	}
+	wg.Wait()
+	s := tr.Stats()
+	assert.Equal(t, uint64(1000), s.Inserts)
+	assert.Equal(t, uint64(900), s.Removes)
+	assert.Equal(t, uint64(40), s.Searches)
+	assert.Equal(t, uint64(40), s.KNNs)
+	assert.True(t, s.Splits > 100)
+	assert.True(t, s.Condenses > 0)
+
+	assert.Equal(t, s, tr.Clone().Stats())
+	tr.ResetStats()
+	assert.Equal(t, Stats{}, tr.Stats())
+}
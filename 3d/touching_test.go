@@ -0,0 +1,66 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestExcludeTouching(t *testing.T) {
+	opts := *DefaultOptions
+	for _, exclude := range []bool{false, true} {
+		opts.ExcludeTouching = exclude
+		tr := New(&opts)
+		// a grid of unit squares, and a point at each corner
+		for x := 0.0; x < 10; x++ {
+			for y := 0.0; y < 10; y++ {
+				tr.Insert(makeBoundsPair3("", x, y, 0, x+1, y+1, 1))
+				tr.Insert(makePointPair3("", x, y, 0))
+			}
+		}
+		count := func(min, max [3]float64) int {
+			var n int
+			tr.SearchRect(min, max, func(item pair.Pair) bool {
+				n++
+				return true
+			})
+			return n
+		}
+		square := count([3]float64{4, 4, 0}, [3]float64{5, 5, 1})
+		inside := count([3]float64{4.5, 4.5, 0.5}, [3]float64{4.5, 4.5, 0.5})
+		line := count([3]float64{4.5, 0, 0.5}, [3]float64{4.5, 3, 0.5})
+		budget := 1 << 20
+		var limited int
+		tr.SearchRectLimited([3]float64{4, 4, 0}, [3]float64{5, 5, 1}, &budget,
+			func(item pair.Pair) bool {
+				limited++
+				return true
+			})
+		var reps int
+		tr.RepresentativesRect([3]float64{4, 4, 0}, [3]float64{5, 5, 1}, 100,
+			func(item pair.Pair) bool {
+				reps++
+				return true
+			})
+		var touching int
+		tr.SearchRectTouching([3]float64{4, 4, 0}, [3]float64{5, 5, 1}, func(item pair.Pair) bool {
+			touching++
+			return true
+		})
+		if exclude {
+			// only the square itself
+			assert.Equal(t, 1, square)
+			// the squares that it crosses, but not the ones that it ends on
+			assert.Equal(t, 3, line)
+		} else {
+			// the 3x3 squares around it, and the 2x2 points at its corners
+			assert.Equal(t, 13, square)
+			assert.Equal(t, 4, line)
+		}
+		assert.Equal(t, 1, inside)
+		assert.Equal(t, 13, touching)
+		assert.Equal(t, square, limited)
+		assert.Equal(t, square, reps)
+	}
+}
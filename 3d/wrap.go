@@ -0,0 +1,88 @@
+package rtree
+
+import (
+	"math"
+	"unsafe"
+
+	"github.com/tidwall/pair"
+)
+
+// wrapDist returns the distance from k to the range along an axis that
+// wraps around at the period, which is the shortest way around.
+func wrapDist(k, min, max, period float64) float64 {
+	width := max - min
+	if width >= period {
+		return 0
+	}
+	c := math.Mod(k-min, period)
+	if c < 0 {
+		c += period
+	}
+	if c <= width {
+		return 0
+	}
+	return mathMin(c-width, period-c)
+}
+
+// xDist is axisDist for the x axis, which may wrap.
+func (tr *RTree) xDist(x, min, max float64) float64 {
+	if tr.wrapX > 0 {
+		return wrapDist(x, min, max, tr.wrapX)
+	}
+	return axisDist(x, min, max)
+}
+
+// dist returns the distance from the point to the rect with the metric,
+// taking the WrapX option into account.
+func (tr *RTree) dist(metric Metric, x, y, z float64, min, max [3]float64) float64 {
+	if tr.wrapX <= 0 {
+		return metric.dist(x, y, z, min, max)
+	}
+	axes := [3]float64{tr.xDist(x, min[0], max[0]), axisDist(y, min[1], max[1]),
+		axisDist(z, min[2], max[2])}
+	if metric == nil {
+		return axes[0]*axes[0] + axes[1]*axes[1] + axes[2]*axes[2]
+	}
+	return metric(axes)
+}
+
+// searchWrapped searches each copy of the bbox, shifted by whole periods,
+// that overlaps the tree, with the search func. An item that overlaps more
+// than one copy is only returned once.
+func (tr *RTree) searchWrapped(bbox treeNode, iter func(item pair.Pair) bool,
+	search func(bbox *treeNode, iter func(item pair.Pair) bool) bool) bool {
+	period := tr.wrapX
+	if bbox.maxX-bbox.minX >= period {
+		bbox.minX, bbox.maxX = mathInfNeg, mathInfPos
+		if !tr.data.intersects(&bbox) {
+			return true
+		}
+		return search(&bbox, iter)
+	}
+	kmin := math.Floor((tr.data.minX - bbox.maxX) / period)
+	kmax := math.Ceil((tr.data.maxX - bbox.minX) / period)
+	var seen map[unsafe.Pointer]bool
+	if kmax > kmin {
+		seen = make(map[unsafe.Pointer]bool)
+		inner := iter
+		iter = func(item pair.Pair) bool {
+			if seen[item.Pointer()] {
+				return true
+			}
+			seen[item.Pointer()] = true
+			return inner(item)
+		}
+	}
+	for k := kmin; k <= kmax; k++ {
+		shifted := bbox
+		shifted.minX += k * period
+		shifted.maxX += k * period
+		if !tr.data.intersects(&shifted) {
+			continue
+		}
+		if !search(&shifted, iter) {
+			return false
+		}
+	}
+	return true
+}
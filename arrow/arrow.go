@@ -0,0 +1,226 @@
+// Package arrow exports tree items in the Apache Arrow IPC stream format.
+//
+// The stream has a key column, the min_x, min_y, min_z, max_x, max_y, and
+// max_z columns of the untransformed item bboxes, and an optional value
+// column with the raw geobin bytes. The z columns are null for 2d items.
+package arrow
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+type Options struct {
+	// Values includes a value column with the geobin bytes of each item.
+	Values bool
+	// BatchSize is the maximum number of rows in each record batch.
+	BatchSize int
+}
+
+var DefaultOptions = &Options{
+	Values:    false,
+	BatchSize: 64 * 1024,
+}
+
+// Arrow flatbuffer enums
+const (
+	metadataV5        = 4
+	headerSchema      = 1
+	headerRecordBatch = 3
+	typeFloatingPoint = 3
+	typeBinary        = 4
+	precisionDouble   = 2
+)
+
+var coordNames = [6]string{"min_x", "min_y", "min_z", "max_x", "max_y", "max_z"}
+
+// Write writes a stream of every item returned by scan, which may be the
+// Scan method of a tree or a function that wraps a query.
+//
+//	arrow.Write(w, tr.Scan, nil)
+func Write(w io.Writer, scan func(iter func(item pair.Pair) bool) bool, opts *Options) error {
+	if opts == nil {
+		opts = DefaultOptions
+	}
+	batchSize := opts.BatchSize
+	if batchSize < 1 {
+		batchSize = DefaultOptions.BatchSize
+	}
+	bw := bufio.NewWriter(w)
+	if err := writeMessage(bw, schema(opts), headerSchema, nil); err != nil {
+		return err
+	}
+	var b batch
+	var err error
+	scan(func(item pair.Pair) bool {
+		b.add(item, opts.Values)
+		if b.n == batchSize {
+			err = b.write(bw, opts.Values)
+			b.reset()
+		}
+		return err == nil
+	})
+	if err == nil && b.n > 0 {
+		err = b.write(bw, opts.Values)
+	}
+	if err != nil {
+		return err
+	}
+	// end of stream
+	bw.Write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0})
+	return bw.Flush()
+}
+
+func field(name string, nullable bool, typ uint8, typeTable *fbTable) *fbTable {
+	return (&fbTable{}).
+		ref(0, fbString(name)).
+		bool(1, nullable).
+		u8(2, typ).
+		ref(3, typeTable).
+		ref(5, fbTables{})
+}
+
+func schema(opts *Options) *fbTable {
+	fields := fbTables{field("key", false, typeBinary, &fbTable{})}
+	for i, name := range coordNames {
+		double := (&fbTable{}).i16(0, precisionDouble)
+		fields = append(fields, field(name, i%3 == 2, typeFloatingPoint, double))
+	}
+	if opts.Values {
+		fields = append(fields, field("value", false, typeBinary, &fbTable{}))
+	}
+	return (&fbTable{}).i16(0, 0).ref(1, fields)
+}
+
+// writeMessage writes an encapsulated message, which is the metadata
+// flatbuffer padded to eight bytes, followed by the body.
+func writeMessage(w io.Writer, header *fbTable, headerType uint8, body []byte) error {
+	msg := (&fbTable{}).
+		i16(0, metadataV5).
+		u8(1, headerType).
+		ref(2, header).
+		i64(3, int64(len(body)))
+	meta := (&fbBuilder{}).finish(msg)
+	size := alignUp(8+len(meta), 8) - 8
+	var prefix [8]byte
+	binary.LittleEndian.PutUint32(prefix[0:], 0xffffffff)
+	binary.LittleEndian.PutUint32(prefix[4:], uint32(size))
+	if _, err := w.Write(prefix[:]); err != nil {
+		return err
+	}
+	meta = append(meta, make([]byte, size-len(meta))...)
+	if _, err := w.Write(meta); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// batch holds the columns of a record batch.
+type batch struct {
+	n          int
+	keys       []byte
+	keyOffsets []int32
+	values     []byte
+	valOffsets []int32
+	coords     [6][]float64
+	valid      []byte // validity of the z columns
+	nulls      int
+}
+
+func (b *batch) reset() {
+	b.n, b.nulls = 0, 0
+	b.keys, b.keyOffsets = b.keys[:0], b.keyOffsets[:0]
+	b.values, b.valOffsets = b.values[:0], b.valOffsets[:0]
+	for i := range b.coords {
+		b.coords[i] = b.coords[i][:0]
+	}
+	b.valid = b.valid[:0]
+}
+
+func (b *batch) add(item pair.Pair, values bool) {
+	if b.n == 0 {
+		b.keyOffsets = append(b.keyOffsets, 0)
+		b.valOffsets = append(b.valOffsets, 0)
+	}
+	b.keys = append(b.keys, item.Key()...)
+	b.keyOffsets = append(b.keyOffsets, int32(len(b.keys)))
+	if values {
+		b.values = append(b.values, item.Value()...)
+		b.valOffsets = append(b.valOffsets, int32(len(b.values)))
+	}
+	g := geobin.WrapBinary(item.Value())
+	min, max := g.Rect(nil)
+	if b.n%8 == 0 {
+		b.valid = append(b.valid, 0)
+	}
+	if g.Dims() == 2 {
+		min[2], max[2] = 0, 0
+		b.nulls++
+	} else {
+		b.valid[b.n/8] |= 1 << uint(b.n%8)
+	}
+	for i := 0; i < 3; i++ {
+		b.coords[i] = append(b.coords[i], min[i])
+		b.coords[3+i] = append(b.coords[3+i], max[i])
+	}
+	b.n++
+}
+
+func (b *batch) write(w io.Writer, values bool) error {
+	var body []byte
+	var buffers []byte
+	addBuffer := func(data []byte) {
+		var rec [16]byte
+		binary.LittleEndian.PutUint64(rec[0:], uint64(len(body)))
+		binary.LittleEndian.PutUint64(rec[8:], uint64(len(data)))
+		buffers = append(buffers, rec[:]...)
+		body = append(body, data...)
+		body = append(body, make([]byte, alignUp(len(body), 8)-len(body))...)
+	}
+	var nodes []byte
+	addNode := func(nulls int) {
+		var rec [16]byte
+		binary.LittleEndian.PutUint64(rec[0:], uint64(b.n))
+		binary.LittleEndian.PutUint64(rec[8:], uint64(nulls))
+		nodes = append(nodes, rec[:]...)
+	}
+	addBinary := func(offsets []int32, data []byte) {
+		addNode(0)
+		addBuffer(nil)
+		raw := make([]byte, 4*len(offsets))
+		for i, off := range offsets {
+			binary.LittleEndian.PutUint32(raw[i*4:], uint32(off))
+		}
+		addBuffer(raw)
+		addBuffer(data)
+	}
+	addBinary(b.keyOffsets, b.keys)
+	for i, col := range b.coords {
+		if i%3 == 2 {
+			addNode(b.nulls)
+			addBuffer(b.valid)
+		} else {
+			addNode(0)
+			addBuffer(nil)
+		}
+		raw := make([]byte, 8*len(col))
+		for j, v := range col {
+			binary.LittleEndian.PutUint64(raw[j*8:], math.Float64bits(v))
+		}
+		addBuffer(raw)
+	}
+	if values {
+		addBinary(b.valOffsets, b.values)
+	}
+	header := (&fbTable{}).
+		i64(0, int64(b.n)).
+		ref(1, fbStructs{nodes, len(nodes) / 16, 8}).
+		ref(2, fbStructs{buffers, len(buffers) / 16, 8})
+	return writeMessage(w, header, headerRecordBatch, body)
+}
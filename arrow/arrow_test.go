@@ -0,0 +1,121 @@
+package arrow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+// fbReader reads flatbuffer tables for verifying the output.
+type fbReader []byte
+
+func (r fbReader) u16(p int) int   { return int(binary.LittleEndian.Uint16(r[p:])) }
+func (r fbReader) u32(p int) int   { return int(binary.LittleEndian.Uint32(r[p:])) }
+func (r fbReader) i64(p int) int   { return int(binary.LittleEndian.Uint64(r[p:])) }
+func (r fbReader) deref(p int) int { return p + r.u32(p) }
+
+// field returns the position of a table field, or zero if it's absent.
+func (r fbReader) field(table, id int) int {
+	vt := table - int(int32(r.u32(table)))
+	if 4+2*id >= r.u16(vt) || r.u16(vt+4+2*id) == 0 {
+		return 0
+	}
+	return table + r.u16(vt+4+2*id)
+}
+
+func (r fbReader) str(p int) string {
+	p = r.deref(p)
+	return string(r[p+4 : p+4+r.u32(p)])
+}
+
+type message struct {
+	meta fbReader
+	root int
+	body []byte
+}
+
+func readMessages(t *testing.T, data []byte) []message {
+	var msgs []message
+	for {
+		assert.Equal(t, uint32(0xffffffff), binary.LittleEndian.Uint32(data))
+		size := int(binary.LittleEndian.Uint32(data[4:]))
+		if size == 0 {
+			assert.Equal(t, 8, len(data))
+			return msgs
+		}
+		assert.Equal(t, 0, (8+size)%8)
+		meta := fbReader(data[8 : 8+size])
+		root := meta.deref(0)
+		assert.Equal(t, metadataV5, meta.u16(meta.field(root, 0)))
+		bodyLen := meta.i64(meta.field(root, 3))
+		msgs = append(msgs, message{meta, root, data[8+size : 8+size+bodyLen]})
+		data = data[8+size+bodyLen:]
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tr := rtree.New(nil)
+	tr.Insert(pair.New([]byte("a"), geobin.Make2DPoint(1, 2).Binary()))
+	tr.Insert(pair.New([]byte("bb"), geobin.Make3DRect(1, 2, 3, 4, 5, 6).Binary()))
+	tr.Insert(pair.New([]byte("ccc"), geobin.Make3DPoint(7, 8, 9).Binary()))
+	var buf bytes.Buffer
+	opts := *DefaultOptions
+	opts.Values = true
+	opts.BatchSize = 2
+	assert.NoError(t, Write(&buf, tr.ScanSorted, &opts))
+	msgs := readMessages(t, buf.Bytes())
+	assert.Equal(t, 3, len(msgs))
+
+	// schema
+	m := msgs[0].meta
+	assert.Equal(t, headerSchema, int(m[m.field(msgs[0].root, 1)]))
+	schema := m.deref(m.field(msgs[0].root, 2))
+	fields := m.deref(m.field(schema, 1))
+	var names []string
+	for i := 0; i < m.u32(fields); i++ {
+		f := m.deref(fields + 4 + 4*i)
+		names = append(names, m.str(m.field(f, 0)))
+		assert.True(t, m.field(f, 5) != 0)
+	}
+	assert.Equal(t, []string{"key", "min_x", "min_y", "min_z", "max_x", "max_y", "max_z", "value"}, names)
+
+	// batches
+	var keys []string
+	var minz []float64
+	var nulls []int
+	for _, msg := range msgs[1:] {
+		m := msg.meta
+		assert.Equal(t, headerRecordBatch, int(m[m.field(msg.root, 1)]))
+		rb := m.deref(m.field(msg.root, 2))
+		n := m.i64(m.field(rb, 0))
+		nodes := m.deref(m.field(rb, 1))
+		buffers := m.deref(m.field(rb, 2))
+		assert.Equal(t, 8, m.u32(nodes))
+		assert.Equal(t, 3+6*2+3, m.u32(buffers))
+		assert.Equal(t, 0, (nodes+4)%8)
+		buffer := func(i int) []byte {
+			off := m.i64(buffers + 4 + 16*i)
+			return msg.body[off : off+m.i64(buffers+4+16*i+8)]
+		}
+		offsets, data := buffer(1), buffer(2)
+		for i := 0; i < n; i++ {
+			start := binary.LittleEndian.Uint32(offsets[i*4:])
+			end := binary.LittleEndian.Uint32(offsets[i*4+4:])
+			keys = append(keys, string(data[start:end]))
+			valid := buffer(7)[i/8]&(1<<uint(i%8)) != 0
+			if valid {
+				minz = append(minz, math.Float64frombits(binary.LittleEndian.Uint64(buffer(8)[i*8:])))
+			}
+		}
+		nulls = append(nulls, m.i64(nodes+4+16*3+8))
+	}
+	assert.Equal(t, []string{"a", "bb", "ccc"}, keys)
+	assert.Equal(t, []float64{3, 9}, minz)
+	assert.Equal(t, []int{1, 0}, nulls)
+}
@@ -0,0 +1,172 @@
+package arrow
+
+import (
+	"encoding/binary"
+)
+
+// A minimal FlatBuffers encoder for the Arrow IPC metadata. Objects are
+// written front to back, with every child following the object that refers
+// to it, so that all offsets point forward. Vtables are written directly
+// before their tables.
+
+type fbTable struct {
+	fields []fbField // indexed by field id
+}
+
+type fbField struct {
+	set    bool
+	scalar []byte
+	ref    interface{} // *fbTable, fbTables, fbStructs, or fbString
+}
+
+type fbTables []*fbTable
+
+type fbStructs struct {
+	data  []byte
+	count int
+	align int
+}
+
+type fbString string
+
+func (t *fbTable) field(id int) *fbField {
+	for len(t.fields) <= id {
+		t.fields = append(t.fields, fbField{})
+	}
+	t.fields[id].set = true
+	return &t.fields[id]
+}
+
+func (t *fbTable) u8(id int, v uint8) *fbTable {
+	t.field(id).scalar = []byte{v}
+	return t
+}
+
+func (t *fbTable) i16(id int, v int16) *fbTable {
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, uint16(v))
+	t.field(id).scalar = b
+	return t
+}
+
+func (t *fbTable) i32(id int, v int32) *fbTable {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, uint32(v))
+	t.field(id).scalar = b
+	return t
+}
+
+func (t *fbTable) i64(id int, v int64) *fbTable {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(v))
+	t.field(id).scalar = b
+	return t
+}
+
+func (t *fbTable) bool(id int, v bool) *fbTable {
+	if v {
+		return t.u8(id, 1)
+	}
+	return t.u8(id, 0)
+}
+
+func (t *fbTable) ref(id int, v interface{}) *fbTable {
+	t.field(id).ref = v
+	return t
+}
+
+type fbBuilder struct {
+	buf []byte
+}
+
+func (b *fbBuilder) padTo(pos int) {
+	for len(b.buf) < pos {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+func (b *fbBuilder) putOffset(at, target int) {
+	binary.LittleEndian.PutUint32(b.buf[at:], uint32(target-at))
+}
+
+func alignUp(n, align int) int {
+	return (n + align - 1) &^ (align - 1)
+}
+
+// finish returns the encoded buffer with root as the root table.
+func (b *fbBuilder) finish(root *fbTable) []byte {
+	b.buf = make([]byte, 4)
+	b.putOffset(0, b.write(root))
+	return b.buf
+}
+
+func (b *fbBuilder) write(obj interface{}) int {
+	switch obj := obj.(type) {
+	case *fbTable:
+		return b.writeTable(obj)
+	case fbTables:
+		pos := alignUp(len(b.buf), 4)
+		b.padTo(pos + 4 + 4*len(obj))
+		binary.LittleEndian.PutUint32(b.buf[pos:], uint32(len(obj)))
+		for i, t := range obj {
+			b.putOffset(pos+4+4*i, b.writeTable(t))
+		}
+		return pos
+	case fbStructs:
+		// the elements must be aligned, which follow the length
+		pos := alignUp(len(b.buf), 4)
+		for obj.align > 4 && (pos+4)%obj.align != 0 {
+			pos += 4
+		}
+		b.padTo(pos + 4)
+		binary.LittleEndian.PutUint32(b.buf[pos:], uint32(obj.count))
+		b.buf = append(b.buf, obj.data...)
+		return pos
+	case fbString:
+		pos := alignUp(len(b.buf), 4)
+		b.padTo(pos + 4)
+		binary.LittleEndian.PutUint32(b.buf[pos:], uint32(len(obj)))
+		b.buf = append(b.buf, obj...)
+		b.buf = append(b.buf, 0)
+		return pos
+	}
+	panic("invalid flatbuffer object")
+}
+
+func (b *fbBuilder) writeTable(t *fbTable) int {
+	vtPos := alignUp(len(b.buf), 2)
+	vtSize := 4 + 2*len(t.fields)
+	tablePos := alignUp(vtPos+vtSize, 8)
+	offsets := make([]int, len(t.fields))
+	size := 4
+	for i, f := range t.fields {
+		if !f.set {
+			continue
+		}
+		n := len(f.scalar)
+		if f.ref != nil {
+			n = 4
+		}
+		size = alignUp(tablePos+size, n) - tablePos
+		offsets[i] = size
+		size += n
+	}
+	b.padTo(tablePos + size)
+	binary.LittleEndian.PutUint16(b.buf[vtPos:], uint16(vtSize))
+	binary.LittleEndian.PutUint16(b.buf[vtPos+2:], uint16(size))
+	for i, off := range offsets {
+		binary.LittleEndian.PutUint16(b.buf[vtPos+4+2*i:], uint16(off))
+	}
+	binary.LittleEndian.PutUint32(b.buf[tablePos:], uint32(tablePos-vtPos))
+	for i, f := range t.fields {
+		if f.set && f.ref == nil {
+			copy(b.buf[tablePos+offsets[i]:], f.scalar)
+		}
+	}
+	for i, f := range t.fields {
+		if f.set && f.ref != nil {
+			b.putOffset(tablePos+offsets[i], b.write(f.ref))
+		}
+	}
+	return tablePos
+}
@@ -0,0 +1,286 @@
+// Package autosave writes snapshots of a tree in the background, either on
+// an interval or after a number of mutations, and keeps a fixed number of
+// generations on disk.
+//
+// Each snapshot is written to a temporary file that is synced and then
+// renamed into place, so a crash never leaves a partial generation behind.
+// Restore loads the newest generation that is valid.
+package autosave
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+	"github.com/tidwall/pair-rtree/snapshot"
+)
+
+const (
+	prefix = "rtree-"
+	suffix = ".snap"
+)
+
+type Options struct {
+	// Interval is how often to save when the tree has changed. Zero
+	// disables saving on an interval.
+	Interval time.Duration
+	// Mutations is the number of mutations that triggers a save. Zero
+	// disables saving on mutations.
+	Mutations int
+	// Generations is the number of snapshots to keep.
+	Generations int
+	// Locker guards the tree. It's locked while a snapshot is written, and
+	// while subscribing to the tree's mutations. Use the RLocker of a
+	// sync.RWMutex to allow for reads during a save.
+	Locker sync.Locker
+}
+
+var DefaultOptions = &Options{
+	Interval:    time.Minute,
+	Mutations:   0,
+	Generations: 3,
+	Locker:      nil,
+}
+
+// Saver saves a tree in the background until it's closed.
+type Saver struct {
+	opts    Options
+	tr      *rtree.RTree
+	dir     string
+	feed    <-chan rtree.Mutation
+	pending int64 // mutations since the last save
+	trigger chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex // serializes saves
+	gen     int
+	err     error
+}
+
+// New starts saving the tree to the directory, which is created if needed.
+// Numbering continues from any generations that are already in the
+// directory.
+func New(tr *rtree.RTree, dir string, opts *Options) (*Saver, error) {
+	if opts == nil {
+		opts = DefaultOptions
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	gens, err := generations(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := &Saver{
+		opts:    *opts,
+		tr:      tr,
+		dir:     dir,
+		trigger: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if s.opts.Generations < 1 {
+		s.opts.Generations = 1
+	}
+	if len(gens) > 0 {
+		s.gen = gens[len(gens)-1]
+	}
+	s.lock()
+	s.feed = tr.Subscribe()
+	s.unlock()
+	s.wg.Add(2)
+	go s.count()
+	go s.run()
+	return s, nil
+}
+
+func (s *Saver) lock() {
+	if s.opts.Locker != nil {
+		s.opts.Locker.Lock()
+	}
+}
+
+func (s *Saver) unlock() {
+	if s.opts.Locker != nil {
+		s.opts.Locker.Unlock()
+	}
+}
+
+// count drains the mutation feed, which must never block the writer, and
+// triggers a save once enough mutations have been seen.
+func (s *Saver) count() {
+	defer s.wg.Done()
+	for range s.feed {
+		n := atomic.AddInt64(&s.pending, 1)
+		if s.opts.Mutations > 0 && n >= int64(s.opts.Mutations) {
+			select {
+			case s.trigger <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func (s *Saver) run() {
+	defer s.wg.Done()
+	var tick <-chan time.Time
+	if s.opts.Interval > 0 {
+		ticker := time.NewTicker(s.opts.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-tick:
+		case <-s.trigger:
+		}
+		if atomic.LoadInt64(&s.pending) > 0 {
+			s.save()
+		}
+	}
+}
+
+// Save writes a new generation now.
+func (s *Saver) Save() error {
+	return s.save()
+}
+
+func (s *Saver) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = s.write()
+	return s.err
+}
+
+// write writes the next generation and removes the oldest ones.
+func (s *Saver) write() error {
+	f, err := os.CreateTemp(s.dir, prefix+"*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	s.lock()
+	// the mutations are counted by another goroutine, so the ones seen so
+	// far may include some that happen after the snapshot. That only causes
+	// an extra save.
+	pending := atomic.LoadInt64(&s.pending)
+	err = snapshot.Write(f, s.tr.Traverse)
+	s.unlock()
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	s.gen++
+	if err := os.Rename(f.Name(), s.path(s.gen)); err != nil {
+		return err
+	}
+	atomic.AddInt64(&s.pending, -pending)
+	syncDir(s.dir)
+	gens, err := generations(s.dir)
+	if err != nil {
+		return err
+	}
+	for len(gens) > s.opts.Generations {
+		if err := os.Remove(s.path(gens[0])); err != nil {
+			return err
+		}
+		gens = gens[1:]
+	}
+	return nil
+}
+
+// Err returns the error from the last save, if any.
+func (s *Saver) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close stops the saver and writes a final generation if the tree has
+// changed since the last save.
+func (s *Saver) Close() error {
+	close(s.done)
+	s.lock()
+	s.tr.Unsubscribe(s.feed)
+	s.unlock()
+	s.wg.Wait()
+	if atomic.LoadInt64(&s.pending) > 0 {
+		return s.save()
+	}
+	return nil
+}
+
+func (s *Saver) path(gen int) string {
+	return genPath(s.dir, gen)
+}
+
+func genPath(dir string, gen int) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%010d%s", prefix, gen, suffix))
+}
+
+// generations returns the generation numbers in the directory, oldest
+// first.
+func generations(dir string) ([]int, error) {
+	names, err := filepath.Glob(filepath.Join(dir, prefix+"*"+suffix))
+	if err != nil {
+		return nil, err
+	}
+	var gens []int
+	for _, name := range names {
+		var gen int
+		base := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(name), prefix), suffix)
+		if _, err := fmt.Sscanf(base, "%d", &gen); err == nil {
+			gens = append(gens, gen)
+		}
+	}
+	sort.Ints(gens)
+	return gens, nil
+}
+
+// syncDir makes a rename durable. Not every platform can sync a directory,
+// so errors are ignored.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// Restore inserts the items from the newest valid generation in the
+// directory into the tree, and returns the path of that generation. Returns
+// os.ErrNotExist when there are no valid generations.
+func Restore(dir string, tr *rtree.RTree) (string, error) {
+	gens, err := generations(dir)
+	if err != nil {
+		return "", err
+	}
+	for i := len(gens) - 1; i >= 0; i-- {
+		path := genPath(dir, gens[i])
+		snap, err := snapshot.Open(path)
+		if err != nil {
+			continue
+		}
+		var items []pair.Pair
+		snap.Scan(func(item pair.Pair) bool {
+			items = append(items, item)
+			return true
+		})
+		snap.Close()
+		tr.Load(items)
+		return path, nil
+	}
+	return "", os.ErrNotExist
+}
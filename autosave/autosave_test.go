@@ -0,0 +1,57 @@
+package autosave
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+func TestAutosave(t *testing.T) {
+	dir := t.TempDir()
+	var mu sync.Mutex
+	tr := rtree.New(nil)
+	opts := *DefaultOptions
+	opts.Interval = 0
+	opts.Mutations = 100
+	opts.Generations = 2
+	opts.Locker = &mu
+	s, err := New(tr, dir, &opts)
+	assert.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		mu.Lock()
+		tr.Insert(pair.New([]byte(fmt.Sprint(i)), geobin.Make2DPoint(float64(i), float64(i)).Binary()))
+		mu.Unlock()
+		if i%100 == 99 {
+			// give the saver a chance to run
+			time.Sleep(time.Millisecond * 10)
+		}
+	}
+	assert.NoError(t, s.Close())
+	assert.NoError(t, s.Err())
+
+	gens, err := generations(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(gens))
+	assert.True(t, gens[1] > 2)
+
+	tr2 := rtree.New(nil)
+	path, err := Restore(dir, tr2)
+	assert.NoError(t, err)
+	assert.Equal(t, genPath(dir, gens[1]), path)
+	assert.Equal(t, 1000, tr2.Count())
+	// a corrupt newest generation falls back to the one before it
+	assert.NoError(t, os.WriteFile(genPath(dir, gens[1]), []byte("garbage"), 0644))
+	path, err = Restore(dir, rtree.New(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, genPath(dir, gens[0]), path)
+
+	_, err = Restore(t.TempDir(), rtree.New(nil))
+	assert.Equal(t, os.ErrNotExist, err)
+}
@@ -0,0 +1,74 @@
+package rtree
+
+import (
+	"bytes"
+
+	"github.com/tidwall/pair"
+)
+
+// keyBloom is a bloom filter of the keys under a node, with three bits set
+// for each key.
+type keyBloom [8]uint64
+
+// bits returns the three bits of a key, from an FNV-1a hash that's split in
+// two for double hashing.
+func (b *keyBloom) bits(key []byte) [3]uint {
+	h := uint64(14695981039346656037)
+	for _, c := range key {
+		h ^= uint64(c)
+		h *= 1099511628211
+	}
+	h1, h2 := uint(h), uint(h>>32)|1
+	n := uint(len(b) * 64)
+	return [3]uint{h1 % n, (h1 + h2) % n, (h1 + 2*h2) % n}
+}
+
+func (b *keyBloom) add(key []byte) {
+	for _, i := range b.bits(key) {
+		b[i/64] |= 1 << (i % 64)
+	}
+}
+
+func (b *keyBloom) has(key []byte) bool {
+	for _, i := range b.bits(key) {
+		if b[i/64]&(1<<(i%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// annotateKeys is the Annotate option of the 2d and 3d trees for the
+// KeyBloom option. A child without a filter may have any key.
+func annotateKeys(items []pair.Pair, children []interface{}) interface{} {
+	b := new(keyBloom)
+	for _, item := range items {
+		b.add(item.Key())
+	}
+	for _, child := range children {
+		c, ok := child.(*keyBloom)
+		for i := range b {
+			if ok {
+				b[i] |= c[i]
+			} else {
+				b[i] = ^uint64(0)
+			}
+		}
+	}
+	return b
+}
+
+// scanKey iterates over the items with the key, in no particular order,
+// skipping the nodes whose filters don't have the key.
+func (tr *RTree) scanKey(key []byte, iter func(item pair.Pair) bool) bool {
+	keep := func(annotation interface{}) bool {
+		return annotation.(*keyBloom).has(key)
+	}
+	match := func(item pair.Pair) bool {
+		if bytes.Equal(item.Key(), key) {
+			return iter(item)
+		}
+		return true
+	}
+	return tr.tr2.ScanAnnotated(keep, match) && tr.tr3.ScanAnnotated(keep, match)
+}
@@ -0,0 +1,63 @@
+package rtree
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestKeyBloom(t *testing.T) {
+	plain := New(nil)
+	opts := *DefaultOptions
+	opts.KeyBloom = true
+	tr := New(&opts)
+	var items []pair.Pair
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprintf("%05d", i%2500)
+		if i%2 == 0 {
+			items = append(items, makePointPair2(key, float64(i%100), float64(i/100)))
+		} else {
+			items = append(items, makePointPair3(key, float64(i%100), float64(i/100), 1))
+		}
+	}
+	plain.Load(items)
+	tr.Load(items[:4000])
+	for _, item := range items[4000:] {
+		tr.Insert(item)
+	}
+	for i := 0; i < 2500; i += 7 {
+		key := []byte(fmt.Sprintf("%05d", i))
+		expect, ok := plain.Get(key)
+		assert.True(t, ok)
+		item, ok := tr.Get(key)
+		assert.True(t, ok)
+		assert.Equal(t, expect, item)
+	}
+	_, ok := tr.Get([]byte("x"))
+	assert.False(t, ok)
+
+	// only the leaves that may have the key are read
+	key := []byte("01234")
+	var read int
+	keep := func(annotation interface{}) bool {
+		return annotation.(*keyBloom).has(key)
+	}
+	tr.tr2.ScanAnnotated(keep, func(item pair.Pair) bool {
+		read++
+		return true
+	})
+	tr.tr3.ScanAnnotated(keep, func(item pair.Pair) bool {
+		read++
+		return true
+	})
+	assert.True(t, read >= 2 && read < tr.Count()/10)
+
+	assert.Equal(t, 2, tr.DeleteByKey(key))
+	assert.Equal(t, 0, tr.DeleteByKey(key))
+	_, ok = tr.Get(key)
+	assert.False(t, ok)
+	assert.Equal(t, 4998, tr.Count())
+	assert.Nil(t, tr.Check())
+}
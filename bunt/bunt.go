@@ -0,0 +1,162 @@
+// Package bunt adapts the tree to the spatial index contract of buntdb, which
+// identifies items by string keys and derives the rect of an item from its
+// string value with a rect function, such as buntdb.IndexRect.
+//
+// An Index can be kept in step with a buntdb spatial index by calling Set
+// and Delete from the same places that write the database, and queried with
+// Intersects and Nearby, which take the same arguments as the buntdb
+// transaction methods of the same names.
+package bunt
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+// RectFunc returns the rect of a value, or nil for a value that is not
+// spatial. It has the same signature as the buntdb index rect functions.
+type RectFunc func(value string) (min, max []float64)
+
+type Options struct {
+	// Rect returns the rect of a value. The default is IndexRect.
+	Rect RectFunc
+	// MaxEntries is passed on to the underlying tree.
+	MaxEntries int
+}
+
+var DefaultOptions = &Options{
+	Rect:       IndexRect,
+	MaxEntries: 9,
+}
+
+// Index is a spatial index of string keys and values.
+type Index struct {
+	tr   *rtree.RTree
+	rect RectFunc
+}
+
+// New returns an empty index.
+func New(opts *Options) *Index {
+	if opts == nil {
+		opts = DefaultOptions
+	}
+	rect := opts.Rect
+	if rect == nil {
+		rect = IndexRect
+	}
+	topts := *rtree.DefaultOptions
+	topts.MaxEntries = opts.MaxEntries
+	// the values are owned by the index, and are removed by key and value,
+	// which is how buntdb identifies them.
+	topts.CopyItems = true
+	topts.RectFunc = func(value []byte) (min, max [3]float64, dims int) {
+		return toRect(rect(string(value)))
+	}
+	return &Index{tr: rtree.New(&topts), rect: rect}
+}
+
+// toRect converts a rect of any number of dimensions to the tree's rect.
+// Dimensions after the third are ignored. A missing max is the same as the
+// min.
+func toRect(rmin, rmax []float64) (min, max [3]float64, dims int) {
+	if len(rmax) == 0 {
+		rmax = rmin
+	}
+	dims = 2
+	if len(rmin) > 2 {
+		dims = 3
+	}
+	for i := 0; i < 3 && i < len(rmin); i++ {
+		min[i] = rmin[i]
+	}
+	for i := 0; i < 3 && i < len(rmax); i++ {
+		max[i] = rmax[i]
+	}
+	return min, max, dims
+}
+
+// Set adds the key and value to the index. Values that are not spatial are
+// ignored. A key that was previously set must be deleted with its previous
+// value first.
+func (idx *Index) Set(key, value string) {
+	if min, _ := idx.rect(value); len(min) == 0 {
+		return
+	}
+	idx.tr.Insert(pair.New([]byte(key), []byte(value)))
+}
+
+// Delete removes the key and value from the index.
+func (idx *Index) Delete(key, value string) {
+	if min, _ := idx.rect(value); len(min) == 0 {
+		return
+	}
+	idx.tr.Remove(pair.New([]byte(key), []byte(value)))
+}
+
+// Len returns the number of indexed items.
+func (idx *Index) Len() int {
+	return idx.tr.Count()
+}
+
+// Intersects iterates over the items that intersect the bounds. The bounds
+// are a value that's read by the rect function.
+func (idx *Index) Intersects(bounds string, iter func(key, value string) bool) bool {
+	if min, _ := idx.rect(bounds); len(min) == 0 {
+		return true
+	}
+	return idx.tr.Search(pair.New(nil, []byte(bounds)), func(item pair.Pair) bool {
+		return iter(string(item.Key()), string(item.Value()))
+	})
+}
+
+// Nearby iterates over the items nearest to the center of the bounds,
+// nearest first.
+func (idx *Index) Nearby(bounds string, iter func(key, value string, dist float64) bool) bool {
+	if min, _ := idx.rect(bounds); len(min) == 0 {
+		return true
+	}
+	return idx.tr.KNN(pair.New(nil, []byte(bounds)), func(item pair.Pair, dist float64) bool {
+		return iter(string(item.Key()), string(item.Value()), dist)
+	})
+}
+
+// Tree returns the underlying tree.
+func (idx *Index) Tree() *rtree.RTree {
+	return idx.tr
+}
+
+// IndexRect reads a rect in the buntdb format, which is a point such as
+// "[10 15]", or a rect such as "[10 15],[20 25]". Coordinates may be "inf"
+// or "-inf". Returns nil for anything else.
+func IndexRect(value string) (min, max []float64) {
+	value = strings.TrimSpace(value)
+	var parts []string
+	if i := strings.Index(value, "],["); i != -1 {
+		parts = []string{value[:i+1], value[i+2:]}
+	} else {
+		parts = []string{value}
+	}
+	var rect [2][]float64
+	for i, part := range parts {
+		if len(part) < 2 || part[0] != '[' || part[len(part)-1] != ']' {
+			return nil, nil
+		}
+		for _, field := range strings.Fields(part[1 : len(part)-1]) {
+			f, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, nil
+			}
+			rect[i] = append(rect[i], f)
+		}
+		if len(rect[i]) == 0 {
+			return nil, nil
+		}
+	}
+	if rect[1] == nil {
+		return rect[0], rect[0]
+	}
+	return rect[0], rect[1]
+}
@@ -0,0 +1,62 @@
+package bunt
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndexRect(t *testing.T) {
+	min, max := IndexRect("[10 15]")
+	assert.Equal(t, []float64{10, 15}, min)
+	assert.Equal(t, []float64{10, 15}, max)
+	min, max = IndexRect("[10 15 1],[20 25 2]")
+	assert.Equal(t, []float64{10, 15, 1}, min)
+	assert.Equal(t, []float64{20, 25, 2}, max)
+	min, max = IndexRect("[-inf 0],[inf 1]")
+	assert.Equal(t, []float64{math.Inf(-1), 0}, min)
+	assert.Equal(t, []float64{math.Inf(+1), 1}, max)
+	for _, s := range []string{"", "hello", "[]", "[1 x]", "[1 2],"} {
+		min, max = IndexRect(s)
+		assert.True(t, min == nil && max == nil, s)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	idx := New(nil)
+	for i := 0; i < 10; i++ {
+		idx.Set(fmt.Sprintf("p:%d", i), fmt.Sprintf("[%d %d]", i, i))
+	}
+	idx.Set("r:1", "[2 2 0],[3 3 1]")
+	idx.Set("name", "not spatial")
+	assert.Equal(t, 11, idx.Len())
+
+	var keys []string
+	idx.Intersects("[1.5 1.5],[3.5 3.5]", func(key, value string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	sort.Strings(keys)
+	assert.Equal(t, []string{"p:2", "p:3", "r:1"}, keys)
+
+	keys = nil
+	idx.Nearby("[7.2 7.2]", func(key, value string, dist float64) bool {
+		keys = append(keys, key)
+		return len(keys) < 2
+	})
+	assert.Equal(t, []string{"p:7", "p:8"}, keys)
+
+	idx.Delete("p:7", "[7 7]")
+	idx.Delete("p:8", "[0 0]") // not the indexed value
+	idx.Delete("name", "not spatial")
+	assert.Equal(t, 10, idx.Len())
+	keys = nil
+	idx.Nearby("[7.2 7.2]", func(key, value string, dist float64) bool {
+		keys = append(keys, key)
+		return false
+	})
+	assert.Equal(t, []string{"p:8"}, keys)
+}
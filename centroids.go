@@ -0,0 +1,138 @@
+package rtree
+
+import (
+	"math"
+	"math/rand"
+)
+
+// leafSummary is the center of a leaf and the number of items in it.
+type leafSummary struct {
+	center [3]float64
+	count  int
+}
+
+// ClusterCentroids returns up to k cluster centroids, in the coordinates of
+// the tree, for a quick approximate clustering. Rather than reading the
+// items, it clusters the centers of the leaf nodes, weighted by the number
+// of items in each leaf, with k-means. The result is the same for the same
+// tree. The 2d items have a z of zero.
+func (tr *RTree) ClusterCentroids(k int) [][3]float64 {
+	leaves := tr.leafSummaries()
+	if k <= 0 || len(leaves) == 0 {
+		return nil
+	}
+	if k > len(leaves) {
+		k = len(leaves)
+	}
+	centroids := seedCentroids(leaves, k)
+	k = len(centroids)
+	assign := make([]int, len(leaves))
+	for iter := 0; iter < 50; iter++ {
+		changed := iter == 0
+		for i, leaf := range leaves {
+			if c := nearestCentroid(leaf.center, centroids); c != assign[i] {
+				assign[i] = c
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		sums := make([][3]float64, k)
+		weights := make([]float64, k)
+		for i, leaf := range leaves {
+			w := float64(leaf.count)
+			for j := 0; j < 3; j++ {
+				sums[assign[i]][j] += leaf.center[j] * w
+			}
+			weights[assign[i]] += w
+		}
+		for c := range centroids {
+			if weights[c] > 0 {
+				for j := 0; j < 3; j++ {
+					centroids[c][j] = sums[c][j] / weights[c]
+				}
+			}
+		}
+	}
+	return centroids
+}
+
+// leafSummaries returns the summaries of the leaves of the 2d and 3d trees.
+// The 2d leaves have a z of zero.
+func (tr *RTree) leafSummaries() []leafSummary {
+	var leaves []leafSummary
+	tr.tr2.Leaves(func(min, max [2]float64, count int) bool {
+		leaves = append(leaves, leafSummary{
+			[3]float64{(min[0] + max[0]) / 2, (min[1] + max[1]) / 2, 0}, count,
+		})
+		return true
+	})
+	tr.tr3.Leaves(func(min, max [3]float64, count int) bool {
+		var center [3]float64
+		for i := 0; i < 3; i++ {
+			center[i] = (min[i] + max[i]) / 2
+		}
+		leaves = append(leaves, leafSummary{center, count})
+		return true
+	})
+	return leaves
+}
+
+// seedCentroids picks the seeds with k-means++, where each seed after the
+// first is chosen with a probability that grows with the squared distance
+// to the seeds so far. A fixed source makes it repeatable.
+func seedCentroids(leaves []leafSummary, k int) [][3]float64 {
+	rng := rand.New(rand.NewSource(1))
+	weights := make([]float64, len(leaves))
+	var total float64
+	for i, leaf := range leaves {
+		weights[i] = float64(leaf.count)
+		total += weights[i]
+	}
+	pick := func() [3]float64 {
+		r := rng.Float64() * total
+		for i, w := range weights {
+			if r < w {
+				return leaves[i].center
+			}
+			r -= w
+		}
+		return leaves[len(leaves)-1].center
+	}
+	centroids := [][3]float64{pick()}
+	dists := make([]float64, len(leaves))
+	for i := range dists {
+		dists[i] = math.Inf(+1)
+	}
+	for len(centroids) < k {
+		last := centroids[len(centroids)-1]
+		total = 0
+		for i, leaf := range leaves {
+			dists[i] = math.Min(dists[i], sqDist(leaf.center, last))
+			weights[i] = dists[i] * float64(leaf.count)
+			total += weights[i]
+		}
+		if total == 0 {
+			// every leaf is on a seed
+			break
+		}
+		centroids = append(centroids, pick())
+	}
+	return centroids
+}
+
+func nearestCentroid(p [3]float64, centroids [][3]float64) int {
+	nearest, min := 0, math.Inf(+1)
+	for i, c := range centroids {
+		if d := sqDist(p, c); d < min {
+			nearest, min = i, d
+		}
+	}
+	return nearest
+}
+
+func sqDist(a, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
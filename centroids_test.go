@@ -0,0 +1,33 @@
+package rtree
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+)
+
+func TestClusterCentroids(t *testing.T) {
+	tr := New(nil)
+	assert.Equal(t, 0, len(tr.ClusterCentroids(3)))
+	centers := [][3]float64{{-100, -50, 0}, {0, 60, 0}, {120, 0, 0}}
+	for i := 0; i < 3000; i++ {
+		c := centers[i%3]
+		x, y := c[0]+rand.Float64()*10-5, c[1]+rand.Float64()*10-5
+		if i%2 == 0 {
+			tr.Insert(makePointPair2("", x, y))
+		} else {
+			tr.Insert(makePointPair3("", x, y, rand.Float64()*10-5))
+		}
+	}
+	centroids := tr.ClusterCentroids(3)
+	assert.Equal(t, 3, len(centroids))
+	sort.Slice(centroids, func(i, j int) bool { return centroids[i][0] < centroids[j][0] })
+	for i, c := range centroids {
+		assert.True(t, math.Abs(c[0]-centers[i][0]) < 2, c)
+		assert.True(t, math.Abs(c[1]-centers[i][1]) < 2, c)
+	}
+	assert.Equal(t, 0, len(tr.ClusterCentroids(0)))
+}
@@ -0,0 +1,31 @@
+package rtree
+
+import (
+	"errors"
+
+	"github.com/tidwall/pair"
+)
+
+// ErrCorrupt is returned by Check when the tree doesn't agree with its
+// items.
+var ErrCorrupt = errors.New("tree is corrupt")
+
+// Check returns ErrCorrupt when a node of the 2d or 3d tree has a stale
+// bbox, or is not at the right height, when an item is in the tree for the
+// wrong dimensions, or when the key index doesn't hold every item. Changing
+// the value of an item after it was inserted is the usual cause, which
+// Repair fixes.
+func (tr *RTree) Check() error {
+	if tr.tr2.Check() != nil || tr.tr3.Check() != nil {
+		return ErrCorrupt
+	}
+	ok := tr.tr2.Scan(func(item pair.Pair) bool {
+		return tr.dims(item.Value()) == 2
+	}) && tr.tr3.Scan(func(item pair.Pair) bool {
+		return tr.dims(item.Value()) != 2
+	})
+	if !ok || (tr.keys != nil && tr.keys.len() != tr.Count()) {
+		return ErrCorrupt
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+// Package citiesbin reads and writes the cities dataset in a compact binary
+// file that is memory mapped, so the cities can be used without the Cities
+// literal of the cities package being compiled into the program.
+package citiesbin
+
+//go:generate go run ../mkbin cities.bin
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// The binary format is a header, followed by fixed size records ordered by
+// city ID, followed by the country and city names. All numbers are little
+// endian.
+//
+//	header: magic [4]byte, count uint32
+//	record: id uint32, lat, lon, alt float64, country, city uint32
+//	name:   length uint8, bytes
+//
+// The country and city fields are the offsets of the names from the start
+// of the file.
+const (
+	magic      = "CTY1"
+	headerSize = 8
+	recordSize = 36
+)
+
+// City is a city in a binary file. It has the same fields as cities.City,
+// so the two can be converted.
+type City struct {
+	ID        int
+	Country   string
+	City      string
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+}
+
+// ErrInvalidFile is returned by Open when the file is not a cities binary
+// file.
+var ErrInvalidFile = errors.New("invalid cities file")
+
+// WriteBinary writes the cities in the binary format that is read by Open.
+// The cities must be ordered by ID and names are truncated to 255 bytes.
+func WriteBinary(w io.Writer, cities []City) error {
+	bw := bufio.NewWriter(w)
+	var hdr [headerSize]byte
+	copy(hdr[:], magic)
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(len(cities)))
+	bw.Write(hdr[:])
+	var names []byte
+	nameOffset := func(name string) uint32 {
+		if len(name) > 255 {
+			name = name[:255]
+		}
+		off := uint32(headerSize + recordSize*len(cities) + len(names))
+		names = append(names, byte(len(name)))
+		names = append(names, name...)
+		return off
+	}
+	var rec [recordSize]byte
+	for _, city := range cities {
+		binary.LittleEndian.PutUint32(rec[0:], uint32(city.ID))
+		binary.LittleEndian.PutUint64(rec[4:], math.Float64bits(city.Latitude))
+		binary.LittleEndian.PutUint64(rec[12:], math.Float64bits(city.Longitude))
+		binary.LittleEndian.PutUint64(rec[20:], math.Float64bits(city.Altitude))
+		binary.LittleEndian.PutUint32(rec[28:], nameOffset(city.Country))
+		binary.LittleEndian.PutUint32(rec[32:], nameOffset(city.City))
+		bw.Write(rec[:])
+	}
+	bw.Write(names)
+	return bw.Flush()
+}
+
+// File is a read-only cities binary file. On most platforms the file is
+// memory mapped and cities are decoded only as they are accessed.
+type File struct {
+	data  []byte
+	count int
+	unmap func() error
+}
+
+// Open opens a cities binary file, such as the cities.bin file in this
+// package directory or one created with WriteBinary. The file must be
+// closed when it's no longer needed.
+func Open(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() < headerSize || fi.Size() > math.MaxUint32 {
+		return nil, ErrInvalidFile
+	}
+	data, unmap, err := mmap(f, int(fi.Size()))
+	if err != nil {
+		return nil, err
+	}
+	count := int(binary.LittleEndian.Uint32(data[4:]))
+	if string(data[:4]) != magic || headerSize+recordSize*count > len(data) {
+		unmap()
+		return nil, ErrInvalidFile
+	}
+	return &File{data: data, count: count, unmap: unmap}, nil
+}
+
+// Close releases the file. The File must not be used after it's closed.
+func (f *File) Close() error {
+	if f.unmap == nil {
+		return nil
+	}
+	err := f.unmap()
+	f.data, f.count, f.unmap = nil, 0, nil
+	return err
+}
+
+// Len returns the number of cities.
+func (f *File) Len() int {
+	return f.count
+}
+
+// City returns the city at index i, which must be less than Len.
+func (f *File) City(i int) City {
+	rec := f.data[headerSize+recordSize*i:]
+	return City{
+		ID:        int(binary.LittleEndian.Uint32(rec[0:])),
+		Latitude:  math.Float64frombits(binary.LittleEndian.Uint64(rec[4:])),
+		Longitude: math.Float64frombits(binary.LittleEndian.Uint64(rec[12:])),
+		Altitude:  math.Float64frombits(binary.LittleEndian.Uint64(rec[20:])),
+		Country:   f.name(binary.LittleEndian.Uint32(rec[28:])),
+		City:      f.name(binary.LittleEndian.Uint32(rec[32:])),
+	}
+}
+
+func (f *File) name(off uint32) string {
+	if int(off) >= len(f.data) {
+		return ""
+	}
+	n := int(f.data[off])
+	if int(off)+1+n > len(f.data) {
+		return ""
+	}
+	return string(f.data[off+1 : int(off)+1+n])
+}
+
+func (f *File) id(i int) int {
+	return int(binary.LittleEndian.Uint32(f.data[headerSize+recordSize*i:]))
+}
+
+// Lookup returns the city with the provided ID.
+func (f *File) Lookup(id int) (City, bool) {
+	i := sort.Search(f.count, func(i int) bool { return f.id(i) >= id })
+	if i < f.count && f.id(i) == id {
+		return f.City(i), true
+	}
+	return City{}, false
+}
+
+// Each iterates over every city in ID order.
+func (f *File) Each(iter func(city City) bool) bool {
+	for i := 0; i < f.count; i++ {
+		if !iter(f.City(i)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package citiesbin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tidwall/pair-rtree/cities"
+)
+
+// Cities is the cities dataset, which is only compiled into the tests.
+var Cities = func() []City {
+	all := make([]City, len(cities.Cities))
+	for i, city := range cities.Cities {
+		all[i] = City(city)
+	}
+	return all
+}()
+
+func TestBinary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cities.bin")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteBinary(out, Cities); err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+	f, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if f.Len() != len(Cities) {
+		t.Fatalf("expected %d, got %d", len(Cities), f.Len())
+	}
+	var i int
+	f.Each(func(city City) bool {
+		if city != Cities[i] {
+			t.Fatalf("expected %v, got %v", Cities[i], city)
+		}
+		i++
+		return true
+	})
+	city, ok := f.Lookup(Cities[500].ID)
+	if !ok || city != Cities[500] {
+		t.Fatalf("expected %v, got %v", Cities[500], city)
+	}
+	if _, ok := f.Lookup(-1); ok {
+		t.Fatal("expected not found")
+	}
+}
+
+func TestGenerated(t *testing.T) {
+	f, err := Open("cities.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if f.Len() != len(Cities) {
+		t.Fatalf("expected %d, got %d", len(Cities), f.Len())
+	}
+	if city := f.City(f.Len() - 1); city != Cities[len(Cities)-1] {
+		t.Fatalf("expected %v, got %v", Cities[len(Cities)-1], city)
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.bin")
+	if err := os.WriteFile(path, []byte("not a cities file"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err != ErrInvalidFile {
+		t.Fatalf("expected %v, got %v", ErrInvalidFile, err)
+	}
+}
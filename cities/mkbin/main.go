@@ -0,0 +1,35 @@
+// Command mkbin writes the cities dataset in the binary format that is read
+// by citiesbin.Open.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/tidwall/pair-rtree/cities"
+	"github.com/tidwall/pair-rtree/cities/citiesbin"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s path\n", os.Args[0])
+		os.Exit(1)
+	}
+	f, err := os.Create(os.Args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	all := make([]citiesbin.City, len(cities.Cities))
+	for i, city := range cities.Cities {
+		all[i] = citiesbin.City(city)
+	}
+	if err := citiesbin.WriteBinary(f, all); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if err := f.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
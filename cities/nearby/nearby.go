@@ -0,0 +1,116 @@
+// Package nearby answers nearest city queries using a 3d rtree of the
+// cities dataset. It lives outside of the cities package because the tree
+// packages import cities in their tests.
+package nearby
+
+import (
+	"container/heap"
+	"math"
+	"strconv"
+
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree/3d"
+	"github.com/tidwall/pair-rtree/cities"
+)
+
+type Index struct {
+	tr   *rtree.RTree
+	byID map[int]int // city ID to index in cities.Cities
+}
+
+// New creates an index of all cities. Positions are transformed to WGS84
+// ECEF coordinates so that distances are in meters.
+func New() *Index {
+	opts := *rtree.DefaultOptions
+	opts.Transformer = rtree.TransformLonLatElevToXYZ_WGS84
+	idx := &Index{
+		tr:   rtree.New(&opts),
+		byID: make(map[int]int, len(cities.Cities)),
+	}
+	for i, city := range cities.Cities {
+		idx.byID[city.ID] = i
+	}
+	idx.tr.Load(cities.Pairs())
+	return idx
+}
+
+func (idx *Index) city(item pair.Pair) cities.City {
+	id, _ := strconv.Atoi(string(item.Key()))
+	return cities.Cities[idx.byID[id]]
+}
+
+// Nearest returns the k nearest cities to the position.
+func (idx *Index) Nearest(lon, lat float64, k int) []cities.City {
+	var res []cities.City
+	p := rtree.TransformLonLatElevToXYZ_WGS84
+	min, _ := p([3]float64{lon, lat, 0}, [3]float64{lon, lat, 0})
+	idx.tr.KNN(min[0], min[1], min[2], func(item pair.Pair, dist float64) bool {
+		if len(res) == k {
+			return false
+		}
+		res = append(res, idx.city(item))
+		return true
+	})
+	return res
+}
+
+// NearestWeighted returns the k cities with the highest gravity score, which
+// is the city weight divided by the squared distance in meters. The dataset
+// does not include population, so the weight function supplies it, for
+// example from an external table keyed by City.ID. Weights must not be
+// negative.
+//
+// Cities are visited nearest to farthest and the search stops once no
+// farther city can outrank the k-th best, which requires the max weight.
+func (idx *Index) NearestWeighted(lon, lat float64, k int,
+	weight func(city cities.City) float64) []cities.City {
+	if k <= 0 {
+		return nil
+	}
+	var maxWeight float64
+	for _, city := range cities.Cities {
+		maxWeight = math.Max(maxWeight, weight(city))
+	}
+	p := rtree.TransformLonLatElevToXYZ_WGS84
+	min, _ := p([3]float64{lon, lat, 0}, [3]float64{lon, lat, 0})
+	var best scored
+	idx.tr.KNN(min[0], min[1], min[2], func(item pair.Pair, dist float64) bool {
+		dist = math.Max(dist, 1) // avoid dividing by zero
+		if len(best) == k && maxWeight/dist < best[0].score {
+			return false
+		}
+		city := idx.city(item)
+		score := weight(city) / dist
+		if len(best) < k {
+			heap.Push(&best, scoredCity{city, score})
+		} else if score > best[0].score {
+			best[0] = scoredCity{city, score}
+			heap.Fix(&best, 0)
+		}
+		return true
+	})
+	res := make([]cities.City, len(best))
+	for i := len(res) - 1; i >= 0; i-- {
+		res[i] = heap.Pop(&best).(scoredCity).city
+	}
+	return res
+}
+
+type scoredCity struct {
+	city  cities.City
+	score float64
+}
+
+// scored is a min-heap by score
+type scored []scoredCity
+
+func (s scored) Len() int            { return len(s) }
+func (s scored) Less(i, j int) bool  { return s[i].score < s[j].score }
+func (s scored) Swap(i, j int)       { s[i], s[j] = s[j], s[i] }
+func (s *scored) Push(x interface{}) { *s = append(*s, x.(scoredCity)) }
+func (s *scored) Pop() interface{} {
+	old := *s
+	x := old[len(old)-1]
+	*s = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,45 @@
+package nearby
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	rtree "github.com/tidwall/pair-rtree/3d"
+	"github.com/tidwall/pair-rtree/cities"
+)
+
+func TestNearestWeighted(t *testing.T) {
+	idx := New()
+	lon, lat := -112.0740, 33.4484 // phoenix
+	near := idx.Nearest(lon, lat, 5)
+	if len(near) != 5 {
+		t.Fatalf("expected 5, got %d", len(near))
+	}
+	weight := func(city cities.City) float64 {
+		return math.Max(city.Altitude, 0)
+	}
+	res := idx.NearestWeighted(lon, lat, 10, weight)
+
+	// brute force
+	p := rtree.TransformLonLatElevToXYZ_WGS84
+	q, _ := p([3]float64{lon, lat, 0}, [3]float64{lon, lat, 0})
+	all := append([]cities.City(nil), cities.Cities...)
+	score := func(city cities.City) float64 {
+		c, _ := p([3]float64{city.Longitude, city.Latitude, city.Altitude},
+			[3]float64{city.Longitude, city.Latitude, city.Altitude})
+		dx, dy, dz := c[0]-q[0], c[1]-q[1], c[2]-q[2]
+		return weight(city) / math.Max(dx*dx+dy*dy+dz*dz, 1)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return score(all[i]) > score(all[j])
+	})
+	if len(res) != 10 {
+		t.Fatalf("expected 10, got %d", len(res))
+	}
+	for i := range res {
+		if score(res[i]) != score(all[i]) {
+			t.Fatalf("%d: expected %v, got %v", i, all[i], res[i])
+		}
+	}
+}
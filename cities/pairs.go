@@ -7,13 +7,52 @@ import (
 	"github.com/tidwall/pair"
 )
 
+// Pairs returns every city as a 3d point pair keyed by the city ID.
 func Pairs() []pair.Pair {
 	pairs := make([]pair.Pair, 0, len(Cities))
+	Each(func(item pair.Pair) bool {
+		pairs = append(pairs, item)
+		return true
+	})
+	return pairs
+}
+
+// Each iterates over every city as a 3d point pair keyed by the city ID,
+// creating each pair only as it's needed.
+func Each(iter func(item pair.Pair) bool) bool {
 	var key []byte
 	for _, city := range Cities {
 		key = strconv.AppendInt(key[:0], int64(city.ID), 10)
 		item := pair.New(key, geobin.Make3DPoint(city.Longitude, city.Latitude, city.Altitude).Binary())
-		pairs = append(pairs, item)
+		if !iter(item) {
+			return false
+		}
 	}
-	return pairs
+	return true
+}
+
+// EachChunk iterates over the cities in chunks of up to n pairs, which is
+// useful for loading a tree incrementally. The chunk slice is reused between
+// calls and should not be retained.
+func EachChunk(n int, iter func(items []pair.Pair) bool) bool {
+	if n < 1 {
+		n = 1
+	}
+	chunk := make([]pair.Pair, 0, n)
+	if !Each(func(item pair.Pair) bool {
+		chunk = append(chunk, item)
+		if len(chunk) == n {
+			if !iter(chunk) {
+				return false
+			}
+			chunk = chunk[:0]
+		}
+		return true
+	}) {
+		return false
+	}
+	if len(chunk) > 0 {
+		return iter(chunk)
+	}
+	return true
 }
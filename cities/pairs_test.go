@@ -0,0 +1,36 @@
+package cities
+
+import (
+	"testing"
+
+	"github.com/tidwall/pair"
+)
+
+func TestEachChunk(t *testing.T) {
+	var count, chunks int
+	EachChunk(1000, func(items []pair.Pair) bool {
+		if len(items) > 1000 {
+			t.Fatalf("chunk too big: %d", len(items))
+		}
+		count += len(items)
+		chunks++
+		return true
+	})
+	if count != len(Cities) {
+		t.Fatalf("expected %d, got %d", len(Cities), count)
+	}
+	if chunks != (len(Cities)+999)/1000 {
+		t.Fatalf("expected %d chunks, got %d", (len(Cities)+999)/1000, chunks)
+	}
+	if len(Pairs()) != len(Cities) {
+		t.Fatalf("expected %d pairs", len(Cities))
+	}
+	count = 0
+	Each(func(item pair.Pair) bool {
+		count++
+		return count < 10
+	})
+	if count != 10 {
+		t.Fatalf("expected 10, got %d", count)
+	}
+}
@@ -0,0 +1,227 @@
+// Package cluster provides zoom-dependent point clustering, similar to
+// supercluster, using a 2d rtree for each zoom level.
+package cluster
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree/2d"
+)
+
+type Options struct {
+	MinZoom   int     // min zoom to generate clusters on
+	MaxZoom   int     // max zoom level to cluster the points on
+	Radius    float64 // cluster radius in pixels
+	Extent    float64 // tile extent (radius is calculated relative to it)
+	MinPoints int     // minimum points to form a cluster
+}
+
+var DefaultOptions = &Options{
+	MinZoom:   0,
+	MaxZoom:   16,
+	Radius:    40,
+	Extent:    512,
+	MinPoints: 2,
+}
+
+// Cluster is a single cluster or point at a specific zoom level.
+// The Item is only set when the Count is one.
+type Cluster struct {
+	Lon, Lat float64
+	Count    int
+	Item     pair.Pair
+}
+
+type point struct {
+	x, y  float64 // projected position, weighted centroid for clusters
+	count int
+	zoom  int // the last zoom the point was processed at
+	item  pair.Pair
+}
+
+type level struct {
+	tr     *rtree.RTree
+	points []*point
+}
+
+type Index struct {
+	opts   Options
+	levels []*level // one level per zoom, from MinZoom to MaxZoom+1
+}
+
+// New creates a cluster index for the provided items. The items must be
+// geobin values, and the center of each item is used as its position.
+func New(items []pair.Pair, opts *Options) *Index {
+	if opts == nil {
+		opts = DefaultOptions
+	}
+	idx := &Index{opts: *opts}
+	if idx.opts.MinPoints < 1 {
+		idx.opts.MinPoints = 1
+	}
+	if idx.opts.MaxZoom < idx.opts.MinZoom {
+		idx.opts.MaxZoom = idx.opts.MinZoom
+	}
+	idx.levels = make([]*level, idx.opts.MaxZoom-idx.opts.MinZoom+2)
+	points := make([]*point, 0, len(items))
+	for _, item := range items {
+		pos := geobin.WrapBinary(item.Value()).Position()
+		points = append(points, &point{
+			x:     lngX(pos.X),
+			y:     latY(pos.Y),
+			count: 1,
+			zoom:  math.MaxInt32,
+			item:  item,
+		})
+	}
+	z := idx.opts.MaxZoom + 1
+	idx.levels[z-idx.opts.MinZoom] = newLevel(points)
+	for z = idx.opts.MaxZoom; z >= idx.opts.MinZoom; z-- {
+		points = idx.cluster(idx.levels[z+1-idx.opts.MinZoom], z)
+		idx.levels[z-idx.opts.MinZoom] = newLevel(points)
+	}
+	return idx
+}
+
+func newLevel(points []*point) *level {
+	lv := &level{tr: rtree.New(nil), points: points}
+	var key []byte
+	for i, p := range points {
+		key = strconv.AppendInt(key[:0], int64(i), 10)
+		lv.tr.Insert(pair.New(key, geobin.Make2DPoint(p.x, p.y).Binary()))
+	}
+	return lv
+}
+
+func (idx *Index) cluster(prev *level, zoom int) []*point {
+	var clusters []*point
+	r := idx.opts.Radius / (idx.opts.Extent * math.Pow(2, float64(zoom)))
+	for i, p := range prev.points {
+		if p.zoom <= zoom {
+			continue // already processed at this zoom
+		}
+		p.zoom = zoom
+		var neighbors []int
+		count := p.count
+		box := pair.New(nil, geobin.Make2DRect(p.x-r, p.y-r, p.x+r, p.y+r).Binary())
+		prev.tr.Search(box, func(item pair.Pair) bool {
+			j, _ := strconv.Atoi(string(item.Key()))
+			b := prev.points[j]
+			if b.zoom <= zoom || j == i {
+				return true
+			}
+			dx, dy := b.x-p.x, b.y-p.y
+			if dx*dx+dy*dy <= r*r {
+				neighbors = append(neighbors, j)
+				count += b.count
+			}
+			return true
+		})
+		if len(neighbors) == 0 || count < idx.opts.MinPoints {
+			// keep the points as they are
+			clusters = append(clusters, &point{
+				x: p.x, y: p.y, count: p.count, zoom: math.MaxInt32,
+				item: p.item,
+			})
+			for _, j := range neighbors {
+				b := prev.points[j]
+				b.zoom = zoom
+				clusters = append(clusters, &point{
+					x: b.x, y: b.y, count: b.count, zoom: math.MaxInt32,
+					item: b.item,
+				})
+			}
+			continue
+		}
+		wx := p.x * float64(p.count)
+		wy := p.y * float64(p.count)
+		for _, j := range neighbors {
+			b := prev.points[j]
+			b.zoom = zoom
+			wx += b.x * float64(b.count)
+			wy += b.y * float64(b.count)
+		}
+		clusters = append(clusters, &point{
+			x:     wx / float64(count),
+			y:     wy / float64(count),
+			count: count,
+			zoom:  math.MaxInt32,
+		})
+	}
+	return clusters
+}
+
+// Clusters returns the clusters and points within the bounding box at the
+// specified zoom. A minLon that is greater than maxLon crosses the
+// antimeridian.
+func (idx *Index) Clusters(minLon, minLat, maxLon, maxLat float64, zoom int) []Cluster {
+	minLon = math.Mod(math.Mod(minLon+180, 360)+360, 360) - 180
+	minLat = math.Max(-90, math.Min(90, minLat))
+	if maxLon != 180 {
+		maxLon = math.Mod(math.Mod(maxLon+180, 360)+360, 360) - 180
+	}
+	maxLat = math.Max(-90, math.Min(90, maxLat))
+	if maxLon-minLon >= 360 {
+		minLon, maxLon = -180, 180
+	} else if minLon > maxLon {
+		west := idx.Clusters(minLon, minLat, 180, maxLat, zoom)
+		east := idx.Clusters(-180, minLat, maxLon, maxLat, zoom)
+		return append(west, east...)
+	}
+	lv := idx.levels[idx.limitZoom(zoom)-idx.opts.MinZoom]
+	box := pair.New(nil, geobin.Make2DRect(
+		lngX(minLon), latY(maxLat), lngX(maxLon), latY(minLat),
+	).Binary())
+	var clusters []Cluster
+	lv.tr.Search(box, func(item pair.Pair) bool {
+		j, _ := strconv.Atoi(string(item.Key()))
+		p := lv.points[j]
+		clusters = append(clusters, Cluster{
+			Lon:   xLng(p.x),
+			Lat:   yLat(p.y),
+			Count: p.count,
+			Item:  p.item,
+		})
+		return true
+	})
+	return clusters
+}
+
+func (idx *Index) limitZoom(zoom int) int {
+	if zoom < idx.opts.MinZoom {
+		return idx.opts.MinZoom
+	}
+	if zoom > idx.opts.MaxZoom+1 {
+		return idx.opts.MaxZoom + 1
+	}
+	return zoom
+}
+
+// spherical mercator to [0..1] range
+func lngX(lng float64) float64 {
+	return lng/360 + 0.5
+}
+
+func latY(lat float64) float64 {
+	sin := math.Sin(lat * math.Pi / 180)
+	y := 0.5 - 0.25*math.Log((1+sin)/(1-sin))/math.Pi
+	if y < 0 {
+		return 0
+	}
+	if y > 1 {
+		return 1
+	}
+	return y
+}
+
+func xLng(x float64) float64 {
+	return (x - 0.5) * 360
+}
+
+func yLat(y float64) float64 {
+	y2 := (180 - y*360) * math.Pi / 180
+	return 360*math.Atan(math.Exp(y2))/math.Pi - 90
+}
@@ -0,0 +1,32 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/pair-rtree/cities"
+)
+
+func TestClusters(t *testing.T) {
+	items := cities.Pairs()
+	idx := New(items, nil)
+	for z := 0; z <= DefaultOptions.MaxZoom+1; z++ {
+		var count int
+		for _, c := range idx.Clusters(-180, -90, 180, 90, z) {
+			if c.Count == 1 {
+				assert.True(t, !c.Item.Zero())
+			}
+			count += c.Count
+		}
+		assert.Equal(t, len(items), count)
+	}
+	assert.True(t, len(idx.Clusters(-180, -90, 180, 90, 0)) < len(items))
+
+	// crossing the antimeridian
+	var count int
+	for _, c := range idx.Clusters(170, -90, -170, 90, DefaultOptions.MaxZoom+1) {
+		assert.True(t, c.Lon >= 170 || c.Lon <= -170)
+		count += c.Count
+	}
+	assert.True(t, count > 0)
+}
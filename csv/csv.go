@@ -0,0 +1,161 @@
+// Package csv imports and exports tree items as CSV with key, longitude,
+// latitude, and optional elevation columns.
+package csv
+
+import (
+	enccsv "encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+// Mapping names the columns in the CSV header.
+type Mapping struct {
+	Key  string
+	Lon  string
+	Lat  string
+	Elev string // optional, empty for 2d points
+}
+
+var DefaultMapping = &Mapping{
+	Key:  "key",
+	Lon:  "lon",
+	Lat:  "lat",
+	Elev: "",
+}
+
+// Import reads CSV with a header row and inserts a point for each record.
+// Records with an empty elevation cell are inserted as 2d points. Columns
+// that are not in the mapping are ignored. It returns the number of items
+// inserted.
+func Import(r io.Reader, tr *rtree.RTree, m *Mapping) (int, error) {
+	if m == nil {
+		m = DefaultMapping
+	}
+	cr := enccsv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return 0, err
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		cols[name] = i
+	}
+	col := func(name string) (int, error) {
+		if i, ok := cols[name]; ok {
+			return i, nil
+		}
+		return -1, fmt.Errorf("csv: missing %q column", name)
+	}
+	keyCol, err := col(m.Key)
+	if err != nil {
+		return 0, err
+	}
+	lonCol, err := col(m.Lon)
+	if err != nil {
+		return 0, err
+	}
+	latCol, err := col(m.Lat)
+	if err != nil {
+		return 0, err
+	}
+	elevCol := -1
+	if m.Elev != "" {
+		if elevCol, err = col(m.Elev); err != nil {
+			return 0, err
+		}
+	}
+	var n int
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		cell := func(i int) string {
+			if i < len(rec) {
+				return rec[i]
+			}
+			return ""
+		}
+		num := func(i int) (float64, error) {
+			v, err := strconv.ParseFloat(cell(i), 64)
+			if err != nil {
+				return 0, fmt.Errorf("csv: line %d: invalid %q value %q", line, header[i], cell(i))
+			}
+			return v, nil
+		}
+		lon, err := num(lonCol)
+		if err != nil {
+			return n, err
+		}
+		lat, err := num(latCol)
+		if err != nil {
+			return n, err
+		}
+		var obj geobin.Object
+		if elevCol == -1 || cell(elevCol) == "" {
+			obj = geobin.Make2DPoint(lon, lat)
+		} else {
+			elev, err := num(elevCol)
+			if err != nil {
+				return n, err
+			}
+			obj = geobin.Make3DPoint(lon, lat, elev)
+		}
+		tr.Insert(pair.New([]byte(cell(keyCol)), obj.Binary()))
+		n++
+	}
+}
+
+// Export writes a header row and a record for each item that intersects the
+// bbox, or for every item when the bbox is a zero pair. Rects are written
+// as their center point. The elevation column is only written when the
+// mapping has one, and is empty for 2d items.
+func Export(w io.Writer, tr *rtree.RTree, bbox pair.Pair, m *Mapping) error {
+	if m == nil {
+		m = DefaultMapping
+	}
+	cw := enccsv.NewWriter(w)
+	header := []string{m.Key, m.Lon, m.Lat}
+	if m.Elev != "" {
+		header = append(header, m.Elev)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	var err error
+	rec := make([]string, len(header))
+	iter := func(item pair.Pair) bool {
+		obj := geobin.WrapBinary(item.Value())
+		min, max := obj.Rect(nil)
+		rec[0] = string(item.Key())
+		rec[1] = strconv.FormatFloat((min[0]+max[0])/2, 'f', -1, 64)
+		rec[2] = strconv.FormatFloat((min[1]+max[1])/2, 'f', -1, 64)
+		if m.Elev != "" {
+			rec[3] = ""
+			if obj.Dims() == 3 {
+				rec[3] = strconv.FormatFloat((min[2]+max[2])/2, 'f', -1, 64)
+			}
+		}
+		err = cw.Write(rec)
+		return err == nil
+	}
+	if bbox.Zero() {
+		tr.Scan(iter)
+	} else {
+		tr.Search(bbox, iter)
+	}
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
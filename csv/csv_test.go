@@ -0,0 +1,42 @@
+package csv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+func TestImportExport(t *testing.T) {
+	tr := rtree.New(nil)
+	m := &Mapping{Key: "name", Lon: "lon", Lat: "lat", Elev: "elev"}
+	n, err := Import(strings.NewReader(
+		"name,country,lat,lon,elev\n"+
+			"Phoenix,US,33.45,-112.07,331\n"+
+			"Tucson,US,32.22,-110.97,\n"), tr, m)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 2, tr.Count())
+
+	var buf bytes.Buffer
+	assert.NoError(t, Export(&buf, tr, pair.Pair{}, m))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.Equal(t, "name,lon,lat,elev", lines[0])
+	assert.True(t, strings.Contains(buf.String(), "Phoenix,-112.07,33.45,331\n"))
+	assert.True(t, strings.Contains(buf.String(), "Tucson,-110.97,32.22,\n"))
+
+	buf.Reset()
+	box := pair.New(nil, geobin.Make2DRect(-113, 33, -112, 34).Binary())
+	assert.NoError(t, Export(&buf, tr, box, nil))
+	assert.Equal(t, "key,lon,lat\nPhoenix,-112.07,33.45\n", buf.String())
+
+	_, err = Import(strings.NewReader("name,lat\nx,1\n"), tr, m)
+	assert.Error(t, err)
+	_, err = Import(strings.NewReader("key,lon,lat\nx,1,y\n"), tr, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,29 @@
+package rtree
+
+import "github.com/tidwall/pair"
+
+// dedupIter wraps a Search iterator to skip the items with a key that was
+// already passed to it.
+func dedupIter(iter func(item pair.Pair) bool) func(item pair.Pair) bool {
+	seen := make(map[string]bool)
+	return func(item pair.Pair) bool {
+		if seen[string(item.Key())] {
+			return true
+		}
+		seen[string(item.Key())] = true
+		return iter(item)
+	}
+}
+
+// dedupKNNIter is like dedupIter, but for KNN, so the nearest item for each
+// key is the one that's reported.
+func dedupKNNIter(iter func(item pair.Pair, dist float64) bool) func(item pair.Pair, dist float64) bool {
+	seen := make(map[string]bool)
+	return func(item pair.Pair, dist float64) bool {
+		if seen[string(item.Key())] {
+			return true
+		}
+		seen[string(item.Key())] = true
+		return iter(item, dist)
+	}
+}
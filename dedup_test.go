@@ -0,0 +1,46 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestDedupKeys(t *testing.T) {
+	for _, dedup := range []bool{false, true} {
+		tr := New(&Options{MaxEntries: 9, DedupKeys: dedup})
+		tr.Insert(makePointPair2("a", 10, 10))
+		tr.Insert(makePointPair3("a", 10, 10, 0))
+		tr.Insert(makePointPair2("b", 20, 20))
+		expect := 3
+		if dedup {
+			expect = 2
+		}
+		var n int
+		tr.Search(makeBoundsPair2("", 0, 0, 30, 30), func(item pair.Pair) bool {
+			n++
+			return true
+		})
+		assert.Equal(t, expect, n)
+		n = 0
+		tr.SearchWith(makeBoundsPair2("", 0, 0, 30, 30), nil, func(item pair.Pair) bool {
+			n++
+			return true
+		})
+		assert.Equal(t, expect, n)
+		var keys []string
+		tr.KNN(makePointPair2("", 0, 0), func(item pair.Pair, dist float64) bool {
+			keys = append(keys, string(item.Key()))
+			return true
+		})
+		assert.Equal(t, expect, len(keys))
+		assert.Equal(t, "b", keys[len(keys)-1])
+		n = 0
+		tr.KNNWith(makePointPair2("", 0, 0), nil, func(item pair.Pair, dist float64) bool {
+			n++
+			return true
+		})
+		assert.Equal(t, expect, n)
+	}
+}
@@ -0,0 +1,19 @@
+package rtree
+
+import "math"
+
+// Distance returns the distance from the rect to the nearest 2d or 3d item,
+// or +Inf when the tree is empty. As with KNN, the z of the rect is ignored
+// for 2d items. The rect is not transformed.
+func (tr *RTree) Distance(min, max [3]float64) float64 {
+	dist2 := tr.tr2.Distance([2]float64{min[0], min[1]}, [2]float64{max[0], max[1]})
+	if dist2 == 0 {
+		return 0
+	}
+	return math.Min(dist2, tr.tr3.Distance(min, max))
+}
+
+// DistanceToPoint returns the distance from the point to the nearest item.
+func (tr *RTree) DistanceToPoint(x, y, z float64) float64 {
+	return tr.Distance([3]float64{x, y, z}, [3]float64{x, y, z})
+}
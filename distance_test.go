@@ -0,0 +1,43 @@
+package rtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+func TestDistance(t *testing.T) {
+	tr := New(nil)
+	assert.True(t, math.IsInf(tr.DistanceToPoint(0, 0, 0), +1))
+	var objs []pair.Pair
+	for i := 0; i < 2000; i++ {
+		obj := rand2DRect()
+		if i%2 == 1 {
+			obj = rand3DPoint()
+		}
+		tr.Insert(obj)
+		objs = append(objs, obj)
+	}
+	for _, q := range [][2][3]float64{
+		{{0, 0, 0}, {0, 0, 0}},
+		{{500, 300, 10}, {500, 300, 10}},
+		{{-400, -400, -400}, {-300, -300, -300}},
+		{{-10, -10, -10}, {10, 10, 10}},
+	} {
+		expect := math.Inf(+1)
+		for _, obj := range objs {
+			min, max := geobin.WrapBinary(obj.Value()).Rect(nil)
+			dims := geobin.WrapBinary(obj.Value()).Dims()
+			var d float64
+			for i := 0; i < dims; i++ {
+				gap := math.Max(0, math.Max(min[i]-q[1][i], q[0][i]-max[i]))
+				d += gap * gap
+			}
+			expect = math.Min(expect, math.Sqrt(d))
+		}
+		assert.True(t, math.Abs(expect-tr.Distance(q[0], q[1])) < 1e-9)
+	}
+}
@@ -0,0 +1,76 @@
+package rtree
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/tidwall/pair"
+)
+
+// Plan describes how Search runs a query on the 2d and 3d trees.
+type Plan struct {
+	// Dims are the dimensions of the query box.
+	Dims int
+	// Min and Max are the rect of the query box in the coordinates of the
+	// tree, after the transformer and the grid.
+	Min, Max [3]float64
+	// Search2D is true when the 2d tree is searched, which is when the box
+	// is 2d, or when it's 3d and it covers a z of zero.
+	Search2D bool
+	// Min3D and Max3D are the rect that the 3d tree is searched with. A 2d
+	// box covers every z.
+	Min3D, Max3D [3]float64
+	// Candidates2D and Candidates3D are the number of items in the leaves
+	// that intersect the rect in each tree, which is an upper bound on the
+	// number of items that are found.
+	Candidates2D, Candidates3D int
+}
+
+// Explain returns the plan for a Search with the box, which shows the trees
+// that are searched and how the box is adapted to each of them, without
+// reading any items.
+func (tr *RTree) Explain(box pair.Pair) Plan {
+	var p Plan
+	p.Dims = tr.dims(box.Value())
+	p.Min, p.Max = tr.rect(box.Value())
+	p.Min3D, p.Max3D = p.Min, p.Max
+	if p.Dims == 2 {
+		p.Min3D[2], p.Max3D[2] = math.Inf(-1), math.Inf(+1)
+	}
+	p.Search2D = tr.coversZero(p.Min3D[2], p.Max3D[2])
+	if p.Search2D {
+		tr.tr2.Leaves(func(min, max [2]float64, count int) bool {
+			if min[0] <= p.Max[0] && min[1] <= p.Max[1] &&
+				max[0] >= p.Min[0] && max[1] >= p.Min[1] {
+				p.Candidates2D += count
+			}
+			return true
+		})
+	}
+	tr.tr3.Leaves(func(min, max [3]float64, count int) bool {
+		if min[0] <= p.Max3D[0] && min[1] <= p.Max3D[1] && min[2] <= p.Max3D[2] &&
+			max[0] >= p.Min3D[0] && max[1] >= p.Min3D[1] && max[2] >= p.Min3D[2] {
+			p.Candidates3D += count
+		}
+		return true
+	})
+	return p
+}
+
+func (p Plan) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%dd box %v-%v\n", p.Dims, p.Min, p.Max)
+	if p.Search2D {
+		fmt.Fprintf(&b, "2d tree: search %v-%v, %d candidates\n",
+			p.Min[:2], p.Max[:2], p.Candidates2D)
+	} else {
+		fmt.Fprintf(&b, "2d tree: skipped, the box doesn't cover a z of zero\n")
+	}
+	fmt.Fprintf(&b, "3d tree: search %v-%v", p.Min3D, p.Max3D)
+	if p.Dims == 2 {
+		fmt.Fprintf(&b, " (z extended)")
+	}
+	fmt.Fprintf(&b, ", %d candidates\n", p.Candidates3D)
+	return b.String()
+}
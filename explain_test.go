@@ -0,0 +1,49 @@
+package rtree
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestExplain(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 1000; i++ {
+		tr.Insert(rand2DPoint())
+		tr.Insert(rand3DPoint())
+	}
+	count := func(box pair.Pair) (n2, n3 int) {
+		tr.Search(box, func(item pair.Pair) bool {
+			if tr.dims(item.Value()) == 2 {
+				n2++
+			} else {
+				n3++
+			}
+			return true
+		})
+		return n2, n3
+	}
+
+	box := makeBoundsPair2("", -50, -20, 50, 20)
+	p := tr.Explain(box)
+	assert.Equal(t, 2, p.Dims)
+	assert.True(t, p.Search2D)
+	assert.Equal(t, math.Inf(-1), p.Min3D[2])
+	assert.Equal(t, math.Inf(+1), p.Max3D[2])
+	n2, n3 := count(box)
+	assert.True(t, p.Candidates2D >= n2 && p.Candidates2D < tr.Count2D())
+	assert.True(t, p.Candidates3D >= n3 && p.Candidates3D < tr.Count3D())
+	assert.True(t, strings.Contains(p.String(), "z extended"))
+
+	// a 3d box above zero skips the 2d tree
+	box = makeBoundsPair3("", -50, -20, 10, 50, 20, 20)
+	p = tr.Explain(box)
+	assert.False(t, p.Search2D)
+	assert.Equal(t, 0, p.Candidates2D)
+	_, n3 = count(box)
+	assert.True(t, p.Candidates3D >= n3)
+	assert.True(t, strings.Contains(p.String(), "skipped"))
+}
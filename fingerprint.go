@@ -0,0 +1,44 @@
+package rtree
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+
+	"github.com/tidwall/pair"
+)
+
+// Fingerprint returns a hash of the keys and rects of the items, which
+// doesn't depend on the order that they were inserted in or on the shape of
+// the tree, so two trees can be compared without a diff. It's kept up to
+// date by every Insert and Remove, and recomputed by Repair.
+func (tr *RTree) Fingerprint() uint64 {
+	return tr.fingerprint
+}
+
+// itemHash returns the hash of the key and rect of an item. The hashes of
+// the items are summed for the fingerprint.
+func (tr *RTree) itemHash(item pair.Pair) uint64 {
+	h := fnv.New64a()
+	h.Write(item.Key())
+	min, max := tr.rect(item.Value())
+	var buf [8]byte
+	for _, v := range append(min[:], max[:]...) {
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+		h.Write(buf[:])
+	}
+	// fnv is mixed poorly for a sum, so finish with splitmix64
+	x := h.Sum64()
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// refingerprint recomputes the fingerprint from the items.
+func (tr *RTree) refingerprint() {
+	tr.fingerprint = 0
+	tr.Scan(func(item pair.Pair) bool {
+		tr.fingerprint += tr.itemHash(item)
+		return true
+	})
+}
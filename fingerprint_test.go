@@ -0,0 +1,41 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestFingerprint(t *testing.T) {
+	var items []pair.Pair
+	for i := 0; i < 500; i++ {
+		items = append(items, rand2DPoint(), rand3DRect())
+	}
+	tr1 := New(nil)
+	tr2 := New(nil)
+	assert.Equal(t, uint64(0), tr1.Fingerprint())
+	for i := range items {
+		tr1.Insert(items[i])
+		tr2.Insert(items[len(items)-1-i])
+	}
+	assert.True(t, tr1.Fingerprint() != 0)
+	assert.Equal(t, tr1.Fingerprint(), tr2.Fingerprint())
+
+	tr3 := New(nil)
+	tr3.Load(items)
+	assert.Equal(t, tr1.Fingerprint(), tr3.Fingerprint())
+
+	// a different key changes the fingerprint
+	fp := tr1.Fingerprint()
+	tr1.Remove(items[0])
+	assert.True(t, tr1.Fingerprint() != fp)
+	tr1.Insert(pair.New([]byte("other"), items[0].Value()))
+	assert.True(t, tr1.Fingerprint() != fp)
+	tr1.Clear()
+	assert.Equal(t, uint64(0), tr1.Fingerprint())
+
+	fp = tr2.Fingerprint()
+	tr2.Repair()
+	assert.Equal(t, fp, tr2.Fingerprint())
+}
@@ -0,0 +1,30 @@
+package rtree
+
+import "errors"
+
+// ErrFrozen is returned, or is the panic value, for a write to a frozen
+// tree.
+var ErrFrozen = errors.New("tree is frozen")
+
+// Freeze makes the tree read-only. Any Insert, Remove, Load, Tag, or Untag
+// that follows panics with ErrFrozen, and Apply returns it, which catches
+// accidental writes to a tree that is shared by goroutines. The scratch
+// space used by writes is released. A frozen tree cannot be unfrozen.
+func (tr *RTree) Freeze() {
+	tr.frozen = true
+	tr.beginWrite()
+	tr.tr2.Freeze()
+	tr.tr3.Freeze()
+	tr.endWrite()
+}
+
+// Frozen returns true if the tree has been frozen.
+func (tr *RTree) Frozen() bool {
+	return tr.frozen
+}
+
+func (tr *RTree) checkFrozen() {
+	if tr.frozen {
+		panic(ErrFrozen)
+	}
+}
@@ -0,0 +1,28 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+)
+
+func TestFreeze(t *testing.T) {
+	tr := New(nil)
+	item := makePointPair2("a", 1, 2)
+	tr.Insert(item)
+	tr.Insert(makePointPair3("b", 1, 2, 3))
+	assert.False(t, tr.Frozen())
+	tr.Freeze()
+	assert.True(t, tr.Frozen())
+	assert.Equal(t, 2, tr.Count())
+	panics := func(fn func()) (err interface{}) {
+		defer func() { err = recover() }()
+		fn()
+		return nil
+	}
+	assert.Equal(t, ErrFrozen, panics(func() { tr.Insert(makePointPair2("c", 3, 4)) }))
+	assert.Equal(t, ErrFrozen, panics(func() { tr.Remove(item) }))
+	assert.Equal(t, ErrFrozen, panics(func() { tr.Tag(item, "tag") }))
+	assert.Equal(t, ErrFrozen, tr.Apply(Mutation{Seq: 3, Op: OpRemove, Item: item}))
+	assert.Equal(t, 2, tr.Count())
+}
@@ -0,0 +1,172 @@
+// Package gen generates random geobin point and rect pairs for tests,
+// benchmarks, and fuzzing.
+package gen
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+type Distribution int
+
+const (
+	// Uniform spreads items evenly over the whole area.
+	Uniform Distribution = iota
+	// Clustered places items in gaussian clusters of equal weight.
+	Clustered
+	// Skewed places most items in a few dense clusters with a long tail of
+	// sparse clusters and some uniform noise, similar to real world data
+	// such as cities or user locations.
+	Skewed
+)
+
+type Options struct {
+	Dims         int          // 2 or 3
+	Distribution Distribution // how item centers are distributed
+	Min, Max     [3]float64   // area of the item centers
+	MaxSize      [3]float64   // max size of a rect along each axis
+	Clusters     int          // number of clusters for Clustered and Skewed
+	Spread       float64      // cluster standard deviation as fraction of area
+	Keys         bool         // give each item a sequential numeric key
+	Seed         int64        // random seed, zero uses the current time
+}
+
+var DefaultOptions = &Options{
+	Dims:         2,
+	Distribution: Uniform,
+	Min:          [3]float64{-180, -90, -50},
+	Max:          [3]float64{180, 90, 50},
+	MaxSize:      [3]float64{20, 20, 20},
+	Clusters:     16,
+	Spread:       0.02,
+	Keys:         false,
+	Seed:         0,
+}
+
+type Generator struct {
+	opts    Options
+	rnd     *rand.Rand
+	zipf    *rand.Zipf
+	centers [][3]float64
+	spreads []float64
+	n       int64
+}
+
+func New(opts *Options) *Generator {
+	if opts == nil {
+		opts = DefaultOptions
+	}
+	g := &Generator{opts: *opts}
+	if g.opts.Dims != 3 {
+		g.opts.Dims = 2
+	}
+	if g.opts.Clusters < 1 {
+		g.opts.Clusters = 1
+	}
+	seed := g.opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	g.rnd = rand.New(rand.NewSource(seed))
+	if g.opts.Distribution != Uniform {
+		for i := 0; i < g.opts.Clusters; i++ {
+			g.centers = append(g.centers, g.uniform())
+			spread := g.opts.Spread
+			if g.opts.Distribution == Skewed {
+				spread *= g.rnd.ExpFloat64()
+			}
+			g.spreads = append(g.spreads, spread)
+		}
+		if g.opts.Distribution == Skewed && g.opts.Clusters > 1 {
+			g.zipf = rand.NewZipf(g.rnd, 1.5, 1, uint64(g.opts.Clusters-1))
+		}
+	}
+	return g
+}
+
+func (g *Generator) uniform() [3]float64 {
+	var p [3]float64
+	for i := 0; i < g.opts.Dims; i++ {
+		p[i] = g.opts.Min[i] + g.rnd.Float64()*(g.opts.Max[i]-g.opts.Min[i])
+	}
+	return p
+}
+
+func (g *Generator) center() [3]float64 {
+	var idx int
+	switch g.opts.Distribution {
+	default:
+		return g.uniform()
+	case Clustered:
+		idx = g.rnd.Intn(len(g.centers))
+	case Skewed:
+		if g.rnd.Float64() < 0.1 {
+			return g.uniform()
+		}
+		if g.zipf != nil {
+			idx = int(g.zipf.Uint64())
+		}
+	}
+	var p [3]float64
+	for i := 0; i < g.opts.Dims; i++ {
+		size := g.opts.Max[i] - g.opts.Min[i]
+		v := g.centers[idx][i] + g.rnd.NormFloat64()*g.spreads[idx]*size
+		p[i] = math.Max(g.opts.Min[i], math.Min(g.opts.Max[i], v))
+	}
+	return p
+}
+
+func (g *Generator) key() []byte {
+	if !g.opts.Keys {
+		return nil
+	}
+	g.n++
+	return strconv.AppendInt(nil, g.n, 10)
+}
+
+// Point returns a random point.
+func (g *Generator) Point() pair.Pair {
+	p := g.center()
+	if g.opts.Dims == 2 {
+		return pair.New(g.key(), geobin.Make2DPoint(p[0], p[1]).Binary())
+	}
+	return pair.New(g.key(), geobin.Make3DPoint(p[0], p[1], p[2]).Binary())
+}
+
+// Rect returns a random rect that is centered on a random point.
+func (g *Generator) Rect() pair.Pair {
+	p := g.center()
+	var min, max [3]float64
+	for i := 0; i < g.opts.Dims; i++ {
+		min[i] = p[i] - g.rnd.Float64()*g.opts.MaxSize[i]/2
+		max[i] = p[i] + g.rnd.Float64()*g.opts.MaxSize[i]/2
+	}
+	if g.opts.Dims == 2 {
+		return pair.New(g.key(), geobin.Make2DRect(min[0], min[1], max[0], max[1]).Binary())
+	}
+	return pair.New(g.key(),
+		geobin.Make3DRect(min[0], min[1], min[2], max[0], max[1], max[2]).Binary())
+}
+
+// Points returns n random points.
+func (g *Generator) Points(n int) []pair.Pair {
+	items := make([]pair.Pair, n)
+	for i := range items {
+		items[i] = g.Point()
+	}
+	return items
+}
+
+// Rects returns n random rects.
+func (g *Generator) Rects(n int) []pair.Pair {
+	items := make([]pair.Pair, n)
+	for i := range items {
+		items[i] = g.Rect()
+	}
+	return items
+}
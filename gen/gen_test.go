@@ -0,0 +1,45 @@
+package gen
+
+import (
+	"testing"
+
+	"github.com/tidwall/geobin"
+)
+
+func TestDistributions(t *testing.T) {
+	for _, dims := range []int{2, 3} {
+		for _, dist := range []Distribution{Uniform, Clustered, Skewed} {
+			opts := *DefaultOptions
+			opts.Dims = dims
+			opts.Distribution = dist
+			opts.Keys = true
+			g := New(&opts)
+			for i, item := range append(g.Points(500), g.Rects(500)...) {
+				o := geobin.WrapBinary(item.Value())
+				if o.Dims() != dims {
+					t.Fatalf("expected %d dims, got %d", dims, o.Dims())
+				}
+				if len(item.Key()) == 0 {
+					t.Fatalf("item %d missing key", i)
+				}
+				pos := o.Position()
+				if pos.X < opts.Min[0]-opts.MaxSize[0] || pos.X > opts.Max[0]+opts.MaxSize[0] {
+					t.Fatalf("out of range %v", pos)
+				}
+			}
+		}
+	}
+}
+
+func TestSeed(t *testing.T) {
+	opts := *DefaultOptions
+	opts.Seed = 1
+	opts.Distribution = Skewed
+	a := New(&opts).Points(100)
+	b := New(&opts).Points(100)
+	for i := range a {
+		if string(a[i].Value()) != string(b[i].Value()) {
+			t.Fatal("expected same values")
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package rtree
+
+import (
+	"math"
+
+	"github.com/tidwall/pair"
+)
+
+// earthRadius is the mean radius of the earth in meters.
+const earthRadius = 6371008.8
+
+// GreatCircleDistance returns the distance in meters between two lon/lat
+// positions, in degrees, on a spherical earth.
+func GreatCircleDistance(lon1, lat1, lon2, lat2 float64) float64 {
+	const degToRad = math.Pi / 180
+	lat1, lat2 = lat1*degToRad, lat2*degToRad
+	sinLat := math.Sin((lat2 - lat1) / 2)
+	sinLon := math.Sin((lon2 - lon1) * degToRad / 2)
+	a := sinLat*sinLat + math.Cos(lat1)*math.Cos(lat2)*sinLon*sinLon
+	return 2 * earthRadius * math.Asin(math.Sqrt(math.Min(1, a)))
+}
+
+// geodesicIter wraps a KNN iterator so that it's passed the great-circle
+// distance from the position to the center of each item, rather than the
+// distance in the tree.
+func (tr *RTree) geodesicIter(pos pair.Pair,
+	iter func(item pair.Pair, dist float64) bool) func(item pair.Pair, dist float64) bool {
+	lon, lat, _ := tr.position(pos.Value())
+	return func(item pair.Pair, dist float64) bool {
+		ilon, ilat, _ := tr.position(item.Value())
+		return iter(item, GreatCircleDistance(lon, lat, ilon, ilat))
+	}
+}
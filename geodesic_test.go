@@ -0,0 +1,37 @@
+package rtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestGreatCircleDistance(t *testing.T) {
+	// one degree of latitude
+	assert.True(t, math.Abs(GreatCircleDistance(0, 0, 0, 1)-111195) < 1)
+	// half way around the earth
+	assert.True(t, math.Abs(GreatCircleDistance(-90, 0, 90, 0)-math.Pi*earthRadius) < 1)
+	assert.Equal(t, 0.0, GreatCircleDistance(10, 20, 10, 20))
+}
+
+func TestGeodesicKNN(t *testing.T) {
+	opts := *DefaultOptions
+	opts.Geodesic = true
+	tr := New(&opts)
+	tr.Insert(makePointPair2("a", 0, 1))
+	tr.Insert(makePointPair2("b", 0, 2))
+	tr.Insert(makePointPair3("c", 0, 3, 100))
+	var keys []string
+	var dists []float64
+	tr.KNN(makePointPair2("", 0, 0), func(item pair.Pair, dist float64) bool {
+		keys = append(keys, string(item.Key()))
+		dists = append(dists, dist)
+		return true
+	})
+	assert.Equal(t, []string{"a", "b", "c"}, keys)
+	for i, dist := range dists {
+		assert.True(t, math.Abs(dist-111195*float64(i+1)) < 3)
+	}
+}
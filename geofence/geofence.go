@@ -0,0 +1,238 @@
+// Package geofence tracks moving objects against a set of rectangular fences
+// and emits enter, exit, and cross events.
+package geofence
+
+import (
+	"math"
+	"time"
+
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+type EventType int
+
+const (
+	Enter EventType = iota // object is now inside the fence
+	Exit                   // object is now outside the fence
+	Cross                  // object passed through the fence between updates
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Enter:
+		return "enter"
+	case Exit:
+		return "exit"
+	case Cross:
+		return "cross"
+	}
+	return "unknown"
+}
+
+type Event struct {
+	Type  EventType
+	Fence string
+	Key   string
+	Time  time.Time
+}
+
+type Options struct {
+	// Dwell is the amount of time that an object must remain on the other
+	// side of a fence before an enter or exit event is emitted. Zero emits
+	// the events immediately.
+	Dwell time.Duration
+	// Transformer is passed on to the underlying tree.
+	Transformer func(minIn, maxIn [3]float64) (minOut, maxOut [3]float64)
+}
+
+var DefaultOptions = &Options{
+	Dwell:       0,
+	Transformer: nil,
+}
+
+type fence struct {
+	item     pair.Pair
+	min, max [3]float64
+}
+
+type state struct {
+	inside  bool
+	pending bool
+	since   time.Time
+}
+
+type object struct {
+	pos    [3]float64
+	fences map[string]*state
+}
+
+type Manager struct {
+	opts    Options
+	tr      *rtree.RTree
+	fences  map[string]*fence
+	objects map[string]*object
+}
+
+func New(opts *Options) *Manager {
+	if opts == nil {
+		opts = DefaultOptions
+	}
+	topts := *rtree.DefaultOptions
+	topts.Transformer = opts.Transformer
+	return &Manager{
+		opts:    *opts,
+		tr:      rtree.New(&topts),
+		fences:  make(map[string]*fence),
+		objects: make(map[string]*object),
+	}
+}
+
+// AddFence adds or replaces the fence with the provided id. The bbox must be
+// a geobin value.
+func (m *Manager) AddFence(id string, bbox pair.Pair) {
+	m.RemoveFence(id)
+	bbox = pair.New([]byte(id), bbox.Value())
+	min, max := m.rect(bbox)
+	m.fences[id] = &fence{item: bbox, min: min, max: max}
+	m.tr.Insert(bbox)
+}
+
+// RemoveFence removes the fence and forgets the state of every object
+// against it.
+func (m *Manager) RemoveFence(id string) {
+	f, ok := m.fences[id]
+	if !ok {
+		return
+	}
+	m.tr.Remove(f.item)
+	delete(m.fences, id)
+	for _, obj := range m.objects {
+		delete(obj.fences, id)
+	}
+}
+
+// Forget removes all state for an object.
+func (m *Manager) Forget(key string) {
+	delete(m.objects, key)
+}
+
+// Update records a new position for the object, which is identified by the
+// item key, and returns the events that it triggered.
+func (m *Manager) Update(item pair.Pair, ts time.Time) []Event {
+	key := string(item.Key())
+	min, max := geobin.WrapBinary(item.Value()).Rect(m.opts.Transformer)
+	pos := [3]float64{(min[0] + max[0]) / 2, (min[1] + max[1]) / 2, (min[2] + max[2]) / 2}
+
+	inside := make(map[string]bool)
+	m.tr.Search(item, func(f pair.Pair) bool {
+		inside[string(f.Key())] = true
+		return true
+	})
+
+	var events []Event
+	obj, ok := m.objects[key]
+	if !ok {
+		obj = &object{fences: make(map[string]*state)}
+		m.objects[key] = obj
+	} else {
+		// look for fences that were passed through since the last update
+		var smin, smax [3]float64
+		for i := 0; i < 3; i++ {
+			smin[i] = math.Min(obj.pos[i], pos[i])
+			smax[i] = math.Max(obj.pos[i], pos[i])
+		}
+		span := geobin.Make3DRect(smin[0], smin[1], smin[2], smax[0], smax[1], smax[2])
+		m.tr.Search(pair.New(nil, span.Binary()), func(item pair.Pair) bool {
+			id := string(item.Key())
+			if inside[id] {
+				return true
+			}
+			if st, ok := obj.fences[id]; ok && (st.inside || st.pending) {
+				return true
+			}
+			f := m.fences[id]
+			if segmentIntersects(obj.pos, pos, f.min, f.max) {
+				events = append(events, Event{Cross, id, key, ts})
+			}
+			return true
+		})
+	}
+	obj.pos = pos
+
+	for id := range inside {
+		if _, ok := obj.fences[id]; !ok {
+			obj.fences[id] = &state{}
+		}
+	}
+	for id, st := range obj.fences {
+		in := inside[id]
+		if in == st.inside {
+			st.pending = false
+		} else {
+			if !st.pending {
+				st.pending = true
+				st.since = ts
+			}
+			if ts.Sub(st.since) >= m.opts.Dwell {
+				st.inside = in
+				st.pending = false
+				typ := Exit
+				if in {
+					typ = Enter
+				}
+				events = append(events, Event{typ, id, key, ts})
+			}
+		}
+		if !st.inside && !st.pending {
+			delete(obj.fences, id)
+		}
+	}
+	return events
+}
+
+// Inside returns true if the object is confirmed to be inside the fence.
+func (m *Manager) Inside(key, id string) bool {
+	if obj, ok := m.objects[key]; ok {
+		if st, ok := obj.fences[id]; ok {
+			return st.inside
+		}
+	}
+	return false
+}
+
+func (m *Manager) rect(item pair.Pair) (min, max [3]float64) {
+	min, max = geobin.WrapBinary(item.Value()).Rect(m.opts.Transformer)
+	if geobin.WrapBinary(item.Value()).Dims() == 2 {
+		// 2d fences extend infinitely along the z axis
+		min[2], max[2] = math.Inf(-1), math.Inf(+1)
+	}
+	return min, max
+}
+
+// segmentIntersects uses the slab method to test if the line segment from a
+// to b intersects the box.
+func segmentIntersects(a, b, min, max [3]float64) bool {
+	t0, t1 := 0.0, 1.0
+	for i := 0; i < 3; i++ {
+		d := b[i] - a[i]
+		if d == 0 {
+			if a[i] < min[i] || a[i] > max[i] {
+				return false
+			}
+			continue
+		}
+		n := (min[i] - a[i]) / d
+		f := (max[i] - a[i]) / d
+		if n > f {
+			n, f = f, n
+		}
+		t0 = math.Max(t0, n)
+		t1 = math.Min(t1, f)
+		if t0 > t1 {
+			return false
+		}
+	}
+	return true
+}
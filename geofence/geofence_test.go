@@ -0,0 +1,49 @@
+package geofence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+func point(key string, x, y float64) pair.Pair {
+	return pair.New([]byte(key), geobin.Make2DPoint(x, y).Binary())
+}
+
+func TestEnterExitCross(t *testing.T) {
+	m := New(nil)
+	m.AddFence("box", pair.New(nil, geobin.Make2DRect(0, 0, 10, 10).Binary()))
+	now := time.Now()
+	assert.Equal(t, 0, len(m.Update(point("car", -5, 5), now)))
+	evs := m.Update(point("car", 5, 5), now)
+	assert.Equal(t, []Event{{Enter, "box", "car", now}}, evs)
+	assert.True(t, m.Inside("car", "box"))
+	assert.Equal(t, 0, len(m.Update(point("car", 6, 6), now)))
+	evs = m.Update(point("car", 15, 5), now)
+	assert.Equal(t, []Event{{Exit, "box", "car", now}}, evs)
+	evs = m.Update(point("car", -5, 5), now)
+	assert.Equal(t, []Event{{Cross, "box", "car", now}}, evs)
+	m.RemoveFence("box")
+	assert.Equal(t, 0, len(m.Update(point("car", 15, 5), now)))
+}
+
+func TestDwell(t *testing.T) {
+	opts := *DefaultOptions
+	opts.Dwell = time.Minute
+	m := New(&opts)
+	m.AddFence("box", pair.New(nil, geobin.Make2DRect(0, 0, 10, 10).Binary()))
+	now := time.Now()
+	m.Update(point("car", 5, 5), now)
+	assert.False(t, m.Inside("car", "box"))
+	// bounce out and back in before the dwell time resets the timer
+	m.Update(point("car", 15, 5), now.Add(time.Second*30))
+	assert.Equal(t, 0, len(m.Update(point("car", 5, 5), now.Add(time.Second*40))))
+	assert.Equal(t, 0, len(m.Update(point("car", 5, 5), now.Add(time.Second*90))))
+	evs := m.Update(point("car", 5, 5), now.Add(time.Second*100))
+	assert.Equal(t, 1, len(evs))
+	assert.Equal(t, Enter, evs[0].Type)
+	assert.True(t, m.Inside("car", "box"))
+}
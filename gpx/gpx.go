@@ -0,0 +1,118 @@
+// Package gpx loads the track points of GPX files into a tree.
+package gpx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+// Point is a track point.
+type Point struct {
+	Track     int    // index of the track in the file
+	TrackName string // name of the track, if any
+	Segment   int    // index of the segment in the track
+	Index     int    // index of the point in the segment
+	Lon, Lat  float64
+	Ele       float64
+	HasEle    bool
+	Time      time.Time // zero if the point has no time
+}
+
+// DefaultKey returns "track:segment:index".
+func DefaultKey(p Point) string {
+	return fmt.Sprintf("%d:%d:%d", p.Track, p.Segment, p.Index)
+}
+
+type trkpt struct {
+	Lat  string `xml:"lat,attr"`
+	Lon  string `xml:"lon,attr"`
+	Ele  string `xml:"ele"`
+	Time string `xml:"time"`
+}
+
+// Import reads the track points from a GPX document and inserts them into
+// the tree as 3d points, with the elevation in meters as z. Points without
+// an elevation are inserted as 2d points. The key function returns the key
+// for each point, and may be nil to use DefaultKey. It returns the number
+// of points inserted.
+func Import(r io.Reader, tr *rtree.RTree, key func(p Point) string) (int, error) {
+	if key == nil {
+		key = DefaultKey
+	}
+	d := xml.NewDecoder(r)
+	var p Point
+	track, segment := -1, -1
+	var n int
+	var inName bool
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			switch tok.Name.Local {
+			case "trk":
+				track++
+				segment = -1
+				p.TrackName = ""
+			case "name":
+				inName = track >= 0 && segment == -1
+			case "trkseg":
+				segment++
+				p.Index = 0
+			case "trkpt":
+				var pt trkpt
+				if err := d.DecodeElement(&pt, &tok); err != nil {
+					return n, err
+				}
+				if p.Lat, err = strconv.ParseFloat(pt.Lat, 64); err != nil {
+					return n, fmt.Errorf("gpx: invalid lat %q", pt.Lat)
+				}
+				if p.Lon, err = strconv.ParseFloat(pt.Lon, 64); err != nil {
+					return n, fmt.Errorf("gpx: invalid lon %q", pt.Lon)
+				}
+				p.Ele, p.HasEle = 0, pt.Ele != ""
+				if p.HasEle {
+					if p.Ele, err = strconv.ParseFloat(pt.Ele, 64); err != nil {
+						return n, fmt.Errorf("gpx: invalid ele %q", pt.Ele)
+					}
+				}
+				p.Time = time.Time{}
+				if pt.Time != "" {
+					if p.Time, err = time.Parse(time.RFC3339, pt.Time); err != nil {
+						return n, fmt.Errorf("gpx: invalid time %q", pt.Time)
+					}
+				}
+				p.Track, p.Segment = track, segment
+				var obj geobin.Object
+				if p.HasEle {
+					obj = geobin.Make3DPoint(p.Lon, p.Lat, p.Ele)
+				} else {
+					obj = geobin.Make2DPoint(p.Lon, p.Lat)
+				}
+				tr.Insert(pair.New([]byte(key(p)), obj.Binary()))
+				p.Index++
+				n++
+			}
+		case xml.CharData:
+			if inName {
+				p.TrackName += string(tok)
+			}
+		case xml.EndElement:
+			if tok.Name.Local == "name" {
+				inName = false
+			}
+		}
+	}
+}
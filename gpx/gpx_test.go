@@ -0,0 +1,65 @@
+package gpx
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+const doc = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <metadata><name>ignored</name></metadata>
+  <wpt lat="1" lon="1"><name>waypoint</name></wpt>
+  <trk>
+    <name>Morning Ride</name>
+    <trkseg>
+      <trkpt lat="33.45" lon="-112.07"><ele>331.5</ele><time>2017-05-31T12:00:00Z</time></trkpt>
+      <trkpt lat="33.46" lon="-112.06"><ele>335</ele></trkpt>
+    </trkseg>
+    <trkseg>
+      <trkpt lat="33.47" lon="-112.05"></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+func TestImport(t *testing.T) {
+	tr := rtree.New(nil)
+	var points []Point
+	n, err := Import(strings.NewReader(doc), tr, func(p Point) string {
+		points = append(points, p)
+		return fmt.Sprintf("%s/%d/%d", p.TrackName, p.Segment, p.Index)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, 3, tr.Count())
+	assert.Equal(t, "Morning Ride", points[0].TrackName)
+	assert.Equal(t, 2017, points[0].Time.Year())
+	assert.True(t, points[1].Time.IsZero())
+	assert.False(t, points[2].HasEle)
+
+	item, ok := tr.Get([]byte("Morning Ride/0/1"))
+	assert.True(t, ok)
+	assert.Equal(t, 3, geobin.WrapBinary(item.Value()).Dims())
+	min, _ := geobin.WrapBinary(item.Value()).Rect(nil)
+	assert.Equal(t, [3]float64{-112.06, 33.46, 335}, min)
+	item, _ = tr.Get([]byte("Morning Ride/1/0"))
+	assert.Equal(t, 2, geobin.WrapBinary(item.Value()).Dims())
+
+	tr = rtree.New(nil)
+	_, err = Import(strings.NewReader(doc), tr, nil)
+	assert.NoError(t, err)
+	var keys []string
+	tr.ScanSorted(func(item pair.Pair) bool {
+		keys = append(keys, string(item.Key()))
+		return true
+	})
+	assert.Equal(t, []string{"0:0:0", "0:0:1", "0:1:0"}, keys)
+
+	_, err = Import(strings.NewReader(`<gpx><trk><trkseg><trkpt lat="x" lon="1"/></trkseg></trk></gpx>`), tr, nil)
+	assert.Error(t, err)
+}
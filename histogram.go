@@ -0,0 +1,95 @@
+package rtree
+
+import (
+	"math"
+
+	"github.com/tidwall/pair"
+)
+
+// Histogram counts values in buckets of equal width from Min to Max.
+type Histogram struct {
+	Min, Max float64
+	Counts   []int
+}
+
+func newHistogram(min, max float64, buckets int) Histogram {
+	return Histogram{Min: min, Max: max, Counts: make([]int, buckets)}
+}
+
+func (h *Histogram) add(v float64) {
+	var i int
+	if h.Max > h.Min {
+		i = int((v - h.Min) / (h.Max - h.Min) * float64(len(h.Counts)))
+	}
+	if i < 0 {
+		i = 0
+	} else if i >= len(h.Counts) {
+		i = len(h.Counts) - 1
+	}
+	h.Counts[i]++
+}
+
+// SizeHistogram summarizes the sizes of the item rects, in the coordinates
+// of the tree.
+type SizeHistogram struct {
+	// Width, Height, and Depth are the extents on each axis, from zero to
+	// the extent of the tree. The 2d items are not in Depth.
+	Width, Height, Depth Histogram
+	// Area is the area on x and y of every item, and Volume is the volume of
+	// the 3d items.
+	Area, Volume Histogram
+	// Aspect is the smallest extent of each item over its largest, from
+	// zero for a line to one for a square or cube. Points are not included.
+	Aspect Histogram
+	// Degenerate is the number of items with no area, or for 3d items no
+	// volume, such as points and lines.
+	Degenerate int
+	// Spanning is the number of items that span the whole tree on x or y,
+	// which makes them overlap every search.
+	Spanning int
+}
+
+// SizeHistogram returns histograms of the item sizes, with the number of
+// buckets in each, for tuning MaxEntries and for finding degenerate or
+// world-spanning items. The items are read in one pass.
+func (tr *RTree) SizeHistogram(buckets int) SizeHistogram {
+	if buckets < 1 {
+		return SizeHistogram{}
+	}
+	bmin, bmax := tr.Bounds()
+	ext := [3]float64{bmax[0] - bmin[0], bmax[1] - bmin[1], bmax[2] - bmin[2]}
+	h := SizeHistogram{
+		Width:  newHistogram(0, ext[0], buckets),
+		Height: newHistogram(0, ext[1], buckets),
+		Depth:  newHistogram(0, ext[2], buckets),
+		Area:   newHistogram(0, ext[0]*ext[1], buckets),
+		Volume: newHistogram(0, ext[0]*ext[1]*ext[2], buckets),
+		Aspect: newHistogram(0, 1, buckets),
+	}
+	tr.Scan(func(item pair.Pair) bool {
+		min, max := tr.rect(item.Value())
+		w, ht, d := max[0]-min[0], max[1]-min[1], max[2]-min[2]
+		h.Width.add(w)
+		h.Height.add(ht)
+		h.Area.add(w * ht)
+		small, large := math.Min(w, ht), math.Max(w, ht)
+		degenerate := w*ht == 0
+		if tr.dims(item.Value()) == 3 {
+			h.Depth.add(d)
+			h.Volume.add(w * ht * d)
+			small, large = math.Min(small, d), math.Max(large, d)
+			degenerate = w*ht*d == 0
+		}
+		if large > 0 {
+			h.Aspect.add(small / large)
+		}
+		if degenerate {
+			h.Degenerate++
+		}
+		if (ext[0] > 0 && w >= ext[0]) || (ext[1] > 0 && ht >= ext[1]) {
+			h.Spanning++
+		}
+		return true
+	})
+	return h
+}
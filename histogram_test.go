@@ -0,0 +1,45 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+)
+
+func TestSizeHistogram(t *testing.T) {
+	tr := New(nil)
+	assert.Equal(t, SizeHistogram{}, tr.SizeHistogram(0))
+	for i := 0; i < 10; i++ {
+		x := float64(i * 10)
+		tr.Insert(makeBoundsPair2("", x, 0, x+5, 5))
+		tr.Insert(makeBoundsPair3("", x, 0, 0, x+5, 10, 10))
+	}
+	tr.Insert(makePointPair2("", 50, 50))
+	tr.Insert(makeBoundsPair2("", 0, 20, 100, 20))
+
+	h := tr.SizeHistogram(10)
+	assert.Equal(t, 22, total(h.Width.Counts))
+	assert.Equal(t, 22, total(h.Area.Counts))
+	assert.Equal(t, 10, total(h.Depth.Counts))
+	assert.Equal(t, 10, total(h.Volume.Counts))
+	assert.Equal(t, [2]float64{0, 100}, [2]float64{h.Width.Min, h.Width.Max})
+	assert.Equal(t, 21, h.Width.Counts[0])
+
+	// the point has no aspect, the line has zero, the squares have one, and
+	// the boxes have a half
+	assert.Equal(t, 21, total(h.Aspect.Counts))
+	assert.Equal(t, 1, h.Aspect.Counts[0])
+	assert.Equal(t, 10, h.Aspect.Counts[9])
+	assert.Equal(t, 10, h.Aspect.Counts[5])
+
+	assert.Equal(t, 2, h.Degenerate)
+	assert.Equal(t, 1, h.Spanning)
+}
+
+func total(counts []int) int {
+	var n int
+	for _, c := range counts {
+		n += c
+	}
+	return n
+}
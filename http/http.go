@@ -0,0 +1,333 @@
+// Package http provides an http.Handler for inspecting and updating a tree
+// over HTTP.
+//
+// All endpoints use JSON. Items are objects with a key and either a point
+// or a rect, in 2d or 3d.
+//
+//	{"key":"phx","point":[-112.07,33.45]}
+//	{"key":"az","rect":[-114.82,31.33,-109.05,37.00]}
+//
+// The endpoints are:
+//
+//	POST /insert           insert an item
+//	POST /delete           delete an item with the same key and geometry
+//	GET  /search?bbox=     items intersecting minx,miny[,minz],maxx,maxy[,maxz]
+//	GET  /knn?point=&k=    nearest k items to x,y[,z]
+//	GET  /query?q=         items matching a query package query string
+//	GET  /stats            item count, bounds, and sequence
+//	GET  /render.png       png of the tree, with optional width, height, and
+//	                       scale params
+//
+// The search, knn, and query endpoints accept an optional limit param.
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"image/png"
+	nethttp "net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+	"github.com/tidwall/pair-rtree/query"
+	"github.com/tidwall/pair-rtree/viz"
+)
+
+// Item is the JSON form of a tree item.
+type Item struct {
+	Key   string    `json:"key"`
+	Point []float64 `json:"point,omitempty"`
+	Rect  []float64 `json:"rect,omitempty"`
+	Dist  *float64  `json:"dist,omitempty"`
+}
+
+var errInvalidItem = errors.New("item requires a 2d or 3d point or rect")
+
+func (item *Item) pair() (pair.Pair, error) {
+	var obj geobin.Object
+	switch {
+	case len(item.Point) == 2 && item.Rect == nil:
+		obj = geobin.Make2DPoint(item.Point[0], item.Point[1])
+	case len(item.Point) == 3 && item.Rect == nil:
+		obj = geobin.Make3DPoint(item.Point[0], item.Point[1], item.Point[2])
+	case len(item.Rect) == 4 && item.Point == nil:
+		obj = geobin.Make2DRect(item.Rect[0], item.Rect[1], item.Rect[2], item.Rect[3])
+	case len(item.Rect) == 6 && item.Point == nil:
+		obj = geobin.Make3DRect(item.Rect[0], item.Rect[1], item.Rect[2],
+			item.Rect[3], item.Rect[4], item.Rect[5])
+	default:
+		return pair.Pair{}, errInvalidItem
+	}
+	return pair.New([]byte(item.Key), obj.Binary()), nil
+}
+
+func makeItem(p pair.Pair) Item {
+	g := geobin.WrapBinary(p.Value())
+	min, max := g.Rect(nil)
+	item := Item{Key: string(p.Key())}
+	dims := g.Dims()
+	if min == max {
+		item.Point = append([]float64{}, min[:dims]...)
+	} else {
+		item.Rect = append(append([]float64{}, min[:dims]...), max[:dims]...)
+	}
+	return item
+}
+
+type handler struct {
+	mu  sync.RWMutex
+	tr  *rtree.RTree
+	mux *nethttp.ServeMux
+}
+
+// Handler returns a handler for the tree. Requests are serialized with an
+// internal lock, so the tree must not be modified elsewhere while the
+// handler is in use.
+func Handler(tr *rtree.RTree) nethttp.Handler {
+	h := &handler{tr: tr, mux: nethttp.NewServeMux()}
+	h.mux.HandleFunc("/insert", h.insert)
+	h.mux.HandleFunc("/delete", h.delete)
+	h.mux.HandleFunc("/search", h.search)
+	h.mux.HandleFunc("/knn", h.knn)
+	h.mux.HandleFunc("/query", h.query)
+	h.mux.HandleFunc("/stats", h.stats)
+	h.mux.HandleFunc("/render.png", h.render)
+	return h
+}
+
+func (h *handler) ServeHTTP(w nethttp.ResponseWriter, r *nethttp.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func writeJSON(w nethttp.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w nethttp.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// floats parses a comma separated list of numbers with one of the provided
+// lengths.
+func floats(s string, lens ...int) ([]float64, error) {
+	var nums []float64
+	for _, part := range strings.Split(s, ",") {
+		n, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, err
+		}
+		nums = append(nums, n)
+	}
+	for _, n := range lens {
+		if len(nums) == n {
+			return nums, nil
+		}
+	}
+	return nil, errors.New("wrong number of coordinates")
+}
+
+// limit returns the limit param, or -1 when there's no limit.
+func limit(r *nethttp.Request) (int, error) {
+	s := r.URL.Query().Get("limit")
+	if s == "" {
+		return -1, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, errors.New("invalid limit")
+	}
+	return n, nil
+}
+
+func (h *handler) readItem(w nethttp.ResponseWriter, r *nethttp.Request) (pair.Pair, bool) {
+	if r.Method != nethttp.MethodPost {
+		writeError(w, nethttp.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return pair.Pair{}, false
+	}
+	var item Item
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		writeError(w, nethttp.StatusBadRequest, err)
+		return pair.Pair{}, false
+	}
+	p, err := item.pair()
+	if err != nil {
+		writeError(w, nethttp.StatusBadRequest, err)
+		return pair.Pair{}, false
+	}
+	return p, true
+}
+
+func (h *handler) insert(w nethttp.ResponseWriter, r *nethttp.Request) {
+	item, ok := h.readItem(w, r)
+	if !ok {
+		return
+	}
+	h.mu.Lock()
+	h.tr.Insert(item)
+	h.mu.Unlock()
+	writeJSON(w, nethttp.StatusOK, map[string]bool{"ok": true})
+}
+
+func (h *handler) delete(w nethttp.ResponseWriter, r *nethttp.Request) {
+	item, ok := h.readItem(w, r)
+	if !ok {
+		return
+	}
+	h.mu.Lock()
+	item, found := h.tr.Find(item)
+	if found {
+		h.tr.Remove(item)
+	}
+	h.mu.Unlock()
+	writeJSON(w, nethttp.StatusOK, map[string]bool{"ok": found})
+}
+
+func (h *handler) search(w nethttp.ResponseWriter, r *nethttp.Request) {
+	nums, err := floats(r.URL.Query().Get("bbox"), 4, 6)
+	if err != nil {
+		writeError(w, nethttp.StatusBadRequest, err)
+		return
+	}
+	n, err := limit(r)
+	if err != nil {
+		writeError(w, nethttp.StatusBadRequest, err)
+		return
+	}
+	var box Item
+	box.Rect = nums
+	bbox, _ := box.pair()
+	items := []Item{}
+	h.mu.RLock()
+	if n != 0 {
+		h.tr.Search(bbox, func(item pair.Pair) bool {
+			items = append(items, makeItem(item))
+			return len(items) != n
+		})
+	}
+	h.mu.RUnlock()
+	writeJSON(w, nethttp.StatusOK, items)
+}
+
+func (h *handler) knn(w nethttp.ResponseWriter, r *nethttp.Request) {
+	nums, err := floats(r.URL.Query().Get("point"), 2, 3)
+	if err != nil {
+		writeError(w, nethttp.StatusBadRequest, err)
+		return
+	}
+	k := 10
+	if s := r.URL.Query().Get("k"); s != "" {
+		if k, err = strconv.Atoi(s); err != nil || k < 0 {
+			writeError(w, nethttp.StatusBadRequest, errors.New("invalid k"))
+			return
+		}
+	}
+	var point Item
+	point.Point = nums
+	pos, _ := point.pair()
+	items := []Item{}
+	h.mu.RLock()
+	if k > 0 {
+		h.tr.KNN(pos, func(item pair.Pair, dist float64) bool {
+			res := makeItem(item)
+			res.Dist = &dist
+			items = append(items, res)
+			return len(items) < k
+		})
+	}
+	h.mu.RUnlock()
+	writeJSON(w, nethttp.StatusOK, items)
+}
+
+func (h *handler) query(w nethttp.ResponseWriter, r *nethttp.Request) {
+	q, err := query.Parse(r.URL.Query().Get("q"))
+	if err != nil {
+		writeError(w, nethttp.StatusBadRequest, err)
+		return
+	}
+	n, err := limit(r)
+	if err != nil {
+		writeError(w, nethttp.StatusBadRequest, err)
+		return
+	}
+	items := []Item{}
+	h.mu.RLock()
+	if n != 0 {
+		q.Run(h.tr, func(item pair.Pair, dist float64) bool {
+			res := makeItem(item)
+			if q.Nearest != nil {
+				res.Dist = &dist
+			}
+			items = append(items, res)
+			return len(items) != n
+		})
+	}
+	h.mu.RUnlock()
+	writeJSON(w, nethttp.StatusOK, items)
+}
+
+func (h *handler) stats(w nethttp.ResponseWriter, r *nethttp.Request) {
+	h.mu.RLock()
+	min, max := h.tr.Bounds()
+	stats := struct {
+		Count int        `json:"count"`
+		Min   [3]float64 `json:"min"`
+		Max   [3]float64 `json:"max"`
+		Seq   uint64     `json:"seq"`
+	}{h.tr.Count(), min, max, h.tr.Seq()}
+	h.mu.RUnlock()
+	writeJSON(w, nethttp.StatusOK, stats)
+}
+
+func (h *handler) render(w nethttp.ResponseWriter, r *nethttp.Request) {
+	width, height, scale := 500, 500, 0.0
+	var err error
+	param := func(name string, v *int) {
+		if s := r.URL.Query().Get(name); s != "" && err == nil {
+			if *v, err = strconv.Atoi(s); err == nil && (*v < 1 || *v > 4096) {
+				err = errors.New("invalid " + name)
+			}
+		}
+	}
+	param("width", &width)
+	param("height", &height)
+	if s := r.URL.Query().Get("scale"); s != "" && err == nil {
+		scale, err = strconv.ParseFloat(s, 64)
+	}
+	if err != nil {
+		writeError(w, nethttp.StatusBadRequest, err)
+		return
+	}
+	opts := *viz.Default2DOptions
+	h.mu.RLock()
+	if scale == 0 {
+		// fit the tree into the image
+		min, max := h.tr.Bounds()
+		if size := maxSize(min, max); size > 0 {
+			scale = 2 / size
+		} else {
+			scale = 1
+		}
+		opts.Center = true
+	}
+	opts.Scale = scale
+	img := viz.RenderImage(h.tr.Traverse, width, height, &opts)
+	h.mu.RUnlock()
+	w.Header().Set("Content-Type", "image/png")
+	png.Encode(w, img)
+}
+
+func maxSize(min, max [3]float64) float64 {
+	var size float64
+	for i := 0; i < 3; i++ {
+		if max[i]-min[i] > size {
+			size = max[i] - min[i]
+		}
+	}
+	return size
+}
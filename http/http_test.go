@@ -0,0 +1,79 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+func do(t *testing.T, h *handler, method, url, body string, v interface{}) int {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, url, strings.NewReader(body)))
+	if v != nil {
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), v))
+	}
+	return w.Code
+}
+
+func TestHandler(t *testing.T) {
+	tr := rtree.New(nil)
+	h := Handler(tr).(*handler)
+	assert.Equal(t, 200, do(t, h, "POST", "/insert", `{"key":"a","point":[1,2]}`, nil))
+	assert.Equal(t, 200, do(t, h, "POST", "/insert", `{"key":"b","point":[5,5,5]}`, nil))
+	assert.Equal(t, 200, do(t, h, "POST", "/insert", `{"key":"c","rect":[10,10,20,20]}`, nil))
+	assert.Equal(t, 400, do(t, h, "POST", "/insert", `{"key":"d","point":[1]}`, nil))
+	assert.Equal(t, 405, do(t, h, "GET", "/insert", ``, nil))
+	assert.Equal(t, 3, tr.Count())
+
+	var items []Item
+	assert.Equal(t, 200, do(t, h, "GET", "/search?bbox=0,0,6,6", "", &items))
+	assert.Equal(t, 2, len(items))
+	assert.Equal(t, 200, do(t, h, "GET", "/search?bbox=0,0,30,30&limit=1", "", &items))
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, 400, do(t, h, "GET", "/search?bbox=0,0,30", "", nil))
+
+	items = nil
+	assert.Equal(t, 200, do(t, h, "GET", "/knn?point=11,11&k=2", "", &items))
+	assert.Equal(t, 2, len(items))
+	assert.Equal(t, Item{Key: "c", Rect: []float64{10, 10, 20, 20}, Dist: items[0].Dist}, items[0])
+	assert.Equal(t, 0.0, *items[0].Dist)
+
+	items = nil
+	assert.Equal(t, 200, do(t, h, "GET", "/query?q=NEAREST(0,0)+LIMIT+1", "", &items))
+	assert.Equal(t, []float64{1, 2}, items[0].Point)
+	assert.Equal(t, 400, do(t, h, "GET", "/query?q=NEAREST(0)", "", nil))
+
+	var ok map[string]bool
+	assert.Equal(t, 200, do(t, h, "POST", "/delete", `{"key":"a","point":[1,2]}`, &ok))
+	assert.True(t, ok["ok"])
+	assert.Equal(t, 200, do(t, h, "POST", "/delete", `{"key":"a","point":[1,2]}`, &ok))
+	assert.False(t, ok["ok"])
+
+	var stats struct {
+		Count int
+		Min   [3]float64
+		Max   [3]float64
+	}
+	assert.Equal(t, 200, do(t, h, "GET", "/stats", "", &stats))
+	assert.Equal(t, 2, stats.Count)
+	assert.Equal(t, [3]float64{5, 5, 5}, stats.Min)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/render.png?width=100&height=100", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+}
+
+func TestDeleteExcludeTouching(t *testing.T) {
+	tr := rtree.New(&rtree.Options{MaxEntries: 9, ExcludeTouching: true})
+	h := Handler(tr).(*handler)
+	assert.Equal(t, 200, do(t, h, "POST", "/insert", `{"key":"a","point":[1,2]}`, nil))
+	var ok map[string]bool
+	assert.Equal(t, 200, do(t, h, "POST", "/delete", `{"key":"a","point":[1,2]}`, &ok))
+	assert.True(t, ok["ok"])
+	assert.Equal(t, 0, tr.Count())
+}
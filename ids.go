@@ -0,0 +1,52 @@
+package rtree
+
+import (
+	"encoding/binary"
+
+	"github.com/tidwall/pair"
+)
+
+// idMarker starts every id key, so that other keys aren't taken for ids.
+const idMarker = "\x00id"
+
+// idSize is the size of an id key, which is the marker followed by the id.
+const idSize = len(idMarker) + 8
+
+// idKey returns the key of an id.
+func idKey(id uint64) [idSize]byte {
+	var key [idSize]byte
+	copy(key[:], idMarker)
+	binary.BigEndian.PutUint64(key[len(idMarker):], id)
+	return key
+}
+
+// NewIDItem returns an item that is identified by a numeric id rather than a
+// key. The id is stored in the key as a marker followed by the big-endian
+// id, so the key order used by ScanKeys and ScanSorted is the same as the id
+// order, and the id items come before keys that don't start with a zero.
+func NewIDItem(id uint64, value []byte) pair.Pair {
+	key := idKey(id)
+	return pair.New(key[:], value)
+}
+
+// ItemID returns the id of an item that was created with NewIDItem. Returns
+// false if the item key is not an id.
+func ItemID(item pair.Pair) (uint64, bool) {
+	key := item.Key()
+	if len(key) != idSize || string(key[:len(idMarker)]) != idMarker {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(key[len(idMarker):]), true
+}
+
+// GetID is like Get, but for an item created with NewIDItem.
+func (tr *RTree) GetID(id uint64) (pair.Pair, bool) {
+	key := idKey(id)
+	return tr.Get(key[:])
+}
+
+// DeleteByID is like DeleteByKey, but for items created with NewIDItem.
+func (tr *RTree) DeleteByID(id uint64) int {
+	key := idKey(id)
+	return tr.DeleteByKey(key[:])
+}
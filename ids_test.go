@@ -0,0 +1,44 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+func TestIDItems(t *testing.T) {
+	opts := *DefaultOptions
+	opts.KeyIndex = true
+	tr := New(&opts)
+	for i := 0; i < 300; i++ {
+		id := uint64(299-i) * 1000
+		tr.Insert(NewIDItem(id, geobin.Make2DPoint(float64(i), float64(i)).Binary()))
+	}
+	item, ok := tr.GetID(5000)
+	assert.True(t, ok)
+	id, ok := ItemID(item)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(5000), id)
+	_, ok = tr.GetID(5001)
+	assert.False(t, ok)
+	_, ok = ItemID(makePointPair2("key", 0, 0))
+	assert.False(t, ok)
+	// a plain key of the same size as an id key
+	_, ok = ItemID(makePointPair2("12345678901", 0, 0))
+	assert.False(t, ok)
+
+	var ids []uint64
+	tr.ScanSorted(func(item pair.Pair) bool {
+		id, _ := ItemID(item)
+		ids = append(ids, id)
+		return len(ids) < 3
+	})
+	assert.Equal(t, []uint64{0, 1000, 2000}, ids)
+
+	assert.Equal(t, 1, tr.DeleteByID(5000))
+	assert.Equal(t, 299, tr.Count())
+	_, ok = tr.GetID(5000)
+	assert.False(t, ok)
+}
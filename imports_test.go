@@ -0,0 +1,38 @@
+package rtree
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestNoRenderImports keeps the image and pinhole dependencies, which are
+// only needed by the viz package, out of the core tree packages.
+func TestNoRenderImports(t *testing.T) {
+	for _, dir := range []string{".", "2d", "3d"} {
+		files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range files {
+			if strings.HasSuffix(file, "_test.go") {
+				continue
+			}
+			f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, imp := range f.Imports {
+				path, _ := strconv.Unquote(imp.Path.Value)
+				if strings.HasPrefix(path, "image") ||
+					strings.HasSuffix(path, "/pinhole") ||
+					strings.HasSuffix(path, "/viz") {
+					t.Errorf("%s imports %s", file, path)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package rtree
+
+import (
+	"sync"
+
+	"github.com/tidwall/pair"
+)
+
+// Ingester inserts items into a tree from a channel. The items are batched,
+// and the rect of each item is computed by a pool of workers, which leaves
+// only the tree updates for the single goroutine that writes to the tree.
+//
+// The tree must not be used by anything else until the ingester is closed.
+// Items are not necessarily inserted in the order that they are sent.
+type Ingester struct {
+	tr      *RTree
+	items   chan pair.Pair
+	batches chan []pair.Pair
+	ready   chan []ingestItem
+	workers sync.WaitGroup
+	done    chan struct{}
+	err     error
+}
+
+// ingestItem is an item with its dims and rect.
+type ingestItem struct {
+	item     pair.Pair
+	dims     int
+	min, max [3]float64
+}
+
+// NewIngester starts an ingester with the number of workers, and the number
+// of items in each batch. Values less than one are treated as one.
+func NewIngester(tr *RTree, workers, batchSize int) *Ingester {
+	if workers < 1 {
+		workers = 1
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	in := &Ingester{
+		tr:      tr,
+		items:   make(chan pair.Pair, batchSize),
+		batches: make(chan []pair.Pair, workers),
+		ready:   make(chan []ingestItem, workers),
+		done:    make(chan struct{}),
+	}
+	go in.batch(batchSize)
+	in.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go in.prepare()
+	}
+	go func() {
+		in.workers.Wait()
+		close(in.ready)
+	}()
+	go in.write()
+	return in
+}
+
+// Items returns the channel that items are sent on.
+func (in *Ingester) Items() chan<- pair.Pair {
+	return in.items
+}
+
+// Insert sends an item to the ingester.
+func (in *Ingester) Insert(item pair.Pair) {
+	in.items <- item
+}
+
+func (in *Ingester) batch(batchSize int) {
+	batch := make([]pair.Pair, 0, batchSize)
+	for item := range in.items {
+		batch = append(batch, item)
+		if len(batch) == batchSize {
+			in.batches <- batch
+			batch = make([]pair.Pair, 0, batchSize)
+		}
+	}
+	if len(batch) > 0 {
+		in.batches <- batch
+	}
+	close(in.batches)
+}
+
+func (in *Ingester) prepare() {
+	defer in.workers.Done()
+	for batch := range in.batches {
+		items := make([]ingestItem, len(batch))
+		for i, item := range batch {
+			item = in.tr.own(item)
+			items[i].item = item
+			items[i].dims = in.tr.dims(item.Value())
+			items[i].min, items[i].max = in.tr.rect(item.Value())
+		}
+		in.ready <- items
+	}
+}
+
+func (in *Ingester) write() {
+	defer close(in.done)
+	for items := range in.ready {
+		if in.tr.frozen {
+			in.err = ErrFrozen
+			continue
+		}
+		for _, item := range items {
+			in.tr.insertRect(item.item, item.dims, item.min, item.max)
+		}
+	}
+}
+
+// Close waits for every item that was sent to be inserted. The items channel
+// is closed by Close, and must not be sent on afterwards. Returns ErrFrozen
+// if the tree was frozen, in which case the remaining items were dropped.
+func (in *Ingester) Close() error {
+	close(in.items)
+	<-in.done
+	return in.err
+}
@@ -0,0 +1,42 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestIngester(t *testing.T) {
+	opts := *DefaultOptions
+	opts.KeyIndex = true
+	tr := New(&opts)
+	var items []pair.Pair
+	for i := 0; i < 5000; i++ {
+		items = append(items, rand2DRect(), rand3DPoint())
+	}
+	in := NewIngester(tr, 4, 64)
+	for _, item := range items {
+		in.Insert(item)
+	}
+	assert.True(t, in.Close() == nil)
+	assert.Equal(t, len(items), tr.Count())
+	assert.Equal(t, len(items), tr.keys.len())
+
+	// same results as inserting one at a time
+	tr2 := New(nil)
+	for _, item := range items {
+		tr2.Insert(item)
+	}
+	box := makeBoundsPair3("", -45, -45, -45, 45, 45, 45)
+	var n1, n2 int
+	tr.Search(box, func(item pair.Pair) bool { n1++; return true })
+	tr2.Search(box, func(item pair.Pair) bool { n2++; return true })
+	assert.True(t, n1 > 0)
+	assert.Equal(t, n2, n1)
+
+	tr.Freeze()
+	in = NewIngester(tr, 1, 1)
+	in.Items() <- rand2DPoint()
+	assert.Equal(t, ErrFrozen, in.Close())
+}
@@ -0,0 +1,54 @@
+package rtree
+
+import (
+	"math"
+
+	"github.com/tidwall/pair"
+)
+
+// JoinWithin calls iter for every pair of items, a from the tree and b from
+// the other tree, with rects that are within maxDist of each other, along
+// with the distance between the rects in the coordinates of the trees. The
+// 2d and 3d trees of each are joined together, skipping the nodes that are
+// too far apart. As with KNN, the z is ignored between a 2d and a 3d item.
+// Returns false if iter returned false.
+func (tr *RTree) JoinWithin(other *RTree, maxDist float64,
+	iter func(a, b pair.Pair, dist float64) bool) bool {
+	if maxDist < 0 {
+		return true
+	}
+	if !tr.tr2.JoinWithin(other.tr2, maxDist, iter) ||
+		!tr.tr3.JoinWithin(other.tr3, maxDist, iter) {
+		return false
+	}
+	if !joinCross(tr, other, maxDist, iter) {
+		return false
+	}
+	return joinCross(other, tr, maxDist, func(b, a pair.Pair, dist float64) bool {
+		return iter(a, b, dist)
+	})
+}
+
+// joinCross joins the 2d items of t2 with the 3d items of t3 by searching
+// t3 around each of the 2d items.
+func joinCross(t2, t3 *RTree, maxDist float64,
+	iter func(a, b pair.Pair, dist float64) bool) bool {
+	if t2.isEmpty(2) || t3.isEmpty(3) {
+		return true
+	}
+	return t2.tr2.Scan(func(a pair.Pair) bool {
+		amin, amax := t2.rect(a.Value())
+		min := [3]float64{amin[0] - maxDist, amin[1] - maxDist, math.Inf(-1)}
+		max := [3]float64{amax[0] + maxDist, amax[1] + maxDist, math.Inf(+1)}
+		return t3.tr3.SearchRect(min, max, func(b pair.Pair) bool {
+			bmin, bmax := t3.rect(b.Value())
+			dx := math.Max(0, math.Max(amin[0]-bmax[0], bmin[0]-amax[0]))
+			dy := math.Max(0, math.Max(amin[1]-bmax[1], bmin[1]-amax[1]))
+			dist := math.Sqrt(dx*dx + dy*dy)
+			if dist > maxDist {
+				return true
+			}
+			return iter(a, b, dist)
+		})
+	})
+}
@@ -0,0 +1,46 @@
+package rtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestJoinWithin(t *testing.T) {
+	tr1, tr2 := New(nil), New(nil)
+	tr1.Insert(makePointPair2("a2", 0, 0))
+	tr1.Insert(makePointPair3("a3", 10, 0, 100))
+	tr2.Insert(makePointPair2("b2", 3, 4))
+	tr2.Insert(makePointPair3("b3", 0, 1, 50))
+	tr2.Insert(makeBoundsPair3("far", 50, 50, 0, 60, 60, 10))
+	tr2.Insert(makePointPair3("c3", 10, 2, 100))
+
+	got := make(map[string]float64)
+	assert.True(t, tr1.JoinWithin(tr2, 5, func(a, b pair.Pair, dist float64) bool {
+		got[string(a.Key())+"-"+string(b.Key())] = dist
+		return true
+	}))
+	assert.Equal(t, map[string]float64{
+		"a2-b2": 5,
+		"a2-b3": 1, // the z is ignored
+		"a3-c3": 2,
+	}, got)
+
+	// the other way around
+	got = make(map[string]float64)
+	tr2.JoinWithin(tr1, 5, func(a, b pair.Pair, dist float64) bool {
+		got[string(a.Key())+"-"+string(b.Key())] = dist
+		return true
+	})
+	assert.Equal(t, 3, len(got))
+	assert.Equal(t, 1.0, got["b3-a2"])
+
+	var n int
+	assert.False(t, tr1.JoinWithin(tr2, math.Inf(+1), func(a, b pair.Pair, dist float64) bool {
+		n++
+		return false
+	}))
+	assert.Equal(t, 1, n)
+}
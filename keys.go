@@ -0,0 +1,368 @@
+package rtree
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/tidwall/pair"
+)
+
+// keyMaxItems is the most entries in a node of the key index, and
+// keyMinItems is the fewest in a node other than the root.
+const (
+	keyMaxItems = 31
+	keyMinItems = keyMaxItems / 2
+)
+
+// keyIndex is an ordered index of every item by key, then by value. The
+// rects of the items, in the coordinates of the tree, are kept alongside.
+// It's a B-tree, so an insert or remove costs O(log n) no matter how many
+// items are in the index.
+type keyIndex struct {
+	root  *keyNode
+	count int
+	slab  []keyNode // nodes that were allocated up front
+}
+
+type keyEntry struct {
+	item pair.Pair
+	rect [2][3]float64
+}
+
+type keyNode struct {
+	leaf     bool
+	count    int
+	entries  [keyMaxItems]keyEntry
+	children [keyMaxItems + 1]*keyNode
+}
+
+// newKeyIndex returns an index with nodes allocated up front for the number
+// of items, which is enough for nodes that are only half full.
+func newKeyIndex(size int) *keyIndex {
+	idx := &keyIndex{}
+	if size > 0 {
+		n := size/keyMinItems + 1
+		idx.slab = make([]keyNode, n+n/keyMinItems+1)
+	}
+	idx.root = idx.newNode(true)
+	return idx
+}
+
+func (idx *keyIndex) newNode(leaf bool) *keyNode {
+	var n *keyNode
+	if len(idx.slab) > 0 {
+		n, idx.slab = &idx.slab[0], idx.slab[1:]
+	} else {
+		n = new(keyNode)
+	}
+	n.leaf = leaf
+	return n
+}
+
+// len returns the number of items in the index.
+func (idx *keyIndex) len() int {
+	return idx.count
+}
+
+// compareItems orders items by key, then by value. Items with the same key
+// and value are ordered by their address, which doesn't change, so every
+// item has its own place in the index.
+func compareItems(a, b pair.Pair) int {
+	if c := bytes.Compare(a.Key(), b.Key()); c != 0 {
+		return c
+	}
+	if c := bytes.Compare(a.Value(), b.Value()); c != 0 {
+		return c
+	}
+	pa, pb := uintptr(a.Pointer()), uintptr(b.Pointer())
+	if pa < pb {
+		return -1
+	}
+	if pa > pb {
+		return 1
+	}
+	return 0
+}
+
+// find returns the position of the first entry in the node that is not
+// less than the item, and whether it's the item.
+func (n *keyNode) find(item pair.Pair) (int, bool) {
+	i := sort.Search(n.count, func(i int) bool {
+		return compareItems(n.entries[i].item, item) >= 0
+	})
+	return i, i < n.count && compareItems(n.entries[i].item, item) == 0
+}
+
+func (idx *keyIndex) insert(item pair.Pair, min, max [3]float64) {
+	if idx.root.count == keyMaxItems {
+		root := idx.newNode(false)
+		root.children[0] = idx.root
+		idx.root = root
+		idx.splitChild(root, 0)
+	}
+	entry := keyEntry{item: item, rect: [2][3]float64{min, max}}
+	n := idx.root
+	for {
+		i, _ := n.find(item)
+		if n.leaf {
+			copy(n.entries[i+1:n.count+1], n.entries[i:n.count])
+			n.entries[i] = entry
+			n.count++
+			idx.count++
+			return
+		}
+		// full nodes are split on the way down, so there's always room for
+		// the entry that a split moves up
+		if n.children[i].count == keyMaxItems {
+			idx.splitChild(n, i)
+			if compareItems(item, n.entries[i].item) > 0 {
+				i++
+			}
+		}
+		n = n.children[i]
+	}
+}
+
+// splitChild splits the full child at i in two, and moves its middle entry
+// up into the node.
+func (idx *keyIndex) splitChild(n *keyNode, i int) {
+	child := n.children[i]
+	right := idx.newNode(child.leaf)
+	mid := keyMaxItems / 2
+	right.count = copy(right.entries[:], child.entries[mid+1:child.count])
+	copy(right.children[:], child.children[mid+1:child.count+1])
+	entry := child.entries[mid]
+	for j := mid; j < child.count; j++ {
+		child.entries[j] = keyEntry{}
+		child.children[j+1] = nil
+	}
+	child.count = mid
+	copy(n.entries[i+1:n.count+1], n.entries[i:n.count])
+	copy(n.children[i+2:n.count+2], n.children[i+1:n.count+1])
+	n.entries[i] = entry
+	n.children[i+1] = right
+	n.count++
+}
+
+func (idx *keyIndex) remove(item pair.Pair) {
+	if _, ok := idx.delete(idx.root, item, false); !ok {
+		return
+	}
+	idx.count--
+	if idx.root.count == 0 && !idx.root.leaf {
+		idx.root = idx.root.children[0]
+	}
+}
+
+// delete removes the item from under the node, or the last entry when max
+// is set, and returns the entry that was removed.
+func (idx *keyIndex) delete(n *keyNode, item pair.Pair, max bool) (keyEntry, bool) {
+	var i int
+	var found bool
+	if max {
+		i, found = n.count-1, n.leaf
+	} else {
+		i, found = n.find(item)
+	}
+	var entry keyEntry
+	if n.leaf {
+		if !found {
+			return entry, false
+		}
+		entry = n.entries[i]
+		copy(n.entries[i:n.count-1], n.entries[i+1:n.count])
+		n.count--
+		n.entries[n.count] = keyEntry{}
+		return entry, true
+	}
+	if max {
+		i = n.count
+		entry, _ = idx.delete(n.children[i], item, true)
+	} else if found {
+		// the entry is replaced by the last entry before it, which is in a
+		// leaf
+		entry = n.entries[i]
+		n.entries[i], _ = idx.delete(n.children[i], item, true)
+	} else {
+		var ok bool
+		if entry, ok = idx.delete(n.children[i], item, false); !ok {
+			return entry, false
+		}
+	}
+	if n.children[i].count < keyMinItems {
+		idx.rebalance(n, i)
+	}
+	return entry, true
+}
+
+// rebalance fills the child at i, which has too few entries, by merging it
+// with a sibling, or by moving an entry over from a sibling with more.
+func (idx *keyIndex) rebalance(n *keyNode, i int) {
+	if i == n.count {
+		i--
+	}
+	left, right := n.children[i], n.children[i+1]
+	switch {
+	case left.count+right.count < keyMaxItems:
+		left.entries[left.count] = n.entries[i]
+		copy(left.entries[left.count+1:], right.entries[:right.count])
+		copy(left.children[left.count+1:], right.children[:right.count+1])
+		left.count += right.count + 1
+		copy(n.entries[i:n.count-1], n.entries[i+1:n.count])
+		copy(n.children[i+1:n.count], n.children[i+2:n.count+1])
+		n.count--
+		n.entries[n.count] = keyEntry{}
+		n.children[n.count+1] = nil
+	case left.count > right.count:
+		copy(right.entries[1:right.count+1], right.entries[:right.count])
+		copy(right.children[1:right.count+2], right.children[:right.count+1])
+		right.entries[0] = n.entries[i]
+		right.children[0] = left.children[left.count]
+		right.count++
+		left.count--
+		n.entries[i] = left.entries[left.count]
+		left.entries[left.count] = keyEntry{}
+		left.children[left.count+1] = nil
+	default:
+		left.entries[left.count] = n.entries[i]
+		left.children[left.count+1] = right.children[0]
+		left.count++
+		n.entries[i] = right.entries[0]
+		copy(right.entries[:right.count-1], right.entries[1:right.count])
+		copy(right.children[:right.count], right.children[1:right.count+1])
+		right.count--
+		right.entries[right.count] = keyEntry{}
+		right.children[right.count+1] = nil
+	}
+}
+
+// ascend iterates over the entries in order, starting with the first that
+// has a key that is not less than start. A nil start is the first entry.
+// The entries may be changed in place, as long as their items aren't.
+func (idx *keyIndex) ascend(start []byte, iter func(entry *keyEntry) bool) bool {
+	return idx.root.ascend(start, iter)
+}
+
+func (n *keyNode) ascend(start []byte, iter func(entry *keyEntry) bool) bool {
+	var i int
+	if start != nil {
+		i = sort.Search(n.count, func(i int) bool {
+			return bytes.Compare(n.entries[i].item.Key(), start) >= 0
+		})
+	}
+	for ; i < n.count; i++ {
+		if !n.leaf {
+			if !n.children[i].ascend(start, iter) {
+				return false
+			}
+			// everything that follows is at or after the start
+			start = nil
+		}
+		if !iter(&n.entries[i]) {
+			return false
+		}
+	}
+	if !n.leaf {
+		return n.children[n.count].ascend(start, iter)
+	}
+	return true
+}
+
+// Get returns the first item, ordered by value, with the provided key. It's
+// fast when the tree was created with the KeyIndex option, reads only the
+// leaves that may have the key with the KeyBloom option, and otherwise
+// scans the entire tree.
+func (tr *RTree) Get(key []byte) (pair.Pair, bool) {
+	var found pair.Pair
+	var ok bool
+	if tr.keys == nil && tr.keyBloom {
+		tr.scanKey(key, func(item pair.Pair) bool {
+			if !ok || bytes.Compare(item.Value(), found.Value()) < 0 {
+				found, ok = item, true
+			}
+			return true
+		})
+		return found, ok
+	}
+	tr.ScanKeys(key, nil, func(item pair.Pair) bool {
+		if bytes.Equal(item.Key(), key) {
+			found, ok = item, true
+		}
+		return false
+	})
+	return found, ok
+}
+
+// BoundsOf returns the rect, in the coordinates of the tree, of the item
+// that Get returns for the key. With the KeyIndex option the rect is kept in
+// the index, so the value isn't read.
+func (tr *RTree) BoundsOf(key []byte) (min, max [3]float64, ok bool) {
+	if tr.keys == nil {
+		item, ok := tr.Get(key)
+		if !ok {
+			return min, max, false
+		}
+		min, max = tr.rect(item.Value())
+		return min, max, true
+	}
+	tr.keys.ascend(key, func(entry *keyEntry) bool {
+		if bytes.Equal(entry.item.Key(), key) {
+			min, max, ok = entry.rect[0], entry.rect[1], true
+		}
+		return false
+	})
+	return min, max, ok
+}
+
+// DeleteByKey removes every item with the provided key and returns the
+// number of items removed.
+func (tr *RTree) DeleteByKey(key []byte) int {
+	var items []pair.Pair
+	if tr.keys == nil && tr.keyBloom {
+		tr.scanKey(key, func(item pair.Pair) bool {
+			items = append(items, item)
+			return true
+		})
+	} else {
+		tr.ScanKeys(key, nil, func(item pair.Pair) bool {
+			if !bytes.Equal(item.Key(), key) {
+				return false
+			}
+			items = append(items, item)
+			return true
+		})
+	}
+	for _, item := range items {
+		tr.Remove(item)
+	}
+	return len(items)
+}
+
+// ScanKeys iterates over the items with keys that are greater than or equal
+// to start and less than end, in key order. A nil end iterates to the last
+// key.
+func (tr *RTree) ScanKeys(start, end []byte, iter func(item pair.Pair) bool) bool {
+	if tr.keys == nil {
+		return tr.ScanSorted(func(item pair.Pair) bool {
+			if bytes.Compare(item.Key(), start) < 0 {
+				return true
+			}
+			if end != nil && bytes.Compare(item.Key(), end) >= 0 {
+				return false
+			}
+			return iter(item)
+		})
+	}
+	var stopped bool
+	tr.keys.ascend(start, func(entry *keyEntry) bool {
+		if end != nil && bytes.Compare(entry.item.Key(), end) >= 0 {
+			return false
+		}
+		if !iter(entry.item) {
+			stopped = true
+			return false
+		}
+		return true
+	})
+	return !stopped
+}
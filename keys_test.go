@@ -0,0 +1,150 @@
+package rtree
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestKeyIndex(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		opts := *DefaultOptions
+		opts.KeyIndex = indexed
+		tr := New(&opts)
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("%03d", i)
+			tr.Insert(makePointPair2(key, float64(i), float64(i)))
+			if i%10 == 0 {
+				tr.Insert(makePointPair3(key, float64(i), float64(i), 1))
+			}
+		}
+		item, ok := tr.Get([]byte("050"))
+		assert.True(t, ok)
+		assert.Equal(t, "050", string(item.Key()))
+		_, ok = tr.Get([]byte("500"))
+		assert.False(t, ok)
+
+		var keys []string
+		tr.ScanKeys([]byte("010"), []byte("013"), func(item pair.Pair) bool {
+			keys = append(keys, string(item.Key()))
+			return true
+		})
+		assert.Equal(t, []string{"010", "010", "011", "012"}, keys)
+
+		assert.Equal(t, 2, tr.DeleteByKey([]byte("010")))
+		assert.Equal(t, 0, tr.DeleteByKey([]byte("010")))
+		assert.Equal(t, 108, tr.Count())
+		item, _ = tr.Get([]byte("020"))
+		tr.Remove(item)
+		keys = nil
+		tr.ScanSorted(func(item pair.Pair) bool {
+			keys = append(keys, string(item.Key()))
+			return true
+		})
+		assert.Equal(t, 107, len(keys))
+		assert.Equal(t, "019", keys[19])
+		assert.Equal(t, "020", keys[20])
+		assert.Equal(t, "021", keys[21])
+	}
+}
+
+func TestBoundsOf(t *testing.T) {
+	for _, keyIndex := range []bool{false, true} {
+		opts := *DefaultOptions
+		opts.KeyIndex = keyIndex
+		tr := New(&opts)
+		tr.Insert(makeBoundsPair2("a", 1, 2, 3, 4))
+		tr.Insert(makePointPair3("b", 5, 6, 7))
+		tr.Insert(makePointPair3("c", 8, 9, 10))
+		min, max, ok := tr.BoundsOf([]byte("a"))
+		assert.True(t, ok)
+		assert.Equal(t, [3]float64{1, 2, 0}, min)
+		assert.Equal(t, [3]float64{3, 4, 0}, max)
+		min, max, ok = tr.BoundsOf([]byte("b"))
+		assert.True(t, ok)
+		assert.Equal(t, [3]float64{5, 6, 7}, min)
+		assert.Equal(t, [3]float64{5, 6, 7}, max)
+		_, _, ok = tr.BoundsOf([]byte("bb"))
+		assert.False(t, ok)
+		tr.DeleteByKey([]byte("b"))
+		_, _, ok = tr.BoundsOf([]byte("b"))
+		assert.False(t, ok)
+		min, _, ok = tr.BoundsOf([]byte("c"))
+		assert.True(t, ok)
+		assert.Equal(t, [3]float64{8, 9, 10}, min)
+	}
+}
+
+func TestKeyIndexRandom(t *testing.T) {
+	tr := New(&Options{MaxEntries: 9, KeyIndex: true})
+	// the keys repeat, so the values decide the order of most items
+	var items []pair.Pair
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprintf("%02d", rand.Intn(50))
+		items = append(items, makePointPair2(key, rand.Float64(), rand.Float64()))
+	}
+	check := func(want []pair.Pair) {
+		want = append([]pair.Pair(nil), want...)
+		sort.Slice(want, func(i, j int) bool {
+			return keyLess(want[i], want[j])
+		})
+		var got []pair.Pair
+		tr.ScanKeys(nil, nil, func(item pair.Pair) bool {
+			got = append(got, item)
+			return true
+		})
+		assert.Equal(t, len(want), len(got))
+		for i := range want {
+			assert.True(t, want[i] == got[i])
+		}
+		assert.True(t, tr.Check() == nil)
+	}
+	for _, i := range rand.Perm(len(items)) {
+		tr.Insert(items[i])
+	}
+	check(items)
+	rand.Shuffle(len(items), func(i, j int) {
+		items[i], items[j] = items[j], items[i]
+	})
+	for _, item := range items[:3000] {
+		tr.Remove(item)
+	}
+	items = items[3000:]
+	check(items)
+	for i := 0; i < 2000; i++ {
+		item := makePointPair2(fmt.Sprintf("%02d", rand.Intn(50)), rand.Float64(), rand.Float64())
+		tr.Insert(item)
+		items = append(items, item)
+	}
+	check(items)
+	for _, item := range items {
+		tr.Remove(item)
+	}
+	check(nil)
+}
+
+func TestExpectedItems(t *testing.T) {
+	idx := newKeyIndex(1000)
+	var items []pair.Pair
+	for i := 0; i < 1000; i++ {
+		items = append(items, rand2DPoint())
+	}
+	// the nodes for the items were allocated up front
+	var n int
+	allocs := testing.AllocsPerRun(len(items)-1, func() {
+		idx.insert(items[n], [3]float64{}, [3]float64{})
+		n++
+	})
+	assert.Equal(t, 0.0, allocs)
+	assert.Equal(t, 1000, idx.len())
+
+	tr := New(&Options{MaxEntries: 9, KeyIndex: true, ExpectedItems: 1000})
+	for i := 0; i < 1000; i++ {
+		tr.Insert(rand2DPoint())
+	}
+	assert.Equal(t, 1000, tr.Count())
+}
@@ -0,0 +1,50 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestKNNDebug(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 1000; i++ {
+		tr.Insert(rand2DPoint())
+		tr.Insert(rand3DPoint())
+	}
+	pos := makePointPair3("", 10, 20, 5)
+	var expect []float64
+	tr.KNN(pos, func(item pair.Pair, dist float64) bool {
+		expect = append(expect, dist)
+		return len(expect) < 100
+	})
+	var got []float64
+	var visits [4]int
+	var returned bool
+	tr.KNNDebug(pos, func(item pair.Pair, dist float64) bool {
+		got = append(got, dist)
+		return len(got) < 100
+	}, func(dims, level int, min, max [3]float64, dist float64) {
+		assert.False(t, returned)
+		assert.True(t, level >= 1)
+		if dims == 2 {
+			assert.Equal(t, 0.0, min[2]+max[2])
+		}
+		visits[dims]++
+	})
+	returned = true
+	assert.Equal(t, expect, got)
+	assert.True(t, visits[2] > 0 && visits[3] > 0)
+
+	// only 3d
+	tr = New(nil)
+	tr.Insert(rand3DPoint())
+	visits = [4]int{}
+	tr.KNNDebug(pos, func(item pair.Pair, dist float64) bool {
+		return true
+	}, func(dims, level int, min, max [3]float64, dist float64) {
+		visits[dims]++
+	})
+	assert.Equal(t, [4]int{0, 0, 0, 1}, visits)
+}
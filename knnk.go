@@ -0,0 +1,34 @@
+package rtree
+
+import "github.com/tidwall/pair"
+
+// KNNK is like ScanNearest for when the number of items, k, is known
+// upfront. Each tree prunes the nodes and items that are farther than its
+// k-th nearest item, and the search stops after k items. With DedupKeys the
+// items of a key that's already passed are not counted in k.
+func (tr *RTree) KNNK(x, y, z float64, k int, iter func(item pair.Pair, dist float64) bool) bool {
+	if k <= 0 || (tr.isEmpty(2) && tr.isEmpty(3)) {
+		return true
+	}
+	if tr.dedupKeys {
+		// the duplicates may push items of other keys out of the k nearest
+		// of a tree, so there's no bound to prune with
+		var stopped bool
+		tr.knnPoint(x, y, z, dedupKNNIter(func(item pair.Pair, dist float64) bool {
+			if !iter(item, dist) {
+				stopped = true
+				return false
+			}
+			k--
+			return k > 0
+		}))
+		return !stopped
+	}
+	items, dists := tr.NewQueryContext().KNN(x, y, z, k)
+	for i, item := range items {
+		if !iter(item, dists[i]) {
+			return false
+		}
+	}
+	return true
+}
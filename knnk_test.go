@@ -0,0 +1,55 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestKNNK(t *testing.T) {
+	tr := New(nil)
+	assert.True(t, tr.KNNK(0, 0, 0, 5, func(item pair.Pair, dist float64) bool {
+		t.Fatal("empty tree")
+		return true
+	}))
+	for i := 0; i < 1000; i++ {
+		tr.Insert(rand2DPoint())
+		tr.Insert(rand3DPoint())
+	}
+	for _, k := range []int{0, 1, 10, 500, 3000} {
+		var expect []float64
+		tr.ScanNearest(10, 20, 5, func(item pair.Pair, dist float64) bool {
+			if len(expect) == k {
+				return false
+			}
+			expect = append(expect, dist)
+			return true
+		})
+		var got []float64
+		assert.True(t, tr.KNNK(10, 20, 5, k, func(item pair.Pair, dist float64) bool {
+			got = append(got, dist)
+			return true
+		}))
+		assert.Equal(t, expect, got)
+	}
+	var n int
+	assert.False(t, tr.KNNK(10, 20, 5, 10, func(item pair.Pair, dist float64) bool {
+		n++
+		return n < 3
+	}))
+	assert.Equal(t, 3, n)
+
+	// duplicate keys are not counted
+	tr = New(&Options{DedupKeys: true})
+	for i := 0; i < 5; i++ {
+		tr.Insert(makePointPair2("a", float64(i), 0))
+	}
+	tr.Insert(makePointPair2("b", 10, 0))
+	var keys []string
+	assert.True(t, tr.KNNK(0, 0, 0, 2, func(item pair.Pair, dist float64) bool {
+		keys = append(keys, string(item.Key()))
+		return true
+	}))
+	assert.Equal(t, []string{"a", "b"}, keys)
+}
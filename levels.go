@@ -0,0 +1,14 @@
+package rtree
+
+// BoundsAtLevel returns the bboxes of the nodes at a level in the 2d tree,
+// followed by the 3d tree, where leaves are level one. The 2d bboxes have a
+// z of zero. Each bbox is the min followed by the max.
+func (tr *RTree) BoundsAtLevel(level int) [][2][3]float64 {
+	var bounds [][2][3]float64
+	for _, b := range tr.tr2.BoundsAtLevel(level) {
+		bounds = append(bounds, [2][3]float64{
+			{b[0][0], b[0][1], 0}, {b[1][0], b[1][1], 0},
+		})
+	}
+	return append(bounds, tr.tr3.BoundsAtLevel(level)...)
+}
@@ -0,0 +1,59 @@
+package rtree
+
+import (
+	"math"
+
+	"github.com/tidwall/pair"
+)
+
+// SearchLimited is like Search, but stops after visiting maxNodes nodes
+// between the 2d and 3d trees, which puts a bound on the work of a query.
+// Returns false if the search was cut short by the limit, in which case
+// some of the items were not returned.
+func (tr *RTree) SearchLimited(box pair.Pair, maxNodes int,
+	iter func(item pair.Pair) bool) (complete bool) {
+	if tr.dedupKeys {
+		iter = dedupIter(iter)
+	}
+	min, max := tr.rect(box.Value())
+	min2, max2 := [2]float64{min[0], min[1]}, [2]float64{max[0], max[1]}
+	if tr.dims(box.Value()) == 2 {
+		min[2], max[2] = math.Inf(-1), math.Inf(+1)
+	}
+	var stopped bool
+	limited := func(item pair.Pair) bool {
+		stopped = !iter(item)
+		return !stopped
+	}
+	budget := maxNodes
+	if tr.coversZero(min[2], max[2]) {
+		if !tr.tr2.SearchRectLimited(min2, max2, &budget, limited) {
+			return false
+		}
+		if stopped {
+			return true
+		}
+	}
+	return tr.tr3.SearchRectLimited(min, max, &budget, limited)
+}
+
+// SearchLimit returns the items that Search would, after skipping the first
+// offset items, and stops once it has limit items. The items are in the
+// order of the tree, so the pages are only consistent while the tree isn't
+// changed. ScanFrom pages through all of the items in a way that survives
+// changes.
+func (tr *RTree) SearchLimit(box pair.Pair, offset, limit int) []pair.Pair {
+	if limit <= 0 {
+		return nil
+	}
+	var items []pair.Pair
+	tr.Search(box, func(item pair.Pair) bool {
+		if offset > 0 {
+			offset--
+			return true
+		}
+		items = append(items, item)
+		return len(items) < limit
+	})
+	return items
+}
@@ -0,0 +1,45 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestSearchLimited(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 2000; i++ {
+		tr.Insert(rand2DPoint())
+		tr.Insert(rand3DPoint())
+	}
+	box := makeBoundsPair2("", -180, -90, 180, 90)
+	var n int
+	count := func(item pair.Pair) bool {
+		n++
+		return true
+	}
+	assert.True(t, tr.SearchLimited(box, 1<<30, count))
+	assert.Equal(t, tr.Count(), n)
+	n = 0
+	assert.False(t, tr.SearchLimited(box, 20, count))
+	assert.True(t, n > 0 && n < tr.Count2D())
+}
+
+func TestSearchLimit(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 20; i++ {
+		tr.Insert(makePointPair2("", float64(i), 0))
+		tr.Insert(makePointPair3("", float64(i), 0, 0))
+	}
+	box := makeBoundsPair2("", 0, 0, 9, 0)
+	var all []pair.Pair
+	tr.Search(box, func(item pair.Pair) bool {
+		all = append(all, item)
+		return true
+	})
+	assert.Equal(t, 20, len(all))
+	assert.Equal(t, all[5:12], tr.SearchLimit(box, 5, 7))
+	assert.Equal(t, all[15:], tr.SearchLimit(box, 15, 10))
+	assert.Equal(t, 0, len(tr.SearchLimit(box, 20, 10)))
+}
@@ -0,0 +1,38 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+	"github.com/tidwall/pair-rtree/rtreetest"
+)
+
+func TestLoad(t *testing.T) {
+	var items []pair.Pair
+	for i := 0; i < 3000; i++ {
+		items = append(items, rand2DPoint(), rand2DRect(), rand3DPoint(), rand3DRect())
+	}
+	tr := New(nil)
+	tr.Load(items[:6000])
+	tr.Load(items[6000:])
+	assert.Equal(t, uint64(len(items)), tr.Stats().Inserts)
+	assert.Equal(t, 6000, tr.Count2D())
+	assert.Equal(t, 6000, tr.Count3D())
+	assert.Nil(t, tr.Check())
+	var got []pair.Pair
+	tr.Scan(func(item pair.Pair) bool {
+		got = append(got, item)
+		return true
+	})
+	assert.True(t, rtreetest.SameItems(items, got))
+	for _, percent := range []float64{0.01, 0.1, 0.5, 1} {
+		testSearch(t, tr, items, percent, true)
+	}
+
+	// only 2d
+	tr = New(nil)
+	tr.Load([]pair.Pair{makePointPair2("a", 1, 2), makePointPair2("b", 3, 4)})
+	assert.Equal(t, 2, tr.Count2D())
+	assert.Equal(t, 0, tr.Count3D())
+}
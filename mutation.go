@@ -0,0 +1,145 @@
+package rtree
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/tidwall/pair"
+)
+
+var (
+	ErrOutOfSequence = errors.New("mutation out of sequence")
+	ErrUnknownOp     = errors.New("unknown mutation op")
+)
+
+type Op int
+
+const (
+	OpInsert Op = iota
+	OpRemove
+)
+
+func (op Op) String() string {
+	switch op {
+	case OpInsert:
+		return "insert"
+	case OpRemove:
+		return "remove"
+	}
+	return "unknown"
+}
+
+// Mutation is a single Insert or Remove operation. The Seq is assigned by
+// the tree and increases by one for every mutation.
+type Mutation struct {
+	Seq  uint64
+	Op   Op
+	Item pair.Pair
+}
+
+// feedBuffer is the channel buffer size for each subscriber.
+const feedBuffer = 1024
+
+// Subscribe returns a channel that receives every subsequent mutation, in
+// order. Mutations are sent synchronously, so a subscriber that stops
+// reading will eventually block the writer. Use Unsubscribe to close the
+// channel.
+func (tr *RTree) Subscribe() <-chan Mutation {
+	ch := make(chan Mutation, feedBuffer)
+	tr.subs = append(tr.subs, ch)
+	return ch
+}
+
+// Unsubscribe removes and closes a channel returned by Subscribe.
+func (tr *RTree) Unsubscribe(ch <-chan Mutation) {
+	for i, sub := range tr.subs {
+		if (<-chan Mutation)(sub) == ch {
+			close(sub)
+			tr.subs[i] = tr.subs[len(tr.subs)-1]
+			tr.subs[len(tr.subs)-1] = nil
+			tr.subs = tr.subs[:len(tr.subs)-1]
+			return
+		}
+	}
+}
+
+// Seq returns the sequence number of the last mutation.
+func (tr *RTree) Seq() uint64 {
+	return tr.seq
+}
+
+// Apply replays a mutation from another tree's feed. Mutations that have
+// already been applied, by sequence number, are ignored, which makes it safe
+// to replay a stream from an earlier position. A mutation that skips ahead
+// returns ErrOutOfSequence, and a frozen tree returns ErrFrozen. An insert
+// returns the errors of TryInsert, and a remove of an item that isn't in the
+// tree returns ErrNotFound. A mutation that fails to apply is skipped, and
+// the sequence number still moves past it, so the mutations that follow
+// can be applied. The skipped mutation leaves a gap in the feed of this tree.
+func (tr *RTree) Apply(m Mutation) error {
+	if tr.frozen {
+		return ErrFrozen
+	}
+	if m.Seq <= tr.seq {
+		return nil
+	}
+	if m.Seq != tr.seq+1 {
+		return ErrOutOfSequence
+	}
+	var err error
+	switch m.Op {
+	case OpInsert:
+		err = tr.TryInsert(m.Item)
+	case OpRemove:
+		// the item may have been decoded from a stream, so find the stored
+		// item with the same key and value.
+		item, ok := tr.Find(m.Item)
+		if ok {
+			err = tr.TryRemove(item)
+		} else {
+			err = ErrNotFound
+		}
+	default:
+		err = ErrUnknownOp
+	}
+	if err != nil {
+		tr.seq = m.Seq
+	}
+	return err
+}
+
+// mutated is called after every Insert or Remove.
+func (tr *RTree) mutated(op Op, item pair.Pair) {
+	tr.seq++
+	if op == OpInsert {
+		atomic.AddUint64(&tr.stats.inserts, 1)
+		tr.fingerprint += tr.itemHash(item)
+	} else {
+		atomic.AddUint64(&tr.stats.removes, 1)
+		tr.fingerprint -= tr.itemHash(item)
+	}
+	if tr.keys != nil {
+		if op == OpInsert {
+			min, max := tr.rect(item.Value())
+			tr.keys.insert(item, min, max)
+		} else {
+			tr.keys.remove(item)
+		}
+	}
+	if op == OpRemove && tr.tags != nil {
+		delete(tr.tags, item)
+	}
+	if len(tr.watchers) > 0 {
+		if op == OpInsert {
+			tr.notify(Enter, item)
+		} else {
+			tr.notify(Leave, item)
+		}
+	}
+	if tr.tileHook != nil {
+		tr.notifyTiles(op, item)
+	}
+	for _, sub := range tr.subs {
+		sub <- Mutation{Seq: tr.seq, Op: op, Item: item}
+	}
+}
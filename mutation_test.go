@@ -0,0 +1,79 @@
+package rtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestSubscribe(t *testing.T) {
+	tr := New(nil)
+	ch := tr.Subscribe()
+	p1 := makePointPair2("p1", 5, 5)
+	p2 := makePointPair3("p2", 1, 1, 1)
+	tr.Insert(p1)
+	tr.Insert(p2)
+	tr.Remove(p1)
+	assert.Equal(t, uint64(3), tr.Seq())
+	assert.Equal(t, Mutation{1, OpInsert, p1}, <-ch)
+	assert.Equal(t, Mutation{2, OpInsert, p2}, <-ch)
+	assert.Equal(t, Mutation{3, OpRemove, p1}, <-ch)
+	tr.Unsubscribe(ch)
+	_, ok := <-ch
+	assert.Equal(t, false, ok)
+	tr.Insert(p1)
+	assert.Equal(t, uint64(4), tr.Seq())
+}
+
+func TestApply(t *testing.T) {
+	leader := New(nil)
+	ch := leader.Subscribe()
+	var objs = []pair.Pair{
+		makePointPair2("p1", 5, 5),
+		makePointPair3("p2", 1, 1, 1),
+		makeBoundsPair2("p3", 1, 1, 2, 2),
+	}
+	for _, obj := range objs {
+		leader.Insert(obj)
+	}
+	leader.Remove(objs[0])
+	var ms []Mutation
+	for len(ch) > 0 {
+		// copy the items as if they were sent over the network
+		m := <-ch
+		m.Item = pair.New(m.Item.Key(), m.Item.Value())
+		ms = append(ms, m)
+	}
+	follower := New(nil)
+	assert.Equal(t, ErrOutOfSequence, follower.Apply(ms[1]))
+	for _, m := range ms[:2] {
+		assert.Equal(t, nil, follower.Apply(m))
+	}
+	// replay from the start, the first two are ignored
+	for _, m := range ms {
+		assert.Equal(t, nil, follower.Apply(m))
+	}
+	assert.Equal(t, leader.Seq(), follower.Seq())
+	assert.Equal(t, leader.Count(), follower.Count())
+	assert.Equal(t, ErrUnknownOp, follower.Apply(Mutation{Seq: follower.Seq() + 1, Op: -1}))
+}
+
+func TestApplyFailed(t *testing.T) {
+	follower := New(nil)
+	p1 := makePointPair2("p1", 5, 5)
+	// a remove of an item that was never inserted, and an insert with a bad
+	// rect, are skipped, and the mutations after them still apply
+	assert.Equal(t, ErrNotFound, follower.Apply(Mutation{Seq: 1, Op: OpRemove, Item: p1}))
+	assert.Equal(t, uint64(1), follower.Seq())
+	bad := makePointPair2("bad", math.NaN(), 5)
+	assert.Equal(t, ErrInvalidRect, follower.Apply(Mutation{Seq: 2, Op: OpInsert, Item: bad}))
+	assert.Equal(t, uint64(2), follower.Seq())
+	assert.Equal(t, nil, follower.Apply(Mutation{Seq: 3, Op: OpInsert, Item: p1}))
+	assert.Equal(t, uint64(3), follower.Seq())
+	assert.Equal(t, 1, follower.Count())
+	// a replay of the failed mutation is ignored
+	assert.Equal(t, nil, follower.Apply(Mutation{Seq: 1, Op: OpRemove, Item: p1}))
+	assert.Equal(t, 1, follower.Count())
+}
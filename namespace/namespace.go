@@ -0,0 +1,220 @@
+// Package namespace keeps many independent trees, one for each namespace,
+// such as the tenants of a service, which share the same options.
+//
+// The trees are created when a namespace is first used. Save writes every
+// tree to a single stream, which Load reads back. The stream is a magic
+// followed by uvarints and bytes:
+//
+//	magic [8]byte, namespaces
+//	namespace: namelen, name, items
+//	item:      keylen, key, valuelen, value
+package namespace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+const magic = "RTNS1\x00\x00\x00"
+
+// maxBytes is the longest name, key, or value that Load reads, and
+// bufferBytes is the longest that's allocated before it's read, so a
+// corrupt length can't allocate more than the stream holds.
+const (
+	maxBytes    = 1 << 30
+	bufferBytes = 1 << 16
+)
+
+// ErrInvalidStream is returned by Load when the stream was not written by
+// Save.
+var ErrInvalidStream = errors.New("invalid stream")
+
+// Namespaces is a set of trees by name. The set is safe for concurrent use,
+// but each tree must be guarded by the caller as any other tree.
+type Namespaces struct {
+	opts  *rtree.Options
+	mu    sync.RWMutex
+	trees map[string]*rtree.RTree
+}
+
+// New returns an empty set of namespaces, which creates its trees with the
+// options.
+func New(opts *rtree.Options) *Namespaces {
+	return &Namespaces{opts: opts, trees: make(map[string]*rtree.RTree)}
+}
+
+// Tree returns the tree for the namespace, creating it when it's not found.
+func (ns *Namespaces) Tree(name string) *rtree.RTree {
+	ns.mu.RLock()
+	tr, ok := ns.trees[name]
+	ns.mu.RUnlock()
+	if ok {
+		return tr
+	}
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if tr, ok := ns.trees[name]; ok {
+		return tr
+	}
+	tr = rtree.New(ns.opts)
+	ns.trees[name] = tr
+	return tr
+}
+
+// Get returns the tree for the namespace, without creating it.
+func (ns *Namespaces) Get(name string) (*rtree.RTree, bool) {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+	tr, ok := ns.trees[name]
+	return tr, ok
+}
+
+// Delete removes the namespace and its tree. Returns false if it's not
+// found.
+func (ns *Namespaces) Delete(name string) bool {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if _, ok := ns.trees[name]; !ok {
+		return false
+	}
+	delete(ns.trees, name)
+	return true
+}
+
+// Names returns the namespaces in order.
+func (ns *Namespaces) Names() []string {
+	ns.mu.RLock()
+	names := make([]string, 0, len(ns.trees))
+	for name := range ns.trees {
+		names = append(names, name)
+	}
+	ns.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// Stats returns the stats of the tree of each namespace.
+func (ns *Namespaces) Stats() map[string]rtree.Stats {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+	stats := make(map[string]rtree.Stats, len(ns.trees))
+	for name, tr := range ns.trees {
+		stats[name] = tr.Stats()
+	}
+	return stats
+}
+
+// Save writes the items of every namespace, in the order of Names. The
+// trees must not change during the save.
+func (ns *Namespaces) Save(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var buf [binary.MaxVarintLen64]byte
+	putUvarint := func(x uint64) {
+		bw.Write(buf[:binary.PutUvarint(buf[:], x)])
+	}
+	putBytes := func(b []byte) {
+		putUvarint(uint64(len(b)))
+		bw.Write(b)
+	}
+	names := ns.Names()
+	bw.WriteString(magic)
+	putUvarint(uint64(len(names)))
+	for _, name := range names {
+		tr, _ := ns.Get(name)
+		putBytes([]byte(name))
+		putUvarint(uint64(tr.Count()))
+		tr.Scan(func(item pair.Pair) bool {
+			putBytes(item.Key())
+			putBytes(item.Value())
+			return true
+		})
+	}
+	return bw.Flush()
+}
+
+// Load reads the namespaces that were written by Save, and loads their
+// items into the trees of the same namespaces, creating them as needed.
+// Nothing is loaded when the stream is not valid.
+func (ns *Namespaces) Load(r io.Reader) error {
+	br := bufio.NewReader(r)
+	var hdr [len(magic)]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return streamErr(err)
+	}
+	if string(hdr[:]) != magic {
+		return ErrInvalidStream
+	}
+	readBytes := func() ([]byte, error) {
+		n, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, err
+		}
+		if n > maxBytes {
+			return nil, ErrInvalidStream
+		}
+		if n > bufferBytes {
+			var buf bytes.Buffer
+			if _, err := io.CopyN(&buf, br, int64(n)); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(br, b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return streamErr(err)
+	}
+	loaded := make(map[string][]pair.Pair)
+	var names []string
+	for i := uint64(0); i < count; i++ {
+		name, err := readBytes()
+		if err != nil {
+			return streamErr(err)
+		}
+		n, err := binary.ReadUvarint(br)
+		if err != nil {
+			return streamErr(err)
+		}
+		var items []pair.Pair
+		for j := uint64(0); j < n; j++ {
+			key, err := readBytes()
+			if err != nil {
+				return streamErr(err)
+			}
+			value, err := readBytes()
+			if err != nil {
+				return streamErr(err)
+			}
+			items = append(items, pair.New(key, value))
+		}
+		if _, ok := loaded[string(name)]; !ok {
+			names = append(names, string(name))
+		}
+		loaded[string(name)] = append(loaded[string(name)], items...)
+	}
+	for _, name := range names {
+		ns.Tree(name).Load(loaded[name])
+	}
+	return nil
+}
+
+// streamErr returns ErrInvalidStream for a stream that ends early.
+func streamErr(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrInvalidStream
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package namespace
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+func TestNamespaces(t *testing.T) {
+	ns := New(&rtree.Options{MaxEntries: 16, KeyIndex: true})
+	_, ok := ns.Get("a")
+	assert.False(t, ok)
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprint("tenant", i%3)
+		ns.Tree(name).Insert(pair.New([]byte(fmt.Sprint(i)),
+			geobin.Make2DPoint(float64(i), float64(i)).Binary()))
+	}
+	ns.Tree("tenant3").Insert(pair.New([]byte("z"),
+		geobin.Make3DPoint(1, 2, 3).Binary()))
+	assert.Equal(t, []string{"tenant0", "tenant1", "tenant2", "tenant3"}, ns.Names())
+	tr, ok := ns.Get("tenant0")
+	assert.True(t, ok)
+	assert.Equal(t, 34, tr.Count())
+	assert.Equal(t, tr, ns.Tree("tenant0"))
+	stats := ns.Stats()
+	assert.Equal(t, uint64(33), stats["tenant1"].Inserts)
+	assert.Equal(t, uint64(1), stats["tenant3"].Inserts)
+
+	var buf bytes.Buffer
+	assert.NoError(t, ns.Save(&buf))
+	data := buf.Bytes()
+
+	ns2 := New(&rtree.Options{MaxEntries: 16, KeyIndex: true})
+	assert.NoError(t, ns2.Load(bytes.NewReader(data)))
+	assert.Equal(t, ns.Names(), ns2.Names())
+	for _, name := range ns.Names() {
+		tr1, _ := ns.Get(name)
+		tr2, _ := ns2.Get(name)
+		assert.Equal(t, tr1.Count(), tr2.Count())
+		assert.Equal(t, tr1.Fingerprint(), tr2.Fingerprint())
+	}
+	item, ok := ns2.Tree("tenant3").Get([]byte("z"))
+	assert.True(t, ok)
+	assert.Equal(t, []byte("z"), item.Key())
+
+	assert.True(t, ns2.Delete("tenant3"))
+	assert.False(t, ns2.Delete("tenant3"))
+	assert.Equal(t, 3, len(ns2.Names()))
+
+	assert.Equal(t, ErrInvalidStream, New(nil).Load(bytes.NewReader(data[:len(data)-1])))
+	assert.Equal(t, ErrInvalidStream, New(nil).Load(bytes.NewReader([]byte("nope"))))
+	ns3 := New(nil)
+	assert.Error(t, ns3.Load(bytes.NewReader(data[:20])))
+	assert.Equal(t, 0, len(ns3.Names()))
+}
+
+func TestLoadCorruptLength(t *testing.T) {
+	stream := func(lengths ...uint64) []byte {
+		b := []byte(magic)
+		b = binary.AppendUvarint(b, 1)
+		for _, n := range lengths {
+			b = binary.AppendUvarint(b, n)
+		}
+		return append(b, "abc"...)
+	}
+	// a name that's longer than any stream, and longer than what's left
+	assert.Equal(t, ErrInvalidStream, New(nil).Load(bytes.NewReader(stream(1<<62))))
+	assert.Equal(t, ErrInvalidStream, New(nil).Load(bytes.NewReader(stream(1<<20))))
+	// a value that's longer than what's left
+	data := stream(1)
+	data = data[:len(data)-2]
+	data = binary.AppendUvarint(data, 1)
+	data = binary.AppendUvarint(data, 1)
+	data = append(data, 'k')
+	data = binary.AppendUvarint(data, 1<<40)
+	assert.Equal(t, ErrInvalidStream, New(nil).Load(bytes.NewReader(data)))
+
+	// a key that's longer than the buffer is still read
+	ns := New(nil)
+	key := bytes.Repeat([]byte("k"), bufferBytes+1)
+	ns.Tree("a").Insert(pair.New(key, geobin.Make2DPoint(1, 2).Binary()))
+	var buf bytes.Buffer
+	assert.NoError(t, ns.Save(&buf))
+	ns2 := New(nil)
+	assert.NoError(t, ns2.Load(&buf))
+	_, ok := ns2.Tree("a").Get(key)
+	assert.True(t, ok)
+}
@@ -0,0 +1,18 @@
+package rtree
+
+import "github.com/tidwall/pair"
+
+// ScanNearest iterates over every item in order of distance from the point,
+// which is in the coordinates of the tree, like KNN without a position item.
+// The items are read as the nodes are opened, so only the queue of the
+// nodes and items that are not yet passed to iter is held in memory, which
+// suits filling a screen outward from a point.
+func (tr *RTree) ScanNearest(x, y, z float64, iter func(item pair.Pair, dist float64) bool) bool {
+	if tr.isEmpty(2) && tr.isEmpty(3) {
+		return true
+	}
+	if tr.dedupKeys {
+		iter = dedupKNNIter(iter)
+	}
+	return tr.knnPoint(x, y, z, iter)
+}
@@ -0,0 +1,36 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestScanNearest(t *testing.T) {
+	tr := New(nil)
+	assert.True(t, tr.ScanNearest(0, 0, 0, func(item pair.Pair, dist float64) bool {
+		t.Fatal("empty tree")
+		return true
+	}))
+	for i := 0; i < 1000; i++ {
+		tr.Insert(rand2DPoint())
+		tr.Insert(rand3DPoint())
+	}
+	var n int
+	last := -1.0
+	tr.ScanNearest(10, 20, 5, func(item pair.Pair, dist float64) bool {
+		assert.True(t, dist >= last)
+		last = dist
+		n++
+		return true
+	})
+	assert.Equal(t, tr.Count(), n)
+
+	n = 0
+	tr.ScanNearest(10, 20, 5, func(item pair.Pair, dist float64) bool {
+		n++
+		return n < 10
+	})
+	assert.Equal(t, 10, n)
+}
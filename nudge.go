@@ -0,0 +1,73 @@
+package rtree
+
+import (
+	"github.com/tidwall/pair"
+	rtree2 "github.com/tidwall/pair-rtree/2d"
+	rtree3 "github.com/tidwall/pair-rtree/3d"
+)
+
+// Update replaces an item with a new item for the same object.
+type Update struct {
+	Item, NewItem pair.Pair
+}
+
+// Nudge replaces an item with a new item for the same object, such as one
+// that has moved a little, which is much faster than a Remove and Insert
+// when the leaf that holds the item still covers the new item. Subscribers
+// and watchers see a remove of the item and an insert of the new item.
+// Returns false, and changes nothing, if the item isn't in the tree.
+func (tr *RTree) Nudge(item, newItem pair.Pair) bool {
+	return tr.UpdateAll([]Update{{item, newItem}}) == 1
+}
+
+// UpdateAll is like Nudge for many items, such as the objects that moved
+// in one tick of a simulation. The updates are applied to the 2d and 3d
+// trees in batches, and an item that changes between 2d and 3d is removed
+// and its new item inserted. Returns the number of updates with items that
+// were in the tree, the rest are skipped.
+func (tr *RTree) UpdateAll(updates []Update) int {
+	tr.checkFrozen()
+	var updates2 []rtree2.Update
+	var updates3 []rtree3.Update
+	var applied []Update
+	var n int
+	for _, u := range updates {
+		item, ok := tr.owned(u.Item)
+		if !ok {
+			continue
+		}
+		newItem := tr.own(u.NewItem)
+		dims := tr.dims(newItem.Value())
+		switch {
+		case dims != tr.dims(item.Value()):
+			if tr.remove(item) {
+				min, max := tr.rect(newItem.Value())
+				tr.insertRect(newItem, dims, min, max)
+				n++
+			}
+			continue
+		case dims == 2:
+			updates2 = append(updates2, rtree2.Update{Item: item, NewItem: newItem})
+		default:
+			updates3 = append(updates3, rtree3.Update{Item: item, NewItem: newItem})
+		}
+		applied = append(applied, Update{item, newItem})
+	}
+	tr.beginWrite()
+	missing := make(map[pair.Pair]bool)
+	for _, u := range tr.tr2.UpdateAll(updates2) {
+		missing[u.Item] = true
+	}
+	for _, u := range tr.tr3.UpdateAll(updates3) {
+		missing[u.Item] = true
+	}
+	tr.endWrite()
+	for _, u := range applied {
+		if !missing[u.Item] {
+			tr.mutated(OpRemove, u.Item)
+			tr.mutated(OpInsert, u.NewItem)
+			n++
+		}
+	}
+	return n
+}
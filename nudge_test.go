@@ -0,0 +1,89 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestNudge(t *testing.T) {
+	tr := New(&Options{MaxEntries: 9, KeyIndex: true})
+	a := makePointPair2("a", 10, 10)
+	b := makePointPair3("b", 20, 20, 20)
+	tr.Insert(a)
+	tr.Insert(b)
+	for i := 0; i < 100; i++ {
+		tr.Insert(rand2DPoint())
+	}
+	fp := tr.Fingerprint()
+	feed := tr.Subscribe()
+
+	a2 := makePointPair2("a", 10.001, 10)
+	assert.True(t, tr.Nudge(a, a2))
+	assert.False(t, tr.Nudge(a, a2))
+	m := <-feed
+	assert.Equal(t, OpRemove, m.Op)
+	m = <-feed
+	assert.Equal(t, OpInsert, m.Op)
+	min, _, ok := tr.BoundsOf([]byte("a"))
+	assert.True(t, ok)
+	assert.Equal(t, 10.001, min[0])
+
+	// from 3d to 2d
+	b2 := makePointPair2("b", 20, 20)
+	assert.True(t, tr.Nudge(b, b2))
+	assert.Equal(t, 102, tr.Count2D())
+	assert.Equal(t, 0, tr.Count3D())
+	var found []pair.Pair
+	tr.Search(makeBoundsPair2("", 0, 0, 30, 30), func(item pair.Pair) bool {
+		if string(item.Key()) != "" {
+			found = append(found, item)
+		}
+		return true
+	})
+	assert.Equal(t, 2, len(found))
+
+	// back to where they were
+	tr.Nudge(a2, a)
+	tr.Nudge(b2, b)
+	assert.Equal(t, fp, tr.Fingerprint())
+}
+
+func TestUpdateAll(t *testing.T) {
+	tr := New(nil)
+	var items []pair.Pair
+	for i := 0; i < 200; i++ {
+		items = append(items, makePointPair2("", float64(i), 0), makePointPair3("", float64(i), 0, 0))
+	}
+	tr.Load(items)
+	var updates []Update
+	for i, item := range items {
+		x := float64(i / 2)
+		switch i % 4 {
+		case 0:
+			items[i] = makePointPair2("", x+0.001, 0)
+		case 1:
+			items[i] = makePointPair3("", x+0.001, 0, 0)
+		case 2:
+			items[i] = makePointPair3("", x, 0, 0)
+		case 3:
+			items[i] = makePointPair2("", x, 0)
+		}
+		updates = append(updates, Update{item, items[i]})
+	}
+	updates = append(updates, Update{rand2DPoint(), rand2DPoint()})
+	feed := tr.Subscribe()
+	assert.Equal(t, 400, tr.UpdateAll(updates))
+	assert.Equal(t, 800, len(feed))
+	assert.Equal(t, 200, tr.Count2D())
+	assert.Equal(t, 200, tr.Count3D())
+	seen := make(map[pair.Pair]bool)
+	tr.Scan(func(item pair.Pair) bool {
+		seen[item] = true
+		return true
+	})
+	for _, item := range items {
+		assert.True(t, seen[item])
+	}
+}
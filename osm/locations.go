@@ -0,0 +1,44 @@
+package osm
+
+import "sort"
+
+// nodeLocations is a compact store of node locations for computing the
+// bboxes of ways. Each node takes 16 bytes: its id, and its lon and lat in
+// units of 100 nanodegrees, which is the precision of OSM. Extracts are
+// usually sorted by id, so the nodes are appended in order and found with a
+// binary search. Nodes that come out of order are sorted before the next
+// lookup.
+type nodeLocations struct {
+	ids      []int64
+	coords   [][2]int32
+	unsorted bool
+}
+
+// add adds a node with its location in nanodegrees.
+func (l *nodeLocations) add(id, lon, lat int64) {
+	if n := len(l.ids); n > 0 && id < l.ids[n-1] {
+		l.unsorted = true
+	}
+	l.ids = append(l.ids, id)
+	l.coords = append(l.coords, [2]int32{int32(lon / 100), int32(lat / 100)})
+}
+
+// get returns the location of a node in nanodegrees.
+func (l *nodeLocations) get(id int64) (lon, lat int64, ok bool) {
+	if l.unsorted {
+		sort.Stable(l)
+		l.unsorted = false
+	}
+	i := sort.Search(len(l.ids), func(i int) bool { return l.ids[i] >= id })
+	if i == len(l.ids) || l.ids[i] != id {
+		return 0, 0, false
+	}
+	return int64(l.coords[i][0]) * 100, int64(l.coords[i][1]) * 100, true
+}
+
+func (l *nodeLocations) Len() int           { return len(l.ids) }
+func (l *nodeLocations) Less(i, j int) bool { return l.ids[i] < l.ids[j] }
+func (l *nodeLocations) Swap(i, j int) {
+	l.ids[i], l.ids[j] = l.ids[j], l.ids[i]
+	l.coords[i], l.coords[j] = l.coords[j], l.coords[i]
+}
@@ -0,0 +1,437 @@
+// Package osm streams nodes and ways from OpenStreetMap PBF extracts into a
+// tree.
+//
+// Nodes are stored as 2d points keyed by "n" and the node id, and ways as
+// the 2d bbox of their nodes keyed by "w" and the way id.
+//
+// Way bboxes are read from the locations on the ways when the extract has
+// them, which is the LocationsOnWays feature of the header, and then no node
+// locations are kept. Otherwise the location of every node is kept in memory
+// while reading, at 16 bytes a node, so the memory grows with the number of
+// nodes in the extract: about 160 MB for 10 million nodes, or over 100 GB
+// for the planet. Large extracts should have the locations added to the
+// ways first, such as with osmium add-locations-to-ways.
+package osm
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+var (
+	ErrUnsupportedCompression = errors.New("osm: unsupported blob compression")
+	ErrTooLarge               = errors.New("osm: block is over the size limit")
+)
+
+// maxHeaderSize and maxBlobSize are the limits of the PBF format on the size
+// of a block header, and on the size of a blob before and after inflating.
+const (
+	maxHeaderSize = 64 << 10
+	maxBlobSize   = 32 << 20
+)
+
+type Options struct {
+	// Nodes includes nodes that have tags.
+	Nodes bool
+	// AllNodes includes every node, including the untagged nodes that only
+	// make up ways.
+	AllNodes bool
+	// Ways includes the bbox of each way. Without locations on the ways,
+	// this keeps the location of every node while reading.
+	Ways bool
+	// Buffer is the number of decoded blocks that may be waiting to be
+	// loaded. The reader stops decoding while the buffer is full.
+	Buffer int
+}
+
+var DefaultOptions = &Options{
+	Nodes:    true,
+	AllNodes: false,
+	Ways:     true,
+	Buffer:   4,
+}
+
+// Load reads an extract and bulk loads the items into the tree, one block
+// at a time. It returns the number of items loaded.
+func Load(tr *rtree.RTree, r io.Reader, opts *Options) (int, error) {
+	var n int
+	err := Read(r, opts, func(items []pair.Pair) error {
+		tr.Load(items)
+		n += len(items)
+		return nil
+	})
+	return n, err
+}
+
+// Read decodes an extract in the background and calls fn with the items of
+// each block, in order. Decoding runs ahead of fn by at most opts.Buffer
+// blocks. An error from fn stops the read and is returned.
+func Read(r io.Reader, opts *Options, fn func(items []pair.Pair) error) error {
+	if opts == nil {
+		opts = DefaultOptions
+	}
+	buffer := opts.Buffer
+	if buffer < 0 {
+		buffer = 0
+	}
+	blocks := make(chan []pair.Pair, buffer)
+	done := make(chan struct{})
+	errc := make(chan error, 1)
+	go func() {
+		d := &decoder{opts: opts}
+		errc <- d.decode(r, func(items []pair.Pair) bool {
+			select {
+			case blocks <- items:
+				return true
+			case <-done:
+				return false
+			}
+		})
+		close(blocks)
+	}()
+	for items := range blocks {
+		if err := fn(items); err != nil {
+			close(done)
+			for range blocks {
+			}
+			<-errc
+			return err
+		}
+	}
+	return <-errc
+}
+
+type decoder struct {
+	opts *Options
+	// locsOnWays is set when the header says that the ways have the
+	// locations of their nodes, so locs isn't needed.
+	locsOnWays bool
+	locs       nodeLocations
+	buf        []byte
+}
+
+// block is the state of a primitive block.
+type block struct {
+	strings     [][]byte
+	granularity int64
+	latOffset   int64
+	lonOffset   int64
+}
+
+// nano returns a coordinate in nanodegrees.
+func (b *block) nano(offset, v int64) int64 {
+	return offset + b.granularity*v
+}
+
+func (b *block) coord(offset, v int64) float64 {
+	return 1e-9 * float64(b.nano(offset, v))
+}
+
+var errStopped = errors.New("stopped")
+
+func (d *decoder) decode(r io.Reader, emit func(items []pair.Pair) bool) error {
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		headerSize := binary.BigEndian.Uint32(size[:])
+		if headerSize > maxHeaderSize {
+			return ErrTooLarge
+		}
+		header := make([]byte, headerSize)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return err
+		}
+		var typ string
+		var dataSize uint64
+		if err := fields(header, func(num int, v uint64, data []byte) error {
+			switch num {
+			case 1:
+				typ = string(data)
+			case 3:
+				dataSize = v
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		if dataSize > maxBlobSize {
+			return ErrTooLarge
+		}
+		blob := make([]byte, dataSize)
+		if _, err := io.ReadFull(r, blob); err != nil {
+			return err
+		}
+		if typ != "OSMHeader" && typ != "OSMData" {
+			continue
+		}
+		data, err := d.inflate(blob)
+		if err != nil {
+			return err
+		}
+		if typ == "OSMHeader" {
+			if err := d.header(data); err != nil {
+				return err
+			}
+			continue
+		}
+		items, err := d.block(data)
+		if err != nil {
+			return err
+		}
+		if len(items) > 0 && !emit(items) {
+			return errStopped
+		}
+	}
+}
+
+func (d *decoder) inflate(blob []byte) ([]byte, error) {
+	var raw, zdata []byte
+	var rawSize uint64
+	var other bool
+	if err := fields(blob, func(num int, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			raw = data
+		case 2:
+			rawSize = v
+		case 3:
+			zdata = data
+		default:
+			other = true
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if raw != nil {
+		return raw, nil
+	}
+	if zdata == nil {
+		if other {
+			return nil, ErrUnsupportedCompression
+		}
+		return nil, nil
+	}
+	if rawSize > maxBlobSize {
+		return nil, ErrTooLarge
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(zdata))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(cap(d.buf)) < rawSize {
+		d.buf = make([]byte, rawSize)
+	}
+	d.buf = d.buf[:rawSize]
+	if _, err := io.ReadFull(zr, d.buf); err != nil {
+		return nil, err
+	}
+	return d.buf, nil
+}
+
+// header reads the optional features of the extract.
+func (d *decoder) header(data []byte) error {
+	return fields(data, func(num int, v uint64, data []byte) error {
+		if num == 5 && string(data) == "LocationsOnWays" {
+			d.locsOnWays = true
+		}
+		return nil
+	})
+}
+
+func (d *decoder) block(data []byte) ([]pair.Pair, error) {
+	b := block{granularity: 100}
+	var groups [][]byte
+	err := fields(data, func(num int, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			return fields(data, func(num int, v uint64, s []byte) error {
+				if num == 1 {
+					b.strings = append(b.strings, s)
+				}
+				return nil
+			})
+		case 2:
+			groups = append(groups, data)
+		case 17:
+			b.granularity = int64(v)
+		case 19:
+			b.latOffset = int64(v)
+		case 20:
+			b.lonOffset = int64(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var items []pair.Pair
+	for _, group := range groups {
+		err := fields(group, func(num int, v uint64, data []byte) error {
+			var err error
+			switch num {
+			case 1:
+				items, err = d.node(&b, data, items)
+			case 2:
+				items, err = d.denseNodes(&b, data, items)
+			case 3:
+				if d.opts.Ways {
+					items, err = d.way(&b, data, items)
+				}
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// addNode adds a node with its location in nanodegrees.
+func (d *decoder) addNode(items []pair.Pair, id int64, lon, lat int64, tagged bool) []pair.Pair {
+	if d.opts.Ways && !d.locsOnWays {
+		d.locs.add(id, lon, lat)
+	}
+	if d.opts.AllNodes || (d.opts.Nodes && tagged) {
+		key := strconv.AppendInt([]byte{'n'}, id, 10)
+		point := geobin.Make2DPoint(1e-9*float64(lon), 1e-9*float64(lat))
+		items = append(items, pair.New(key, point.Binary()))
+	}
+	return items
+}
+
+func (d *decoder) node(b *block, data []byte, items []pair.Pair) ([]pair.Pair, error) {
+	var id, lat, lon int64
+	var tagged bool
+	err := fields(data, func(num int, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			id = unzigzag(v)
+		case 2:
+			tagged = len(data) > 0
+		case 8:
+			lat = unzigzag(v)
+		case 9:
+			lon = unzigzag(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d.addNode(items, id, b.nano(b.lonOffset, lon), b.nano(b.latOffset, lat), tagged), nil
+}
+
+func (d *decoder) denseNodes(b *block, data []byte, items []pair.Pair) ([]pair.Pair, error) {
+	var ids, lats, lons []int64
+	var keysVals []uint64
+	delta := func(data []byte, out *[]int64) error {
+		var last int64
+		return packed(data, func(v uint64) {
+			last += unzigzag(v)
+			*out = append(*out, last)
+		})
+	}
+	err := fields(data, func(num int, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			return delta(data, &ids)
+		case 8:
+			return delta(data, &lats)
+		case 9:
+			return delta(data, &lons)
+		case 10:
+			return packed(data, func(v uint64) { keysVals = append(keysVals, v) })
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(lats) != len(ids) || len(lons) != len(ids) {
+		return nil, errInvalidProto
+	}
+	// keys_vals is a list of key and value string ids for each node, with
+	// each node's tags ended by a zero
+	var kv int
+	for i, id := range ids {
+		tagged := false
+		if len(keysVals) > 0 {
+			for kv < len(keysVals) && keysVals[kv] != 0 {
+				tagged = true
+				kv += 2
+			}
+			kv++
+		}
+		items = d.addNode(items, id, b.nano(b.lonOffset, lons[i]), b.nano(b.latOffset, lats[i]), tagged)
+	}
+	return items, nil
+}
+
+func (d *decoder) way(b *block, data []byte, items []pair.Pair) ([]pair.Pair, error) {
+	var id int64
+	var refs, lats, lons []int64
+	delta := func(data []byte, out *[]int64) error {
+		var last int64
+		return packed(data, func(v uint64) {
+			last += unzigzag(v)
+			*out = append(*out, last)
+		})
+	}
+	err := fields(data, func(num int, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			id = int64(v)
+		case 8:
+			return delta(data, &refs)
+		case 9:
+			return delta(data, &lats)
+		case 10:
+			return delta(data, &lons)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	min := [2]float64{math.Inf(+1), math.Inf(+1)}
+	max := [2]float64{math.Inf(-1), math.Inf(-1)}
+	extend := func(lon, lat float64) {
+		min[0], min[1] = math.Min(min[0], lon), math.Min(min[1], lat)
+		max[0], max[1] = math.Max(max[0], lon), math.Max(max[1], lat)
+	}
+	if len(lats) == len(refs) && len(lons) == len(refs) && len(refs) > 0 {
+		for i := range refs {
+			extend(b.coord(b.lonOffset, lons[i]), b.coord(b.latOffset, lats[i]))
+		}
+	} else {
+		// extracts that are clipped to a region have ways that reference
+		// nodes outside of the extract, which are ignored
+		for _, ref := range refs {
+			if lon, lat, ok := d.locs.get(ref); ok {
+				extend(1e-9*float64(lon), 1e-9*float64(lat))
+			}
+		}
+	}
+	if min[0] > max[0] {
+		return items, nil
+	}
+	key := strconv.AppendInt([]byte{'w'}, id, 10)
+	rect := geobin.Make2DRect(min[0], min[1], max[0], max[1])
+	return append(items, pair.New(key, rect.Binary())), nil
+}
@@ -0,0 +1,205 @@
+package osm
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+// pb is a minimal protobuf encoder for building test extracts.
+type pb []byte
+
+func (b pb) varint(num int, v uint64) pb {
+	b = binary.AppendUvarint(b, uint64(num)<<3|wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func (b pb) bytes(num int, data []byte) pb {
+	b = binary.AppendUvarint(b, uint64(num)<<3|wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func deltas(vals ...int64) []byte {
+	var b []byte
+	var last int64
+	for _, v := range vals {
+		b = binary.AppendUvarint(b, zigzag(v-last))
+		last = v
+	}
+	return b
+}
+
+func fileBlock(typ string, data []byte, compress bool) []byte {
+	var blob pb
+	if compress {
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		zw.Write(data)
+		zw.Close()
+		blob = blob.varint(2, uint64(len(data))).bytes(3, z.Bytes())
+	} else {
+		blob = blob.bytes(1, data)
+	}
+	header := pb{}.bytes(1, []byte(typ)).varint(3, uint64(len(blob)))
+	out := binary.BigEndian.AppendUint32(nil, uint32(len(header)))
+	out = append(out, header...)
+	return append(out, blob...)
+}
+
+func extract() []byte {
+	return extractWith(false)
+}
+
+// extractWith returns a test extract, where locsOnWays adds the node
+// locations to the ways and declares the LocationsOnWays feature.
+func extractWith(locsOnWays bool) []byte {
+	strs := pb{}.bytes(1, nil).bytes(1, []byte("amenity")).bytes(1, []byte("cafe"))
+	// nodes 1, 2, and 3 where only node 2 is tagged
+	kv := []byte{0, 1, 2, 0, 0}
+	dense := pb{}.
+		bytes(1, deltas(1, 2, 3)).
+		bytes(8, deltas(10000000, 20000000, 30000000)).
+		bytes(9, deltas(-10000000, -20000000, -30000000)).
+		bytes(10, kv)
+	nodes := pb{}.bytes(1, strs).bytes(2, pb{}.bytes(2, dense))
+	way := pb{}.varint(1, 7).bytes(8, deltas(1, 3, 99))
+	var header pb
+	if locsOnWays {
+		// the missing node 99 is at lat 2 and lon -2
+		way = way.
+			bytes(9, deltas(10000000, 30000000, 20000000)).
+			bytes(10, deltas(-10000000, -30000000, -20000000))
+		header = header.bytes(5, []byte("LocationsOnWays"))
+	}
+	ways := pb{}.bytes(1, strs).bytes(2, pb{}.bytes(3, way))
+	var out []byte
+	out = append(out, fileBlock("OSMHeader", header, false)...)
+	out = append(out, fileBlock("OSMData", nodes, true)...)
+	out = append(out, fileBlock("OSMData", ways, false)...)
+	return out
+}
+
+func TestLoad(t *testing.T) {
+	tr := rtree.New(nil)
+	n, err := Load(tr, bytes.NewReader(extract()), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	node, ok := tr.Get([]byte("n2"))
+	assert.True(t, ok)
+	min, _ := geobin.WrapBinary(node.Value()).Rect(nil)
+	assert.Equal(t, [3]float64{-2, 2, 0}, min)
+	way, ok := tr.Get([]byte("w7"))
+	assert.True(t, ok)
+	min, max := geobin.WrapBinary(way.Value()).Rect(nil)
+	assert.Equal(t, [3]float64{-3, 1, 0}, min)
+	assert.Equal(t, [3]float64{-1, 3, 0}, max)
+
+	opts := *DefaultOptions
+	opts.AllNodes = true
+	opts.Ways = false
+	tr = rtree.New(nil)
+	n, err = Load(tr, bytes.NewReader(extract()), &opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+}
+
+func TestLocationsOnWays(t *testing.T) {
+	for _, locsOnWays := range []bool{false, true} {
+		d := &decoder{opts: DefaultOptions}
+		var way pair.Pair
+		err := d.decode(bytes.NewReader(extractWith(locsOnWays)), func(items []pair.Pair) bool {
+			for _, item := range items {
+				if string(item.Key()) == "w7" {
+					way = item
+				}
+			}
+			return true
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, locsOnWays, d.locsOnWays)
+		if locsOnWays {
+			assert.Equal(t, 0, len(d.locs.ids))
+		} else {
+			assert.Equal(t, 3, len(d.locs.ids))
+		}
+		min, max := geobin.WrapBinary(way.Value()).Rect(nil)
+		assert.Equal(t, [3]float64{-3, 1, 0}, min)
+		assert.Equal(t, [3]float64{-1, 3, 0}, max)
+	}
+}
+
+func TestNodeLocations(t *testing.T) {
+	var l nodeLocations
+	for _, id := range []int64{5, 1, 9, 3, 7} {
+		l.add(id, -id*1000000000, id*100000000)
+	}
+	assert.True(t, l.unsorted)
+	for _, id := range []int64{1, 3, 5, 7, 9} {
+		lon, lat, ok := l.get(id)
+		assert.True(t, ok)
+		assert.Equal(t, -id*1000000000, lon)
+		assert.Equal(t, id*100000000, lat)
+	}
+	assert.False(t, l.unsorted)
+	_, _, ok := l.get(4)
+	assert.False(t, ok)
+	_, _, ok = l.get(10)
+	assert.False(t, ok)
+
+	// a node added after a lookup is still found
+	l.add(2, 0, 0)
+	_, _, ok = l.get(2)
+	assert.True(t, ok)
+}
+
+func TestReadStop(t *testing.T) {
+	stop := errors.New("stop")
+	opts := *DefaultOptions
+	opts.Buffer = 0
+	var calls int
+	err := Read(bytes.NewReader(extract()), &opts, func(items []pair.Pair) error {
+		calls++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestReadCorrupt(t *testing.T) {
+	read := func(data []byte) error {
+		return Read(bytes.NewReader(data), nil, func(items []pair.Pair) error {
+			return nil
+		})
+	}
+	data := extract()
+	assert.Equal(t, io.ErrUnexpectedEOF, read(data[:len(data)-3]))
+	assert.Equal(t, io.ErrUnexpectedEOF, read(data[:2]))
+
+	// a header size over the limit
+	assert.Equal(t, ErrTooLarge, read([]byte{0xff, 0xff, 0xff, 0xff}))
+
+	// a blob size over the limit
+	header := pb{}.bytes(1, []byte("OSMData")).varint(3, 1<<62)
+	block := binary.BigEndian.AppendUint32(nil, uint32(len(header)))
+	assert.Equal(t, ErrTooLarge, read(append(block, header...)))
+
+	// an inflated size over the limit
+	blob := pb{}.varint(2, 1<<62).bytes(3, []byte{1, 2, 3})
+	header = pb{}.bytes(1, []byte("OSMData")).varint(3, uint64(len(blob)))
+	block = binary.BigEndian.AppendUint32(nil, uint32(len(header)))
+	block = append(append(block, header...), blob...)
+	assert.Equal(t, ErrTooLarge, read(block))
+}
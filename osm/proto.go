@@ -0,0 +1,78 @@
+package osm
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// A minimal protobuf decoder for the OSM PBF messages.
+
+var errInvalidProto = errors.New("osm: invalid protobuf")
+
+const (
+	wireVarint = 0
+	wire64     = 1
+	wireBytes  = 2
+	wire32     = 5
+)
+
+// fields calls fn for every field in a message. For varint fields v is the
+// value, and for length delimited fields data is the value.
+func fields(b []byte, fn func(num int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errInvalidProto
+		}
+		b = b[n:]
+		num := int(key >> 3)
+		var v uint64
+		var data []byte
+		switch key & 7 {
+		case wireVarint:
+			if v, n = binary.Uvarint(b); n <= 0 {
+				return errInvalidProto
+			}
+			b = b[n:]
+		case wire64:
+			if len(b) < 8 {
+				return errInvalidProto
+			}
+			v, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wire32:
+			if len(b) < 4 {
+				return errInvalidProto
+			}
+			v, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return errInvalidProto
+			}
+			data, b = b[n:n+int(size)], b[n+int(size):]
+		default:
+			return errInvalidProto
+		}
+		if err := fn(num, v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// packed decodes a packed repeated varint field.
+func packed(data []byte, fn func(v uint64)) error {
+	for len(data) > 0 {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errInvalidProto
+		}
+		fn(v)
+		data = data[n:]
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package rtree
+
+import (
+	"math"
+
+	"github.com/tidwall/pair"
+)
+
+// Outliers returns the items that are large enough to slow down searches,
+// which are the 2d items with more than areaThreshold of the area of the
+// tree, and the 3d items with more than areaThreshold of its volume. A
+// flat tree compares the 3d items by area too. Items with a NaN coordinate,
+// or with a min that's greater than the max, are always returned.
+func (tr *RTree) Outliers(areaThreshold float64) []pair.Pair {
+	bmin, bmax := tr.Bounds()
+	area := (bmax[0] - bmin[0]) * (bmax[1] - bmin[1])
+	volume := area * (bmax[2] - bmin[2])
+	var items []pair.Pair
+	tr.Scan(func(item pair.Pair) bool {
+		min, max := tr.rect(item.Value())
+		dims := tr.dims(item.Value())
+		var bad bool
+		for i := 0; i < dims; i++ {
+			if math.IsNaN(min[i]) || math.IsNaN(max[i]) || min[i] > max[i] {
+				bad = true
+			}
+		}
+		size := (max[0] - min[0]) * (max[1] - min[1])
+		if bad {
+			items = append(items, item)
+		} else if dims == 3 && volume > 0 {
+			if size*(max[2]-min[2]) > areaThreshold*volume {
+				items = append(items, item)
+			}
+		} else if size > areaThreshold*area {
+			items = append(items, item)
+		}
+		return true
+	})
+	return items
+}
@@ -0,0 +1,36 @@
+package rtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestOutliers(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 100; i++ {
+		x := float64(i)
+		tr.Insert(makeBoundsPair2("", x, x, x+1, x+1))
+		tr.Insert(makeBoundsPair3("", x, x, x, x+1, x+1, x+1))
+	}
+	keys := func(items []pair.Pair) map[string]bool {
+		keys := make(map[string]bool)
+		for _, item := range items {
+			keys[string(item.Key())] = true
+		}
+		return keys
+	}
+	assert.Equal(t, 0, len(tr.Outliers(0.1)))
+	tr.Insert(makeBoundsPair2("world", -180, -90, 180, 90))
+	tr.Insert(makeBoundsPair3("big", 0, 0, 0, 100, 100, 100))
+	assert.Equal(t, map[string]bool{"world": true, "big": true}, keys(tr.Outliers(0.1)))
+	assert.Equal(t, map[string]bool{"world": true}, keys(tr.Outliers(0.5)))
+	assert.Equal(t, 0, len(tr.Outliers(1)))
+
+	// bad rects are always returned
+	tr.Insert(makeBoundsPair2("nan", math.NaN(), 0, 1, 1))
+	tr.Insert(makeBoundsPair3("empty", 5, 5, 5, 4, 4, 4))
+	assert.Equal(t, map[string]bool{"nan": true, "empty": true}, keys(tr.Outliers(math.Inf(+1))))
+}
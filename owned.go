@@ -0,0 +1,64 @@
+package rtree
+
+import (
+	"bytes"
+
+	"github.com/tidwall/pair"
+)
+
+// own returns the item that the tree stores for an insert. With the
+// CopyItems option it's a copy of the key and value that's owned by the
+// tree.
+func (tr *RTree) own(item pair.Pair) pair.Pair {
+	if !tr.copyItems {
+		return item
+	}
+	return pair.New(item.Key(), item.Value())
+}
+
+// owned returns the stored item for a remove. With the CopyItems option the
+// stored item is a copy, so it's found by its key and value rather than by
+// its pointer. Returns false if there's no such item.
+func (tr *RTree) owned(item pair.Pair) (pair.Pair, bool) {
+	if !tr.copyItems {
+		return item, true
+	}
+	return tr.Find(item)
+}
+
+// Find returns the stored item with the same key and value as the item,
+// which may be a copy, such as an item decoded from a stream. The item is
+// looked for by its own rect, which finds it even with the ExcludeTouching
+// option. Returns false if there's no such item.
+func (tr *RTree) Find(item pair.Pair) (pair.Pair, bool) {
+	var found pair.Pair
+	iter := func(stored pair.Pair) bool {
+		if bytes.Equal(stored.Key(), item.Key()) &&
+			bytes.Equal(stored.Value(), item.Value()) {
+			found = stored
+			return false
+		}
+		return true
+	}
+	min, max := tr.rect(item.Value())
+	if tr.dims(item.Value()) == 2 {
+		tr.tr2.SearchRectTouching([2]float64{min[0], min[1]},
+			[2]float64{max[0], max[1]}, iter)
+	} else {
+		tr.tr3.SearchRectTouching(min, max, iter)
+	}
+	return found, !found.Zero()
+}
+
+// Clear removes every item.
+func (tr *RTree) Clear() {
+	tr.checkFrozen()
+	var items []pair.Pair
+	tr.Scan(func(item pair.Pair) bool {
+		items = append(items, item)
+		return true
+	})
+	for _, item := range items {
+		tr.remove(item)
+	}
+}
@@ -0,0 +1,43 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+func TestCopyItems(t *testing.T) {
+	opts := *DefaultOptions
+	opts.CopyItems = true
+	opts.KeyIndex = true
+	tr := New(&opts)
+
+	// insert items that share a reused buffer
+	buf := make([]byte, 0, 64)
+	for i := 0; i < 100; i++ {
+		buf = append(buf[:0], byte('a'+i%26), byte('a'+i/26))
+		item := pair.New(buf, geobin.Make2DPoint(float64(i), float64(i)).Binary())
+		tr.Insert(item)
+		item = pair.New(buf, geobin.Make3DPoint(float64(i), float64(i), 1).Binary())
+		tr.Insert(item)
+	}
+	assert.Equal(t, 200, tr.Count())
+	item, ok := tr.Get([]byte("ab"))
+	assert.True(t, ok)
+	assert.Equal(t, "ab", string(item.Key()))
+
+	// removes by key and value, not by pointer
+	tr.Remove(makePointPair2("ab", 26, 26))
+	tr.Remove(makePointPair3("ab", 26, 26, 1))
+	tr.Remove(makePointPair2("ab", 0, 0))
+	assert.Equal(t, 198, tr.Count())
+	_, ok = tr.Get([]byte("ab"))
+	assert.False(t, ok)
+
+	tr.Clear()
+	assert.Equal(t, 0, tr.Count())
+	_, ok = tr.Get([]byte("aa"))
+	assert.False(t, ok)
+}
@@ -0,0 +1,300 @@
+// Package parquet exports tree items as an Apache Parquet file.
+//
+// The file has a key column, the min_x, min_y, min_z, max_x, max_y, and
+// max_z columns of the untransformed item bboxes, and an optional value
+// column with the raw geobin bytes. The z columns are null for 2d items.
+// Every column chunk has min and max statistics, so engines can skip row
+// groups that are outside of a query.
+package parquet
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+type Options struct {
+	// Values includes a value column with the geobin bytes of each item.
+	Values bool
+	// RowGroupSize is the maximum number of rows in each row group.
+	RowGroupSize int
+}
+
+var DefaultOptions = &Options{
+	Values:       false,
+	RowGroupSize: 64 * 1024,
+}
+
+// Parquet enums
+const (
+	typeDouble    = 5
+	typeByteArray = 6
+	required      = 0
+	optional      = 1
+	encodingPlain = 0
+	encodingRLE   = 3
+	pageData      = 0
+)
+
+var coordNames = [6]string{"min_x", "min_y", "min_z", "max_x", "max_y", "max_z"}
+
+const magic = "PAR1"
+
+type countWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+type column struct {
+	name     string
+	typ      int32
+	optional bool
+}
+
+// chunk is the metadata of a written column chunk.
+type chunk struct {
+	offset   int64
+	size     int64
+	values   int64
+	nulls    int64
+	min, max []byte
+}
+
+type rowGroup struct {
+	rows   int64
+	size   int64
+	chunks []chunk
+}
+
+// Write writes a file of every item returned by scan, which may be the Scan
+// method of a tree or a function that wraps a query.
+//
+//	parquet.Write(w, tr.Scan, nil)
+func Write(w io.Writer, scan func(iter func(item pair.Pair) bool) bool, opts *Options) error {
+	if opts == nil {
+		opts = DefaultOptions
+	}
+	groupSize := opts.RowGroupSize
+	if groupSize < 1 {
+		groupSize = DefaultOptions.RowGroupSize
+	}
+	columns := []column{{"key", typeByteArray, false}}
+	for i, name := range coordNames {
+		columns = append(columns, column{name, typeDouble, i%3 == 2})
+	}
+	if opts.Values {
+		columns = append(columns, column{"value", typeByteArray, false})
+	}
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	cw.Write([]byte(magic))
+	var groups []rowGroup
+	var items []pair.Pair
+	var err error
+	flush := func() {
+		var g rowGroup
+		g, err = writeRowGroup(cw, columns, items)
+		groups = append(groups, g)
+		items = items[:0]
+	}
+	scan(func(item pair.Pair) bool {
+		items = append(items, item)
+		if len(items) == groupSize {
+			flush()
+		}
+		return err == nil
+	})
+	if err == nil && len(items) > 0 {
+		flush()
+	}
+	if err != nil {
+		return err
+	}
+	meta := fileMetaData(columns, groups)
+	cw.Write(meta)
+	var footer [8]byte
+	binary.LittleEndian.PutUint32(footer[:], uint32(len(meta)))
+	copy(footer[4:], magic)
+	cw.Write(footer[:])
+	return cw.w.Flush()
+}
+
+func writeRowGroup(w *countWriter, columns []column, items []pair.Pair) (rowGroup, error) {
+	g := rowGroup{rows: int64(len(items))}
+	rects := make([][2][3]float64, len(items))
+	dims := make([]int, len(items))
+	for i, item := range items {
+		obj := geobin.WrapBinary(item.Value())
+		rects[i][0], rects[i][1] = obj.Rect(nil)
+		dims[i] = obj.Dims()
+	}
+	for i, col := range columns {
+		var data []byte
+		var c chunk
+		c.values = int64(len(items))
+		if col.typ == typeByteArray {
+			for _, item := range items {
+				v := item.Key()
+				if col.name == "value" {
+					v = item.Value()
+				}
+				var n [4]byte
+				binary.LittleEndian.PutUint32(n[:], uint32(len(v)))
+				data = append(data, n[:]...)
+				data = append(data, v...)
+				if c.min == nil || string(v) < string(c.min) {
+					c.min = v
+				}
+				if c.max == nil || string(v) > string(c.max) {
+					c.max = v
+				}
+			}
+		} else {
+			axis := (i - 1) % 3
+			side := (i - 1) / 3
+			var levels []bool
+			min, max := math.Inf(+1), math.Inf(-1)
+			for j := range items {
+				if col.optional {
+					levels = append(levels, dims[j] == 3)
+					if dims[j] != 3 {
+						c.nulls++
+						continue
+					}
+				}
+				v := rects[j][side][axis]
+				min, max = math.Min(min, v), math.Max(max, v)
+				data = appendDouble(data, v)
+			}
+			if col.optional {
+				data = append(definitionLevels(levels), data...)
+			}
+			if c.nulls < c.values {
+				c.min = appendDouble(nil, min)
+				c.max = appendDouble(nil, max)
+			}
+		}
+		var t thrift
+		t.begin()
+		t.i32(1, pageData)
+		t.i32(2, int32(len(data)))
+		t.i32(3, int32(len(data)))
+		t.structField(5)
+		t.i32(1, int32(len(items)))
+		t.i32(2, encodingPlain)
+		t.i32(3, encodingRLE)
+		t.i32(4, encodingRLE)
+		t.end()
+		t.end()
+		c.offset = w.n
+		w.Write(t.buf)
+		if _, err := w.Write(data); err != nil {
+			return g, err
+		}
+		c.size = w.n - c.offset
+		g.size += c.size
+		g.chunks = append(g.chunks, c)
+	}
+	return g, nil
+}
+
+func appendDouble(b []byte, v float64) []byte {
+	var d [8]byte
+	binary.LittleEndian.PutUint64(d[:], math.Float64bits(v))
+	return append(b, d[:]...)
+}
+
+// definitionLevels encodes the levels of an optional column as runs in the
+// RLE/bit-packed hybrid encoding, with the length prefix of a data page.
+func definitionLevels(levels []bool) []byte {
+	data := make([]byte, 4)
+	for i := 0; i < len(levels); {
+		j := i + 1
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		var b [binary.MaxVarintLen64]byte
+		data = append(data, b[:binary.PutUvarint(b[:], uint64(j-i)<<1)]...)
+		if levels[i] {
+			data = append(data, 1)
+		} else {
+			data = append(data, 0)
+		}
+		i = j
+	}
+	binary.LittleEndian.PutUint32(data, uint32(len(data)-4))
+	return data
+}
+
+func fileMetaData(columns []column, groups []rowGroup) []byte {
+	var t thrift
+	t.begin()
+	t.i32(1, 1)
+	t.list(2, tStruct, len(columns)+1)
+	t.begin()
+	t.string(4, "schema")
+	t.i32(5, int32(len(columns)))
+	t.end()
+	for _, col := range columns {
+		t.begin()
+		t.i32(1, col.typ)
+		if col.optional {
+			t.i32(3, optional)
+		} else {
+			t.i32(3, required)
+		}
+		t.string(4, col.name)
+		t.end()
+	}
+	var rows int64
+	for _, g := range groups {
+		rows += g.rows
+	}
+	t.i64(3, rows)
+	t.list(4, tStruct, len(groups))
+	for _, g := range groups {
+		t.begin()
+		t.list(1, tStruct, len(g.chunks))
+		for i, c := range g.chunks {
+			col := columns[i]
+			t.begin()
+			t.i64(2, c.offset)
+			t.structField(3)
+			t.i32(1, col.typ)
+			t.list(2, tI32, 2)
+			t.elemI32(encodingPlain)
+			t.elemI32(encodingRLE)
+			t.list(3, tBinary, 1)
+			t.elemString(col.name)
+			t.i32(4, 0) // uncompressed
+			t.i64(5, c.values)
+			t.i64(6, c.size)
+			t.i64(7, c.size)
+			t.i64(9, c.offset)
+			t.structField(12)
+			t.i64(3, c.nulls)
+			if c.min != nil {
+				t.binary(5, c.max)
+				t.binary(6, c.min)
+			}
+			t.end()
+			t.end()
+			t.end()
+		}
+		t.i64(2, g.size)
+		t.i64(3, g.rows)
+		t.end()
+	}
+	t.string(6, "github.com/tidwall/pair-rtree/parquet")
+	t.end()
+	return t.buf
+}
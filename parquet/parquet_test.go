@@ -0,0 +1,136 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+// decoder reads the Thrift compact protocol into maps of field ids to
+// values for verifying the output.
+type decoder struct {
+	buf []byte
+}
+
+func (d *decoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.buf)
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	v := d.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (d *decoder) value(typ byte) interface{} {
+	switch typ {
+	case 1:
+		return true
+	case 2:
+		return false
+	case tI32, tI64:
+		return d.varint()
+	case tBinary:
+		n := d.uvarint()
+		v := d.buf[:n]
+		d.buf = d.buf[n:]
+		return v
+	case tList:
+		h := d.buf[0]
+		d.buf = d.buf[1:]
+		n := int(h >> 4)
+		if n == 15 {
+			n = int(d.uvarint())
+		}
+		var list []interface{}
+		for i := 0; i < n; i++ {
+			list = append(list, d.value(h&0xf))
+		}
+		return list
+	case tStruct:
+		m := map[int16]interface{}{}
+		var last int16
+		for {
+			h := d.buf[0]
+			d.buf = d.buf[1:]
+			if h == 0 {
+				return m
+			}
+			if h>>4 != 0 {
+				last += int16(h >> 4)
+			} else {
+				last = int16(d.varint())
+			}
+			m[last] = d.value(h & 0xf)
+		}
+	}
+	panic("unknown type")
+}
+
+func TestWrite(t *testing.T) {
+	tr := rtree.New(nil)
+	tr.Insert(pair.New([]byte("a"), geobin.Make2DPoint(1, 2).Binary()))
+	tr.Insert(pair.New([]byte("bb"), geobin.Make3DRect(1, 2, 3, 4, 5, 6).Binary()))
+	tr.Insert(pair.New([]byte("ccc"), geobin.Make3DPoint(7, 8, 9).Binary()))
+	var buf bytes.Buffer
+	opts := *DefaultOptions
+	opts.Values = true
+	opts.RowGroupSize = 2
+	assert.NoError(t, Write(&buf, tr.ScanSorted, &opts))
+	data := buf.Bytes()
+	assert.Equal(t, magic, string(data[:4]))
+	assert.Equal(t, magic, string(data[len(data)-4:]))
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	d := &decoder{data[len(data)-8-size : len(data)-8]}
+	meta := d.value(tStruct).(map[int16]interface{})
+	assert.Equal(t, 0, len(d.buf))
+	assert.Equal(t, int64(3), meta[3])
+	var names []string
+	for _, el := range meta[2].([]interface{}) {
+		names = append(names, string(el.(map[int16]interface{})[4].([]byte)))
+	}
+	assert.Equal(t, []string{"schema", "key", "min_x", "min_y", "min_z", "max_x", "max_y", "max_z", "value"}, names)
+
+	groups := meta[4].([]interface{})
+	assert.Equal(t, 2, len(groups))
+	var keys []string
+	var minz []float64
+	var nulls []int64
+	for _, g := range groups {
+		chunks := g.(map[int16]interface{})[1].([]interface{})
+		rows := int(g.(map[int16]interface{})[3].(int64))
+		// key column
+		cmeta := chunks[0].(map[int16]interface{})[3].(map[int16]interface{})
+		page := &decoder{data[cmeta[9].(int64):]}
+		hdr := page.value(tStruct).(map[int16]interface{})
+		body := page.buf[:hdr[2].(int64)]
+		for i := 0; i < rows; i++ {
+			n := binary.LittleEndian.Uint32(body)
+			keys = append(keys, string(body[4:4+n]))
+			body = body[4+n:]
+		}
+		// min_z column, with definition levels
+		cmeta = chunks[3].(map[int16]interface{})[3].(map[int16]interface{})
+		page = &decoder{data[cmeta[9].(int64):]}
+		hdr = page.value(tStruct).(map[int16]interface{})
+		body = page.buf[:hdr[2].(int64)]
+		levels := binary.LittleEndian.Uint32(body)
+		body = body[4+levels:]
+		for len(body) > 0 {
+			minz = append(minz, math.Float64frombits(binary.LittleEndian.Uint64(body)))
+			body = body[8:]
+		}
+		nulls = append(nulls, cmeta[12].(map[int16]interface{})[3].(int64))
+	}
+	assert.Equal(t, []string{"a", "bb", "ccc"}, keys)
+	assert.Equal(t, []float64{3, 9}, minz)
+	assert.Equal(t, []int64{1, 0}, nulls)
+	assert.Equal(t, []byte{1 << 1, 0, 1 << 1, 1}, definitionLevels([]bool{false, true})[4:])
+}
@@ -0,0 +1,94 @@
+package parquet
+
+import "encoding/binary"
+
+// A minimal Thrift compact protocol encoder for the Parquet metadata.
+
+const (
+	tI32    = 5
+	tI64    = 6
+	tBinary = 8
+	tList   = 9
+	tStruct = 12
+)
+
+type thrift struct {
+	buf  []byte
+	last []int16 // last field id of each open struct
+}
+
+func (t *thrift) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	t.buf = append(t.buf, b[:binary.PutUvarint(b[:], v)]...)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func (t *thrift) field(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.varint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (t *thrift) begin() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thrift) end() {
+	t.buf = append(t.buf, 0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thrift) i32(id int16, v int32) {
+	t.field(id, tI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thrift) i64(id int16, v int64) {
+	t.field(id, tI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thrift) binary(id int16, v []byte) {
+	t.field(id, tBinary)
+	t.varint(uint64(len(v)))
+	t.buf = append(t.buf, v...)
+}
+
+func (t *thrift) string(id int16, v string) {
+	t.binary(id, []byte(v))
+}
+
+// list starts a list field. Struct elements are written with begin and end,
+// and other elements with the elem methods.
+func (t *thrift) list(id int16, elemType byte, n int) {
+	t.field(id, tList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|elemType)
+	} else {
+		t.buf = append(t.buf, 0xf0|elemType)
+		t.varint(uint64(n))
+	}
+}
+
+func (t *thrift) elemI32(v int32) {
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thrift) elemString(v string) {
+	t.varint(uint64(len(v)))
+	t.buf = append(t.buf, v...)
+}
+
+// structField starts a struct field, which is ended with end.
+func (t *thrift) structField(id int16) {
+	t.field(id, tStruct)
+	t.begin()
+}
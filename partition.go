@@ -0,0 +1,102 @@
+package rtree
+
+import (
+	"math"
+	"sort"
+)
+
+// Box is a rect in the coordinates of the tree.
+type Box struct {
+	Min, Max [3]float64
+}
+
+// PartitionRegions splits the bounds of the tree into n boxes that hold
+// about the same number of items each, for spreading the items over workers
+// by their density rather than by area. The boxes are cut from the bounds
+// one at a time, at the count that splits the items in proportion to the
+// number of boxes on each side, across the axis that splits them closest to
+// that count. The counts come from the leaves, so they are off by up to a
+// leaf, and an item is counted in the box with the center of its leaf. The
+// boxes share their edges and cover the bounds. Returns nil for an empty
+// tree.
+func (tr *RTree) PartitionRegions(n int) []Box {
+	leaves := tr.leafSummaries()
+	if n <= 0 || len(leaves) == 0 {
+		return nil
+	}
+	min, max := tr.Bounds()
+	if !tr.isEmpty(2) {
+		// the 2d items are at a z of zero
+		min[2], max[2] = math.Min(min[2], 0), math.Max(max[2], 0)
+	}
+	return partition(Box{min, max}, leaves, n, nil)
+}
+
+// partition appends n boxes that split the box, which holds the leaves.
+func partition(box Box, leaves []leafSummary, n int, boxes []Box) []Box {
+	if n == 1 {
+		return append(boxes, box)
+	}
+	var total int
+	for _, leaf := range leaves {
+		total += leaf.count
+	}
+	left := n / 2
+	target := total * left / n
+	// cut across the axis that comes closest to the target, or the longest
+	// of those that are as close
+	axis, index, miss := -1, 0, 0
+	for a := 0; a < 3; a++ {
+		i, sum := cutLeaves(leaves, a, target)
+		d := abs(sum - target)
+		if axis == -1 || d < miss || (d == miss &&
+			box.Max[a]-box.Min[a] > box.Max[axis]-box.Min[axis]) {
+			axis, index, miss = a, i, d
+		}
+	}
+	sortLeaves(leaves, axis)
+	var cut float64
+	switch {
+	case index == 0:
+		cut = box.Min[axis]
+	case index == len(leaves):
+		cut = box.Max[axis]
+	default:
+		cut = (leaves[index-1].center[axis] + leaves[index].center[axis]) / 2
+	}
+	lbox, rbox := box, box
+	lbox.Max[axis], rbox.Min[axis] = cut, cut
+	boxes = partition(lbox, leaves[:index], left, boxes)
+	return partition(rbox, leaves[index:], n-left, boxes)
+}
+
+// cutLeaves sorts the leaves on the axis and returns the index at which to
+// cut them so the leaves before it hold about the target count, along with
+// their count. Leaves with the same center on the axis are not cut apart.
+func cutLeaves(leaves []leafSummary, axis, target int) (index, sum int) {
+	sortLeaves(leaves, axis)
+	var best, bestSum, n int
+	for i := 1; i <= len(leaves); i++ {
+		n += leaves[i-1].count
+		if i < len(leaves) && leaves[i-1].center[axis] == leaves[i].center[axis] {
+			continue
+		}
+		if abs(n-target) < abs(bestSum-target) {
+			best, bestSum = i, n
+		}
+	}
+	return best, bestSum
+}
+
+func sortLeaves(leaves []leafSummary, axis int) {
+	sort.Slice(leaves, func(i, j int) bool {
+		return leaves[i].center[axis] < leaves[j].center[axis]
+	})
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
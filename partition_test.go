@@ -0,0 +1,57 @@
+package rtree
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestPartitionRegions(t *testing.T) {
+	tr := New(nil)
+	assert.Equal(t, 0, len(tr.PartitionRegions(4)))
+	// a dense cluster and a sparse spread
+	for i := 0; i < 4000; i++ {
+		tr.Insert(makePointPair2("", rand.Float64(), rand.Float64()))
+	}
+	for i := 0; i < 1000; i++ {
+		tr.Insert(makePointPair3("", rand.Float64()*100, rand.Float64()*100, rand.Float64()))
+	}
+	assert.Equal(t, 0, len(tr.PartitionRegions(0)))
+	for _, n := range []int{1, 2, 5, 8} {
+		boxes := tr.PartitionRegions(n)
+		assert.Equal(t, n, len(boxes))
+		var total int
+		for _, box := range boxes {
+			// count the items by their centers, with the shared edges in
+			// the first box
+			var count int
+			tr.Scan(func(item pair.Pair) bool {
+				x, y, z := tr.position(item.Value())
+				if inBox(box, x, y, z) && firstBox(boxes, x, y, z) == box {
+					count++
+				}
+				return true
+			})
+			total += count
+			expect := tr.Count() / n
+			assert.True(t, count > expect-expect/4 && count < expect+expect/4)
+		}
+		assert.Equal(t, tr.Count(), total)
+	}
+}
+
+func inBox(box Box, x, y, z float64) bool {
+	return x >= box.Min[0] && x <= box.Max[0] && y >= box.Min[1] &&
+		y <= box.Max[1] && z >= box.Min[2] && z <= box.Max[2]
+}
+
+func firstBox(boxes []Box, x, y, z float64) Box {
+	for _, box := range boxes {
+		if inBox(box, x, y, z) {
+			return box
+		}
+	}
+	return Box{}
+}
@@ -0,0 +1,52 @@
+package rtree
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+// payloadLenSize is the size of the payload length near the end of a value.
+const payloadLenSize = 4
+
+// payloadMagic ends the value of an item from NewPayloadItem, after the
+// payload length, which tells it apart from a plain geobin object. The last
+// byte is the version of the layout.
+const payloadMagic = "gbp\x01"
+
+// NewPayloadItem returns an item with a value that is the geobin object
+// followed by the payload, the length of the payload, and a marker. The tree
+// reads the rect from the object at the front of the value, and the payload
+// is found from the end, so it's never decoded along with the object.
+func NewPayloadItem(key []byte, obj geobin.Object, payload []byte) pair.Pair {
+	b := obj.Binary()
+	value := make([]byte, len(b)+len(payload)+payloadLenSize+len(payloadMagic))
+	copy(value, b)
+	copy(value[len(b):], payload)
+	end := len(b) + len(payload)
+	binary.LittleEndian.PutUint32(value[end:], uint32(len(payload)))
+	copy(value[end+payloadLenSize:], payloadMagic)
+	return pair.New(key, value)
+}
+
+// ItemPayload returns the payload of an item that was created with
+// NewPayloadItem. The payload is a slice of the item value, so it's read in
+// search callbacks without copying it, or wrapping and parsing the geobin
+// object. Returns false if the value doesn't end with the marker of
+// NewPayloadItem, such as a plain geobin object, or if the payload length
+// is more than the value holds.
+func ItemPayload(item pair.Pair) ([]byte, bool) {
+	value := item.Value()
+	if len(value) < payloadLenSize+len(payloadMagic) ||
+		!bytes.HasSuffix(value, []byte(payloadMagic)) {
+		return nil, false
+	}
+	end := len(value) - len(payloadMagic) - payloadLenSize
+	n := binary.LittleEndian.Uint32(value[end:])
+	if uint64(n) > uint64(end) {
+		return nil, false
+	}
+	return value[end-int(n) : end : end], true
+}
@@ -0,0 +1,47 @@
+package rtree
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+func TestItemPayload(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 100; i++ {
+		payload := []byte(fmt.Sprintf("payload %d", i))
+		if i%2 == 0 {
+			tr.Insert(NewPayloadItem([]byte("a"), geobin.Make2DPoint(float64(i), 0), payload))
+		} else {
+			tr.Insert(NewPayloadItem([]byte("b"),
+				geobin.Make3DRect(float64(i), 0, 0, float64(i)+0.5, 1, 1), payload))
+		}
+	}
+	// the rects are read from the objects in front of the payloads
+	var got []string
+	tr.Search(makeBoundsPair2("", 9.75, -1, 12.25, 1), func(item pair.Pair) bool {
+		payload, ok := ItemPayload(item)
+		assert.True(t, ok)
+		got = append(got, string(payload))
+		return true
+	})
+	sort.Strings(got)
+	assert.Equal(t, []string{"payload 10", "payload 11", "payload 12"}, got)
+
+	payload, ok := ItemPayload(NewPayloadItem(nil, geobin.Make2DPoint(1, 2), nil))
+	assert.True(t, ok)
+	assert.Equal(t, 0, len(payload))
+	_, ok = ItemPayload(makePointPair2("key", 1, 2))
+	assert.False(t, ok)
+	_, ok = ItemPayload(pair.New([]byte("key"), []byte{1}))
+	assert.False(t, ok)
+	// the zeros at the end of a plain object are not a payload length
+	_, ok = ItemPayload(pair.New([]byte("key"), geobin.Make2DPoint(0, 0).Binary()))
+	assert.False(t, ok)
+	_, ok = ItemPayload(pair.New([]byte("key"), []byte("gbp\x01")))
+	assert.False(t, ok)
+}
@@ -0,0 +1,324 @@
+// Package query parses a small spatial query language and runs it against
+// a tree.
+//
+// A query is one or more clauses joined with AND, followed by an optional
+// LIMIT. Keywords are case insensitive.
+//
+//	INTERSECTS(minx, miny, maxx, maxy)
+//	INTERSECTS(minx, miny, minz, maxx, maxy, maxz)
+//	NEAREST(x, y)
+//	NEAREST(x, y, z)
+//	TAGGED("restaurant & open")
+//	LIMIT n
+//
+// For example:
+//
+//	INTERSECTS(-112, 33, -111, 34) AND NEAREST(-111.9, 33.4) LIMIT 10
+//
+// Items must match every INTERSECTS and TAGGED clause. With a NEAREST
+// clause the items are returned in order of distance from the point,
+// otherwise they're returned in search order. At most one NEAREST clause
+// is allowed, and a query must have at least one INTERSECTS or NEAREST
+// clause.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+// ErrNoSpatialClause is returned by Parse when a query does not have an
+// INTERSECTS or NEAREST clause.
+var ErrNoSpatialClause = errors.New("query: missing INTERSECTS or NEAREST clause")
+
+// Query is a parsed query.
+type Query struct {
+	Boxes   []pair.Pair // INTERSECTS boxes
+	Nearest *pair.Pair  // NEAREST point, or nil
+	Tags    []string    // TAGGED expressions
+	Limit   int         // LIMIT, or zero for no limit
+}
+
+type token struct {
+	kind byte // 'i' ident, 'n' number, 's' string, or punctuation
+	text string
+	pos  int
+}
+
+func tokenize(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')' || c == ',':
+			toks = append(toks, token{c, s[i : i+1], i})
+			i++
+		case c == '"' || c == '\'':
+			j := strings.IndexByte(s[i+1:], c)
+			if j == -1 {
+				return nil, fmt.Errorf("query: unterminated string at %d", i)
+			}
+			toks = append(toks, token{'s', s[i+1 : i+1+j], i})
+			i += j + 2
+		case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(s) && strings.IndexByte("0123456789.eE+-", s[j]) != -1 {
+				j++
+			}
+			toks = append(toks, token{'n', s[i:j], i})
+			i = j
+		case unicode.IsLetter(rune(c)):
+			j := i + 1
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '_') {
+				j++
+			}
+			toks = append(toks, token{'i', strings.ToUpper(s[i:j]), i})
+			i = j
+		default:
+			return nil, fmt.Errorf("query: unexpected '%c' at %d", c, i)
+		}
+	}
+	return toks, nil
+}
+
+type parser struct {
+	toks []token
+	i    int
+	end  int
+}
+
+func (p *parser) next() (token, error) {
+	if p.i == len(p.toks) {
+		return token{}, fmt.Errorf("query: unexpected end at %d", p.end)
+	}
+	p.i++
+	return p.toks[p.i-1], nil
+}
+
+func (p *parser) expect(kind byte) (token, error) {
+	tok, err := p.next()
+	if err != nil {
+		return tok, err
+	}
+	if tok.kind != kind {
+		return tok, fmt.Errorf("query: unexpected '%s' at %d", tok.text, tok.pos)
+	}
+	return tok, nil
+}
+
+// args parses a parenthesized list of numbers.
+func (p *parser) args() ([]float64, error) {
+	if _, err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var nums []float64
+	for {
+		tok, err := p.expect('n')
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil || math.IsNaN(n) {
+			return nil, fmt.Errorf("query: invalid number '%s' at %d", tok.text, tok.pos)
+		}
+		nums = append(nums, n)
+		if tok, err = p.next(); err != nil {
+			return nil, err
+		}
+		if tok.kind == ')' {
+			return nums, nil
+		}
+		if tok.kind != ',' {
+			return nil, fmt.Errorf("query: unexpected '%s' at %d", tok.text, tok.pos)
+		}
+	}
+}
+
+// Parse parses a query string.
+func Parse(s string) (*Query, error) {
+	toks, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks, end: len(s)}
+	q := &Query{}
+	for {
+		tok, err := p.expect('i')
+		if err != nil {
+			return nil, err
+		}
+		switch tok.text {
+		case "INTERSECTS":
+			nums, err := p.args()
+			if err != nil {
+				return nil, err
+			}
+			var box geobin.Object
+			switch len(nums) {
+			case 4:
+				box = geobin.Make2DRect(nums[0], nums[1], nums[2], nums[3])
+			case 6:
+				box = geobin.Make3DRect(nums[0], nums[1], nums[2], nums[3], nums[4], nums[5])
+			default:
+				return nil, fmt.Errorf("query: INTERSECTS at %d requires 4 or 6 arguments", tok.pos)
+			}
+			q.Boxes = append(q.Boxes, pair.New(nil, box.Binary()))
+		case "NEAREST":
+			if q.Nearest != nil {
+				return nil, fmt.Errorf("query: duplicate NEAREST at %d", tok.pos)
+			}
+			nums, err := p.args()
+			if err != nil {
+				return nil, err
+			}
+			var point geobin.Object
+			switch len(nums) {
+			case 2:
+				point = geobin.Make2DPoint(nums[0], nums[1])
+			case 3:
+				point = geobin.Make3DPoint(nums[0], nums[1], nums[2])
+			default:
+				return nil, fmt.Errorf("query: NEAREST at %d requires 2 or 3 arguments", tok.pos)
+			}
+			item := pair.New(nil, point.Binary())
+			q.Nearest = &item
+		case "TAGGED":
+			if _, err := p.expect('('); err != nil {
+				return nil, err
+			}
+			expr, err := p.expect('s')
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(')'); err != nil {
+				return nil, err
+			}
+			q.Tags = append(q.Tags, expr.text)
+		default:
+			return nil, fmt.Errorf("query: unknown clause '%s' at %d", tok.text, tok.pos)
+		}
+		if p.i == len(p.toks) {
+			break
+		}
+		if tok, err = p.expect('i'); err != nil {
+			return nil, err
+		}
+		if tok.text == "AND" {
+			continue
+		}
+		if tok.text != "LIMIT" {
+			return nil, fmt.Errorf("query: unexpected '%s' at %d", tok.text, tok.pos)
+		}
+		if tok, err = p.expect('n'); err != nil {
+			return nil, err
+		}
+		limit, err := strconv.Atoi(tok.text)
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("query: invalid limit '%s' at %d", tok.text, tok.pos)
+		}
+		q.Limit = limit
+		if p.i != len(p.toks) {
+			tok = p.toks[p.i]
+			return nil, fmt.Errorf("query: unexpected '%s' at %d", tok.text, tok.pos)
+		}
+		break
+	}
+	if len(q.Boxes) == 0 && q.Nearest == nil {
+		return nil, ErrNoSpatialClause
+	}
+	return q, nil
+}
+
+// Run runs the query on the tree. The dist is zero unless the query has a
+// NEAREST clause.
+func (q *Query) Run(tr *rtree.RTree, iter func(item pair.Pair, dist float64) bool) bool {
+	var n int
+	emit := func(item pair.Pair, dist float64) bool {
+		n++
+		return iter(item, dist) && (q.Limit == 0 || n < q.Limit)
+	}
+	// candidates are the items that match every box and tag expression,
+	// except for the first box when there's no NEAREST clause, which is
+	// used for the final search instead.
+	var candidates map[pair.Pair]bool
+	boxes := q.Boxes
+	tags := q.Tags
+	if q.Nearest == nil {
+		boxes = boxes[1:]
+	}
+	filter := func(box pair.Pair, expr string) {
+		next := make(map[pair.Pair]bool)
+		tr.SearchTagged(box, expr, func(item pair.Pair) bool {
+			if candidates == nil || candidates[item] {
+				next[item] = true
+			}
+			return true
+		})
+		candidates = next
+	}
+	for _, box := range boxes {
+		filter(box, "")
+	}
+	if candidates != nil || q.Nearest == nil {
+		for _, expr := range tags {
+			if candidates == nil {
+				filter(q.Boxes[0], expr)
+			} else {
+				next := make(map[pair.Pair]bool)
+				for item := range candidates {
+					if tr.MatchTags(item, expr) {
+						next[item] = true
+					}
+				}
+				candidates = next
+			}
+		}
+		tags = nil
+	}
+	if q.Nearest == nil {
+		return tr.Search(q.Boxes[0], func(item pair.Pair) bool {
+			if candidates != nil && !candidates[item] {
+				return true
+			}
+			return emit(item, 0)
+		})
+	}
+	remain := len(candidates)
+	if candidates != nil && remain == 0 {
+		return true
+	}
+	return tr.KNN(*q.Nearest, func(item pair.Pair, dist float64) bool {
+		if candidates != nil {
+			if !candidates[item] {
+				return true
+			}
+			remain--
+		}
+		for _, expr := range tags {
+			if !tr.MatchTags(item, expr) {
+				return true
+			}
+		}
+		return emit(item, dist) && (candidates == nil || remain > 0)
+	})
+}
+
+// Run parses and runs a query on the tree.
+func Run(tr *rtree.RTree, s string, iter func(item pair.Pair, dist float64) bool) error {
+	q, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	q.Run(tr, iter)
+	return nil
+}
@@ -0,0 +1,65 @@
+package query
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+func TestParse(t *testing.T) {
+	q, err := Parse(`intersects(0, 0, 10, 10) AND NEAREST(1,2) AND TAGGED("a & b") LIMIT 5`)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(q.Boxes))
+	assert.NotNil(t, q.Nearest)
+	assert.Equal(t, []string{"a & b"}, q.Tags)
+	assert.Equal(t, 5, q.Limit)
+
+	for _, s := range []string{
+		"",
+		"LIMIT 10",
+		"TAGGED('a')",
+		"INTERSECTS(1,2,3)",
+		"INTERSECTS(1,2,3,4",
+		"INTERSECTS(1,2,3,4) OR NEAREST(1,2)",
+		"NEAREST(1,2) AND NEAREST(1,2)",
+		"NEAREST(1,2) LIMIT 0",
+		"NEAREST(1,2) LIMIT 10 AND",
+		"NEAREST(1,x)",
+		"NEAREST(1,2) ?",
+	} {
+		_, err := Parse(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestRun(t *testing.T) {
+	tr := rtree.New(nil)
+	for i := 0; i < 100; i++ {
+		item := pair.New([]byte(fmt.Sprint(i)), geobin.Make2DPoint(float64(i), float64(i)).Binary())
+		tr.Insert(item)
+		if i%2 == 0 {
+			tr.Tag(item, "even")
+		}
+	}
+	run := func(s string) []string {
+		var keys []string
+		err := Run(tr, s, func(item pair.Pair, dist float64) bool {
+			keys = append(keys, string(item.Key()))
+			return true
+		})
+		assert.NoError(t, err)
+		return keys
+	}
+	assert.Equal(t, 11, len(run("INTERSECTS(10, 10, 20, 20)")))
+	assert.Equal(t, 6, len(run("INTERSECTS(10, 10, 20, 20) AND INTERSECTS(15, 15, 30, 30)")))
+	assert.Equal(t, 6, len(run("INTERSECTS(10, 10, 20, 20) AND TAGGED('even')")))
+	assert.Equal(t, 3, len(run("INTERSECTS(10, 10, 20, 20) LIMIT 3")))
+	assert.Equal(t, []string{"50", "49", "51"}, run("NEAREST(49.9, 49.9) LIMIT 3"))
+	assert.Equal(t, []string{"50", "52", "48"}, run("NEAREST(50.9, 50.9) AND TAGGED('even') LIMIT 3"))
+	assert.Equal(t, []string{"12", "13", "11", "14", "10"}, run("INTERSECTS(10, 10, 14, 14) AND NEAREST(12.1, 12.1)"))
+	assert.Equal(t, []string{"12", "14", "10"}, run("INTERSECTS(10, 10, 14, 14) AND NEAREST(12.1, 12.1) AND TAGGED('even')"))
+}
@@ -0,0 +1,78 @@
+package rtree
+
+import (
+	"sync/atomic"
+
+	"github.com/tidwall/pair"
+	rtree2 "github.com/tidwall/pair-rtree/2d"
+	rtree3 "github.com/tidwall/pair-rtree/3d"
+)
+
+// QueryContext runs queries on a tree with buffers that are kept from one
+// query to the next, so that repeated queries don't allocate once the
+// buffers have grown. A context is not safe for concurrent use, so each
+// goroutine should have its own. The slices that a query returns are reused
+// by the next query on the context. The queries of a context don't apply
+// DedupKeys or Geodesic.
+type QueryContext struct {
+	tr    *RTree
+	ctx2  *rtree2.QueryContext
+	ctx3  *rtree3.QueryContext
+	items []pair.Pair
+	dists []float64
+	add   func(item pair.Pair) bool
+}
+
+// NewQueryContext returns a context for querying the tree.
+func (tr *RTree) NewQueryContext() *QueryContext {
+	ctx := &QueryContext{
+		tr:   tr,
+		ctx2: tr.tr2.NewQueryContext(),
+		ctx3: tr.tr3.NewQueryContext(),
+	}
+	ctx.add = func(item pair.Pair) bool {
+		ctx.items = append(ctx.items, item)
+		return true
+	}
+	return ctx
+}
+
+// Search returns the items that intersect the box.
+func (ctx *QueryContext) Search(box pair.Pair) []pair.Pair {
+	ctx.items = ctx.items[:0]
+	min, max := ctx.tr.rect(box.Value())
+	ctx.tr.searchRect(ctx.tr.dims(box.Value()), min, max, ctx.add)
+	return ctx.items
+}
+
+// KNN returns the k nearest items to the point, which is in the coordinates
+// of the tree, nearest first, with their distances as in KNNK.
+func (ctx *QueryContext) KNN(x, y, z float64, k int) (items []pair.Pair, dists []float64) {
+	ctx.items, ctx.dists = ctx.items[:0], ctx.dists[:0]
+	if k <= 0 {
+		return ctx.items, ctx.dists
+	}
+	atomic.AddUint64(&ctx.tr.stats.knns, 1)
+	var items2, items3 []pair.Pair
+	var dists2, dists3 []float64
+	if !ctx.tr.isEmpty(2) {
+		items2, dists2 = ctx.ctx2.KNN(x, y, k)
+	}
+	if !ctx.tr.isEmpty(3) {
+		items3, dists3 = ctx.ctx3.KNN(x, y, z, k)
+	}
+	// merge the nearest of each tree
+	var i, j int
+	for len(ctx.items) < k && (i < len(items2) || j < len(items3)) {
+		if j == len(items3) || (i < len(items2) && dists2[i] < dists3[j]) {
+			ctx.items = append(ctx.items, items2[i])
+			ctx.dists = append(ctx.dists, dists2[i])
+			i++
+		} else {
+			ctx.items = append(ctx.items, items3[j])
+			ctx.dists = append(ctx.dists, dists3[j])
+			j++
+		}
+	}
+	return ctx.items, ctx.dists
+}
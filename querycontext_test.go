@@ -0,0 +1,41 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestQueryContext(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 1000; i++ {
+		tr.Insert(rand2DPoint())
+		tr.Insert(rand3DPoint())
+	}
+	ctx := tr.NewQueryContext()
+	box := makeBoundsPair2("", -50, -50, 50, 50)
+	for _, box := range []pair.Pair{rand3DRect(), rand3DRect(), box} {
+		var n int
+		tr.Search(box, func(item pair.Pair) bool {
+			n++
+			return true
+		})
+		assert.Equal(t, n, len(ctx.Search(box)))
+	}
+
+	var expect []float64
+	tr.ScanNearest(10, 20, 5, func(item pair.Pair, dist float64) bool {
+		expect = append(expect, dist)
+		return len(expect) < 30
+	})
+	items, dists := ctx.KNN(10, 20, 5, 30)
+	assert.Equal(t, 30, len(items))
+	assert.Equal(t, expect, dists)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		ctx.Search(box)
+		ctx.KNN(10, 20, 5, 30)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
@@ -0,0 +1,60 @@
+package rtree
+
+import (
+	"errors"
+
+	"github.com/tidwall/pair"
+)
+
+// ErrInvalidRect is returned by Repair when an item has a NaN coordinate.
+var ErrInvalidRect = errors.New("item has an invalid rect")
+
+// Repair recomputes the node bboxes of the 2d and 3d trees and returns the
+// number of nodes that were wrong. Items that were changed between 2d and 3d
+// after they were inserted are moved to the other tree, and each one that is
+// moved is also counted. The rects kept by the key index are refreshed too.
+// Returns ErrFrozen for a frozen tree, and ErrInvalidRect when an item has a
+// NaN coordinate.
+func (tr *RTree) Repair() (fixed int, err error) {
+	if tr.frozen {
+		return 0, ErrFrozen
+	}
+	tr.beginWrite()
+	defer tr.endWrite()
+	fixed2, err2 := tr.tr2.Repair()
+	fixed3, err3 := tr.tr3.Repair()
+	fixed = fixed2 + fixed3
+	if err2 != nil || err3 != nil {
+		err = ErrInvalidRect
+	}
+	var moved2, moved3 []pair.Pair
+	tr.tr2.Scan(func(item pair.Pair) bool {
+		if tr.dims(item.Value()) != 2 {
+			moved2 = append(moved2, item)
+		}
+		return true
+	})
+	tr.tr3.Scan(func(item pair.Pair) bool {
+		if tr.dims(item.Value()) == 2 {
+			moved3 = append(moved3, item)
+		}
+		return true
+	})
+	for _, item := range moved2 {
+		tr.tr2.Remove(item)
+		tr.tr3.Insert(item)
+	}
+	for _, item := range moved3 {
+		tr.tr3.Remove(item)
+		tr.tr2.Insert(item)
+	}
+	if tr.keys != nil {
+		tr.keys.ascend(nil, func(entry *keyEntry) bool {
+			min, max := tr.rect(entry.item.Value())
+			entry.rect = [2][3]float64{min, max}
+			return true
+		})
+	}
+	tr.refingerprint()
+	return fixed + len(moved2) + len(moved3), err
+}
@@ -0,0 +1,47 @@
+package rtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+func TestRepair(t *testing.T) {
+	tr := New(nil)
+	var items []pair.Pair
+	for i := 0; i < 1000; i++ {
+		item := rand2DPoint()
+		if i%2 == 1 {
+			item = rand3DPoint()
+		}
+		tr.Insert(item)
+		items = append(items, item)
+	}
+	fixed, err := tr.Repair()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fixed)
+
+	// move an item far outside of its node by changing its value in place
+	value := items[0].Value()
+	copy(value, geobin.Make2DPoint(1000, 1000).Binary())
+	found := func() bool {
+		var ok bool
+		tr.Search(makeBoundsPair2("", 999, 999, 1001, 1001), func(item pair.Pair) bool {
+			ok = item == items[0]
+			return !ok
+		})
+		return ok
+	}
+	assert.False(t, found())
+	fixed, err = tr.Repair()
+	assert.NoError(t, err)
+	assert.True(t, fixed > 0)
+	assert.True(t, found())
+
+	copy(items[2].Value(), geobin.Make2DPoint(math.NaN(), 0).Binary())
+	_, err = tr.Repair()
+	assert.Equal(t, ErrInvalidRect, err)
+}
@@ -0,0 +1,25 @@
+package rtree
+
+import (
+	"math"
+
+	"github.com/tidwall/pair"
+)
+
+// Representatives iterates over up to perNode items from each leaf of the 2d
+// and 3d trees that intersect the box, for thinning out dense areas before
+// rendering or sending a preview. The box is read like it is by Search.
+func (tr *RTree) Representatives(box pair.Pair, perNode int,
+	iter func(item pair.Pair) bool) bool {
+	min, max := tr.rect(box.Value())
+	min2, max2 := [2]float64{min[0], min[1]}, [2]float64{max[0], max[1]}
+	if tr.dims(box.Value()) == 2 {
+		min[2], max[2] = math.Inf(-1), math.Inf(+1)
+	}
+	if tr.coversZero(min[2], max[2]) {
+		if !tr.tr2.RepresentativesRect(min2, max2, perNode, iter) {
+			return false
+		}
+	}
+	return tr.tr3.RepresentativesRect(min, max, perNode, iter)
+}
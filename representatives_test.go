@@ -0,0 +1,41 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestRepresentatives(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 2000; i++ {
+		tr.Insert(rand2DPoint())
+		tr.Insert(rand3DPoint())
+	}
+	box := makeBoundsPair2("", -90, -45, 90, 45)
+	found := make(map[pair.Pair]bool)
+	tr.Search(box, func(item pair.Pair) bool {
+		found[item] = true
+		return true
+	})
+	var dims2, dims3 int
+	tr.Representatives(box, 2, func(item pair.Pair) bool {
+		assert.True(t, found[item])
+		if tr.dims(item.Value()) == 2 {
+			dims2++
+		} else {
+			dims3++
+		}
+		return true
+	})
+	assert.True(t, dims2 > 0 && dims3 > 0)
+	assert.True(t, dims2+dims3 < len(found))
+
+	var n int
+	tr.Representatives(box, 1000, func(item pair.Pair) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, len(found), n)
+}
@@ -0,0 +1,154 @@
+// Package results encodes search and KNN results directly to MessagePack
+// or CBOR streams.
+//
+// Each result is encoded as a three element array of the key as binary, the
+// bbox as an array of 4 or 6 floats (the 2d or 3d min followed by the max),
+// and the distance as a float, which is zero for searches. The results are
+// written back to back, as a MessagePack stream or a CBOR sequence.
+package results
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+type Format int
+
+const (
+	Msgpack Format = iota
+	CBOR
+)
+
+// Encoder writes results to a stream. It's buffered, so Flush must be
+// called when done.
+type Encoder struct {
+	w      *bufio.Writer
+	format Format
+	buf    []byte
+	err    error
+}
+
+func NewEncoder(w io.Writer, format Format) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w), format: format}
+}
+
+// Encode writes a single result. Once a write fails, Encode returns that
+// error for all future calls.
+func (e *Encoder) Encode(item pair.Pair, dist float64) error {
+	if e.err != nil {
+		return e.err
+	}
+	g := geobin.WrapBinary(item.Value())
+	dims := g.Dims()
+	min, max := g.Rect(nil)
+	b := e.buf[:0]
+	if e.format == CBOR {
+		b = append(b, 0x83)
+		b = cborHead(b, 2, uint64(len(item.Key())))
+		b = append(b, item.Key()...)
+		b = append(b, 0x80|byte(dims*2))
+		b = appendCBORFloats(b, min[:dims])
+		b = appendCBORFloats(b, max[:dims])
+		b = appendCBORFloats(b, []float64{dist})
+	} else {
+		b = append(b, 0x93)
+		b = msgpackBin(b, len(item.Key()))
+		b = append(b, item.Key()...)
+		b = append(b, 0x90|byte(dims*2))
+		b = appendMsgpackFloats(b, min[:dims])
+		b = appendMsgpackFloats(b, max[:dims])
+		b = appendMsgpackFloats(b, []float64{dist})
+	}
+	e.buf = b
+	_, e.err = e.w.Write(b)
+	return e.err
+}
+
+// Flush writes any buffered results to the underlying writer.
+func (e *Encoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	e.err = e.w.Flush()
+	return e.err
+}
+
+// Err returns the first write error.
+func (e *Encoder) Err() error {
+	return e.err
+}
+
+// Search returns an iterator for a tree Search that encodes each item and
+// stops on the first error.
+func (e *Encoder) Search() func(item pair.Pair) bool {
+	return func(item pair.Pair) bool {
+		return e.Encode(item, 0) == nil
+	}
+}
+
+// KNN returns an iterator for a tree KNN that encodes each item and stops on
+// the first error.
+func (e *Encoder) KNN() func(item pair.Pair, dist float64) bool {
+	return func(item pair.Pair, dist float64) bool {
+		return e.Encode(item, dist) == nil
+	}
+}
+
+func msgpackBin(b []byte, n int) []byte {
+	switch {
+	case n <= math.MaxUint8:
+		return append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xc5, 0, 0)
+		binary.BigEndian.PutUint16(b[len(b)-2:], uint16(n))
+		return b
+	default:
+		b = append(b, 0xc6, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(b[len(b)-4:], uint32(n))
+		return b
+	}
+}
+
+func appendMsgpackFloats(b []byte, fs []float64) []byte {
+	for _, f := range fs {
+		b = append(b, 0xcb, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(b[len(b)-8:], math.Float64bits(f))
+	}
+	return b
+}
+
+// cborHead appends the initial bytes of a CBOR data item.
+func cborHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, major|25, 0, 0)
+		binary.BigEndian.PutUint16(b[len(b)-2:], uint16(n))
+		return b
+	case n <= math.MaxUint32:
+		b = append(b, major|26, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(b[len(b)-4:], uint32(n))
+		return b
+	default:
+		b = append(b, major|27, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(b[len(b)-8:], n)
+		return b
+	}
+}
+
+func appendCBORFloats(b []byte, fs []float64) []byte {
+	for _, f := range fs {
+		b = append(b, 0xfb, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(b[len(b)-8:], math.Float64bits(f))
+	}
+	return b
+}
@@ -0,0 +1,47 @@
+package results
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+func TestEncode(t *testing.T) {
+	item := pair.New([]byte("a"), geobin.Make2DPoint(1, 2).Binary())
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, Msgpack)
+	assert.NoError(t, e.Encode(item, 0.5))
+	assert.NoError(t, e.Flush())
+	assert.Equal(t, "93c4016194"+
+		"cb3ff0000000000000cb4000000000000000"+
+		"cb3ff0000000000000cb4000000000000000"+
+		"cb3fe0000000000000", hex.EncodeToString(buf.Bytes()))
+
+	buf.Reset()
+	e = NewEncoder(&buf, CBOR)
+	assert.NoError(t, e.Encode(item, 0.5))
+	assert.NoError(t, e.Flush())
+	assert.Equal(t, "83416184"+
+		"fb3ff0000000000000fb4000000000000000"+
+		"fb3ff0000000000000fb4000000000000000"+
+		"fb3fe0000000000000", hex.EncodeToString(buf.Bytes()))
+}
+
+func TestStream(t *testing.T) {
+	tr := rtree.New(nil)
+	for i := 0; i < 100; i++ {
+		tr.Insert(pair.New([]byte("k"), geobin.Make3DPoint(float64(i), 0, 0).Binary()))
+	}
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, CBOR)
+	tr.Search(pair.New(nil, geobin.Make2DRect(0, 0, 9, 0).Binary()), e.Search())
+	assert.NoError(t, e.Flush())
+	// header, key, 6 coords, and dist
+	size := 1 + 2 + 1 + 9*7
+	assert.Equal(t, size*10, buf.Len())
+}
@@ -0,0 +1,169 @@
+// Package rpc is a transport-agnostic implementation of the RTree service
+// that is defined in rtree.proto.
+//
+// This package has no gRPC glue and no generated code. It only has the
+// Service, which takes plain Go types, so it can be served over gRPC, HTTP,
+// or anything else. To serve it over gRPC, generate the rtreepb package from
+// rtree.proto and write a server that forwards each call to the Service,
+// converting between the generated messages and the types in this package.
+// Streaming calls take a Stream, which the generated server stream can be
+// wrapped to satisfy.
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+var ErrInvalidRequest = errors.New("invalid request")
+
+type Item struct {
+	Key   []byte
+	Value []byte // geobin
+}
+
+type SearchRequest struct {
+	Min, Max []float64 // 2 or 3 coordinates
+	Limit    uint32    // zero for no limit
+}
+
+type KNNRequest struct {
+	Point []float64 // 2 or 3 coordinates
+	Limit uint32    // zero for no limit
+}
+
+type Result struct {
+	Key   []byte
+	Value []byte  // geobin
+	Dist  float64 // knn only
+}
+
+// Stream receives the results of a streaming call.
+type Stream interface {
+	Context() context.Context
+	Send(*Result) error
+}
+
+// Service serves a tree. Calls are serialized with an internal lock, so the
+// tree must not be modified elsewhere while the service is in use.
+type Service struct {
+	mu sync.RWMutex
+	tr *rtree.RTree
+}
+
+func NewService(tr *rtree.RTree) *Service {
+	return &Service{tr: tr}
+}
+
+func (s *Service) Insert(ctx context.Context, item *Item) error {
+	if dims := geobin.WrapBinary(item.Value).Dims(); dims != 2 && dims != 3 {
+		return ErrInvalidRequest
+	}
+	s.mu.Lock()
+	s.tr.Insert(pair.New(item.Key, item.Value))
+	s.mu.Unlock()
+	return nil
+}
+
+// Delete removes the item with the same key and value, and returns false if
+// there was no such item.
+func (s *Service) Delete(ctx context.Context, item *Item) (bool, error) {
+	if dims := geobin.WrapBinary(item.Value).Dims(); dims != 2 && dims != 3 {
+		return false, ErrInvalidRequest
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var found pair.Pair
+	s.tr.Search(pair.New(nil, item.Value), func(candidate pair.Pair) bool {
+		if bytes.Equal(candidate.Key(), item.Key) &&
+			bytes.Equal(candidate.Value(), item.Value) {
+			found = candidate
+			return false
+		}
+		return true
+	})
+	if found.Zero() {
+		return false, nil
+	}
+	s.tr.Remove(found)
+	return true, nil
+}
+
+// Search streams the items that intersect the box. The stream stops early
+// when the context is done or a send fails.
+func (s *Service) Search(req *SearchRequest, stream Stream) error {
+	var box geobin.Object
+	switch {
+	case len(req.Min) == 2 && len(req.Max) == 2:
+		box = geobin.Make2DRect(req.Min[0], req.Min[1], req.Max[0], req.Max[1])
+	case len(req.Min) == 3 && len(req.Max) == 3:
+		box = geobin.Make3DRect(req.Min[0], req.Min[1], req.Min[2],
+			req.Max[0], req.Max[1], req.Max[2])
+	default:
+		return ErrInvalidRequest
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	send := sender(req.Limit, stream)
+	var err error
+	s.tr.Search(pair.New(nil, box.Binary()), func(item pair.Pair) bool {
+		err = send(item, 0)
+		return err == nil
+	})
+	return ignoreDone(err)
+}
+
+// KNN streams the items nearest to the point.
+func (s *Service) KNN(req *KNNRequest, stream Stream) error {
+	var point geobin.Object
+	switch len(req.Point) {
+	case 2:
+		point = geobin.Make2DPoint(req.Point[0], req.Point[1])
+	case 3:
+		point = geobin.Make3DPoint(req.Point[0], req.Point[1], req.Point[2])
+	default:
+		return ErrInvalidRequest
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	send := sender(req.Limit, stream)
+	var err error
+	s.tr.KNN(pair.New(nil, point.Binary()), func(item pair.Pair, dist float64) bool {
+		err = send(item, dist)
+		return err == nil
+	})
+	return ignoreDone(err)
+}
+
+// errDone stops a stream that reached its limit.
+var errDone = errors.New("done")
+
+func sender(limit uint32, stream Stream) func(item pair.Pair, dist float64) error {
+	var n uint32
+	return func(item pair.Pair, dist float64) error {
+		if err := stream.Context().Err(); err != nil {
+			return err
+		}
+		if err := stream.Send(&Result{item.Key(), item.Value(), dist}); err != nil {
+			return err
+		}
+		n++
+		if n == limit {
+			return errDone
+		}
+		return nil
+	}
+}
+
+func ignoreDone(err error) error {
+	if err == errDone {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,50 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+type stream struct {
+	ctx     context.Context
+	results []*Result
+}
+
+func (s *stream) Context() context.Context { return s.ctx }
+func (s *stream) Send(r *Result) error {
+	s.results = append(s.results, r)
+	return nil
+}
+
+func TestService(t *testing.T) {
+	s := NewService(rtree.New(nil))
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		item := &Item{[]byte{byte('a' + i)}, geobin.Make2DPoint(float64(i), float64(i)).Binary()}
+		assert.NoError(t, s.Insert(ctx, item))
+	}
+	st := &stream{ctx: ctx}
+	assert.NoError(t, s.Search(&SearchRequest{Min: []float64{2, 2}, Max: []float64{5, 5}}, st))
+	assert.Equal(t, 4, len(st.results))
+	st = &stream{ctx: ctx}
+	assert.NoError(t, s.KNN(&KNNRequest{Point: []float64{7.1, 7.1}, Limit: 2}, st))
+	assert.Equal(t, 2, len(st.results))
+	assert.Equal(t, "h", string(st.results[0].Key))
+	assert.Equal(t, ErrInvalidRequest, s.KNN(&KNNRequest{Point: []float64{1}}, st))
+
+	found, err := s.Delete(ctx, &Item{[]byte("h"), geobin.Make2DPoint(7, 7).Binary()})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	found, _ = s.Delete(ctx, &Item{[]byte("h"), geobin.Make2DPoint(7, 7).Binary()})
+	assert.False(t, found)
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	st = &stream{ctx: cctx}
+	assert.Equal(t, context.Canceled, s.KNN(&KNNRequest{Point: []float64{0, 0}}, st))
+	assert.Equal(t, 0, len(st.results))
+}
@@ -3,6 +3,7 @@ package rtree
 import (
 	"math"
 	"sync"
+	"sync/atomic"
 
 	"github.com/tidwall/geobin"
 	"github.com/tidwall/pair"
@@ -13,94 +14,431 @@ import (
 type transformer func(minIn, maxIn [3]float64) (minOut, maxOut [3]float64)
 
 type RTree struct {
-	tr2 *rtree2.RTree
-	tr3 *rtree3.RTree
-	t   transformer
+	tr2         *rtree2.RTree
+	tr3         *rtree3.RTree
+	t           transformer
+	rectFunc    func(value []byte) (min, max [3]float64, dims int)
+	grid        float64
+	watchers    []*watcher
+	subs        []chan Mutation
+	seq         uint64
+	keys        *keyIndex
+	tags        map[pair.Pair][]string
+	frozen      bool
+	copyItems   bool
+	geodesic    bool
+	dedupKeys   bool
+	exclusive   bool
+	keyBloom    bool
+	fingerprint uint64
+	stats       *stats
+	tileHook    *tileHook
+	snapMu      sync.Mutex // guards the nodes while a snapshot scan starts
+	shared      *share
+	obsolete    []*share // replaced by writes, but still read by scans
 }
 
 type Options struct {
 	MaxEntries  int
 	Transformer func(minIn, maxIn [3]float64) (minOut, maxOut [3]float64)
+	// KeyIndex maintains an ordered index of the items by key, which is
+	// used by Get, DeleteByKey, ScanKeys, and ScanSorted.
+	KeyIndex bool
+	// KeyBloom keeps a bloom filter of the keys under each node, so that
+	// Get, BoundsOf, and DeleteByKey without a KeyIndex only read the leaves
+	// that may have the key, rather than sorting every item. It's a middle
+	// ground for trees that can't afford the memory of a KeyIndex, at 64
+	// bytes per node and a little more work for each write.
+	KeyBloom bool
+	// RectFunc returns the rect and dimensions of an item value, which
+	// allows for values that are not geobin objects. It's also used for the
+	// values of the search and KNN pairs. The default reads the value as a
+	// geobin object.
+	RectFunc func(value []byte) (min, max [3]float64, dims int)
+	// Grid snaps the coordinates of items and queries to multiples of this
+	// resolution, after the transformer, which reduces float noise and packs
+	// gridded data into tighter nodes. Snapping the queries too means that
+	// an item that intersects a query before snapping is still found. Zero
+	// disables snapping.
+	Grid float64
+	// ReinsertOrphans removes nodes that are left with fewer than the
+	// minimum number of entries by a Remove, and reinserts their children.
+	ReinsertOrphans bool
+	// WrapX is the period at which the x axis wraps around, in the
+	// coordinates of the tree, so searches and KNN near an edge find the
+	// items on the other side. Zero disables wrapping.
+	WrapX float64
+	// Geodesic treats the untransformed item values as lon/lat positions,
+	// and has KNN report the great-circle distance in meters to the center
+	// of each item, rather than the distance in the tree. The items are still
+	// returned in the order of the tree, which is the great-circle order for
+	// points when the tree uses a lon/lat to xyz transformer.
+	Geodesic bool
+	// CopyItems stores a copy of the key and value of each inserted item,
+	// so the caller may reuse the memory of an item once it's inserted. The
+	// copy is released when the item is removed. Remove finds the copy by
+	// the key and value, and the items returned by the tree are the copies.
+	CopyItems bool
+	// DedupKeys has Search, SearchWith, KNN, and KNNWith report only the
+	// first item found for each key, so an object that's in both the 2d and
+	// 3d trees is seen once. The keys that were seen are kept for the length
+	// of each query.
+	DedupKeys bool
+	// ExpectedItems is a hint for the number of items that the tree will
+	// hold, which presizes the children of the nodes and the key index, so
+	// that loading many items one at a time doesn't keep growing them.
+	ExpectedItems int
+	// ExcludeTouching has searches skip the items that only touch the
+	// search box, sharing an edge or a corner with it but none of its
+	// inside, as with the interior intersection of some GIS systems. A point
+	// is then found only when it's inside of the box, not on its edge. The
+	// 2d items, which are at a z of zero, are only found by a 3d box that
+	// has zero inside of its z range. By default, touching items are found.
+	ExcludeTouching bool
 }
 
 var DefaultOptions = &Options{
 	MaxEntries:  9,
 	Transformer: nil,
+	KeyIndex:    false,
+	RectFunc:    nil,
+	Grid:        0,
 }
 
 func New(opts *Options) *RTree {
 	var opts2 *rtree2.Options
 	var opts3 *rtree3.Options
 	var t transformer
+	var rectFunc func(value []byte) (min, max [3]float64, dims int)
+	var grid float64
+	var keys *keyIndex
+	var copyItems bool
+	var geodesic bool
+	var dedupKeys bool
+	var exclusive bool
+	var keyBloom bool
 	if opts != nil {
 		opts2 = &rtree2.Options{}
 		*opts2 = *rtree2.DefaultOptions
 		opts2.MaxEntries = opts.MaxEntries
 		opts2.Transformer = opts.Transformer
+		opts2.RectFunc = opts.RectFunc
+		opts2.Grid = opts.Grid
+		opts2.ReinsertOrphans = opts.ReinsertOrphans
+		opts2.WrapX = opts.WrapX
+		opts2.ExpectedItems = opts.ExpectedItems
+		opts2.ExcludeTouching = opts.ExcludeTouching
+		if opts.KeyBloom {
+			opts2.Annotate = annotateKeys
+		}
 		opts3 = &rtree3.Options{}
 		*opts3 = *rtree3.DefaultOptions
 		opts3.MaxEntries = opts.MaxEntries
 		opts3.Transformer = opts.Transformer
+		opts3.RectFunc = opts.RectFunc
+		opts3.Grid = opts.Grid
+		opts3.ReinsertOrphans = opts.ReinsertOrphans
+		opts3.WrapX = opts.WrapX
+		opts3.ExpectedItems = opts.ExpectedItems
+		opts3.ExcludeTouching = opts.ExcludeTouching
+		if opts.KeyBloom {
+			opts3.Annotate = annotateKeys
+		}
 		t = opts.Transformer
+		rectFunc = opts.RectFunc
+		grid = opts.Grid
+		if opts.KeyIndex {
+			keys = newKeyIndex(opts.ExpectedItems)
+		}
+		copyItems = opts.CopyItems
+		geodesic = opts.Geodesic
+		dedupKeys = opts.DedupKeys
+		exclusive = opts.ExcludeTouching
+		keyBloom = opts.KeyBloom
 	}
 	return &RTree{
-		tr2: rtree2.New(opts2),
-		tr3: rtree3.New(opts3),
-		t:   t,
+		tr2:       rtree2.New(opts2),
+		tr3:       rtree3.New(opts3),
+		t:         t,
+		rectFunc:  rectFunc,
+		grid:      grid,
+		keys:      keys,
+		copyItems: copyItems,
+		geodesic:  geodesic,
+		dedupKeys: dedupKeys,
+		exclusive: exclusive,
+		keyBloom:  keyBloom,
+		stats:     &stats{},
+	}
+}
+
+// dims returns the number of dimensions of a value.
+func (tr *RTree) dims(value []byte) int {
+	if tr.rectFunc == nil {
+		return geobin.WrapBinary(value).Dims()
+	}
+	_, _, dims := tr.rectFunc(value)
+	return dims
+}
+
+// rect returns the transformed, and snapped, rect of a value.
+func (tr *RTree) rect(value []byte) (min, max [3]float64) {
+	return tr.rectWith(value, tr.t)
+}
+
+// rectWith is like rect, but uses the transformer rather than the tree's.
+func (tr *RTree) rectWith(value []byte, t transformer) (min, max [3]float64) {
+	if tr.rectFunc == nil {
+		min, max = geobin.WrapBinary(value).Rect(t)
+	} else {
+		min, max, _ = tr.rectFunc(value)
+		if t != nil {
+			min, max = t(min, max)
+		}
+	}
+	return tr.snap(min, max)
+}
+
+// snap snaps a rect that's in the coordinates of the tree to the grid.
+func (tr *RTree) snap(min, max [3]float64) ([3]float64, [3]float64) {
+	if tr.grid > 0 {
+		for i := 0; i < 3; i++ {
+			min[i] = math.Round(min[i]/tr.grid) * tr.grid
+			max[i] = math.Round(max[i]/tr.grid) * tr.grid
+		}
+	}
+	return min, max
+}
+
+// position returns the untransformed center of a value.
+func (tr *RTree) position(value []byte) (x, y, z float64) {
+	if tr.rectFunc == nil {
+		p := geobin.WrapBinary(value).Position()
+		return p.X, p.Y, p.Z
 	}
+	min, max, _ := tr.rectFunc(value)
+	return (min[0] + max[0]) / 2, (min[1] + max[1]) / 2, (min[2] + max[2]) / 2
 }
 
 func (tr *RTree) Insert(item pair.Pair) {
-	if geobin.WrapBinary(item.Value()).Dims() == 2 {
+	tr.checkFrozen()
+	item = tr.own(item)
+	tr.beginWrite()
+	if tr.dims(item.Value()) == 2 {
 		tr.tr2.Insert(item)
 	} else {
 		tr.tr3.Insert(item)
 	}
+	tr.endWrite()
+	tr.mutated(OpInsert, item)
+}
+
+// insertRect inserts an item with dims and a rect that were already
+// computed by dims and rect.
+func (tr *RTree) insertRect(item pair.Pair, dims int, min, max [3]float64) {
+	tr.beginWrite()
+	if dims == 2 {
+		tr.tr2.InsertRect(item, [2]float64{min[0], min[1]}, [2]float64{max[0], max[1]})
+	} else {
+		tr.tr3.InsertRect(item, min, max)
+	}
+	tr.endWrite()
+	tr.mutated(OpInsert, item)
 }
 
 func (tr *RTree) Remove(item pair.Pair) {
-	if geobin.WrapBinary(item.Value()).Dims() == 2 {
-		tr.tr2.Remove(item)
+	tr.RemoveOK(item)
+}
+
+// RemoveOK is like Remove, but returns false if the item wasn't in the
+// tree. The item is looked for in the 2d or 3d tree by its dimensions, so
+// an item that was changed between 2d and 3d after it was inserted isn't
+// found until Repair moves it.
+func (tr *RTree) RemoveOK(item pair.Pair) bool {
+	tr.checkFrozen()
+	item, ok := tr.owned(item)
+	return ok && tr.remove(item)
+}
+
+func (tr *RTree) remove(item pair.Pair) bool {
+	tr.beginWrite()
+	var removed bool
+	if tr.dims(item.Value()) == 2 {
+		removed = tr.tr2.RemoveOK(item)
 	} else {
-		tr.tr3.Remove(item)
+		removed = tr.tr3.RemoveOK(item)
 	}
+	tr.endWrite()
+	if removed {
+		tr.mutated(OpRemove, item)
+	}
+	return removed
 }
 
 func (tr *RTree) Search(box pair.Pair, iter func(item pair.Pair) bool) bool {
-	dims := geobin.WrapBinary(box.Value()).Dims()
-	min, max := geobin.WrapBinary(box.Value()).Rect(tr.t)
+	if tr.dedupKeys {
+		iter = dedupIter(iter)
+	}
+	min, max := tr.rect(box.Value())
+	return tr.searchRect(tr.dims(box.Value()), min, max, iter)
+}
+
+// searchRect searches with a rect that's in the coordinates of the tree.
+// The rect of a 2d box covers every z.
+func (tr *RTree) searchRect(dims int, min, max [3]float64,
+	iter func(item pair.Pair) bool) bool {
+	atomic.AddUint64(&tr.stats.searches, 1)
+	min2, max2 := [2]float64{min[0], min[1]}, [2]float64{max[0], max[1]}
 	if dims == 2 {
-		if !tr.tr2.Search(box, iter) {
+		if !tr.tr2.SearchRect(min2, max2, iter) {
 			return false
 		}
-		box = pair.New(nil, geobin.Make3DRect(min[0], min[1], math.Inf(-1), max[0], max[1], math.Inf(+1)).Binary())
-		return tr.tr3.Search(box, iter)
-	} else {
-		if min[2] <= 0 && max[2] >= 0 {
-			if !tr.tr2.Search(box, iter) {
-				return false
-			}
+		min[2], max[2] = math.Inf(-1), math.Inf(+1)
+		return tr.tr3.SearchRect(min, max, iter)
+	}
+	if tr.coversZero(min[2], max[2]) {
+		if !tr.tr2.SearchRect(min2, max2, iter) {
+			return false
 		}
-		return tr.tr3.Search(box, iter)
 	}
+	return tr.tr3.SearchRect(min, max, iter)
+}
+
+// coversZero returns true when the z range of a 3d box reaches the 2d items,
+// which are at a z of zero.
+func (tr *RTree) coversZero(minZ, maxZ float64) bool {
+	if tr.exclusive {
+		return minZ < 0 && maxZ > 0
+	}
+	return minZ <= 0 && maxZ >= 0
 }
+
+// intersects returns true when the rect of an item meets a search rect by
+// the rules of Search, which with ExcludeTouching needs more than a shared
+// edge or corner.
+func (tr *RTree) intersects(min, max, smin, smax [3]float64) bool {
+	if tr.exclusive {
+		return min[0] < smax[0] && min[1] < smax[1] && min[2] < smax[2] &&
+			max[0] > smin[0] && max[1] > smin[1] && max[2] > smin[2]
+	}
+	return min[0] <= smax[0] && min[1] <= smax[1] && min[2] <= smax[2] &&
+		max[0] >= smin[0] && max[1] >= smin[1] && max[2] >= smin[2]
+}
+
 func (tr *RTree) Count() int {
 	return tr.tr2.Count() + tr.tr3.Count()
 }
+
+// Count2D returns the number of items in the 2d tree.
+func (tr *RTree) Count2D() int {
+	return tr.tr2.Count()
+}
+
+// Count3D returns the number of items in the 3d tree.
+func (tr *RTree) Count3D() int {
+	return tr.tr3.Count()
+}
+
+// Underflows returns the number of nodes in the 2d and 3d trees that were
+// left under-filled by a Remove and then merged into a sibling or
+// reinserted. Both are zero unless the ReinsertOrphans option is set.
+func (tr *RTree) Underflows() (merged, reinserted int) {
+	merged2, reinserted2 := tr.tr2.Underflows()
+	merged3, reinserted3 := tr.tr3.Underflows()
+	return merged2 + merged3, reinserted2 + reinserted3
+}
+
+// KNNAxes is like KNN, but also passes the distance along each axis from the
+// position to the item. The z distance of a 2d item is zero. With the
+// Geodesic option the dist is in meters, but the axes are not.
+func (tr *RTree) KNNAxes(pos pair.Pair,
+	iter func(item pair.Pair, dist float64, axes [3]float64) bool) bool {
+	x, y, z := tr.position(pos.Value())
+	return tr.KNN(pos, func(item pair.Pair, dist float64) bool {
+		min, max := tr.rect(item.Value())
+		var axes [3]float64
+		for i, k := range [3]float64{x, y, z} {
+			axes[i] = math.Max(math.Max(min[i]-k, k-max[i]), 0)
+		}
+		if tr.dims(item.Value()) == 2 {
+			axes[2] = 0
+		}
+		return iter(item, dist, axes)
+	})
+}
+
 func (tr *RTree) KNN(pos pair.Pair, iter func(item pair.Pair, dist float64) bool) bool {
+	if tr.isEmpty(2) && tr.isEmpty(3) {
+		return true
+	}
+	if tr.geodesic {
+		iter = tr.geodesicIter(pos, iter)
+	}
+	if tr.dedupKeys {
+		iter = dedupKNNIter(iter)
+	}
+	x, y, z := tr.position(pos.Value())
+	return tr.knnPoint(x, y, z, iter)
+}
+
+// KNNDebug is like KNN, but also calls visit for each node as it's popped
+// from the queue of the 2d or 3d tree, which shows the regions that the
+// search expands into and why. The level of a node counts from one at the
+// leaves, and its distance is a lower bound on the distances of its items.
+// Each node is visited before the items that it leads to, though when both
+// trees have items, one tree may run ahead of the items passed to iter. The
+// rects of the 2d nodes have a z of zero.
+func (tr *RTree) KNNDebug(pos pair.Pair, iter func(item pair.Pair, dist float64) bool,
+	visit func(dims, level int, min, max [3]float64, dist float64)) bool {
+	if tr.isEmpty(2) && tr.isEmpty(3) {
+		return true
+	}
+	if tr.geodesic {
+		iter = tr.geodesicIter(pos, iter)
+	}
+	if tr.dedupKeys {
+		iter = dedupKNNIter(iter)
+	}
+	x, y, z := tr.position(pos.Value())
+	return tr.knnVisit(x, y, z, iter, visit)
+}
+
+// knnPoint performs the KNN from a point. At least one of the trees must not
+// be empty.
+func (tr *RTree) knnPoint(x, y, z float64, iter func(item pair.Pair, dist float64) bool) bool {
+	return tr.knnVisit(x, y, z, iter, nil)
+}
+
+// knnVisit is like knnPoint, but also calls visit, when it's not nil, for
+// each node that's popped from the queue of either tree.
+func (tr *RTree) knnVisit(x, y, z float64, iter func(item pair.Pair, dist float64) bool,
+	visit func(dims, level int, min, max [3]float64, dist float64)) bool {
+	atomic.AddUint64(&tr.stats.knns, 1)
 	empty2 := tr.isEmpty(2)
 	empty3 := tr.isEmpty(3)
-	if empty2 && empty3 {
-		return true
+	knn2 := func(iter func(item pair.Pair, dist float64) bool) bool {
+		if visit == nil {
+			return tr.tr2.KNN(x, y, iter)
+		}
+		return tr.tr2.KNNDebug(x, y, iter, func(level int, min, max [2]float64, dist float64) {
+			visit(2, level, [3]float64{min[0], min[1]}, [3]float64{max[0], max[1]}, dist)
+		})
+	}
+	knn3 := func(iter func(item pair.Pair, dist float64) bool) bool {
+		if visit == nil {
+			return tr.tr3.KNN(x, y, z, iter)
+		}
+		return tr.tr3.KNNDebug(x, y, z, iter, func(level int, min, max [3]float64, dist float64) {
+			visit(3, level, min, max, dist)
+		})
 	}
-	p := geobin.WrapBinary(pos.Value()).Position()
 	if empty3 {
 		// only 2d
-		return tr.tr2.KNN(p.X, p.Y, iter)
+		return knn2(iter)
 	}
 	if empty2 {
 		// only 3d
-		return tr.tr3.KNN(p.X, p.Y, p.Z, iter)
+		return knn3(iter)
 	}
 	// mux 3d and 2d
 	type ctx struct {
@@ -138,8 +476,20 @@ func (tr *RTree) KNN(pos pair.Pair, iter func(item pair.Pair, dist float64) bool
 		cond.Broadcast()
 		mu.Unlock()
 	}
-	go func() { qdone(tr.tr2.KNN(p.X, p.Y, fn(0))) }()
-	go func() { qdone(tr.tr3.KNN(p.X, p.Y, p.Z, fn(1))) }()
+	if visit != nil {
+		// the trees are searched together, so their nodes are reported
+		// one at a time, and not after the search has returned
+		v := visit
+		visit = func(dims, level int, min, max [3]float64, dist float64) {
+			mu.Lock()
+			if !exit {
+				v(dims, level, min, max, dist)
+			}
+			mu.Unlock()
+		}
+	}
+	go func() { qdone(knn2(fn(0))) }()
+	go func() { qdone(knn3(fn(1))) }()
 	for {
 		mu.Lock()
 		for len(queues[0]) > 0 && len(queues[1]) > 0 {
@@ -205,6 +555,19 @@ func (tr *RTree) Scan(iter func(item pair.Pair) bool) bool {
 	}
 	return tr.tr3.Scan(iter)
 }
+
+// Traverse iterates over every node and item in the 2d tree, followed by
+// the 3d tree. The 2d nodes and items have a z of zero.
+func (tr *RTree) Traverse(iter func(min, max [3]float64, level int, item pair.Pair) bool) {
+	var stop bool
+	tr.tr2.Traverse(func(min, max [2]float64, level int, item pair.Pair) bool {
+		stop = !iter([3]float64{min[0], min[1], 0}, [3]float64{max[0], max[1], 0}, level, item)
+		return !stop
+	})
+	if !stop {
+		tr.tr3.Traverse(iter)
+	}
+}
 func (tr *RTree) Bounds() (min, max [3]float64) {
 	empty2 := tr.isEmpty(2)
 	empty3 := tr.isEmpty(3)
@@ -235,16 +598,45 @@ func (tr *RTree) Bounds() (min, max [3]float64) {
 	return min, max
 }
 
+// Load bulk loads items, which is much faster than inserting them one at a
+// time. The items are split by their dimensions, and the 2d and 3d trees
+// are packed in parallel. Each item is then passed to the watchers and
+// subscribers as an insert.
 func (tr *RTree) Load(items []pair.Pair) {
+	tr.checkFrozen()
 	var items2D []pair.Pair
 	var items3D []pair.Pair
+	if tr.copyItems {
+		owned := make([]pair.Pair, len(items))
+		for i, item := range items {
+			owned[i] = tr.own(item)
+		}
+		items = owned
+	}
 	for _, item := range items {
-		if geobin.WrapBinary(item.Value()).Dims() == 2 {
+		if tr.dims(item.Value()) == 2 {
 			items2D = append(items2D, item)
 		} else {
 			items3D = append(items3D, item)
 		}
 	}
-	tr.tr2.Load(items2D)
-	tr.tr2.Load(items3D)
+	tr.beginWrite()
+	if len(items2D) > 0 && len(items3D) > 0 {
+		// the trees don't share anything, so they're built at the same time
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			tr.tr2.Load(items2D)
+			wg.Done()
+		}()
+		tr.tr3.Load(items3D)
+		wg.Wait()
+	} else {
+		tr.tr2.Load(items2D)
+		tr.tr3.Load(items3D)
+	}
+	tr.endWrite()
+	for _, item := range items {
+		tr.mutated(OpInsert, item)
+	}
 }
@@ -4,13 +4,16 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
-	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/json-iterator/go/assert"
 	"github.com/tidwall/geobin"
 	"github.com/tidwall/pair"
+	"github.com/tidwall/pair-rtree/gen"
+	"github.com/tidwall/pair-rtree/rtreetest"
 )
 
 func TestTree2DPoints(t *testing.T) {
@@ -92,7 +95,7 @@ func testRandom(t *testing.T, n, lb, ub int, wgs84 bool) {
 		arr = append(arr, item)
 		return true
 	})
-	assert.True(t, testHasSameItems(objs, arr))
+	assert.True(t, rtreetest.SameItems(objs, arr))
 
 	// search
 	testSearch(t, tr, objs, 0.20, true)
@@ -148,97 +151,38 @@ func testKNN(t *testing.T, tr *RTree, objs []pair.Pair, n int, check bool) {
 	assert.True(t, n > len(objs) || n == len(arr1))
 
 	// get the KNN for the original array
-	nobjs := make([]pair.Pair, len(objs))
-	copy(nobjs, objs)
-	sort.Slice(nobjs, func(i, j int) bool {
-		io := geobin.WrapBinary(nobjs[i].Value())
-		jo := geobin.WrapBinary(nobjs[j].Value())
-		imin, imax := io.Rect(nil)
-		jmin, jmax := jo.Rect(nil)
-		// boxDist is a private function.
-		var idist, jdist float64
-		if io.Dims() == 2 {
-			idist = testBoxDist2(x, y, imin, imax)
-		} else {
-			idist = testBoxDist3(x, y, z, imin, imax)
-		}
-		if jo.Dims() == 2 {
-			jdist = testBoxDist2(x, y, jmin, jmax)
-		} else {
-			jdist = testBoxDist3(x, y, z, jmin, jmax)
-		}
-		return idist < jdist
-	})
-
-	arr2 := nobjs[:len(arr1)]
-	var dists2 []float64
-	for i := 0; i < len(arr2); i++ {
-		o := geobin.WrapBinary(arr2[i].Value())
-		min, max := o.Rect(nil)
-		var dist float64
-		if o.Dims() == 2 {
-			dist = testBoxDist2(x, y, min, max)
-		} else {
-			dist = testBoxDist3(x, y, z, min, max)
-		}
-		dists2 = append(dists2, dist)
-	}
+	_, dists2 := rtreetest.KNN(objs, x, y, z)
+	dists2 = dists2[:len(arr1)]
 	// only compare the distances, not the objects because rectangles with
 	// a dist of zero will not be ordered.
 	assert.Equal(t, dists1, dists2)
 
 }
-func testBoxDist2(x, y float64, min, max [3]float64) float64 {
-	dx := textAxisDist(x, min[0], max[0])
-	dy := textAxisDist(y, min[1], max[1])
-	return dx*dx + dy*dy
-}
-func testBoxDist3(x, y, z float64, min, max [3]float64) float64 {
-	dx := textAxisDist(x, min[0], max[0])
-	dy := textAxisDist(y, min[1], max[1])
-	dz := textAxisDist(z, min[2], max[2])
-	return dx*dx + dy*dy + dz*dz
-}
-func textAxisDist(k, min, max float64) float64 {
-	if k < min {
-		return min - k
-	}
-	if k <= max {
-		return 0
-	}
-	return k - max
+
+var (
+	genPoints2D = newGen(2, [3]float64{-180, -90, 0}, [3]float64{180, 90, 0})
+	genRects2D  = newGen(2, [3]float64{-170, -80, 0}, [3]float64{170, 80, 0})
+	genPoints3D = newGen(3, [3]float64{-180, -90, -50}, [3]float64{180, 90, 50})
+	genRects3D  = newGen(3, [3]float64{-170, -80, -30}, [3]float64{170, 80, 50})
+)
+
+func newGen(dims int, min, max [3]float64) *gen.Generator {
+	opts := *gen.DefaultOptions
+	opts.Dims = dims
+	opts.Min, opts.Max = min, max
+	return gen.New(&opts)
 }
 func rand2DPoint() pair.Pair {
-	x := rand.Float64()*360 - 180
-	y := rand.Float64()*180 - 90
-	return makePointPair2("", x, y)
+	return genPoints2D.Point()
 }
 func rand2DRect() pair.Pair {
-	x := rand.Float64()*340 - 170
-	y := rand.Float64()*160 - 80
-	minx := x - rand.Float64()*10
-	miny := y - rand.Float64()*10
-	maxx := x + rand.Float64()*10
-	maxy := y + rand.Float64()*10
-	return makeBoundsPair2("", minx, miny, maxx, maxy)
+	return genRects2D.Rect()
 }
 func rand3DPoint() pair.Pair {
-	x := rand.Float64()*360 - 180
-	y := rand.Float64()*180 - 90
-	z := rand.Float64()*100 - 50
-	return makePointPair3("", x, y, z)
+	return genPoints3D.Point()
 }
 func rand3DRect() pair.Pair {
-	x := rand.Float64()*340 - 170
-	y := rand.Float64()*160 - 80
-	z := rand.Float64()*80 - 30
-	minx := x - rand.Float64()*10
-	miny := y - rand.Float64()*10
-	minz := z - rand.Float64()*10
-	maxx := x + rand.Float64()*10
-	maxy := y + rand.Float64()*10
-	maxz := z + rand.Float64()*10
-	return makeBoundsPair3("", minx, miny, minz, maxx, maxy, maxz)
+	return genRects3D.Rect()
 }
 func makePointPair3(key string, x, y, z float64) pair.Pair {
 	return pair.New([]byte(key), geobin.Make3DPoint(x, y, z).Binary())
@@ -272,56 +216,9 @@ func testSearch(t *testing.T, tr *RTree, objs []pair.Pair, percent float64, chec
 		return
 	}
 
-	var arr2 []pair.Pair
-	for _, obj := range objs {
-		ok := testIntersects(obj, box)
-		if ok {
-			arr2 = append(arr2, obj)
-		}
-		/*
-			var found bool
-			for _, o := range arr1 {
-				if o == obj {
-					found = true
-					break
-				}
-			}
-			var res string
-			if ok {
-				if found {
-					res = "[++]" // search and test found
-				} else {
-					res = "[-+]"
-				}
-			} else {
-				if found {
-					res = "[+-]" // search found, but test did not
-				} else {
-					res = "[--]"
-				}
-			}
-			if res != "[--]" {
-				fmt.Printf("%02d: %s %s %s\n", i, res, rectString(box), rectString(obj))
-			}
-		*/
-	}
+	arr2 := rtreetest.Search(objs, box)
 	assert.Equal(t, len(arr1), len(arr2))
-	var missing int
-	for _, o1 := range arr1 {
-		var found bool
-		for _, o2 := range arr2 {
-			if o2 == o1 {
-				found = true
-				break
-			}
-		}
-		if !found {
-			missing++
-		}
-	}
-	if missing > 0 {
-		t.Fatalf("missing %d\n", missing)
-	}
+	assert.True(t, rtreetest.SameItems(arr1, arr2))
 }
 
 func rectString(item pair.Pair) string {
@@ -333,56 +230,103 @@ func rectString(item pair.Pair) string {
 	return fmt.Sprintf("[%7.2f %7.2f %7.2f %7.2f %7.2f %7.2f]", min[0], min[1], min[2], max[0], max[1], max[2])
 }
 
-func testIntersects(obj, box pair.Pair) bool {
-	odims := geobin.WrapBinary(obj.Value()).Dims()
-	omin, omax := geobin.WrapBinary(obj.Value()).Rect(nil)
-	bdims := geobin.WrapBinary(box.Value()).Dims()
-	bmin, bmax := geobin.WrapBinary(box.Value()).Rect(nil)
-	if odims == 2 {
-		if bdims == 2 {
-			return testIntersects2(omin, omax, bmin, bmax)
-		} else {
-			if bmin[2] <= 0 && bmax[2] >= 0 {
-				return testIntersects2(omin, omax, bmin, bmax)
-			} else {
-				return false
-			}
-		}
-	} else if odims == 3 {
-		if bdims == 3 {
-			return testIntersects3(omin, omax, bmin, bmax)
-		} else {
-			bmin[2], bmax[2] = math.Inf(-1), math.Inf(+1)
-			return testIntersects3(omin, omax, bmin, bmax)
+func TestRectFunc(t *testing.T) {
+	// values are comma separated coordinates
+	opts := *DefaultOptions
+	opts.RectFunc = func(value []byte) (min, max [3]float64, dims int) {
+		parts := strings.Split(string(value), ",")
+		for i, part := range parts {
+			min[i], _ = strconv.ParseFloat(part, 64)
 		}
+		return min, min, len(parts)
+	}
+	tr := New(&opts)
+	for i := 0; i < 100; i++ {
+		tr.Insert(pair.New([]byte(fmt.Sprint(i)), []byte(fmt.Sprintf("%d,%d", i, i))))
+		tr.Insert(pair.New([]byte(fmt.Sprint(i)), []byte(fmt.Sprintf("%d,%d,%d", i, i, i))))
 	}
-	return false
+	assert.Equal(t, 200, tr.Count())
+	min, max := tr.Bounds()
+	assert.Equal(t, [3]float64{0, 0, 0}, min)
+	assert.Equal(t, [3]float64{99, 99, 99}, max)
+	var n int
+	tr.Search(pair.New(nil, []byte("10,10")), func(item pair.Pair) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, 2, n)
+	var values []string
+	tr.KNN(pair.New(nil, []byte("50,50,50")), func(item pair.Pair, dist float64) bool {
+		values = append(values, string(item.Value()))
+		return len(values) < 2
+	})
+	assert.Equal(t, []string{"50,50,50", "50,50"}, values)
 }
 
-func testIntersects2(amin, amax, bmin, bmax [3]float64) bool {
-	return bmin[0] <= amax[0] && bmin[1] <= amax[1] &&
-		bmax[0] >= amin[0] && bmax[1] >= amin[1]
+func TestGrid(t *testing.T) {
+	opts := *DefaultOptions
+	opts.Grid = 0.5
+	tr := New(&opts)
+	tr.Insert(makePointPair2("a", 10.2, 10.4))
+	tr.Insert(makePointPair3("b", -3.3, 7.9, 1.1))
+	min, max := tr.Bounds()
+	assert.Equal(t, [3]float64{-3.5, 8, 1}, min)
+	assert.Equal(t, [3]float64{10, 10.5, 1}, max)
+	// queries are snapped too, so a box around the original point still
+	// finds it.
+	var keys []string
+	tr.Search(makeBoundsPair2("", 10.15, 10.35, 10.25, 10.45), func(item pair.Pair) bool {
+		keys = append(keys, string(item.Key()))
+		return true
+	})
+	tr.Search(makeBoundsPair3("", -3.4, 7.8, 1.05, -3.2, 7.95, 1.15), func(item pair.Pair) bool {
+		keys = append(keys, string(item.Key()))
+		return true
+	})
+	assert.Equal(t, []string{"a", "b"}, keys)
 }
 
-func testIntersects3(amin, amax, bmin, bmax [3]float64) bool {
-	return bmin[0] <= amax[0] && bmin[1] <= amax[1] && bmin[2] <= amax[2] &&
-		bmax[0] >= amin[0] && bmax[1] >= amin[1] && bmax[2] >= amin[2]
+func TestKNNAxes(t *testing.T) {
+	tr := New(nil)
+	tr.Insert(makePointPair3("a", 1, 2, 50))
+	tr.Insert(makePointPair3("b", 3, 3, 1))
+	tr.Insert(makeBoundsPair2("c", 4, -1, 6, 1))
+	var keys []string
+	var axes [][3]float64
+	tr.KNNAxes(makePointPair3("", 0, 0, 0), func(item pair.Pair, dist float64, a [3]float64) bool {
+		keys = append(keys, string(item.Key()))
+		axes = append(axes, a)
+		return true
+	})
+	assert.Equal(t, []string{"c", "b", "a"}, keys)
+	assert.Equal(t, [][3]float64{{4, 0, 0}, {3, 3, 1}, {1, 2, 50}}, axes)
 }
-func testHasSameItems(a1, a2 []pair.Pair) bool {
-	if len(a1) != len(a2) {
-		return false
-	}
-	for _, p1 := range a1 {
-		var found bool
-		for _, p2 := range a2 {
-			if p1 == p2 {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
-		}
-	}
-	return true
+
+func TestBoundsAtLevel(t *testing.T) {
+	tr := New(nil)
+	tr.Insert(makePointPair2("a", 1, 2))
+	tr.Insert(makePointPair3("b", 3, 4, 5))
+	assert.Equal(t, [][2][3]float64{{{1, 2, 0}, {1, 2, 0}}, {{3, 4, 5}, {3, 4, 5}}},
+		tr.BoundsAtLevel(1))
+	assert.Equal(t, 0, len(tr.BoundsAtLevel(2)))
+}
+
+func TestRemoveOK(t *testing.T) {
+	tr := New(nil)
+	a := makePointPair2("a", 1, 2)
+	b := makePointPair3("b", 1, 2, 3)
+	tr.Insert(a)
+	tr.Insert(b)
+	tr.Insert(makePointPair3("c", 4, 5, 6))
+	assert.Equal(t, 1, tr.Count2D())
+	assert.Equal(t, 2, tr.Count3D())
+	feed := tr.Subscribe()
+	assert.True(t, tr.RemoveOK(a))
+	assert.False(t, tr.RemoveOK(a))
+	assert.True(t, tr.RemoveOK(b))
+	assert.False(t, tr.RemoveOK(makePointPair3("b", 1, 2, 3)))
+	assert.Equal(t, 0, tr.Count2D())
+	assert.Equal(t, 1, tr.Count3D())
+	// only the removes that landed are in the feed
+	assert.Equal(t, 2, len(feed))
 }
@@ -0,0 +1,113 @@
+// Package rtreetest provides brute-force reference implementations that can
+// be used to verify the results of the rtree packages. All functions work
+// on untransformed geobin values.
+package rtreetest
+
+import (
+	"math"
+	"sort"
+
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+// Intersects returns true if the object intersects the box, using the same
+// rules as the combined tree. A 2d box matches 3d objects at any z, and a 3d
+// box only matches 2d objects when the box z range includes zero.
+func Intersects(obj, box pair.Pair) bool {
+	odims := geobin.WrapBinary(obj.Value()).Dims()
+	omin, omax := geobin.WrapBinary(obj.Value()).Rect(nil)
+	bdims := geobin.WrapBinary(box.Value()).Dims()
+	bmin, bmax := geobin.WrapBinary(box.Value()).Rect(nil)
+	if odims == 2 {
+		if bdims != 2 && (bmin[2] > 0 || bmax[2] < 0) {
+			return false
+		}
+		return bmin[0] <= omax[0] && bmin[1] <= omax[1] &&
+			bmax[0] >= omin[0] && bmax[1] >= omin[1]
+	}
+	if bdims == 2 {
+		bmin[2], bmax[2] = math.Inf(-1), math.Inf(+1)
+	}
+	return bmin[0] <= omax[0] && bmin[1] <= omax[1] && bmin[2] <= omax[2] &&
+		bmax[0] >= omin[0] && bmax[1] >= omin[1] && bmax[2] >= omin[2]
+}
+
+// Search returns all objects that intersect the box.
+func Search(objs []pair.Pair, box pair.Pair) []pair.Pair {
+	var res []pair.Pair
+	for _, obj := range objs {
+		if Intersects(obj, box) {
+			res = append(res, obj)
+		}
+	}
+	return res
+}
+
+// BoxDist returns the squared distance from the point to the object. The z
+// coordinate is ignored for 2d objects.
+func BoxDist(obj pair.Pair, x, y, z float64) float64 {
+	o := geobin.WrapBinary(obj.Value())
+	min, max := o.Rect(nil)
+	dx := axisDist(x, min[0], max[0])
+	dy := axisDist(y, min[1], max[1])
+	if o.Dims() == 2 {
+		return dx*dx + dy*dy
+	}
+	dz := axisDist(z, min[2], max[2])
+	return dx*dx + dy*dy + dz*dz
+}
+
+func axisDist(k, min, max float64) float64 {
+	if k < min {
+		return min - k
+	}
+	if k <= max {
+		return 0
+	}
+	return k - max
+}
+
+// KNN returns all objects ordered nearest to farthest from the point along
+// with their distances. Objects with the same distance are not ordered, so
+// it's best to compare distances instead of objects.
+func KNN(objs []pair.Pair, x, y, z float64) (items []pair.Pair, dists []float64) {
+	items = make([]pair.Pair, len(objs))
+	copy(items, objs)
+	dists = make([]float64, len(objs))
+	for i, obj := range items {
+		dists[i] = BoxDist(obj, x, y, z)
+	}
+	sort.Sort(&byDist{items, dists})
+	return items, dists
+}
+
+type byDist struct {
+	items []pair.Pair
+	dists []float64
+}
+
+func (arr *byDist) Len() int           { return len(arr.items) }
+func (arr *byDist) Less(i, j int) bool { return arr.dists[i] < arr.dists[j] }
+func (arr *byDist) Swap(i, j int) {
+	arr.items[i], arr.items[j] = arr.items[j], arr.items[i]
+	arr.dists[i], arr.dists[j] = arr.dists[j], arr.dists[i]
+}
+
+// SameItems returns true if both slices contain the same items, in any order.
+func SameItems(a1, a2 []pair.Pair) bool {
+	if len(a1) != len(a2) {
+		return false
+	}
+	counts := make(map[pair.Pair]int, len(a1))
+	for _, p := range a1 {
+		counts[p]++
+	}
+	for _, p := range a2 {
+		if counts[p] == 0 {
+			return false
+		}
+		counts[p]--
+	}
+	return true
+}
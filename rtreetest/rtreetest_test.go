@@ -0,0 +1,39 @@
+package rtreetest
+
+import (
+	"testing"
+
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+func TestIntersects(t *testing.T) {
+	p2 := pair.New(nil, geobin.Make2DPoint(5, 5).Binary())
+	p3 := pair.New(nil, geobin.Make3DPoint(5, 5, 100).Binary())
+	box2 := pair.New(nil, geobin.Make2DRect(0, 0, 10, 10).Binary())
+	box3 := pair.New(nil, geobin.Make3DRect(0, 0, 50, 10, 10, 150).Binary())
+	if !Intersects(p2, box2) || !Intersects(p3, box2) || !Intersects(p3, box3) {
+		t.Fatal("expected intersection")
+	}
+	if Intersects(p2, box3) {
+		t.Fatal("expected no intersection")
+	}
+}
+
+func TestKNN(t *testing.T) {
+	objs := []pair.Pair{
+		pair.New(nil, geobin.Make2DPoint(3, 0).Binary()),
+		pair.New(nil, geobin.Make3DPoint(2, 0, 100).Binary()),
+		pair.New(nil, geobin.Make2DRect(-1, -1, 1, 1).Binary()),
+	}
+	items, dists := KNN(objs, 0, 0, 0)
+	if items[0] != objs[2] || items[1] != objs[0] || items[2] != objs[1] {
+		t.Fatal("out of order")
+	}
+	if dists[0] != 0 || dists[1] != 9 || dists[2] != 10004 {
+		t.Fatalf("bad distances %v", dists)
+	}
+	if !SameItems(objs, items) || SameItems(objs, items[1:]) {
+		t.Fatal("expected same items")
+	}
+}
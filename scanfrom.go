@@ -0,0 +1,96 @@
+package rtree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/tidwall/pair"
+)
+
+// ErrInvalidToken is returned by ScanFrom for a token that it didn't create.
+var ErrInvalidToken = errors.New("invalid scan token")
+
+// ScanFrom iterates over the items in key order, then by value, starting
+// after the position in the token, and returns the token for the position
+// after the last item passed to iter when iter stops the scan. A nil token
+// starts from the first item, and a nil next means that the scan finished.
+// The token holds the key and value of the last item rather than a place in
+// the tree, so a scan can be resumed after the tree has changed. Items
+// inserted before the position are skipped. Like ScanKeys, it's fast with
+// the KeyIndex option, otherwise the items are sorted for each call.
+func (tr *RTree) ScanFrom(token []byte, iter func(item pair.Pair) bool) (next []byte, err error) {
+	var key, value []byte
+	var n uint64 // the items with the key and value that were seen
+	if token != nil {
+		if key, value, n, err = decodeToken(token); err != nil {
+			return nil, err
+		}
+	}
+	var stopped bool
+	tr.ScanKeys(key, nil, func(item pair.Pair) bool {
+		if token != nil {
+			c := bytes.Compare(item.Key(), key)
+			if c == 0 {
+				c = bytes.Compare(item.Value(), value)
+			}
+			if c < 0 {
+				return true
+			}
+			if c == 0 && n > 0 {
+				n--
+				return true
+			}
+			token = nil
+		}
+		// count the items with the same key and value as the one before
+		if bytes.Equal(item.Key(), key) && bytes.Equal(item.Value(), value) {
+			n++
+		} else {
+			key, value, n = item.Key(), item.Value(), 1
+		}
+		if !iter(item) {
+			stopped = true
+			return false
+		}
+		return true
+	})
+	if !stopped {
+		return nil, nil
+	}
+	return encodeToken(key, value, n), nil
+}
+
+func encodeToken(key, value []byte, n uint64) []byte {
+	var num [binary.MaxVarintLen64]byte
+	var token []byte
+	token = append(token, num[:binary.PutUvarint(num[:], uint64(len(key)))]...)
+	token = append(token, key...)
+	token = append(token, num[:binary.PutUvarint(num[:], uint64(len(value)))]...)
+	token = append(token, value...)
+	return append(token, num[:binary.PutUvarint(num[:], n)]...)
+}
+
+func decodeToken(token []byte) (key, value []byte, n uint64, err error) {
+	field := func() []byte {
+		size, sz := binary.Uvarint(token)
+		if sz <= 0 || size > uint64(len(token)-sz) {
+			err = ErrInvalidToken
+			return nil
+		}
+		b := token[sz : sz+int(size)]
+		token = token[sz+int(size):]
+		return b
+	}
+	if key = field(); err != nil {
+		return nil, nil, 0, err
+	}
+	if value = field(); err != nil {
+		return nil, nil, 0, err
+	}
+	n, sz := binary.Uvarint(token)
+	if sz <= 0 || sz != len(token) {
+		return nil, nil, 0, ErrInvalidToken
+	}
+	return key, value, n, nil
+}
@@ -0,0 +1,59 @@
+package rtree
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestScanFrom(t *testing.T) {
+	for _, keyIndex := range []bool{false, true} {
+		tr := New(&Options{MaxEntries: 9, KeyIndex: keyIndex})
+		for i := 0; i < 100; i++ {
+			tr.Insert(makePointPair2(fmt.Sprintf("%03d", i), float64(i), 0))
+		}
+		// a duplicate item
+		dup := makePointPair3("050", 50, 0, 0)
+		tr.Insert(dup)
+		tr.Insert(makePointPair3("050", 50, 0, 0))
+
+		var keys []string
+		var token []byte
+		var err error
+		for {
+			var n int
+			token, err = tr.ScanFrom(token, func(item pair.Pair) bool {
+				keys = append(keys, string(item.Key()))
+				n++
+				return n < 7
+			})
+			assert.Nil(t, err)
+			if token == nil {
+				break
+			}
+			// change the tree between calls
+			if len(keys) == 49 {
+				tr.Remove(dup)
+				tr.Insert(makePointPair2("000a", 0, 0))
+				tr.Insert(makePointPair2("099a", 0, 0))
+			}
+		}
+		var expect []string
+		for i := 0; i < 100; i++ {
+			expect = append(expect, fmt.Sprintf("%03d", i))
+			if i == 50 {
+				expect = append(expect, "050")
+			}
+		}
+		expect = append(expect, "099a")
+		assert.Equal(t, expect, keys)
+	}
+
+	tr := New(nil)
+	_, err := tr.ScanFrom([]byte{200}, func(item pair.Pair) bool { return true })
+	assert.Equal(t, ErrInvalidToken, err)
+	token, err := tr.ScanFrom(nil, func(item pair.Pair) bool { return false })
+	assert.True(t, token == nil && err == nil)
+}
@@ -0,0 +1,140 @@
+package rtree
+
+import (
+	"sync/atomic"
+
+	"github.com/tidwall/pair"
+)
+
+// share counts the snapshot scans that are reading a version of the nodes.
+type share struct {
+	scans    int
+	nodes    int   // the number of nodes, once the version is obsolete
+	canceled int32 // set by CompactSnapshots
+}
+
+// SnapshotStats describe the snapshot scans that are running, and the
+// memory that they hold onto.
+type SnapshotStats struct {
+	// Scans is the number of snapshot scans that are running, not counting
+	// the ones that were stopped by CompactSnapshots.
+	Scans int
+	// Obsolete is the number of versions of the nodes that were replaced by
+	// writes, but are kept for the scans that are still reading them.
+	Obsolete int
+	// ObsoleteNodes is the number of nodes in the obsolete versions.
+	ObsoleteNodes int
+}
+
+// ScanSnapshot is like Scan, but iterates over the items as they were when
+// it was called, even while other goroutines insert and remove items, which
+// suits a long export. The nodes are shared with the scan until the next
+// write, which first copies them, so there's no cost unless the tree changes
+// during the scan. The writers must still be serialized with each other.
+func (tr *RTree) ScanSnapshot(iter func(item pair.Pair) bool) bool {
+	tr.snapMu.Lock()
+	if tr.shared == nil {
+		tr.shared = &share{}
+	}
+	s := tr.shared
+	s.scans++
+	tr2, tr3 := tr.tr2, tr.tr3
+	tr.snapMu.Unlock()
+	defer func() {
+		tr.snapMu.Lock()
+		s.scans--
+		if s.scans == 0 {
+			if tr.shared == s {
+				tr.shared = nil
+			} else {
+				tr.releaseObsolete(s)
+			}
+		}
+		tr.snapMu.Unlock()
+	}()
+	scan := func(item pair.Pair) bool {
+		return atomic.LoadInt32(&s.canceled) == 0 && iter(item)
+	}
+	if !tr2.Scan(scan) {
+		return false
+	}
+	return tr3.Scan(scan)
+}
+
+// releaseObsolete forgets an obsolete version that no scan is reading.
+func (tr *RTree) releaseObsolete(s *share) {
+	for i, o := range tr.obsolete {
+		if o == s {
+			tr.obsolete = append(tr.obsolete[:i], tr.obsolete[i+1:]...)
+			return
+		}
+	}
+}
+
+// SnapshotStats returns the numbers of snapshot scans and of the obsolete
+// nodes that they hold onto.
+func (tr *RTree) SnapshotStats() SnapshotStats {
+	tr.snapMu.Lock()
+	defer tr.snapMu.Unlock()
+	var st SnapshotStats
+	if tr.shared != nil {
+		st.Scans = tr.shared.scans
+	}
+	for _, s := range tr.obsolete {
+		st.Scans += s.scans
+		st.ObsoleteNodes += s.nodes
+	}
+	st.Obsolete = len(tr.obsolete)
+	return st
+}
+
+// CompactSnapshots stops the snapshot scans that are reading obsolete
+// versions of the nodes, which lets those versions be released. The scans
+// that are reading the current nodes go on. A stopped scan returns false, as
+// if its iterator had. Returns the number of scans that were stopped.
+func (tr *RTree) CompactSnapshots() int {
+	tr.snapMu.Lock()
+	defer tr.snapMu.Unlock()
+	var n int
+	for _, s := range tr.obsolete {
+		atomic.StoreInt32(&s.canceled, 1)
+		n += s.scans
+	}
+	tr.obsolete = nil
+	return n
+}
+
+// beginWrite is called before the nodes are changed, and copies them when
+// they are shared with a snapshot scan. It must be followed by endWrite.
+func (tr *RTree) beginWrite() {
+	tr.snapMu.Lock()
+	if tr.shared != nil {
+		tr.shared.nodes = tr.countNodes()
+		tr.obsolete = append(tr.obsolete, tr.shared)
+		tr.tr2 = tr.tr2.Clone()
+		tr.tr3 = tr.tr3.Clone()
+		tr.shared = nil
+	}
+}
+
+func (tr *RTree) endWrite() {
+	tr.snapMu.Unlock()
+}
+
+// countNodes returns the number of nodes in the 2d and 3d trees.
+func (tr *RTree) countNodes() int {
+	var n int
+	tr.tr2.Traverse(func(min, max [2]float64, level int, item pair.Pair) bool {
+		if level > 0 {
+			n++
+		}
+		return true
+	})
+	tr.tr3.Traverse(func(min, max [3]float64, level int, item pair.Pair) bool {
+		if level > 0 {
+			n++
+		}
+		return true
+	})
+	return n
+}
@@ -0,0 +1,100 @@
+package rtree
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestScanSnapshot(t *testing.T) {
+	tr := New(nil)
+	var items []pair.Pair
+	for i := 0; i < 2000; i++ {
+		items = append(items, rand2DPoint(), rand3DPoint())
+	}
+	tr.Load(items)
+	expect := make(map[pair.Pair]bool)
+	for _, item := range items {
+		expect[item] = true
+	}
+
+	// change the tree from another goroutine while scanning
+	var wg sync.WaitGroup
+	started := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-started
+		for _, item := range items[:1000] {
+			tr.Remove(item)
+		}
+		for i := 0; i < 1000; i++ {
+			tr.Insert(rand3DPoint())
+		}
+	}()
+	seen := make(map[pair.Pair]bool)
+	tr.ScanSnapshot(func(item pair.Pair) bool {
+		if len(seen) == 0 {
+			close(started)
+		}
+		assert.True(t, expect[item])
+		seen[item] = true
+		return true
+	})
+	wg.Wait()
+	assert.Equal(t, len(expect), len(seen))
+	assert.Equal(t, len(items), tr.Count())
+	assert.True(t, tr.shared == nil)
+
+	// a write with no scan in progress doesn't copy the nodes
+	tr2 := tr.tr2
+	tr.Insert(rand2DPoint())
+	assert.True(t, tr2 == tr.tr2)
+}
+
+func TestCompactSnapshots(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 1000; i++ {
+		tr.Insert(rand2DPoint())
+	}
+	assert.Equal(t, SnapshotStats{}, tr.SnapshotStats())
+
+	// a scan that waits on each item, and is stopped by the compaction
+	inside := make(chan struct{})
+	next := make(chan struct{})
+	result := make(chan bool)
+	go func() {
+		result <- tr.ScanSnapshot(func(item pair.Pair) bool {
+			inside <- struct{}{}
+			<-next
+			return true
+		})
+	}()
+	<-inside
+	assert.Equal(t, SnapshotStats{Scans: 1}, tr.SnapshotStats())
+	tr.Insert(rand2DPoint())
+	st := tr.SnapshotStats()
+	assert.Equal(t, 1, st.Scans)
+	assert.Equal(t, 1, st.Obsolete)
+	assert.True(t, st.ObsoleteNodes > 1000/9)
+
+	assert.Equal(t, 1, tr.CompactSnapshots())
+	assert.Equal(t, SnapshotStats{Scans: 0}, tr.SnapshotStats())
+	next <- struct{}{}
+	assert.False(t, <-result)
+	assert.Equal(t, SnapshotStats{}, tr.SnapshotStats())
+	assert.Equal(t, 0, tr.CompactSnapshots())
+
+	// a scan of the current nodes is not stopped
+	var n int
+	assert.True(t, tr.ScanSnapshot(func(item pair.Pair) bool {
+		if n == 0 {
+			assert.Equal(t, 0, tr.CompactSnapshots())
+		}
+		n++
+		return true
+	}))
+	assert.Equal(t, tr.Count(), n)
+}
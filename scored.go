@@ -0,0 +1,94 @@
+package rtree
+
+import (
+	"math"
+
+	"github.com/tidwall/pair"
+)
+
+// SearchScored returns the topK items in the box with the highest scores,
+// highest first, along with their scores. The score of each item is
+// computed from the item and its rect, in the coordinates of the tree, as
+// the tree is searched, and only the best topK are kept, in a heap, so
+// ranking the items of a large box doesn't collect all of them first. The
+// order of items with equal scores is undefined, and items with a NaN score
+// are skipped.
+func (tr *RTree) SearchScored(box pair.Pair,
+	score func(item pair.Pair, min, max [3]float64) float64,
+	topK int) (items []pair.Pair, scores []float64) {
+	if topK <= 0 {
+		return nil, nil
+	}
+	var h scoredHeap
+	tr.Search(box, func(item pair.Pair) bool {
+		min, max := tr.rect(item.Value())
+		s := score(item, min, max)
+		switch {
+		case math.IsNaN(s):
+		case len(h.items) < topK:
+			h.push(item, s)
+		case s > h.scores[0]:
+			h.items[0], h.scores[0] = item, s
+			h.down(0)
+		}
+		return true
+	})
+	items = make([]pair.Pair, len(h.items))
+	scores = make([]float64, len(h.items))
+	for i := len(items) - 1; i >= 0; i-- {
+		items[i], scores[i] = h.pop()
+	}
+	return items, scores
+}
+
+// scoredHeap is a binary min-heap of items by score, so the lowest of the
+// best items found so far is on top, where it's replaced by a better one.
+type scoredHeap struct {
+	items  []pair.Pair
+	scores []float64
+}
+
+func (h *scoredHeap) swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.scores[i], h.scores[j] = h.scores[j], h.scores[i]
+}
+
+func (h *scoredHeap) push(item pair.Pair, score float64) {
+	h.items = append(h.items, item)
+	h.scores = append(h.scores, score)
+	for i := len(h.items) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if h.scores[parent] <= h.scores[i] {
+			break
+		}
+		h.swap(i, parent)
+		i = parent
+	}
+}
+
+// pop removes and returns the item with the lowest score, which must exist.
+func (h *scoredHeap) pop() (pair.Pair, float64) {
+	item, score := h.items[0], h.scores[0]
+	last := len(h.items) - 1
+	h.swap(0, last)
+	h.items, h.scores = h.items[:last], h.scores[:last]
+	h.down(0)
+	return item, score
+}
+
+// down moves the item at i down until it's not more than its children.
+func (h *scoredHeap) down(i int) {
+	for {
+		least := i
+		for _, child := range [2]int{2*i + 1, 2*i + 2} {
+			if child < len(h.items) && h.scores[child] < h.scores[least] {
+				least = child
+			}
+		}
+		if least == i {
+			return
+		}
+		h.swap(i, least)
+		i = least
+	}
+}
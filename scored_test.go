@@ -0,0 +1,58 @@
+package rtree
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestSearchScored(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 2000; i++ {
+		tr.Insert(rand2DPoint())
+		tr.Insert(rand3DRect())
+	}
+	// the nearer to the origin, the better, with 3d items worth double
+	score := func(item pair.Pair, min, max [3]float64) float64 {
+		s := -math.Hypot(min[0], min[1])
+		if tr.dims(item.Value()) == 3 {
+			s *= 0.5
+		}
+		return s
+	}
+	box := makeBoundsPair2("", -50, -50, 50, 50)
+	var expect []float64
+	tr.Search(box, func(item pair.Pair) bool {
+		min, max := tr.rect(item.Value())
+		expect = append(expect, score(item, min, max))
+		return true
+	})
+	sort.Sort(sort.Reverse(sort.Float64Slice(expect)))
+	assert.True(t, len(expect) > 20)
+
+	items, scores := tr.SearchScored(box, score, 20)
+	assert.Equal(t, 20, len(items))
+	assert.Equal(t, expect[:20], scores)
+	for i, item := range items {
+		min, max := tr.rect(item.Value())
+		assert.Equal(t, scores[i], score(item, min, max))
+	}
+
+	// fewer items than topK
+	items, scores = tr.SearchScored(box, score, len(expect)+10)
+	assert.Equal(t, len(expect), len(items))
+	assert.Equal(t, expect, scores)
+
+	// NaN scores are skipped
+	items, _ = tr.SearchScored(box, func(item pair.Pair, min, max [3]float64) float64 {
+		return math.NaN()
+	}, 10)
+	assert.Equal(t, 0, len(items))
+
+	items, scores = tr.SearchScored(box, score, 0)
+	assert.Nil(t, items)
+	assert.Nil(t, scores)
+}
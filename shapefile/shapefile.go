@@ -0,0 +1,230 @@
+// Package shapefile loads the features of an ESRI shapefile into a tree.
+//
+// Each feature is stored as its bounding box, or as a point for point
+// shapes, and is keyed by an attribute from the .dbf file. Shapes with z
+// values are stored in 3d.
+package shapefile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+var ErrInvalidShapefile = errors.New("invalid shapefile")
+
+// shape types
+const (
+	shapeNull        = 0
+	shapePoint       = 1
+	shapePolyline    = 3
+	shapePolygon     = 5
+	shapeMultiPoint  = 8
+	shapePointZ      = 11
+	shapePolylineZ   = 13
+	shapePolygonZ    = 15
+	shapeMultiPointZ = 18
+	shapePointM      = 21
+	shapePolylineM   = 23
+	shapePolygonM    = 25
+	shapeMultiPointM = 28
+	shapeMultiPatch  = 31
+)
+
+// LoadFile loads the path.shp and path.dbf files. See Load.
+func LoadFile(tr *rtree.RTree, path, keyField string) (int, error) {
+	path = strings.TrimSuffix(path, ".shp")
+	shp, err := os.Open(path + ".shp")
+	if err != nil {
+		return 0, err
+	}
+	defer shp.Close()
+	var dbf io.Reader
+	if keyField != "" {
+		f, err := os.Open(path + ".dbf")
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		dbf = f
+	}
+	return Load(tr, shp, dbf, keyField)
+}
+
+// Load reads the shapes from shp and bulk loads them into the tree. The
+// keys are the values of the keyField attribute in dbf, or the one-based
+// record numbers when keyField is empty, in which case dbf may be nil.
+// Null shapes and deleted records are skipped. It returns the number of
+// items loaded.
+func Load(tr *rtree.RTree, shp, dbf io.Reader, keyField string) (int, error) {
+	var keys *dbfReader
+	if keyField != "" {
+		var err error
+		if keys, err = newDBFReader(dbf, keyField); err != nil {
+			return 0, err
+		}
+	}
+	r := bufio.NewReader(shp)
+	var hdr [100]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, err
+	}
+	if binary.BigEndian.Uint32(hdr[0:]) != 9994 {
+		return 0, ErrInvalidShapefile
+	}
+	var items []pair.Pair
+	var content []byte
+	for {
+		var rec [8]byte
+		if _, err := io.ReadFull(r, rec[:]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return 0, err
+		}
+		num := binary.BigEndian.Uint32(rec[0:])
+		size := int(binary.BigEndian.Uint32(rec[4:])) * 2
+		if cap(content) < size {
+			content = make([]byte, size)
+		}
+		content = content[:size]
+		if _, err := io.ReadFull(r, content); err != nil {
+			return 0, err
+		}
+		key := strconv.FormatUint(uint64(num), 10)
+		deleted := false
+		if keys != nil {
+			var err error
+			if key, deleted, err = keys.next(); err != nil {
+				return 0, err
+			}
+		}
+		obj, ok, err := shape(content)
+		if err != nil {
+			return 0, fmt.Errorf("shapefile: record %d: %v", num, err)
+		}
+		if ok && !deleted {
+			items = append(items, pair.New([]byte(key), obj.Binary()))
+		}
+	}
+	tr.Load(items)
+	return len(items), nil
+}
+
+// shape returns the geobin object for a shape record, and false for a null
+// shape.
+func shape(b []byte) (geobin.Object, bool, error) {
+	if len(b) < 4 {
+		return geobin.Object{}, false, ErrInvalidShapefile
+	}
+	f64 := func(off int) float64 {
+		return math.Float64frombits(binary.LittleEndian.Uint64(b[off:]))
+	}
+	typ := binary.LittleEndian.Uint32(b)
+	switch typ {
+	case shapeNull:
+		return geobin.Object{}, false, nil
+	case shapePoint, shapePointM:
+		if len(b) < 20 {
+			return geobin.Object{}, false, ErrInvalidShapefile
+		}
+		return geobin.Make2DPoint(f64(4), f64(12)), true, nil
+	case shapePointZ:
+		if len(b) < 28 {
+			return geobin.Object{}, false, ErrInvalidShapefile
+		}
+		return geobin.Make3DPoint(f64(4), f64(12), f64(20)), true, nil
+	case shapePolyline, shapePolygon, shapeMultiPoint,
+		shapePolylineM, shapePolygonM, shapeMultiPointM:
+		if len(b) < 36 {
+			return geobin.Object{}, false, ErrInvalidShapefile
+		}
+		return geobin.Make2DRect(f64(4), f64(12), f64(20), f64(28)), true, nil
+	case shapePolylineZ, shapePolygonZ, shapeMultiPointZ, shapeMultiPatch:
+		// the z range follows the parts, part types, and points
+		if len(b) < 40 {
+			return geobin.Object{}, false, ErrInvalidShapefile
+		}
+		off := 40
+		numPoints := int(binary.LittleEndian.Uint32(b[36:]))
+		if typ != shapeMultiPointZ {
+			if len(b) < 44 {
+				return geobin.Object{}, false, ErrInvalidShapefile
+			}
+			numParts := int(binary.LittleEndian.Uint32(b[36:]))
+			numPoints = int(binary.LittleEndian.Uint32(b[40:]))
+			off = 44 + numParts*4
+			if typ == shapeMultiPatch {
+				off += numParts * 4
+			}
+		}
+		off += numPoints * 16
+		if numPoints < 0 || off < 0 || len(b) < off+16 {
+			return geobin.Object{}, false, ErrInvalidShapefile
+		}
+		return geobin.Make3DRect(f64(4), f64(12), f64(off), f64(20), f64(28), f64(off+8)), true, nil
+	}
+	return geobin.Object{}, false, fmt.Errorf("unsupported shape type %d", typ)
+}
+
+// dbfReader reads a single field from each record of a dbf file.
+type dbfReader struct {
+	r      *bufio.Reader
+	rec    []byte
+	offset int
+	length int
+}
+
+func newDBFReader(r io.Reader, field string) (*dbfReader, error) {
+	if r == nil {
+		return nil, errors.New("shapefile: missing dbf")
+	}
+	d := &dbfReader{r: bufio.NewReader(r)}
+	var hdr [32]byte
+	if _, err := io.ReadFull(d.r, hdr[:]); err != nil {
+		return nil, err
+	}
+	headerLen := int(binary.LittleEndian.Uint16(hdr[8:]))
+	recordLen := int(binary.LittleEndian.Uint16(hdr[10:]))
+	if headerLen < 33 || recordLen < 1 {
+		return nil, ErrInvalidShapefile
+	}
+	desc := make([]byte, headerLen-32)
+	if _, err := io.ReadFull(d.r, desc); err != nil {
+		return nil, err
+	}
+	d.rec = make([]byte, recordLen)
+	d.offset = -1
+	// the deletion flag precedes the fields
+	offset := 1
+	for i := 0; i+32 <= len(desc) && desc[i] != 0x0d; i += 32 {
+		name := strings.TrimRight(string(desc[i:i+11]), "\x00")
+		length := int(desc[i+16])
+		if strings.EqualFold(name, field) {
+			d.offset, d.length = offset, length
+		}
+		offset += length
+	}
+	if d.offset == -1 || d.offset+d.length > recordLen {
+		return nil, fmt.Errorf("shapefile: missing %q field", field)
+	}
+	return d, nil
+}
+
+func (d *dbfReader) next() (key string, deleted bool, err error) {
+	if _, err := io.ReadFull(d.r, d.rec); err != nil {
+		return "", false, err
+	}
+	key = strings.TrimSpace(string(d.rec[d.offset : d.offset+d.length]))
+	return key, d.rec[0] == '*', nil
+}
@@ -0,0 +1,103 @@
+package shapefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+func le(vals ...interface{}) []byte {
+	var buf bytes.Buffer
+	for _, v := range vals {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	return buf.Bytes()
+}
+
+func makeSHP(records ...[]byte) []byte {
+	var buf bytes.Buffer
+	hdr := make([]byte, 100)
+	binary.BigEndian.PutUint32(hdr, 9994)
+	buf.Write(hdr)
+	for i, content := range records {
+		binary.Write(&buf, binary.BigEndian, uint32(i+1))
+		binary.Write(&buf, binary.BigEndian, uint32(len(content)/2))
+		buf.Write(content)
+	}
+	return buf.Bytes()
+}
+
+func makeDBF(field string, deleted []bool, values ...string) []byte {
+	var buf bytes.Buffer
+	hdr := make([]byte, 32)
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(len(values)))
+	binary.LittleEndian.PutUint16(hdr[8:], 32+64+1)
+	binary.LittleEndian.PutUint16(hdr[10:], 1+4+10)
+	buf.Write(hdr)
+	for _, f := range []struct {
+		name   string
+		length byte
+	}{{"ID", 4}, {field, 10}} {
+		desc := make([]byte, 32)
+		copy(desc, f.name)
+		desc[11] = 'C'
+		desc[16] = f.length
+		buf.Write(desc)
+	}
+	buf.WriteByte(0x0d)
+	for i, v := range values {
+		if deleted[i] {
+			buf.WriteByte('*')
+		} else {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString("0000")
+		buf.WriteString((v + "          ")[:10])
+	}
+	return buf.Bytes()
+}
+
+func TestLoad(t *testing.T) {
+	shp := makeSHP(
+		le(int32(shapePoint), 1.0, 2.0),
+		le(int32(shapePolygon), 0.0, 0.0, 10.0, 10.0, int32(1), int32(4), int32(0),
+			0.0, 0.0, 10.0, 0.0, 10.0, 10.0, 0.0, 0.0),
+		le(int32(shapeNull)),
+		le(int32(shapePolylineZ), 0.0, 0.0, 5.0, 5.0, int32(1), int32(2), int32(0),
+			0.0, 0.0, 5.0, 5.0, -1.0, 3.0, -1.0, 3.0),
+		le(int32(shapePointZ), 7.0, 8.0, 9.0, 0.0),
+	)
+	dbf := makeDBF("NAME", []bool{false, false, false, false, true}, "a", "b", "c", "d", "e")
+	tr := rtree.New(nil)
+	n, err := Load(tr, bytes.NewReader(shp), bytes.NewReader(dbf), "name")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, 3, tr.Count())
+
+	items := map[string][2][3]float64{}
+	tr.Scan(func(item pair.Pair) bool {
+		min, max := geobin.WrapBinary(item.Value()).Rect(nil)
+		items[string(item.Key())] = [2][3]float64{min, max}
+		return true
+	})
+	assert.Equal(t, [2][3]float64{{1, 2, 0}, {1, 2, 0}}, items["a"])
+	assert.Equal(t, [2][3]float64{{0, 0, 0}, {10, 10, 0}}, items["b"])
+	assert.Equal(t, [2][3]float64{{0, 0, -1}, {5, 5, 3}}, items["d"])
+
+	tr = rtree.New(nil)
+	n, err = Load(tr, bytes.NewReader(shp), nil, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	_, ok := tr.Get([]byte("5"))
+	assert.True(t, ok)
+
+	_, err = Load(tr, bytes.NewReader(shp), bytes.NewReader(dbf), "missing")
+	assert.Error(t, err)
+	_, err = Load(tr, bytes.NewReader(make([]byte, 100)), nil, "")
+	assert.Equal(t, ErrInvalidShapefile, err)
+}
@@ -0,0 +1,280 @@
+// Package snapshot writes trees to a flat binary format that can be memory
+// mapped and queried in place, without decoding the nodes and items first.
+//
+// A snapshot holds the node structure of a combined tree, as returned by
+// Traverse, so loading a snapshot is an mmap plus a validation pass over
+// the node and item records. The format is little endian.
+//
+//	header: magic [8]byte, nodes, items, roots, reserved uint32
+//	node:   min, max [3]float64, level, first, count, reserved uint32
+//	item:   min, max [3]float64, key, keylen, value, valuelen uint32
+//	data:   key and value bytes
+//
+// The first node records are the roots. The children of a node at level 1
+// are the count item records starting at first, and the children of higher
+// nodes are node records. Child records always follow their parent. The key
+// and value fields are offsets from the start of the file.
+package snapshot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+
+	"github.com/tidwall/pair"
+)
+
+const (
+	magic      = "RTSNAP1\x00"
+	headerSize = 24
+	recordSize = 64
+)
+
+// ErrInvalidSnapshot is returned by Open when the file is not a valid
+// snapshot.
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+type node struct {
+	min, max [3]float64
+	level    int
+	item     pair.Pair
+	children []*node
+}
+
+// Write writes a snapshot of the tree, where tr is the Traverse method of a
+// 3d or combined tree. Use viz.From2D for a 2d tree.
+func Write(w io.Writer, tr func(iter func(min, max [3]float64, level int, item pair.Pair) bool)) error {
+	// rebuild the node structure from the traversal order
+	var roots []*node
+	var stack []*node
+	tr(func(min, max [3]float64, level int, item pair.Pair) bool {
+		n := &node{min: min, max: max, level: level, item: item}
+		for len(stack) > 0 && stack[len(stack)-1].level <= level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, n)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, n)
+		}
+		if level > 0 {
+			stack = append(stack, n)
+		}
+		return true
+	})
+	// order the nodes breadth first so that children are contiguous
+	nodes := append([]*node(nil), roots...)
+	var items []*node
+	for i := 0; i < len(nodes); i++ {
+		if nodes[i].level == 1 {
+			items = append(items, nodes[i].children...)
+		} else {
+			nodes = append(nodes, nodes[i].children...)
+		}
+	}
+	bw := bufio.NewWriter(w)
+	var hdr [headerSize]byte
+	copy(hdr[:], magic)
+	binary.LittleEndian.PutUint32(hdr[8:], uint32(len(nodes)))
+	binary.LittleEndian.PutUint32(hdr[12:], uint32(len(items)))
+	binary.LittleEndian.PutUint32(hdr[16:], uint32(len(roots)))
+	bw.Write(hdr[:])
+	var rec [recordSize]byte
+	putRect := func(n *node) {
+		for i := 0; i < 3; i++ {
+			binary.LittleEndian.PutUint64(rec[i*8:], math.Float64bits(n.min[i]))
+			binary.LittleEndian.PutUint64(rec[24+i*8:], math.Float64bits(n.max[i]))
+		}
+	}
+	nextNode, nextItem := len(roots), 0
+	for _, n := range nodes {
+		putRect(n)
+		binary.LittleEndian.PutUint32(rec[48:], uint32(n.level))
+		if n.level == 1 {
+			binary.LittleEndian.PutUint32(rec[52:], uint32(nextItem))
+			nextItem += len(n.children)
+		} else {
+			binary.LittleEndian.PutUint32(rec[52:], uint32(nextNode))
+			nextNode += len(n.children)
+		}
+		binary.LittleEndian.PutUint32(rec[56:], uint32(len(n.children)))
+		binary.LittleEndian.PutUint32(rec[60:], 0)
+		bw.Write(rec[:])
+	}
+	off := headerSize + recordSize*(len(nodes)+len(items))
+	for _, n := range items {
+		putRect(n)
+		key, value := n.item.Key(), n.item.Value()
+		binary.LittleEndian.PutUint32(rec[48:], uint32(off))
+		binary.LittleEndian.PutUint32(rec[52:], uint32(len(key)))
+		off += len(key)
+		binary.LittleEndian.PutUint32(rec[56:], uint32(off))
+		binary.LittleEndian.PutUint32(rec[60:], uint32(len(value)))
+		off += len(value)
+		bw.Write(rec[:])
+	}
+	if off > math.MaxUint32 {
+		return errors.New("snapshot too large")
+	}
+	for _, n := range items {
+		bw.Write(n.item.Key())
+		bw.Write(n.item.Value())
+	}
+	return bw.Flush()
+}
+
+// Snapshot is a read-only, memory mapped snapshot.
+type Snapshot struct {
+	data  []byte
+	nodes int
+	items int
+	roots int
+	unmap func() error
+}
+
+// Open memory maps and validates a snapshot file. The snapshot must be
+// closed when it's no longer needed.
+func Open(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() < headerSize || fi.Size() > math.MaxUint32 {
+		return nil, ErrInvalidSnapshot
+	}
+	data, unmap, err := mmap(f, int(fi.Size()))
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{data: data, unmap: unmap}
+	if !s.validate() {
+		unmap()
+		return nil, ErrInvalidSnapshot
+	}
+	return s, nil
+}
+
+func (s *Snapshot) u32(off int) int {
+	return int(binary.LittleEndian.Uint32(s.data[off:]))
+}
+
+func (s *Snapshot) validate() bool {
+	if string(s.data[:8]) != magic {
+		return false
+	}
+	s.nodes, s.items, s.roots = s.u32(8), s.u32(12), s.u32(16)
+	if s.roots > s.nodes || headerSize+recordSize*(s.nodes+s.items) > len(s.data) {
+		return false
+	}
+	for i := 0; i < s.nodes; i++ {
+		rec := headerSize + recordSize*i
+		level, first, count := s.u32(rec+48), s.u32(rec+52), s.u32(rec+56)
+		if level == 1 {
+			if first+count > s.items {
+				return false
+			}
+		} else if level < 1 || first <= i || first+count > s.nodes {
+			return false
+		}
+	}
+	for i := 0; i < s.items; i++ {
+		rec := headerSize + recordSize*(s.nodes+i)
+		for _, f := range [2]int{48, 56} {
+			off, n := s.u32(rec+f), s.u32(rec+f+4)
+			if off+n > len(s.data) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Close unmaps the snapshot. The snapshot must not be used after it's
+// closed.
+func (s *Snapshot) Close() error {
+	if s.unmap == nil {
+		return nil
+	}
+	err := s.unmap()
+	s.data, s.unmap = nil, nil
+	return err
+}
+
+// Count returns the number of items.
+func (s *Snapshot) Count() int {
+	return s.items
+}
+
+func (s *Snapshot) rect(rec int) (min, max [3]float64) {
+	for i := 0; i < 3; i++ {
+		min[i] = math.Float64frombits(binary.LittleEndian.Uint64(s.data[rec+i*8:]))
+		max[i] = math.Float64frombits(binary.LittleEndian.Uint64(s.data[rec+24+i*8:]))
+	}
+	return min, max
+}
+
+func (s *Snapshot) item(i int) pair.Pair {
+	rec := headerSize + recordSize*(s.nodes+i)
+	key, value := s.u32(rec+48), s.u32(rec+56)
+	return pair.New(s.data[key:key+s.u32(rec+52)], s.data[value:value+s.u32(rec+60)])
+}
+
+func intersects(min, max, bmin, bmax [3]float64) bool {
+	for i := 0; i < 3; i++ {
+		if bmin[i] > max[i] || bmax[i] < min[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Search iterates over the items with bboxes that intersect the box. The
+// box is in the same coordinates as the tree, after any transformation, and
+// 2d items have a z of zero.
+func (s *Snapshot) Search(min, max [3]float64, iter func(item pair.Pair) bool) bool {
+	for i := 0; i < s.roots; i++ {
+		if !s.search(i, min, max, iter) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Snapshot) search(i int, min, max [3]float64, iter func(item pair.Pair) bool) bool {
+	rec := headerSize + recordSize*i
+	if nmin, nmax := s.rect(rec); !intersects(nmin, nmax, min, max) {
+		return true
+	}
+	level, first, count := s.u32(rec+48), s.u32(rec+52), s.u32(rec+56)
+	for j := first; j < first+count; j++ {
+		if level == 1 {
+			imin, imax := s.rect(headerSize + recordSize*(s.nodes+j))
+			if intersects(imin, imax, min, max) && !iter(s.item(j)) {
+				return false
+			}
+		} else if !s.search(j, min, max, iter) {
+			return false
+		}
+	}
+	return true
+}
+
+// Scan iterates over every item. The items can be loaded into a tree with
+// Insert.
+func (s *Snapshot) Scan(iter func(item pair.Pair) bool) bool {
+	for i := 0; i < s.items; i++ {
+		if !iter(s.item(i)) {
+			return false
+		}
+	}
+	return true
+}
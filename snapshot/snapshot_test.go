@@ -0,0 +1,88 @@
+package snapshot
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+)
+
+// sameItems compares items by key and value, since snapshot items are not
+// the same pairs that were written.
+func sameItems(a1, a2 []pair.Pair) bool {
+	if len(a1) != len(a2) {
+		return false
+	}
+	counts := make(map[string]int, len(a1))
+	for _, p := range a1 {
+		counts[string(p.Key())+":"+string(p.Value())]++
+	}
+	for _, p := range a2 {
+		k := string(p.Key()) + ":" + string(p.Value())
+		if counts[k] == 0 {
+			return false
+		}
+		counts[k]--
+	}
+	return true
+}
+
+func TestSnapshot(t *testing.T) {
+	tr := rtree.New(nil)
+	var objs []pair.Pair
+	for i := 0; i < 5000; i++ {
+		x, y := rand.Float64()*100, rand.Float64()*100
+		var obj pair.Pair
+		if i%2 == 0 {
+			obj = pair.New([]byte("2d"), geobin.Make2DPoint(x, y).Binary())
+		} else {
+			obj = pair.New([]byte("3d"), geobin.Make3DPoint(x, y, rand.Float64()*100).Binary())
+		}
+		tr.Insert(obj)
+		objs = append(objs, obj)
+	}
+	path := filepath.Join(t.TempDir(), "tree.snap")
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	assert.NoError(t, Write(f, tr.Traverse))
+	f.Close()
+
+	s, err := Open(path)
+	assert.NoError(t, err)
+	defer s.Close()
+	assert.Equal(t, len(objs), s.Count())
+	var all []pair.Pair
+	s.Scan(func(item pair.Pair) bool {
+		all = append(all, item)
+		return true
+	})
+	assert.True(t, sameItems(objs, all))
+
+	min := [3]float64{10, 10, math.Inf(-1)}
+	max := [3]float64{30, 30, math.Inf(+1)}
+	var expect, found []pair.Pair
+	tr.Search(pair.New(nil, geobin.Make2DRect(10, 10, 30, 30).Binary()), func(item pair.Pair) bool {
+		expect = append(expect, item)
+		return true
+	})
+	s.Search(min, max, func(item pair.Pair) bool {
+		found = append(found, item)
+		return true
+	})
+	assert.True(t, len(expect) > 0)
+	assert.True(t, sameItems(expect, found))
+}
+
+func TestInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.snap")
+	data := []byte(magic + "\x05\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00")
+	assert.NoError(t, os.WriteFile(path, data, 0600))
+	_, err := Open(path)
+	assert.Equal(t, ErrInvalidSnapshot, err)
+}
@@ -0,0 +1,49 @@
+package rtree
+
+import (
+	"bytes"
+
+	"github.com/tidwall/pair"
+	rtree2 "github.com/tidwall/pair-rtree/2d"
+	rtree3 "github.com/tidwall/pair-rtree/3d"
+)
+
+// ScanSorted iterates over all 2d and 3d items merged in key order, then by
+// value for equal keys. Unlike Scan, the order does not depend on the shape
+// of the trees or on which items are 2d or 3d. The KeyIndex option avoids
+// gathering and sorting the items first.
+func (tr *RTree) ScanSorted(iter func(item pair.Pair) bool) bool {
+	if tr.keys != nil {
+		return tr.keys.ascend(nil, func(entry *keyEntry) bool {
+			return iter(entry.item)
+		})
+	}
+	var items2, items3 []pair.Pair
+	tr.tr2.ScanSorted(rtree2.KeyOrder, func(item pair.Pair) bool {
+		items2 = append(items2, item)
+		return true
+	})
+	tr.tr3.ScanSorted(rtree3.KeyOrder, func(item pair.Pair) bool {
+		items3 = append(items3, item)
+		return true
+	})
+	for len(items2) > 0 || len(items3) > 0 {
+		var item pair.Pair
+		if len(items3) == 0 || (len(items2) > 0 && !keyLess(items3[0], items2[0])) {
+			item, items2 = items2[0], items2[1:]
+		} else {
+			item, items3 = items3[0], items3[1:]
+		}
+		if !iter(item) {
+			return false
+		}
+	}
+	return true
+}
+
+func keyLess(a, b pair.Pair) bool {
+	if c := bytes.Compare(a.Key(), b.Key()); c != 0 {
+		return c < 0
+	}
+	return bytes.Compare(a.Value(), b.Value()) < 0
+}
@@ -0,0 +1,37 @@
+package rtree
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestScanSorted(t *testing.T) {
+	tr := New(nil)
+	var keys []string
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("%d", i)
+		keys = append(keys, key)
+		if i%2 == 0 {
+			tr.Insert(makePointPair2(key, float64(i), float64(i)))
+		} else {
+			tr.Insert(makePointPair3(key, float64(i), float64(i), float64(i)))
+		}
+	}
+	sort.Strings(keys)
+	var res []string
+	tr.ScanSorted(func(item pair.Pair) bool {
+		res = append(res, string(item.Key()))
+		return true
+	})
+	assert.Equal(t, keys, res)
+	var n int
+	assert.False(t, tr.ScanSorted(func(item pair.Pair) bool {
+		n++
+		return n < 10
+	}))
+	assert.Equal(t, 10, n)
+}
@@ -0,0 +1,41 @@
+package rtree
+
+import (
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+)
+
+// SourceRect returns the rect of an item as it was provided, before the
+// transformer and the grid were applied.
+func (tr *RTree) SourceRect(item pair.Pair) (min, max [3]float64) {
+	if tr.rectFunc == nil {
+		return geobin.WrapBinary(item.Value()).Rect(nil)
+	}
+	min, max, _ = tr.rectFunc(item.Value())
+	return min, max
+}
+
+// TraverseSource is like Traverse, but also passes the source rect of each
+// item, from SourceRect. The source rect of a node is the same as its rect,
+// which is in the coordinates of the tree.
+func (tr *RTree) TraverseSource(iter func(min, max [3]float64, level int, item pair.Pair,
+	srcMin, srcMax [3]float64) bool) {
+	tr.Traverse(func(min, max [3]float64, level int, item pair.Pair) bool {
+		if item.Zero() {
+			return iter(min, max, level, item, min, max)
+		}
+		srcMin, srcMax := tr.SourceRect(item)
+		return iter(min, max, level, item, srcMin, srcMax)
+	})
+}
+
+// KNNSource is like KNN, but also passes the source rect of each item, from
+// SourceRect, while the dist is still measured in the coordinates of the
+// tree.
+func (tr *RTree) KNNSource(pos pair.Pair, iter func(item pair.Pair, dist float64,
+	srcMin, srcMax [3]float64) bool) bool {
+	return tr.KNN(pos, func(item pair.Pair, dist float64) bool {
+		srcMin, srcMax := tr.SourceRect(item)
+		return iter(item, dist, srcMin, srcMax)
+	})
+}
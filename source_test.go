@@ -0,0 +1,47 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestSourceRect(t *testing.T) {
+	opts := *DefaultOptions
+	opts.Transformer = func(min, max [3]float64) ([3]float64, [3]float64) {
+		for i := 0; i < 3; i++ {
+			min[i], max[i] = min[i]*10, max[i]*10
+		}
+		return min, max
+	}
+	tr := New(&opts)
+	tr.Insert(makeBoundsPair2("a", 1, 2, 3, 4))
+	tr.Insert(makePointPair3("b", 5, 6, 7))
+
+	min, max := tr.SourceRect(makeBoundsPair2("", 1, 2, 3, 4))
+	assert.Equal(t, [3]float64{1, 2, 0}, min)
+	assert.Equal(t, [3]float64{3, 4, 0}, max)
+
+	srcs := map[string][3]float64{}
+	tr.TraverseSource(func(min, max [3]float64, level int, item pair.Pair,
+		srcMin, srcMax [3]float64) bool {
+		if level == 0 {
+			assert.Equal(t, min[0], srcMin[0]*10)
+			srcs[string(item.Key())] = srcMin
+		} else {
+			assert.Equal(t, min, srcMin)
+		}
+		return true
+	})
+	assert.Equal(t, map[string][3]float64{"a": {1, 2, 0}, "b": {5, 6, 7}}, srcs)
+
+	var keys []string
+	tr.KNNSource(makePointPair3("", 5, 6, 7), func(item pair.Pair, dist float64,
+		srcMin, srcMax [3]float64) bool {
+		keys = append(keys, string(item.Key()))
+		assert.Equal(t, srcMin, srcs[string(item.Key())])
+		return true
+	})
+	assert.Equal(t, 2, len(keys))
+}
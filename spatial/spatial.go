@@ -0,0 +1,63 @@
+// Package spatial defines a small interface for spatial indexes, which allows
+// for applications to be written, and benchmarked, against one interface
+// while the index implementation is swapped out.
+//
+// The combined tree implements SpatialIndex as is. The 2d and 3d trees take
+// plain coordinates for KNN, so they're wrapped with From2D and From3D.
+package spatial
+
+import (
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+	rtree2 "github.com/tidwall/pair-rtree/2d"
+	rtree3 "github.com/tidwall/pair-rtree/3d"
+)
+
+// SpatialIndex is an index of items with geobin values.
+type SpatialIndex interface {
+	// Insert adds an item.
+	Insert(item pair.Pair)
+	// Remove removes an item.
+	Remove(item pair.Pair)
+	// Search iterates over the items that intersect the bbox of the box
+	// value. Returning false from iter stops the search, and Search returns
+	// false.
+	Search(box pair.Pair, iter func(item pair.Pair) bool) bool
+	// KNN iterates over the items nearest to the position of the pos value,
+	// nearest first. Returning false from iter stops the iteration, and KNN
+	// returns false.
+	KNN(pos pair.Pair, iter func(item pair.Pair, dist float64) bool) bool
+	// Count returns the number of items.
+	Count() int
+}
+
+var _ SpatialIndex = (*rtree.RTree)(nil)
+
+type index2 struct {
+	*rtree2.RTree
+}
+
+// From2D returns a SpatialIndex for a 2d tree.
+func From2D(tr *rtree2.RTree) SpatialIndex {
+	return index2{tr}
+}
+
+func (idx index2) KNN(pos pair.Pair, iter func(item pair.Pair, dist float64) bool) bool {
+	p := geobin.WrapBinary(pos.Value()).Position()
+	return idx.RTree.KNN(p.X, p.Y, iter)
+}
+
+type index3 struct {
+	*rtree3.RTree
+}
+
+// From3D returns a SpatialIndex for a 3d tree.
+func From3D(tr *rtree3.RTree) SpatialIndex {
+	return index3{tr}
+}
+
+func (idx index3) KNN(pos pair.Pair, iter func(item pair.Pair, dist float64) bool) bool {
+	p := geobin.WrapBinary(pos.Value()).Position()
+	return idx.RTree.KNN(p.X, p.Y, p.Z, iter)
+}
@@ -0,0 +1,64 @@
+package spatial
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree"
+	rtree2 "github.com/tidwall/pair-rtree/2d"
+	rtree3 "github.com/tidwall/pair-rtree/3d"
+)
+
+func testIndex(t *testing.T, idx SpatialIndex, point func(x, y float64) []byte) {
+	var items []pair.Pair
+	for i := 0; i < 100; i++ {
+		item := pair.New([]byte(fmt.Sprint(i)), point(float64(i%10), float64(i/10)))
+		items = append(items, item)
+		idx.Insert(item)
+	}
+	assert.Equal(t, 100, idx.Count())
+
+	var n int
+	box := pair.New(nil, geobin.Make3DRect(2, 2, -1, 4, 3, 1).Binary())
+	idx.Search(box, func(item pair.Pair) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, 6, n)
+
+	var nearest []string
+	idx.KNN(pair.New(nil, point(5.1, 5)), func(item pair.Pair, dist float64) bool {
+		nearest = append(nearest, string(item.Key()))
+		return len(nearest) < 2
+	})
+	assert.Equal(t, []string{"55", "56"}, nearest)
+
+	for _, item := range items[:50] {
+		idx.Remove(item)
+	}
+	assert.Equal(t, 50, idx.Count())
+}
+
+func TestSpatialIndex(t *testing.T) {
+	point2 := func(x, y float64) []byte {
+		return geobin.Make2DPoint(x, y).Binary()
+	}
+	point3 := func(x, y float64) []byte {
+		return geobin.Make3DPoint(x, y, 0).Binary()
+	}
+	t.Run("2d", func(t *testing.T) {
+		testIndex(t, From2D(rtree2.New(nil)), point2)
+	})
+	t.Run("3d", func(t *testing.T) {
+		testIndex(t, From3D(rtree3.New(nil)), point3)
+	})
+	t.Run("combined-2d", func(t *testing.T) {
+		testIndex(t, rtree.New(nil), point2)
+	})
+	t.Run("combined-3d", func(t *testing.T) {
+		testIndex(t, rtree.New(nil), point3)
+	})
+}
@@ -0,0 +1,45 @@
+package rtree
+
+import "sync/atomic"
+
+// Stats are the numbers of operations on a tree since it was created, or
+// since ResetStats.
+type Stats struct {
+	Inserts   uint64
+	Removes   uint64 // only the removes of items that were in the tree
+	Searches  uint64 // searches with a box
+	KNNs      uint64
+	Splits    uint64 // nodes in the 2d and 3d trees that were split by inserts
+	Condenses uint64 // nodes that were emptied or under-filled by removes
+}
+
+// stats are the counters of the operations on the combined tree, which are
+// updated atomically. The splits and condenses are counted by the 2d and 3d
+// trees.
+type stats struct {
+	inserts, removes, searches, knns uint64
+}
+
+// Stats returns the numbers of operations on the tree. A search or KNN is
+// counted once, even though it may search both the 2d and 3d trees.
+func (tr *RTree) Stats() Stats {
+	s2, s3 := tr.tr2.Stats(), tr.tr3.Stats()
+	return Stats{
+		Inserts:   atomic.LoadUint64(&tr.stats.inserts),
+		Removes:   atomic.LoadUint64(&tr.stats.removes),
+		Searches:  atomic.LoadUint64(&tr.stats.searches),
+		KNNs:      atomic.LoadUint64(&tr.stats.knns),
+		Splits:    s2.Splits + s3.Splits,
+		Condenses: s2.Condenses + s3.Condenses,
+	}
+}
+
+// ResetStats sets the numbers of operations to zero.
+func (tr *RTree) ResetStats() {
+	s := tr.stats
+	for _, n := range []*uint64{&s.inserts, &s.removes, &s.searches, &s.knns} {
+		atomic.StoreUint64(n, 0)
+	}
+	tr.tr2.ResetStats()
+	tr.tr3.ResetStats()
+}
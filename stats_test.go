@@ -0,0 +1,38 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestStats(t *testing.T) {
+	tr := New(nil)
+	var items []pair.Pair
+	for i := 0; i < 500; i++ {
+		items = append(items, rand2DPoint(), rand3DPoint())
+	}
+	for _, item := range items {
+		tr.Insert(item)
+	}
+	for _, item := range items[:800] {
+		tr.Remove(item)
+	}
+	tr.Remove(items[0])
+	box := makeBoundsPair2("", -10, -10, 10, 10)
+	for i := 0; i < 5; i++ {
+		tr.Search(box, func(item pair.Pair) bool { return true })
+		tr.KNN(box, func(item pair.Pair, dist float64) bool { return false })
+	}
+	s := tr.Stats()
+	assert.Equal(t, uint64(1000), s.Inserts)
+	assert.Equal(t, uint64(800), s.Removes)
+	assert.Equal(t, uint64(5), s.Searches)
+	assert.Equal(t, uint64(5), s.KNNs)
+	assert.True(t, s.Splits > 100)
+	assert.True(t, s.Condenses > 0)
+
+	tr.ResetStats()
+	assert.Equal(t, Stats{}, tr.Stats())
+}
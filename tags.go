@@ -0,0 +1,144 @@
+package rtree
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/tidwall/pair"
+)
+
+// Tag adds tags to an item that is in the tree. Tags are removed along with
+// the item. With the CopyItems option the item is found by its key and
+// value, as with Remove.
+func (tr *RTree) Tag(item pair.Pair, tags ...string) {
+	tr.checkFrozen()
+	item, ok := tr.owned(item)
+	if !ok {
+		return
+	}
+	if tr.tags == nil {
+		tr.tags = make(map[pair.Pair][]string)
+	}
+	itags := tr.tags[item]
+	for _, tag := range tags {
+		i := sort.SearchStrings(itags, tag)
+		if i < len(itags) && itags[i] == tag {
+			continue
+		}
+		itags = append(itags, "")
+		copy(itags[i+1:], itags[i:])
+		itags[i] = tag
+	}
+	tr.tags[item] = itags
+}
+
+// Untag removes tags from an item.
+func (tr *RTree) Untag(item pair.Pair, tags ...string) {
+	tr.checkFrozen()
+	item, ok := tr.owned(item)
+	if !ok {
+		return
+	}
+	itags := tr.tags[item]
+	for _, tag := range tags {
+		i := sort.SearchStrings(itags, tag)
+		if i < len(itags) && itags[i] == tag {
+			itags = append(itags[:i], itags[i+1:]...)
+		}
+	}
+	if len(itags) == 0 {
+		delete(tr.tags, item)
+	} else {
+		tr.tags[item] = itags
+	}
+}
+
+// Tags returns the sorted tags for an item.
+func (tr *RTree) Tags(item pair.Pair) []string {
+	item, ok := tr.owned(item)
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), tr.tags[item]...)
+}
+
+func (tr *RTree) hasTag(item pair.Pair, tag string) bool {
+	itags := tr.tags[item]
+	i := sort.SearchStrings(itags, tag)
+	return i < len(itags) && itags[i] == tag
+}
+
+type tagTerm struct {
+	tag string
+	not bool
+}
+
+// parseTagExpr parses an expression into groups of terms. An item matches
+// the expression when it matches every term in any one of the groups.
+func parseTagExpr(expr string) [][]tagTerm {
+	var groups [][]tagTerm
+	for _, or := range strings.Split(expr, "|") {
+		var group []tagTerm
+		for _, and := range strings.Split(or, "&") {
+			and = strings.TrimSpace(and)
+			var term tagTerm
+			if strings.HasPrefix(and, "!") {
+				term.not = true
+				and = strings.TrimSpace(and[1:])
+			}
+			if and == "" {
+				continue
+			}
+			term.tag = and
+			group = append(group, term)
+		}
+		if len(group) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// SearchTagged is like Search, but only returns items with tags that match
+// the expression. Tags are combined with "&" for and, "|" for or, and "!"
+// for not, where "&" binds tighter than "|". For example, the expression
+// "restaurant & open | cafe" matches open restaurants and all cafes. An
+// empty expression matches every item.
+func (tr *RTree) SearchTagged(box pair.Pair, expr string, iter func(item pair.Pair) bool) bool {
+	groups := parseTagExpr(expr)
+	if len(groups) == 0 {
+		return tr.Search(box, iter)
+	}
+	return tr.Search(box, func(item pair.Pair) bool {
+		if tr.matchTags(item, groups) {
+			return iter(item)
+		}
+		return true
+	})
+}
+
+// MatchTags returns true if the item's tags match the expression, using the
+// same syntax as SearchTagged.
+func (tr *RTree) MatchTags(item pair.Pair, expr string) bool {
+	groups := parseTagExpr(expr)
+	if len(groups) > 0 {
+		item, _ = tr.owned(item)
+	}
+	return len(groups) == 0 || tr.matchTags(item, groups)
+}
+
+func (tr *RTree) matchTags(item pair.Pair, groups [][]tagTerm) bool {
+	for _, group := range groups {
+		match := true
+		for _, term := range group {
+			if tr.hasTag(item, term.tag) == term.not {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package rtree
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestSearchTagged(t *testing.T) {
+	// with CopyItems the tree stores copies, which are found by key and
+	// value
+	for _, copyItems := range []bool{false, true} {
+		tr := New(&Options{MaxEntries: 9, CopyItems: copyItems})
+		var items []pair.Pair
+		for i := 0; i < 100; i++ {
+			item := makePointPair2(fmt.Sprint(i), float64(i), float64(i))
+			tr.Insert(item)
+			items = append(items, item)
+			switch i % 4 {
+			case 0:
+				tr.Tag(item, "restaurant", "open")
+			case 1:
+				tr.Tag(item, "restaurant")
+			case 2:
+				tr.Tag(item, "cafe", "open")
+			}
+		}
+		assert.Equal(t, []string{"open", "restaurant"}, tr.Tags(items[0]))
+		box := makeBoundsPair2("", 0, 0, 49, 49)
+		count := func(expr string) int {
+			var n int
+			tr.SearchTagged(box, expr, func(item pair.Pair) bool {
+				n++
+				return true
+			})
+			return n
+		}
+		assert.Equal(t, 50, count(""))
+		assert.Equal(t, 26, count("restaurant"))
+		assert.Equal(t, 13, count("restaurant & open"))
+		assert.Equal(t, 13, count("restaurant & !open"))
+		assert.Equal(t, 25, count("restaurant & open | cafe"))
+		assert.Equal(t, 12, count("!restaurant & !cafe"))
+		assert.True(t, tr.MatchTags(items[2], "cafe & open"))
+		assert.False(t, tr.MatchTags(items[3], "cafe | open"))
+		tr.Untag(items[0], "open")
+		assert.Equal(t, 12, count("restaurant & open"))
+		tr.Remove(items[4])
+		assert.Equal(t, 11, count("restaurant & open"))
+		assert.Equal(t, 0, len(tr.Tags(items[4])))
+	}
+}
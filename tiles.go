@@ -0,0 +1,70 @@
+package rtree
+
+import (
+	"math"
+
+	"github.com/tidwall/pair"
+)
+
+// Tile is a slippy map tile, where x and y count from the top left tile at
+// zoom z.
+type Tile struct {
+	X, Y, Z int
+}
+
+type tileHook struct {
+	minZoom, maxZoom int
+	fn               func(op Op, item pair.Pair, tiles []Tile)
+}
+
+// SetTileHook calls fn after every subsequent Insert or Remove with the
+// tiles, from minZoom to maxZoom, that the item touches, which lets a tile
+// cache invalidate only the tiles that changed. The item values are read as
+// lon/lat, before the transformer. A large item at a high zoom touches many
+// tiles, so keep maxZoom to the zooms that are cached. The hook replaces any
+// previous one, and a nil fn removes it.
+func (tr *RTree) SetTileHook(minZoom, maxZoom int,
+	fn func(op Op, item pair.Pair, tiles []Tile)) {
+	if fn == nil {
+		tr.tileHook = nil
+		return
+	}
+	tr.tileHook = &tileHook{minZoom: minZoom, maxZoom: maxZoom, fn: fn}
+}
+
+func (tr *RTree) notifyTiles(op Op, item pair.Pair) {
+	min, max := tr.SourceRect(item)
+	h := tr.tileHook
+	h.fn(op, item, TilesOf(min[0], min[1], max[0], max[1], h.minZoom, h.maxZoom))
+}
+
+// TilesOf returns the tiles, from minZoom to maxZoom, that a lon/lat rect
+// touches.
+func TilesOf(minLon, minLat, maxLon, maxLat float64, minZoom, maxZoom int) []Tile {
+	var tiles []Tile
+	for z := minZoom; z <= maxZoom; z++ {
+		n := 1 << uint(z)
+		x0, x1 := tileCoord(mercX(minLon), n), tileCoord(mercX(maxLon), n)
+		// y grows to the south
+		y0, y1 := tileCoord(mercY(maxLat), n), tileCoord(mercY(minLat), n)
+		for y := y0; y <= y1; y++ {
+			for x := x0; x <= x1; x++ {
+				tiles = append(tiles, Tile{x, y, z})
+			}
+		}
+	}
+	return tiles
+}
+
+// tileCoord returns the tile that a web mercator coordinate, from zero to
+// one, is on when there are n tiles across.
+func tileCoord(v float64, n int) int {
+	t := int(math.Floor(v * float64(n)))
+	if t < 0 {
+		return 0
+	}
+	if t >= n {
+		return n - 1
+	}
+	return t
+}
@@ -0,0 +1,37 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestTilesOf(t *testing.T) {
+	assert.Equal(t, []Tile{{0, 0, 0}}, TilesOf(-180, -85, 180, 85, 0, 0))
+	assert.Equal(t, []Tile{{1, 0, 1}, {1, 1, 1}}, TilesOf(10, -10, 20, 10, 1, 1))
+	assert.Equal(t, []Tile{{0, 1, 1}, {1, 1, 1}}, TilesOf(-10, -20, 10, -10, 1, 1))
+	// london at zoom 10
+	assert.Equal(t, []Tile{{511, 340, 10}}, TilesOf(-0.1276, 51.5072, -0.1276, 51.5072, 10, 10))
+	assert.Equal(t, 3, len(TilesOf(5, 5, 5, 5, 0, 2)))
+}
+
+func TestTileHook(t *testing.T) {
+	tr := New(nil)
+	var ops []Op
+	var tiles [][]Tile
+	tr.SetTileHook(0, 2, func(op Op, item pair.Pair, t []Tile) {
+		ops = append(ops, op)
+		tiles = append(tiles, t)
+	})
+	item := makePointPair2("a", 100, 40)
+	tr.Insert(item)
+	tr.Remove(item)
+	assert.Equal(t, []Op{OpInsert, OpRemove}, ops)
+	expect := []Tile{{0, 0, 0}, {1, 0, 1}, {3, 1, 2}}
+	assert.Equal(t, [][]Tile{expect, expect}, tiles)
+
+	tr.SetTileHook(0, 0, nil)
+	tr.Insert(item)
+	assert.Equal(t, 2, len(ops))
+}
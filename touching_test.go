@@ -0,0 +1,63 @@
+package rtree
+
+import (
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestExcludeTouching(t *testing.T) {
+	for _, exclude := range []bool{false, true} {
+		tr := New(&Options{MaxEntries: 9, ExcludeTouching: exclude})
+		tr.Insert(makeBoundsPair2("a", 0, 0, 1, 1))
+		tr.Insert(makeBoundsPair2("b", 1, 0, 2, 1))
+		tr.Insert(makeBoundsPair3("c", 0, 0, 1, 1, 1, 2))
+		keys := func(box pair.Pair) string {
+			var s string
+			tr.Search(box, func(item pair.Pair) bool {
+				s += string(item.Key())
+				return true
+			})
+			return s
+		}
+		if exclude {
+			// b shares an edge
+			assert.Equal(t, "ac", keys(makeBoundsPair2("", 0, 0, 1, 1)))
+			// the 3d box touches the 2d items at z zero, and c at z one
+			assert.Equal(t, "", keys(makeBoundsPair3("", 0, 0, 0, 1, 1, 1)))
+			assert.Equal(t, "a", keys(makeBoundsPair3("", 0, 0, -1, 1, 1, 1)))
+			assert.False(t, tr.Explain(makeBoundsPair3("", 0, 0, 0, 1, 1, 1)).Search2D)
+		} else {
+			assert.Equal(t, "abc", keys(makeBoundsPair2("", 0, 0, 1, 1)))
+			assert.Equal(t, "abc", keys(makeBoundsPair3("", 0, 0, 0, 1, 1, 1)))
+			assert.True(t, tr.Explain(makeBoundsPair3("", 0, 0, 0, 1, 1, 1)).Search2D)
+		}
+	}
+}
+
+func TestExcludeTouchingPoints(t *testing.T) {
+	// a point has no inside, so it's only found by its own rect when the
+	// touching items are found
+	for _, copyItems := range []bool{false, true} {
+		tr := New(&Options{MaxEntries: 9, ExcludeTouching: true, CopyItems: copyItems})
+		p2 := makePointPair2("a", 1, 2)
+		p3 := makePointPair3("b", 1, 2, 3)
+		tr.Insert(p2)
+		tr.Insert(p3)
+		item, ok := tr.Find(makePointPair2("a", 1, 2))
+		assert.True(t, ok)
+		assert.Equal(t, "a", string(item.Key()))
+		assert.True(t, tr.RemoveOK(p2))
+		tr.Remove(p3)
+		assert.Equal(t, 0, tr.Count())
+	}
+
+	// a follower removes a point that was decoded from a stream
+	follower := New(&Options{MaxEntries: 9, ExcludeTouching: true})
+	item := makePointPair3("a", 1, 2, 3)
+	assert.Nil(t, follower.Apply(Mutation{Seq: 1, Op: OpInsert, Item: item}))
+	decoded := makePointPair3("a", 1, 2, 3)
+	assert.Nil(t, follower.Apply(Mutation{Seq: 2, Op: OpRemove, Item: decoded}))
+	assert.Equal(t, 0, follower.Count())
+}
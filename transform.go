@@ -0,0 +1,36 @@
+package rtree
+
+import (
+	"github.com/tidwall/pair"
+)
+
+// SearchWith is like Search, but the rect of the box is read with the
+// transformer rather than with the transformer of the tree, which allows
+// for a query in other coordinates, such as a lon/lat box against a tree of
+// ECEF points. A nil transformer uses the rect as is.
+func (tr *RTree) SearchWith(box pair.Pair, t func(minIn, maxIn [3]float64) (minOut, maxOut [3]float64),
+	iter func(item pair.Pair) bool) bool {
+	if tr.dedupKeys {
+		iter = dedupIter(iter)
+	}
+	min, max := tr.rectWith(box.Value(), t)
+	return tr.searchRect(tr.dims(box.Value()), min, max, iter)
+}
+
+// KNNWith is like KNN, but the position is the center of the rect of pos
+// after it's transformed by the transformer, rather than the untransformed
+// center.
+func (tr *RTree) KNNWith(pos pair.Pair, t func(minIn, maxIn [3]float64) (minOut, maxOut [3]float64),
+	iter func(item pair.Pair, dist float64) bool) bool {
+	if tr.isEmpty(2) && tr.isEmpty(3) {
+		return true
+	}
+	if tr.geodesic {
+		iter = tr.geodesicIter(pos, iter)
+	}
+	if tr.dedupKeys {
+		iter = dedupKNNIter(iter)
+	}
+	min, max := tr.rectWith(pos.Value(), t)
+	return tr.knnPoint((min[0]+max[0])/2, (min[1]+max[1])/2, (min[2]+max[2])/2, iter)
+}
@@ -0,0 +1,40 @@
+package rtree
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+	rtree3 "github.com/tidwall/pair-rtree/3d"
+)
+
+func TestQueryTransformer(t *testing.T) {
+	opts := *DefaultOptions
+	opts.Transformer = rtree3.TransformLonLatElevToXYZ_Sphere
+	tr := New(&opts)
+	tr.Insert(makePointPair3("a", 10, 10, 0))
+	tr.Insert(makePointPair3("b", 11, 11, 0))
+	tr.Insert(makePointPair3("c", -50, 40, 0))
+
+	// a box of ECEF coordinates, around the first two points
+	min, max := rtree3.TransformLonLatElevToXYZ_Sphere([3]float64{9, 9, 0}, [3]float64{12, 12, 0})
+	box := makeBoundsPair3("", min[0], min[1], min[2], max[0], max[1], max[2])
+	var keys []string
+	tr.SearchWith(box, nil, func(item pair.Pair) bool {
+		keys = append(keys, string(item.Key()))
+		return true
+	})
+	sort.Strings(keys)
+	assert.Equal(t, []string{"a", "b"}, keys)
+
+	// a lon/lat position against the ECEF tree
+	keys = nil
+	tr.KNNWith(makePointPair3("", -49, 41, 0), rtree3.TransformLonLatElevToXYZ_Sphere,
+		func(item pair.Pair, dist float64) bool {
+			keys = append(keys, string(item.Key()))
+			return true
+		})
+	assert.Equal(t, 3, len(keys))
+	assert.Equal(t, "c", keys[0])
+}
@@ -0,0 +1,49 @@
+package rtree
+
+import (
+	"errors"
+	"math"
+
+	"github.com/tidwall/pair"
+)
+
+var (
+	// ErrWrongDims is returned when an item is not 2d or 3d.
+	ErrWrongDims = errors.New("item is not 2d or 3d")
+	// ErrNotFound is returned when an item to remove is not in the tree.
+	ErrNotFound = errors.New("item not found")
+)
+
+// TryInsert is like Insert, but returns an error rather than inserting an
+// item that can't be searched, or panicking. Returns ErrFrozen for a frozen
+// tree, ErrWrongDims for an item that is not 2d or 3d, and ErrInvalidRect
+// for an item with a NaN coordinate.
+func (tr *RTree) TryInsert(item pair.Pair) error {
+	if tr.frozen {
+		return ErrFrozen
+	}
+	dims := tr.dims(item.Value())
+	if dims != 2 && dims != 3 {
+		return ErrWrongDims
+	}
+	min, max := tr.rect(item.Value())
+	for i := 0; i < dims; i++ {
+		if math.IsNaN(min[i]) || math.IsNaN(max[i]) {
+			return ErrInvalidRect
+		}
+	}
+	tr.Insert(item)
+	return nil
+}
+
+// TryRemove is like Remove, but returns ErrFrozen for a frozen tree rather
+// than panicking, and ErrNotFound when the item isn't in the tree.
+func (tr *RTree) TryRemove(item pair.Pair) error {
+	if tr.frozen {
+		return ErrFrozen
+	}
+	if !tr.RemoveOK(item) {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package rtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestTryInsertRemove(t *testing.T) {
+	tr := New(nil)
+	item := makePointPair2("a", 1, 2)
+	assert.Equal(t, nil, tr.TryInsert(item))
+	assert.Equal(t, ErrInvalidRect, tr.TryInsert(makePointPair2("b", math.NaN(), 2)))
+	assert.Equal(t, nil, tr.TryRemove(item))
+	assert.Equal(t, ErrNotFound, tr.TryRemove(item))
+	assert.Equal(t, 0, tr.Count())
+
+	tr = New(&Options{MaxEntries: 9, RectFunc: func(value []byte) (min, max [3]float64, dims int) {
+		return min, max, len(value)
+	}})
+	assert.Equal(t, ErrWrongDims, tr.TryInsert(pair.New(nil, []byte("x"))))
+	assert.Equal(t, nil, tr.TryInsert(pair.New(nil, []byte("xyz"))))
+	assert.Equal(t, 1, tr.Count3D())
+
+	tr.Freeze()
+	assert.Equal(t, ErrFrozen, tr.TryInsert(item))
+	assert.Equal(t, ErrFrozen, tr.TryRemove(item))
+}
+
+func TestCheck(t *testing.T) {
+	tr := New(&Options{MaxEntries: 9, KeyIndex: true})
+	assert.Equal(t, nil, tr.Check())
+	for i := 0; i < 500; i++ {
+		tr.Insert(rand2DPoint())
+		tr.Insert(rand3DPoint())
+	}
+	assert.Equal(t, nil, tr.Check())
+
+	// a 2d item that's changed to 3d after it was inserted
+	item := makeBoundsPair2("", 0, 0, 1, 1)
+	tr.Insert(item)
+	assert.Equal(t, nil, tr.Check())
+	copy(item.Value(), makePointPair3("", 1, 2, 3).Value())
+	assert.Equal(t, ErrCorrupt, tr.Check())
+	_, err := tr.Repair()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, tr.Check())
+}
@@ -0,0 +1,120 @@
+package rtree
+
+import (
+	"math"
+
+	"github.com/tidwall/pair"
+)
+
+const (
+	// tileSize is the width and height of a web map tile in pixels.
+	tileSize = 256
+	// viewportCell is the size in pixels of the grid cells that thin out the
+	// results of QueryViewport.
+	viewportCell = 4
+)
+
+// ViewportItem is an item returned by QueryViewport, along with its position
+// on the screen in pixels from the top left.
+type ViewportItem struct {
+	Item pair.Pair
+	X, Y float64
+}
+
+// QueryViewport returns the items that are on a web mercator map screen
+// that's centered on the lon/lat at the zoom, where the world is 256 pixels
+// wide at zoom zero. The item values are read as lon/lat, and the query goes
+// through the transformer of the tree. A screen that crosses the
+// antimeridian finds the items on both sides. The results are thinned to
+// the first item in each 4x4 pixel cell, so dense areas don't return more
+// items than can be drawn.
+func (tr *RTree) QueryViewport(centerLon, centerLat, zoom float64,
+	screenW, screenH int) []ViewportItem {
+	world := tileSize * math.Pow(2, zoom)
+	cx, cy := mercX(centerLon), mercY(centerLat)
+	halfW := float64(screenW) / 2 / world
+	halfH := float64(screenH) / 2 / world
+	minY := math.Max(0, cy-halfH)
+	maxY := math.Min(1, cy+halfH)
+	if minY >= maxY {
+		return nil
+	}
+	minLat, maxLat := mercLat(maxY), mercLat(minY)
+	var boxes [][2][2]float64
+	if halfW >= 0.5 {
+		boxes = append(boxes, [2][2]float64{{-180, minLat}, {180, maxLat}})
+	} else {
+		minX, maxX := cx-halfW, cx+halfW
+		switch {
+		case minX < 0:
+			boxes = append(boxes,
+				[2][2]float64{{mercLon(minX + 1), minLat}, {180, maxLat}},
+				[2][2]float64{{-180, minLat}, {mercLon(maxX), maxLat}})
+		case maxX > 1:
+			boxes = append(boxes,
+				[2][2]float64{{mercLon(minX), minLat}, {180, maxLat}},
+				[2][2]float64{{-180, minLat}, {mercLon(maxX - 1), maxLat}})
+		default:
+			boxes = append(boxes,
+				[2][2]float64{{mercLon(minX), minLat}, {mercLon(maxX), maxLat}})
+		}
+	}
+	var items []ViewportItem
+	seen := make(map[pair.Pair]bool)
+	cells := make(map[[2]int]bool)
+	for _, box := range boxes {
+		tr.searchLonLat(box[0], box[1], func(item pair.Pair) bool {
+			if seen[item] {
+				return true
+			}
+			seen[item] = true
+			lon, lat, _ := tr.position(item.Value())
+			dx := mercX(lon) - cx
+			if halfW < 0.5 {
+				// take the copy of the item that's nearest to the center
+				dx -= math.Round(dx)
+			}
+			x := dx*world + float64(screenW)/2
+			y := (mercY(lat)-cy)*world + float64(screenH)/2
+			cell := [2]int{int(math.Floor(x / viewportCell)), int(math.Floor(y / viewportCell))}
+			if cells[cell] {
+				return true
+			}
+			cells[cell] = true
+			items = append(items, ViewportItem{item, x, y})
+			return true
+		})
+	}
+	return items
+}
+
+// searchLonLat searches with a lon/lat rect that's transformed like the
+// rect of an item.
+func (tr *RTree) searchLonLat(min, max [2]float64, iter func(item pair.Pair) bool) bool {
+	min3, max3 := [3]float64{min[0], min[1], 0}, [3]float64{max[0], max[1], 0}
+	if tr.t != nil {
+		min3, max3 = tr.t(min3, max3)
+	}
+	min3, max3 = tr.snap(min3, max3)
+	return tr.searchRect(2, min3, max3, iter)
+}
+
+// mercX and mercY project a lon/lat to web mercator, where the world is
+// one by one and y grows to the south. The poles are clamped.
+func mercX(lon float64) float64 {
+	return lon/360 + 0.5
+}
+
+func mercY(lat float64) float64 {
+	sin := math.Sin(lat * math.Pi / 180)
+	y := 0.5 - 0.25*math.Log((1+sin)/(1-sin))/math.Pi
+	return math.Max(0, math.Min(1, y))
+}
+
+func mercLon(x float64) float64 {
+	return (x - 0.5) * 360
+}
+
+func mercLat(y float64) float64 {
+	return 360*math.Atan(math.Exp((180-y*360)*math.Pi/180))/math.Pi - 90
+}
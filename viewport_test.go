@@ -0,0 +1,57 @@
+package rtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+)
+
+func TestQueryViewport(t *testing.T) {
+	tr := New(nil)
+	for lon := -180.0; lon < 180; lon += 10 {
+		for lat := -80.0; lat <= 80; lat += 10 {
+			tr.Insert(makePointPair2("", lon, lat))
+			tr.Insert(makePointPair2("", lon+1, lat))
+		}
+	}
+	// the whole world at zoom zero, thinned to one item per cell
+	items := tr.QueryViewport(0, 0, 0, 256, 256)
+	assert.True(t, len(items) > 0 && len(items) < tr.Count())
+	for _, it := range items {
+		assert.True(t, it.X >= 0 && it.X <= 256 && it.Y >= 0 && it.Y <= 256)
+	}
+
+	// a larger world has room for every item
+	assert.Equal(t, tr.Count(), len(tr.QueryViewport(0, 0, 4, 4096, 4096)))
+
+	// the screen is centered on the item
+	items = tr.QueryViewport(10, 20, 7, 100, 100)
+	assert.Equal(t, 1, len(items))
+	x, y, _ := tr.position(items[0].Item.Value())
+	assert.Equal(t, [2]float64{10, 20}, [2]float64{x, y})
+	assert.True(t, math.Abs(items[0].X-50) < 1e-6 && math.Abs(items[0].Y-50) < 1e-6)
+
+	// crossing the antimeridian
+	tr = New(nil)
+	tr.Insert(makePointPair2("a", 175, 0))
+	tr.Insert(makePointPair2("b", -175, 0))
+	tr.Insert(makePointPair2("c", 0, 0))
+	items = tr.QueryViewport(180, 0, 3, 256, 256)
+	assert.Equal(t, 2, len(items))
+	for _, it := range items {
+		lon, _, _ := tr.position(it.Item.Value())
+		if lon > 0 {
+			assert.True(t, it.X < 128)
+		} else {
+			assert.True(t, it.X > 128)
+		}
+	}
+
+	// identical items are thinned to one
+	tr = New(nil)
+	for i := 0; i < 100; i++ {
+		tr.Insert(makePointPair2("", 50, 50))
+	}
+	assert.Equal(t, 1, len(tr.QueryViewport(50, 50, 10, 512, 512)))
+}
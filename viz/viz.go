@@ -0,0 +1,477 @@
+// Package viz renders 2d and 3d rtrees to png images and animated gifs.
+// It operates on a tree's Traverse method, which keeps the image and
+// pinhole dependencies out of the core tree packages.
+package viz
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	"github.com/tidwall/pinhole"
+)
+
+// Traverser calls iter for every node and item in a tree, in the same way as
+// the Traverse method of the 3d tree. Items have a level of zero.
+type Traverser func(iter func(min, max [3]float64, level int, item pair.Pair) bool)
+
+// Tree2D is implemented by the 2d rtree.
+type Tree2D interface {
+	Traverse(iter func(min, max [2]float64, level int, item pair.Pair) bool)
+}
+
+// Tree3D is implemented by the 3d rtree.
+type Tree3D interface {
+	Traverse(iter func(min, max [3]float64, level int, item pair.Pair) bool)
+}
+
+// From2D returns a Traverser for a 2d tree. The z coordinates are zero.
+func From2D(tr Tree2D) Traverser {
+	return func(iter func(min, max [3]float64, level int, item pair.Pair) bool) {
+		tr.Traverse(func(min, max [2]float64, level int, item pair.Pair) bool {
+			return iter([3]float64{min[0], min[1], 0}, [3]float64{max[0], max[1], 0}, level, item)
+		})
+	}
+}
+
+// From3D returns a Traverser for a 3d tree.
+func From3D(tr Tree3D) Traverser {
+	return tr.Traverse
+}
+
+type Options struct {
+	Scale       float64       // scale applied to the tree coordinates
+	Center      bool          // center the tree in the image
+	LineWidth   float64       // width of node lines
+	Background  color.Color   // image background
+	ItemColor   color.Color   // color of item dots
+	ItemSize    float64       // radius of item dots
+	LevelColors []color.Color // node colors by level, starting at level 1
+	NodeColor   color.Color   // node color for levels past LevelColors
+	ShowNodes   bool          // draw the node boxes
+	ShowItems   bool          // draw the items
+	GIF         bool          // also write a rotating animated gif
+	Frames      int           // number of gif frames
+	Rotation    [3]float64    // rotation per gif frame along x, y, z
+	Delay       int           // delay per gif frame in 100ths of a second
+
+	// CameraPath, when set, returns the absolute rotation along x, y, z for
+	// each gif frame and is used instead of Rotation.
+	CameraPath func(frame, frames int) (rx, ry, rz float64)
+	// Progress, when set, is called after each gif frame is rendered with
+	// the percent complete, and replaces the per-frame messages written to
+	// the printer.
+	Progress func(percent float64, frame, frames int)
+
+	// Queries are search boxes to overlay onto the render. Nodes that the
+	// queries touch and items that they match are highlighted. The queries
+	// are geobin values and are converted with the Transformer, which should
+	// be the same as the tree's.
+	Queries      []pair.Pair
+	Transformer  func(minIn, maxIn [3]float64) (minOut, maxOut [3]float64)
+	QueryColor   color.Color // color of the query boxes
+	TouchedColor color.Color // color of nodes touched by the queries
+	MatchColor   color.Color // color of items matched by the queries
+}
+
+// DefaultOptions are suited for 3d trees.
+var DefaultOptions = &Options{
+	Scale:      1,
+	Center:     true,
+	LineWidth:  0.045,
+	Background: color.Black,
+	ItemColor:  color.White,
+	ItemSize:   0.04,
+	LevelColors: []color.Color{
+		color.RGBA{32, 64, 32, 64},
+		color.RGBA{48, 48, 96, 96},
+		color.RGBA{96, 128, 128, 128},
+		color.RGBA{128, 128, 196, 196},
+	},
+	NodeColor: color.RGBA{96, 96, 96, 128},
+	ShowNodes: true,
+	ShowItems: true,
+	GIF:       false,
+	Frames:    60,
+	Rotation:  [3]float64{0, math.Pi * 2 / 60.0, 0},
+	Delay:     0,
+
+	QueryColor:   color.RGBA{255, 64, 64, 255},
+	TouchedColor: color.RGBA{196, 128, 32, 196},
+	MatchColor:   color.RGBA{255, 255, 0, 255},
+}
+
+// Default2DOptions are suited for 2d trees.
+var Default2DOptions = func() *Options {
+	opts := *DefaultOptions
+	opts.Center = false
+	opts.LineWidth = 0.025
+	opts.ItemSize = 0.05
+	opts.NodeColor = color.RGBA{64, 64, 64, 128}
+	return &opts
+}()
+
+type box struct {
+	min, max [3]float64
+}
+
+func (a box) intersects(b box) bool {
+	return b.min[0] <= a.max[0] && b.min[1] <= a.max[1] && b.min[2] <= a.max[2] &&
+		b.max[0] >= a.min[0] && b.max[1] >= a.min[1] && b.max[2] >= a.min[2]
+}
+
+// draw3D adds the tree to a new pinhole. The overlay, if any, is drawn on top
+// of the tree before the pinhole is scaled.
+func draw3D(tr Traverser, opts *Options, overlay func(p *pinhole.Pinhole)) *pinhole.Pinhole {
+	p := pinhole.New()
+	queries := make([]box, len(opts.Queries))
+	for i, query := range opts.Queries {
+		queries[i].min, queries[i].max = geobin.WrapBinary(query.Value()).Rect(opts.Transformer)
+	}
+	queried := func(min, max [3]float64) bool {
+		for _, query := range queries {
+			if query.intersects(box{min, max}) {
+				return true
+			}
+		}
+		return false
+	}
+	tr(func(min, max [3]float64, level int, item pair.Pair) bool {
+		if level > 0 {
+			if !opts.ShowNodes {
+				return true
+			}
+			p.Begin()
+			p.DrawCube(min[0], min[1], min[2], max[0], max[1], max[2])
+			if queried(min, max) {
+				p.Colorize(opts.TouchedColor)
+			} else if level-1 < len(opts.LevelColors) {
+				p.Colorize(opts.LevelColors[level-1])
+			} else {
+				p.Colorize(opts.NodeColor)
+			}
+			p.End()
+		} else if opts.ShowItems {
+			p.Begin()
+			p.DrawDot(min[0], min[1], min[2], opts.ItemSize)
+			if queried(min, max) {
+				p.Colorize(opts.MatchColor)
+			} else {
+				p.Colorize(opts.ItemColor)
+			}
+			p.End()
+		}
+		return true
+	})
+	for _, query := range queries {
+		p.Begin()
+		p.DrawCube(query.min[0], query.min[1], query.min[2], query.max[0], query.max[1], query.max[2])
+		p.Colorize(opts.QueryColor)
+		p.End()
+	}
+	if overlay != nil {
+		overlay(p)
+	}
+	if opts.Center {
+		p.Center()
+	}
+	p.Scale(opts.Scale, opts.Scale, opts.Scale)
+	return p
+}
+
+func imageOptions(opts *Options) *pinhole.ImageOptions {
+	iopts := *pinhole.DefaultImageOptions
+	iopts.LineWidth = opts.LineWidth
+	iopts.BGColor = opts.Background
+	return &iopts
+}
+
+// RenderImage renders the tree to an image.
+func RenderImage(tr Traverser, width, height int, opts *Options) image.Image {
+	if opts == nil {
+		opts = DefaultOptions
+	}
+	return draw3D(tr, opts, nil).Image(width, height, imageOptions(opts))
+}
+
+// SavePNG renders the tree to a png file. When opts.GIF is set, a rotating
+// animated gif is also written to the same path with a .gif extension.
+func SavePNG(tr Traverser, path string, width, height int, opts *Options, printer io.Writer) error {
+	return SavePNGContext(context.Background(), tr, path, width, height, opts, printer)
+}
+
+// SavePNGContext is like SavePNG, but the render stops with the context
+// error when the context is done. The context is checked between gif
+// frames, and no gif file is written for an aborted render.
+func SavePNGContext(ctx context.Context, tr Traverser, path string, width, height int, opts *Options, printer io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if opts == nil {
+		opts = DefaultOptions
+	}
+	p := draw3D(tr, opts, nil)
+	iopts := imageOptions(opts)
+	if err := p.SavePNG(path, width, height, iopts); err != nil {
+		return err
+	}
+	if printer != nil {
+		fmt.Fprintf(printer, "wrote %s\n", path)
+	}
+	if opts.GIF {
+		outGif := &gif.GIF{}
+		var rot [3]float64
+		for i := 0; i < opts.Frames; i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if opts.CameraPath != nil {
+				rx, ry, rz := opts.CameraPath(i, opts.Frames)
+				p.Rotate(rx-rot[0], ry-rot[1], rz-rot[2])
+				rot = [3]float64{rx, ry, rz}
+			} else {
+				p.Rotate(opts.Rotation[0], opts.Rotation[1], opts.Rotation[2])
+			}
+			addFrame(outGif, p.Image(width, height, iopts), opts.Delay)
+			progress(opts, printer, i, opts.Frames)
+		}
+		if strings.HasSuffix(path, ".png") {
+			path = path[:len(path)-4] + ".gif"
+		}
+		if err := writeGIF(path, outGif); err != nil {
+			return err
+		}
+		if printer != nil {
+			fmt.Fprintf(printer, "wrote %s\n", path)
+		}
+	}
+	return nil
+}
+
+func addFrame(g *gif.GIF, img image.Image, delay int) {
+	frame := image.NewPaletted(img.Bounds(), palette.WebSafe)
+	draw.Draw(frame, img.Bounds(), img, image.Point{}, draw.Src)
+	g.Image = append(g.Image, frame)
+	g.Delay = append(g.Delay, delay)
+}
+
+func progress(opts *Options, printer io.Writer, frame, frames int) {
+	if opts.Progress != nil {
+		opts.Progress(float64(frame+1)/float64(frames)*100, frame, frames)
+	} else if printer != nil {
+		fmt.Fprintf(printer, "wrote gif frame %d/%d\n", frame, frames)
+	}
+}
+
+func writeGIF(path string, g *gif.GIF) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gif.EncodeAll(f, g)
+}
+
+// node is a tree node or item rebuilt from a Traverser.
+type node struct {
+	box
+	level    int
+	children []*node
+}
+
+// rebuild reconstructs the tree structure from the traversal order, where
+// every node is followed by its children.
+func rebuild(tr Traverser) *node {
+	var root *node
+	var stack []*node
+	tr(func(min, max [3]float64, level int, item pair.Pair) bool {
+		n := &node{box: box{min, max}, level: level}
+		if root == nil {
+			root = n
+		} else {
+			for stack[len(stack)-1].level <= level {
+				stack = stack[:len(stack)-1]
+			}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, n)
+		}
+		if level > 0 {
+			stack = append(stack, n)
+		}
+		return true
+	})
+	return root
+}
+
+func boxDist(x, y, z float64, b box) float64 {
+	dx := axisDist(x, b.min[0], b.max[0])
+	dy := axisDist(y, b.min[1], b.max[1])
+	dz := axisDist(z, b.min[2], b.max[2])
+	return dx*dx + dy*dy + dz*dz
+}
+
+func axisDist(k, min, max float64) float64 {
+	if k < min {
+		return min - k
+	}
+	if k <= max {
+		return 0
+	}
+	return k - max
+}
+
+type queueItem struct {
+	node *node
+	dist float64
+}
+
+type queue []queueItem
+
+func (q queue) Len() int            { return len(q) }
+func (q queue) Less(i, j int) bool  { return q[i].dist < q[j].dist }
+func (q queue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *queue) Push(x interface{}) { *q = append(*q, x.(queueItem)) }
+func (q *queue) Pop() interface{} {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
+
+// knnSteps returns the nodes and items in the order that a KNN query visits
+// them, stopping after n items.
+func knnSteps(root *node, x, y, z float64, n int) []*node {
+	var steps []*node
+	var q queue
+	for nd := root; nd != nil && n > 0; {
+		steps = append(steps, nd)
+		for _, child := range nd.children {
+			heap.Push(&q, queueItem{child, boxDist(x, y, z, child.box)})
+		}
+		for n > 0 && len(q) > 0 && q[0].node.level == 0 {
+			steps = append(steps, heap.Pop(&q).(queueItem).node)
+			n--
+		}
+		nd = nil
+		if len(q) > 0 {
+			nd = heap.Pop(&q).(queueItem).node
+		}
+	}
+	return steps
+}
+
+// SaveKNNGIF writes an animated gif that shows the order in which a KNN
+// query from the point visits nodes and returns items, one frame per step.
+// The query stops after n items. Visited nodes are drawn with
+// opts.TouchedColor, returned items with opts.MatchColor, and the point with
+// opts.QueryColor. The point is in the same coordinates as the tree, with a
+// z of zero for 2d trees.
+func SaveKNNGIF(tr Traverser, path string, width, height int, x, y, z float64, n int, opts *Options, printer io.Writer) error {
+	if opts == nil {
+		opts = DefaultOptions
+	}
+	var steps []*node
+	if root := rebuild(tr); root != nil {
+		steps = knnSteps(root, x, y, z, n)
+	}
+	iopts := imageOptions(opts)
+	outGif := &gif.GIF{}
+	for i := 0; i <= len(steps); i++ {
+		p := draw3D(tr, opts, func(p *pinhole.Pinhole) {
+			for _, step := range steps[:i] {
+				p.Begin()
+				if step.level == 0 {
+					p.DrawDot(step.min[0], step.min[1], step.min[2], opts.ItemSize*2)
+					p.Colorize(opts.MatchColor)
+				} else {
+					p.DrawCube(step.min[0], step.min[1], step.min[2],
+						step.max[0], step.max[1], step.max[2])
+					p.Colorize(opts.TouchedColor)
+				}
+				p.End()
+			}
+			p.Begin()
+			p.DrawDot(x, y, z, opts.ItemSize*2)
+			p.Colorize(opts.QueryColor)
+			p.End()
+		})
+		addFrame(outGif, p.Image(width, height, iopts), opts.Delay)
+		progress(opts, printer, i, len(steps)+1)
+	}
+	if err := writeGIF(path, outGif); err != nil {
+		return err
+	}
+	if printer != nil {
+		fmt.Fprintf(printer, "wrote %s\n", path)
+	}
+	return nil
+}
+
+// SaveSlicePNG renders the cross-section of the tree along a plane to a png
+// file. The plane is perpendicular to the axis (0=x, 1=y, 2=z) at the value,
+// and only the nodes and items that intersect it are drawn, using the
+// remaining two axes for the image.
+func SaveSlicePNG(tr Traverser, path string, width, height int, axis int, value float64, opts *Options, printer io.Writer) error {
+	if opts == nil {
+		opts = DefaultOptions
+	}
+	var u, v int
+	switch axis {
+	case 0:
+		u, v = 1, 2
+	case 1:
+		u, v = 0, 2
+	default:
+		axis, u, v = 2, 0, 1
+	}
+	p := pinhole.New()
+	tr(func(min, max [3]float64, level int, item pair.Pair) bool {
+		if value < min[axis] || value > max[axis] {
+			return true
+		}
+		if level > 0 {
+			if opts.ShowNodes {
+				p.Begin()
+				p.DrawCube(min[u], min[v], 0, max[u], max[v], 0)
+				if level-1 < len(opts.LevelColors) {
+					p.Colorize(opts.LevelColors[level-1])
+				} else {
+					p.Colorize(opts.NodeColor)
+				}
+				p.End()
+			}
+		} else if opts.ShowItems {
+			p.Begin()
+			if min[u] == max[u] && min[v] == max[v] {
+				p.DrawDot(min[u], min[v], 0, opts.ItemSize)
+			} else {
+				p.DrawCube(min[u], min[v], 0, max[u], max[v], 0)
+			}
+			p.Colorize(opts.ItemColor)
+			p.End()
+		}
+		return true
+	})
+	if opts.Center {
+		p.Center()
+	}
+	p.Scale(opts.Scale, opts.Scale, opts.Scale)
+	if err := p.SavePNG(path, width, height, imageOptions(opts)); err != nil {
+		return err
+	}
+	if printer != nil {
+		fmt.Fprintf(printer, "wrote %s\n", path)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package viz
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/geobin"
+	"github.com/tidwall/pair"
+	rtree "github.com/tidwall/pair-rtree/3d"
+)
+
+func TestKNNSteps(t *testing.T) {
+	tr := rtree.New(nil)
+	for i := 0; i < 1000; i++ {
+		x, y, z := rand.Float64()*100, rand.Float64()*100, rand.Float64()*100
+		tr.Insert(pair.New(nil, geobin.Make3DPoint(x, y, z).Binary()))
+	}
+	root := rebuild(From3D(tr))
+	var count int
+	From3D(tr)(func(min, max [3]float64, level int, item pair.Pair) bool {
+		count++
+		return true
+	})
+	var walk func(n *node) int
+	walk = func(n *node) int {
+		c := 1
+		for _, child := range n.children {
+			assert.True(t, child.level < n.level)
+			c += walk(child)
+		}
+		return c
+	}
+	assert.Equal(t, count, walk(root))
+
+	var dists []float64
+	tr.KNN(50, 50, 50, func(item pair.Pair, dist float64) bool {
+		dists = append(dists, dist)
+		return len(dists) < 10
+	})
+	var items []float64
+	for _, step := range knnSteps(root, 50, 50, 50, 10) {
+		if step.level == 0 {
+			items = append(items, boxDist(50, 50, 50, step.box))
+		}
+	}
+	assert.Equal(t, dists, items)
+}
@@ -0,0 +1,69 @@
+package rtree
+
+import (
+	"math"
+
+	"github.com/tidwall/pair"
+)
+
+type EventType int
+
+const (
+	Enter EventType = iota // item was inserted into the watched area
+	Leave                  // item was removed from the watched area
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Enter:
+		return "enter"
+	case Leave:
+		return "leave"
+	}
+	return "unknown"
+}
+
+type Event struct {
+	Type EventType
+	Item pair.Pair
+}
+
+type watcher struct {
+	min, max [3]float64
+	ch       chan<- Event
+}
+
+// Watch sends an event to the channel for every subsequent Insert or Remove
+// of an item that intersects the bbox. The box is matched using the same
+// rules as Search. Events are sent synchronously from the mutating call, so
+// the channel should be buffered or drained from another goroutine.
+func (tr *RTree) Watch(bbox pair.Pair, ch chan<- Event) {
+	min, max := tr.rect(bbox.Value())
+	if tr.dims(bbox.Value()) == 2 {
+		min[2], max[2] = math.Inf(-1), math.Inf(+1)
+	}
+	tr.watchers = append(tr.watchers, &watcher{min: min, max: max, ch: ch})
+}
+
+// Unwatch stops all watches that send to the channel.
+func (tr *RTree) Unwatch(ch chan<- Event) {
+	watchers := tr.watchers[:0]
+	for _, w := range tr.watchers {
+		if w.ch != ch {
+			watchers = append(watchers, w)
+		}
+	}
+	for i := len(watchers); i < len(tr.watchers); i++ {
+		tr.watchers[i] = nil
+	}
+	tr.watchers = watchers
+}
+
+func (tr *RTree) notify(typ EventType, item pair.Pair) {
+	min, max := tr.rect(item.Value())
+	for _, w := range tr.watchers {
+		if tr.intersects(min, max, w.min, w.max) {
+			w.ch <- Event{Type: typ, Item: item}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package rtree
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/json-iterator/go/assert"
+	"github.com/tidwall/pair"
+)
+
+func TestWatch(t *testing.T) {
+	tr := New(nil)
+	ch := make(chan Event, 16)
+	tr.Watch(makeBoundsPair2("", -10, -10, 10, 10), ch)
+	p1 := makePointPair2("p1", 5, 5)
+	p2 := makePointPair3("p2", 1, 1, 1000)
+	p3 := makePointPair2("p3", 50, 50)
+	tr.Insert(p1)
+	tr.Insert(p2)
+	tr.Insert(p3)
+	tr.Remove(p1)
+	assert.Equal(t, 3, len(ch))
+	assert.Equal(t, Event{Enter, p1}, <-ch)
+	assert.Equal(t, Event{Enter, p2}, <-ch)
+	assert.Equal(t, Event{Leave, p1}, <-ch)
+
+	// 3d boxes only match 2d items when the z range includes zero
+	ch3 := make(chan Event, 16)
+	tr.Watch(makeBoundsPair3("", -10, -10, 500, 10, 10, 1500), ch3)
+	tr.Insert(p1)
+	tr.Remove(p2)
+	assert.Equal(t, 1, len(ch3))
+	assert.Equal(t, Event{Leave, p2}, <-ch3)
+
+	tr.Unwatch(ch)
+	tr.Unwatch(ch3)
+	for len(ch) > 0 {
+		<-ch
+	}
+	tr.Insert(p2)
+	assert.Equal(t, 0, len(ch))
+	assert.Equal(t, 0, len(ch3))
+}
+
+func TestWatchExcludeTouching(t *testing.T) {
+	// the watchers see the same items as Search
+	tr := New(&Options{MaxEntries: 9, ExcludeTouching: true})
+	boxes := []pair.Pair{
+		makeBoundsPair2("", 0, 0, 1, 1),
+		makeBoundsPair3("", 0, 0, 0, 1, 1, 1),
+		makeBoundsPair3("", 0, 0, -1, 1, 1, 1),
+	}
+	var chs []chan Event
+	for _, box := range boxes {
+		ch := make(chan Event, 16)
+		tr.Watch(box, ch)
+		chs = append(chs, ch)
+	}
+	items := []pair.Pair{
+		makeBoundsPair2("a", 1, 0, 2, 1),
+		makePointPair2("b", 0.5, 0.5),
+		makePointPair2("c", 1, 0.5),
+		makePointPair3("d", 0.5, 0.5, 1),
+		makePointPair3("e", 0.5, 0.5, 0.5),
+	}
+	for _, item := range items {
+		tr.Insert(item)
+	}
+	for i, box := range boxes {
+		var expect []string
+		tr.Search(box, func(item pair.Pair) bool {
+			expect = append(expect, string(item.Key()))
+			return true
+		})
+		var got []string
+		for len(chs[i]) > 0 {
+			got = append(got, string((<-chs[i]).Item.Key()))
+		}
+		sort.Strings(expect)
+		assert.Equal(t, expect, got)
+	}
+}